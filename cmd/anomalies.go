@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	anomalyOpts     = fleet.DefaultAnomalyOptions()
+	anomalyBaseline time.Duration
+	anomalyDropPct  float64
+	anomalyRejPct   float64
+)
+
+var anomaliesCmd = &cobra.Command{
+	Use:   "anomalies",
+	Short: "Flag degrading miners against their own history and their peers",
+	Long: `Judge each miner's recent samples (--window) from the history store against
+its own baseline (the --baseline period before the window) and against the
+median hashrate of the miners of the same model. Flags sustained hashrate
+drops, rising hardware error rates, reject/stale spikes and temperature
+drift, each with a score; a miner's severity score is their sum, up to 100.
+
+With -i the miners are polled first and the fresh samples are recorded into
+the history, so anomalies works both on its own and after history record.
+
+Examples:
+  miner-cli anomalies -i 192.168.1.0/24
+  miner-cli anomalies --window 2h --baseline 72h
+  miner-cli anomalies -i 10.0.0.0/22 --drop 5 --temp-drift 8 -o json`,
+	// without -i only the history is analyzed
+	PersistentPreRunE: func(c *cobra.Command, args []string) error {
+		if len(ipRanges) == 0 {
+			return nil
+		}
+		return fleet.ValidateFirmware(firmware, fleet.FirmwareAuto, fleet.FirmwareCGMiner, fleet.FirmwareBraiins, fleet.FirmwareVnish)
+	},
+	RunE: runAnomalies,
+}
+
+func init() {
+	anomaliesCmd.Flags().StringVar(&historyDir, "history-dir", fleet.DefaultHistoryDir(), "History store directory")
+	anomaliesCmd.Flags().DurationVar(&anomalyOpts.Window, "window", anomalyOpts.Window, "Recent period to judge")
+	anomaliesCmd.Flags().DurationVar(&anomalyBaseline, "baseline", 7*24*time.Hour, "Period before the window that sets each miner's baseline")
+	anomaliesCmd.Flags().Float64Var(&anomalyDropPct, "drop", anomalyOpts.Drop*100, "Hashrate drop in percent below baseline or peers to flag")
+	anomaliesCmd.Flags().Float64Var(&anomalyOpts.ErrorFactor, "error-factor", anomalyOpts.ErrorFactor, "Hardware error rate multiple of the baseline to flag")
+	anomaliesCmd.Flags().Float64Var(&anomalyRejPct, "reject-rate", anomalyOpts.RejectRate*100, "Rejected and stale shares in percent to flag")
+	anomaliesCmd.Flags().Float64Var(&anomalyOpts.TempDrift, "temp-drift", anomalyOpts.TempDrift, "Degrees Celsius above baseline to flag")
+	anomaliesCmd.Flags().IntVar(&anomalyOpts.MinPeers, "min-peers", anomalyOpts.MinPeers, "Miners of a model needed to compare against peers")
+
+	rootCmd.AddCommand(anomaliesCmd)
+}
+
+func runAnomalies(cmd *cobra.Command, args []string) error {
+	if anomalyDropPct <= 0 || anomalyDropPct >= 100 {
+		return fmt.Errorf("--drop must be between 0 and 100")
+	}
+	if anomalyOpts.Window <= 0 {
+		return fmt.Errorf("--window must be positive")
+	}
+	a := anomalyOpts
+	a.Drop = anomalyDropPct / 100
+	a.RejectRate = anomalyRejPct / 100
+
+	store := fleet.NewHistoryStore(historyDir)
+	q := fleet.HistoryQuery{Since: time.Now().Add(-a.Window - anomalyBaseline)}
+
+	if len(ipRanges) > 0 {
+		ips, err := targetIPs()
+		if err != nil {
+			return err
+		}
+		opts, err := fleetOptions()
+		if err != nil {
+			return err
+		}
+		if outputFormat != "json" {
+			fmt.Printf("Sampling %d hosts...\n", len(ips))
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), fleetDeadline(len(ips), time.Duration(timeout)*time.Second))
+		results := fleet.NewRunner(workers).Run(ctx, ips, fleetPort(), "anomalies", func(ctx context.Context, host string) (interface{}, error) {
+			return fleet.CollectSample(ctx, opts, host)
+		})
+		cancel()
+		if _, err := fleet.RecordSamples(store, results); err != nil {
+			return err
+		}
+
+		q.Hosts = make(map[string]bool, len(ips))
+		for _, ip := range ips {
+			q.Hosts[ip] = true
+		}
+	}
+
+	samples, err := store.Query(q)
+	if err != nil {
+		return err
+	}
+	report := fleet.DetectAnomalies(samples, time.Now(), a)
+
+	if outputFormat == "json" {
+		return output.PrintJSON(report, verbose)
+	}
+	output.WriteAnomalyReport(os.Stdout, report, verbose)
+	return nil
+}
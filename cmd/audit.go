@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/audit"
+	"github.com/sinkers/miner-cli/internal/client"
+	"github.com/sinkers/miner-cli/internal/iprange"
+	"github.com/sinkers/miner-cli/internal/output"
+	"github.com/sinkers/miner-cli/internal/policy"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var (
+	auditLog    string
+	auditSyslog bool
+
+	auditSince   time.Duration
+	auditUntil   string
+	auditUser    string
+	auditCommand string
+	auditHosts   []string
+	auditFailed  bool
+)
+
+var (
+	// auditedCmd is the running command when it is audited
+	auditedCmd *cobra.Command
+	// auditOutcomes collects the per-host results of the running command
+	auditOutcomes []client.Result
+	// auditFlushed is set once a long running command logged a record
+	auditFlushed bool
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show the audit log of commands that changed miners",
+	Long: `Show the audit log of commands that changed miners.
+
+The log is a file written by the operator running the CLI, by default in
+their own config directory, so it is not tamper-proof: they can edit or
+delete it. A policy can name a shared log with audit_log and force
+audit_syslog to keep a copy out of the operator's reach.`,
+	// reads the local log only
+	PersistentPreRunE: func(c *cobra.Command, args []string) error { return nil },
+}
+
+func init() {
+	showCmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show audit records",
+		Long: `Show who ran which write command, when, against which hosts, with what
+parameters (secrets redacted) and the outcome on every host. Use -v for the
+parameters and per-host errors.
+
+Examples:
+  miner-cli audit show --since 168h
+  miner-cli audit show --command addpool --host 10.0.0.0/24
+  miner-cli audit show --user alice --failed -v
+  miner-cli audit show --since 720h --until 2024-05-02T00:00:00Z -o json`,
+		RunE: runAuditShow,
+	}
+	showCmd.Flags().DurationVar(&auditSince, "since", 24*time.Hour, "Only records newer than this, 0 for all")
+	showCmd.Flags().StringVar(&auditUntil, "until", "", "Only records older than this RFC 3339 time")
+	showCmd.Flags().StringVar(&auditUser, "user", "", "Only records of this user")
+	showCmd.Flags().StringVar(&auditCommand, "command", "", `Only this command or its subcommands, e.g. "vnish settings"`)
+	showCmd.Flags().StringSliceVar(&auditHosts, "host", nil, "Only records touching these miners (IP ranges)")
+	showCmd.Flags().BoolVar(&auditFailed, "failed", false, "Only records with a failure")
+
+	auditCmd.AddCommand(showCmd)
+	rootCmd.AddCommand(auditCmd)
+
+	rootCmd.PersistentFlags().StringVar(&auditLog, "audit-log", audit.DefaultPath(), "Audit log of write commands (JSON lines), unless the policy sets one")
+	rootCmd.PersistentFlags().BoolVar(&auditSyslog, "audit-syslog", false, "Also send audit records to syslog")
+}
+
+func auditLogger() *audit.Logger {
+	return &audit.Logger{Path: auditLog, Syslog: auditSyslog}
+}
+
+// commandName is the command path without the program name
+func commandName(c *cobra.Command) string {
+	return strings.TrimPrefix(c.CommandPath(), rootCmd.Name()+" ")
+}
+
+// isWriteCommand reports whether c changes anything this run
+func isWriteCommand(c *cobra.Command) bool {
+	return policy.IsWrite(commandName(c), customCmd) && !readsOnly(c)
+}
+
+// readsOnly reports whether a write command only reads this run: a dry
+// run, or serve with --read-only, which refuses every write job
+func readsOnly(c *cobra.Command) bool {
+	return isDryRun(c) || (c == serveCmd && readOnly)
+}
+
+func isDryRun(c *cobra.Command) bool {
+	f := c.Flags().Lookup("dry-run")
+	return f != nil && f.Value.String() == "true"
+}
+
+// checkAuditLog refuses to start a write command whose record could not
+// be written
+func checkAuditLog(c *cobra.Command) {
+	if !isWriteCommand(c) {
+		return
+	}
+	if err := auditLogger().Check(); err != nil {
+		fmt.Fprintf(os.Stderr, "Refusing to run an unaudited write command: %v\n", err)
+		os.Exit(1)
+	}
+	auditedCmd = c
+}
+
+// auditResults adds the per-host results of a write to the record of the
+// running command
+func auditResults(results []client.Result) {
+	auditOutcomes = append(auditOutcomes, results...)
+}
+
+// flushAudit logs the results collected so far, for commands that keep
+// writing until interrupted
+func flushAudit() error {
+	if auditedCmd == nil || len(auditOutcomes) == 0 {
+		return nil
+	}
+	err := auditLogger().Log(newAuditRecord(auditedCmd, auditOutcomes, nil))
+	auditOutcomes, auditFlushed = nil, true
+	return err
+}
+
+// finishAudit logs the run of the command once it returned
+func finishAudit(c *cobra.Command, runErr error) error {
+	if auditedCmd == nil || c != auditedCmd {
+		return nil
+	}
+	if auditFlushed && len(auditOutcomes) == 0 && runErr == nil {
+		return nil
+	}
+	return auditLogger().Log(newAuditRecord(c, auditOutcomes, runErr))
+}
+
+func newAuditRecord(c *cobra.Command, results []client.Result, runErr error) audit.Record {
+	r := audit.NewRecord(commandName(c))
+	r.Args = c.Flags().Args()
+	r.Targets = ipRanges
+	c.Flags().Visit(func(f *pflag.Flag) {
+		if f.Name != "ips" {
+			r.SetFlag(f.Name, f.Value.String())
+		}
+	})
+	for _, result := range results {
+		r.AddOutcome(result.IP, result.Error)
+	}
+	if runErr != nil {
+		r.Error = runErr.Error()
+	}
+	return r
+}
+
+func runAuditShow(cmd *cobra.Command, args []string) error {
+	f := audit.Filter{User: auditUser, Command: auditCommand, Failed: auditFailed}
+	if auditSince > 0 {
+		f.Since = time.Now().Add(-auditSince)
+	}
+	if auditUntil != "" {
+		until, err := time.Parse(time.RFC3339, auditUntil)
+		if err != nil {
+			return fmt.Errorf("failed to parse --until: %w", err)
+		}
+		f.Until = until
+	}
+	if len(auditHosts) > 0 {
+		ipRange, err := iprange.ParseMultipleRanges(auditHosts)
+		if err != nil {
+			return fmt.Errorf("failed to parse --host: %w", err)
+		}
+		f.Hosts = make(map[string]bool)
+		for _, ip := range ipRange.GetIPs() {
+			f.Hosts[ip] = true
+		}
+	}
+
+	records, err := audit.Read(auditLog, f)
+	if err != nil {
+		return err
+	}
+	if outputFormat == "json" {
+		return output.PrintJSON(records, verbose)
+	}
+	output.WriteAuditRecords(os.Stdout, records, verbose)
+	return nil
+}
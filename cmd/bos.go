@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/spf13/cobra"
+)
+
+var bosCmd = &cobra.Command{
+	Use:   "bos",
+	Short: "Braiins OS specific operations",
+	Long: `Commands that use Braiins OS+ gRPC APIs without an equivalent on other
+firmware. The firmware is assumed to be Braiins OS unless --firmware is set.`,
+	PersistentPreRunE: func(c *cobra.Command, args []string) error {
+		if len(ipRanges) == 0 {
+			return fmt.Errorf("no IP ranges specified, use -i flag")
+		}
+		if firmware == fleet.FirmwareAuto {
+			firmware = fleet.FirmwareBraiins
+		}
+		return fleet.ValidateFirmware(firmware, fleet.FirmwareBraiins)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(bosCmd)
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	archiveOpts    fleet.ArchiveOptions
+	archiveMaxMB   int64
+	archiveTimeout time.Duration
+)
+
+var bosArchiveCmd = &cobra.Command{
+	Use:   "support-archive",
+	Short: "Download support archives from many miners",
+	Long: `Stream Braiins OS support archives (GetSupportArchive) into one file per
+host and record them in manifest.json in the output directory. Hosts that
+already have an archive are skipped, so an interrupted run can be repeated
+with the same arguments; use --force to download them again.
+
+Examples:
+  miner-cli bos support-archive -i 192.168.1.0/24 --out ./archives/
+  miner-cli bos support-archive -i 192.168.1.100 --out ./rma/ --format bos --max-size 100`,
+	PreRunE: func(c *cobra.Command, args []string) error {
+		archiveOpts.MaxSize = archiveMaxMB * 1024 * 1024
+		return archiveOpts.Validate()
+	},
+	RunE: runSupportArchive,
+}
+
+func init() {
+	bosArchiveCmd.Flags().StringVar(&archiveOpts.Dir, "out", "./archives", "Output directory")
+	bosArchiveCmd.Flags().StringVar(&archiveOpts.Format, "format", "zip", "Archive format (zip, bos, zip-encrypted)")
+	bosArchiveCmd.Flags().Int64Var(&archiveMaxMB, "max-size", 512, "Maximum archive size in MB (0 for no limit)")
+	bosArchiveCmd.Flags().BoolVar(&archiveOpts.Force, "force", false, "Download again even when an archive exists")
+	bosArchiveCmd.Flags().DurationVar(&archiveTimeout, "archive-timeout", 10*time.Minute, "Maximum time to download one archive")
+
+	bosCmd.AddCommand(bosArchiveCmd)
+}
+
+func runSupportArchive(cmd *cobra.Command, args []string) error {
+	ips, err := targetIPs()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(archiveOpts.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	manifest, err := fleet.LoadArchiveManifest(archiveOpts.Dir)
+	if err != nil {
+		return err
+	}
+
+	if outputFormat != "json" {
+		fmt.Printf("Downloading support archives from %d hosts to %s...\n", len(ips), archiveOpts.Dir)
+	}
+
+	// Archives take far longer than a normal API call, so the run is bounded
+	// per host instead of by the global timeout. Ctrl-C stops the run but
+	// still writes the manifest for completed hosts.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+	a := archiveOpts
+	results := fleet.NewRunner(workers).Run(ctx, ips, grpcPort, "support-archive", func(ctx context.Context, host string) (interface{}, error) {
+		ctx, cancel := context.WithTimeout(ctx, archiveTimeout)
+		defer cancel()
+		return fleet.DownloadSupportArchive(ctx, opts, host, a)
+	})
+
+	manifest.Merge(a.Format, results)
+	if err := manifest.Save(a.Dir); err != nil {
+		return err
+	}
+
+	if err := output.GetFormatter(outputFormat, verbose).Format(results); err != nil {
+		return err
+	}
+
+	if outputFormat != "json" {
+		downloaded, skipped, failed := 0, 0, 0
+		for _, r := range results {
+			switch res, ok := r.Response.(*fleet.ArchiveResult); {
+			case r.Error != "":
+				failed++
+			case ok && res.Skipped:
+				skipped++
+			default:
+				downloaded++
+			}
+		}
+		fmt.Printf("\nDownloaded: %d | Skipped: %d | Failed: %d\n", downloaded, skipped, failed)
+		fmt.Printf("Manifest written to %s\n", filepath.Join(archiveOpts.Dir, fleet.ManifestFile))
+	}
+	return nil
+}
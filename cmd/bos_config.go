@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	configDir      string
+	configTemplate string
+	configSections []string
+	configDryRun   bool
+)
+
+var bosConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Snapshot, compare and restore miner configuration",
+}
+
+func init() {
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write the configuration of every miner to a snapshot file",
+		Long: `Save the full Braiins OS configuration (GetMinerConfiguration: pools,
+tuner, cooling, DPS and hashboards) as one JSON file per miner. The files
+include pool passwords and are readable by the owner only.
+
+Examples:
+  miner-cli bos config export -i 192.168.1.0/24 --dir snapshots/`,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := os.MkdirAll(configDir, 0700); err != nil {
+				return fmt.Errorf("failed to create snapshot directory: %w", err)
+			}
+			opts, err := fleetOptions()
+			if err != nil {
+				return err
+			}
+			dir := configDir
+			return runFleet("config export", func(ctx context.Context, host string) (interface{}, error) {
+				return fleet.ExportConfig(ctx, opts, host, dir)
+			})
+		},
+	}
+	exportCmd.Flags().StringVar(&configDir, "dir", "./snapshots", "Snapshot directory")
+
+	diffCmd := &cobra.Command{
+		Use:   "diff [old.json new.json]",
+		Short: "Compare two snapshots or miners against a template",
+		Long: `Compare two snapshot files, or the running configuration of every miner
+against a golden template (a snapshot or a GetMinerConfiguration JSON
+document). Pool and pool group UIDs are ignored, and passwords are shown as
+*** unless --verbose is set.
+
+Examples:
+  miner-cli bos config diff snapshots/192.168.1.10.json snapshots/192.168.1.11.json
+  miner-cli bos config diff -i 192.168.1.0/24 --template golden.json`,
+		Args: func(c *cobra.Command, args []string) error {
+			if len(args) != 0 && len(args) != 2 {
+				return fmt.Errorf("expected two snapshot files or --template with -i")
+			}
+			return nil
+		},
+		// comparing two files needs no miners
+		PersistentPreRunE: func(c *cobra.Command, args []string) error {
+			if len(args) == 2 {
+				return nil
+			}
+			if configTemplate == "" {
+				return fmt.Errorf("--template is required when comparing miners")
+			}
+			return bosCmd.PersistentPreRunE(c, args)
+		},
+		RunE: runConfigDiff,
+	}
+	diffCmd.Flags().StringVar(&configTemplate, "template", "", "Golden configuration to compare miners against")
+
+	applyCmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Push pool, tuner and cooling settings from a template",
+		Long: `Apply the selected sections of a snapshot or golden template to every
+miner. Only sections that differ from the running configuration are sent:
+pools through SetPoolGroups, the tuner target through SetPerformanceMode and
+cooling through SetCoolingMode. Use --dry-run to list the differences;
+passwords are shown as *** unless --verbose is set.
+
+Examples:
+  miner-cli bos config apply -i 192.168.1.0/24 --template golden.json --dry-run
+  miner-cli bos config apply -i 192.168.1.0/24 --template golden.json --sections pools`,
+		PreRunE: func(c *cobra.Command, args []string) error {
+			return fleet.ValidateConfigSections(configSections)
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			template, err := fleet.LoadConfig(configTemplate)
+			if err != nil {
+				return err
+			}
+			opts, err := fleetOptions()
+			if err != nil {
+				return err
+			}
+			sections, dryRun := configSections, configDryRun
+			return runFleet("config apply", func(ctx context.Context, host string) (interface{}, error) {
+				result, err := fleet.ApplyConfig(ctx, opts, host, template, sections, dryRun)
+				if err == nil && !verbose {
+					result.Redact()
+				}
+				return result, err
+			})
+		},
+	}
+	applyCmd.Flags().StringVar(&configTemplate, "template", "", "Snapshot or golden configuration to apply")
+	applyCmd.Flags().StringSliceVar(&configSections, "sections", fleet.ConfigSections, "Sections to apply (pools, tuner, cooling)")
+	applyCmd.Flags().BoolVar(&configDryRun, "dry-run", false, "Show the differences without applying them")
+	applyCmd.MarkFlagRequired("template")
+
+	bosConfigCmd.AddCommand(exportCmd, diffCmd, applyCmd)
+	bosCmd.AddCommand(bosConfigCmd)
+}
+
+func runConfigDiff(cmd *cobra.Command, args []string) error {
+	if len(args) == 2 {
+		from, err := fleet.LoadConfig(args[0])
+		if err != nil {
+			return err
+		}
+		to, err := fleet.LoadConfig(args[1])
+		if err != nil {
+			return err
+		}
+		changes, err := fleet.DiffConfig(from, to)
+		if err != nil {
+			return err
+		}
+		if !verbose {
+			changes = fleet.RedactChanges(changes)
+		}
+		if outputFormat == "json" {
+			return output.PrintJSON(changes, verbose)
+		}
+		output.WriteConfigChanges(os.Stdout, changes, args[0], args[1])
+		return nil
+	}
+
+	template, err := fleet.LoadConfig(configTemplate)
+	if err != nil {
+		return err
+	}
+	ips, err := targetIPs()
+	if err != nil {
+		return err
+	}
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+
+	if outputFormat != "json" {
+		fmt.Printf("Comparing %d hosts with %s...\n", len(ips), configTemplate)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fleetDeadline(len(ips), time.Duration(timeout)*time.Second))
+	defer cancel()
+
+	results := fleet.NewRunner(workers).Run(ctx, ips, grpcPort, "config diff", func(ctx context.Context, host string) (interface{}, error) {
+		return fleet.DiffConfigHost(ctx, opts, host, template)
+	})
+
+	report := fleet.SummarizeConfigDiffs(results)
+	if !verbose {
+		report.Redact()
+	}
+
+	if outputFormat == "json" {
+		return output.PrintJSON(report, verbose)
+	}
+
+	output.WriteConfigDiffReport(os.Stdout, report, verbose)
+	return nil
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	licenseWarn    time.Duration
+	licenseKeyFile string
+)
+
+var bosLicenseCmd = &cobra.Command{
+	Use:   "license",
+	Short: "Report license state and apply contract keys",
+}
+
+func init() {
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Report license type and dev fee across the fleet",
+		Long: `Read the Braiins OS+ license of every miner and summarize the license
+states (none, limited, valid, expired) and dev fees. Miners without a
+license, with a limited or expired license or entering restricted mode
+within --warn are listed.
+
+Examples:
+  miner-cli bos license status -i 192.168.1.0/24
+  miner-cli bos license status -i 192.168.1.0/24 --warn 336h -o json`,
+		RunE: runLicenseStatus,
+	}
+	statusCmd.Flags().DurationVar(&licenseWarn, "warn", 7*24*time.Hour, "Flag licenses entering restricted mode within this time")
+
+	applyCmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Apply a contract key to many miners",
+		Long: `Install a license contract key (ApplyContractKey) on every miner and
+report the resulting license state.
+
+Examples:
+  miner-cli bos license apply -i 192.168.1.0/24 --key-file contract.key`,
+		RunE: func(c *cobra.Command, args []string) error {
+			key, err := fleet.ReadContractKey(licenseKeyFile)
+			if err != nil {
+				return err
+			}
+			opts, err := fleetOptions()
+			if err != nil {
+				return err
+			}
+			return runFleet("license apply", func(ctx context.Context, host string) (interface{}, error) {
+				return fleet.ApplyContractKey(ctx, opts, host, key)
+			})
+		},
+	}
+	applyCmd.Flags().StringVar(&licenseKeyFile, "key-file", "", "File containing the contract key")
+	applyCmd.MarkFlagRequired("key-file")
+
+	bosLicenseCmd.AddCommand(statusCmd, applyCmd)
+	bosCmd.AddCommand(bosLicenseCmd)
+}
+
+func runLicenseStatus(cmd *cobra.Command, args []string) error {
+	ips, err := targetIPs()
+	if err != nil {
+		return err
+	}
+
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+
+	if outputFormat != "json" {
+		fmt.Printf("Reading licenses from %d hosts...\n", len(ips))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fleetDeadline(len(ips), time.Duration(timeout)*time.Second))
+	defer cancel()
+
+	results := fleet.NewRunner(workers).Run(ctx, ips, grpcPort, "license status", func(ctx context.Context, host string) (interface{}, error) {
+		return fleet.GetLicense(ctx, opts, host)
+	})
+
+	report := fleet.SummarizeLicenses(results, licenseWarn)
+
+	if outputFormat == "json" {
+		return output.PrintJSON(report, verbose)
+	}
+
+	output.WriteLicenseReport(os.Stdout, report, verbose)
+	return nil
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/inventory"
+	"github.com/sinkers/miner-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	networkDHCP          bool
+	networkHostname      string
+	networkMapping       string
+	networkDryRun        bool
+	networkSkipProbe     bool
+	networkVerifyTimeout time.Duration
+)
+
+var bosNetworkCmd = &cobra.Command{
+	Use:   "network",
+	Short: "Manage hostname and DHCP/static addressing",
+}
+
+func init() {
+	getCmd := &cobra.Command{
+		Use:   "get",
+		Short: "Show MAC address, hostname and addressing of each miner",
+		RunE: func(c *cobra.Command, args []string) error {
+			opts, err := fleetOptions()
+			if err != nil {
+				return err
+			}
+			return runFleet("network get", func(ctx context.Context, host string) (interface{}, error) {
+				return fleet.GetNetwork(ctx, opts, host)
+			})
+		},
+	}
+
+	setCmd := &cobra.Command{
+		Use:   "set",
+		Short: "Switch to DHCP, set the hostname or migrate to static addresses",
+		Long: `Change the network configuration of Braiins OS miners.
+
+--mapping migrates miners to static addresses from a CSV file with the
+columns mac, ip, netmask, gateway and optionally dns (separated by spaces
+or semicolons) and hostname. Miners found in the -i ranges are matched by
+MAC address. Before anything is changed the plan is checked for duplicate
+addresses in the file, addresses used by other scanned miners and
+addresses that already answer on the network; any conflict aborts the
+migration. Each miner is then verified on its new address.
+
+Examples:
+  # Preview a renumbering
+  miner-cli bos network set -i 10.0.0.0/22 --mapping site.csv --dry-run
+
+  # Apply it
+  miner-cli bos network set -i 10.0.0.0/22 --mapping site.csv
+
+  # Return miners to DHCP
+  miner-cli bos network set -i 10.1.0.10-10.1.0.20 --dhcp
+
+  # Rename a single miner
+  miner-cli bos network set -i 10.1.0.10 --hostname r1-s1`,
+		RunE: runNetworkSet,
+	}
+	setCmd.Flags().BoolVar(&networkDHCP, "dhcp", false, "Switch to DHCP addressing")
+	setCmd.Flags().StringVar(&networkHostname, "hostname", "", "Set the hostname (single miner only)")
+	setCmd.Flags().StringVar(&networkMapping, "mapping", "", "CSV file mapping MAC to static ip,netmask,gateway,dns,hostname")
+	setCmd.Flags().BoolVar(&networkDryRun, "dry-run", false, "Show the migration plan without applying it")
+	setCmd.Flags().BoolVar(&networkSkipProbe, "skip-probe", false, "Do not probe target addresses for existing hosts")
+	setCmd.Flags().DurationVar(&networkVerifyTimeout, "verify-timeout", time.Minute, "How long to wait for a miner on its new address")
+
+	bosNetworkCmd.AddCommand(getCmd, setCmd)
+	bosCmd.AddCommand(bosNetworkCmd)
+}
+
+func runNetworkSet(cmd *cobra.Command, args []string) error {
+	if networkMapping != "" {
+		if networkDHCP || networkHostname != "" {
+			return fmt.Errorf("--mapping cannot be combined with --dhcp or --hostname")
+		}
+		return runNetworkMigration()
+	}
+	if !networkDHCP && networkHostname == "" {
+		return fmt.Errorf("specify --dhcp, --hostname or --mapping")
+	}
+
+	ips, err := targetIPs()
+	if err != nil {
+		return err
+	}
+	if networkHostname != "" && len(ips) > 1 {
+		return fmt.Errorf("--hostname can only be set on a single miner, use --mapping for per-miner hostnames")
+	}
+
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+	change := fleet.NetworkChange{DHCP: networkDHCP, Hostname: networkHostname}
+	return runFleet("network set", func(ctx context.Context, host string) (interface{}, error) {
+		return fleet.SetNetwork(ctx, opts, host, change)
+	})
+}
+
+func runNetworkMigration() error {
+	mappings, err := inventory.LoadNetworkMappings(networkMapping)
+	if err != nil {
+		return err
+	}
+
+	ips, err := targetIPs()
+	if err != nil {
+		return err
+	}
+
+	if outputFormat != "json" {
+		fmt.Printf("Reading network configuration from %d hosts...\n", len(ips))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fleetDeadline(len(ips), time.Duration(timeout)*time.Second))
+	defer cancel()
+
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+	runner := fleet.NewRunner(workers)
+	current := runner.Run(ctx, ips, grpcPort, "network get", func(ctx context.Context, host string) (interface{}, error) {
+		return fleet.GetNetwork(ctx, opts, host)
+	})
+
+	plan := fleet.PlanMigration(current, mappings)
+	if !networkSkipProbe {
+		plan.ProbeConflicts(ctx, runner, []int{80, 22, grpcPort}, opts.Timeout)
+	}
+
+	if outputFormat == "json" && (networkDryRun || len(plan.Conflicts) > 0) {
+		if err := output.PrintJSON(plan, verbose); err != nil {
+			return err
+		}
+	} else if outputFormat != "json" {
+		output.WriteMigrationPlan(os.Stdout, plan)
+	}
+
+	if len(plan.Conflicts) > 0 {
+		return fmt.Errorf("aborting migration: %d address conflicts", len(plan.Conflicts))
+	}
+	if networkDryRun || len(plan.Steps) == 0 {
+		return nil
+	}
+
+	hosts := make([]string, 0, len(plan.Steps))
+	steps := make(map[string]fleet.MigrationStep)
+	for _, s := range plan.Steps {
+		hosts = append(hosts, s.Host)
+		steps[s.Host] = s
+	}
+
+	if outputFormat != "json" {
+		fmt.Printf("\nMigrating %d miners to static addresses...\n", len(hosts))
+	}
+
+	// Verification waits for each miner to come back, so the run is bounded
+	// by --verify-timeout per host instead of the global timeout
+	migrateCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	results := runner.Run(migrateCtx, hosts, grpcPort, "network migrate", func(ctx context.Context, host string) (interface{}, error) {
+		return fleet.Migrate(ctx, opts, steps[host], networkVerifyTimeout)
+	})
+	auditResults(results)
+	return output.GetFormatter(outputFormat, verbose).Format(results)
+}
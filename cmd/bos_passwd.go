@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/credentials"
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	passwdFile   string
+	passwdNoSave bool
+)
+
+var bosPasswdCmd = &cobra.Command{
+	Use:   "passwd",
+	Short: "Rotate the login password of Braiins OS miners",
+	Long: `Set a new password (AuthenticationService.SetPassword) on every miner and
+log in again with it to verify it. Miners are accessed with the
+credentials from the credential store, or --username/--password.
+
+The new password of each miner that took it, verified or not, is saved to
+the encrypted --credentials file unless --no-save is given, so later
+commands keep working: entries for that host alone are updated, otherwise a
+per-host entry is added. Saving requires ` + credentials.EnvKey + `.
+
+Examples:
+  miner-cli bos passwd -i 192.168.1.0/24 --new-from-file new-password.txt`,
+	RunE: runPasswd,
+}
+
+func init() {
+	bosPasswdCmd.Flags().StringVar(&passwdFile, "new-from-file", "", "File containing the new password")
+	bosPasswdCmd.Flags().BoolVar(&passwdNoSave, "no-save", false, "Do not record the new passwords in the credential store")
+	bosPasswdCmd.MarkFlagRequired("new-from-file")
+
+	bosCmd.AddCommand(bosPasswdCmd)
+}
+
+func runPasswd(cmd *cobra.Command, args []string) error {
+	newPassword, err := fleet.ReadPassword(passwdFile)
+	if err != nil {
+		return err
+	}
+
+	key := os.Getenv(credentials.EnvKey)
+	save := !passwdNoSave
+	if save && (os.Getenv(credentials.EnvJSON) != "" || key == "") {
+		return fmt.Errorf("cannot record new passwords: set %s and use an encrypted --credentials file, or pass --no-save", credentials.EnvKey)
+	}
+
+	ips, err := targetIPs()
+	if err != nil {
+		return err
+	}
+
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+
+	if outputFormat != "json" {
+		fmt.Printf("Changing password on %d hosts...\n", len(ips))
+	}
+
+	// Each host needs two logins and the password change
+	ctx, cancel := context.WithTimeout(context.Background(), fleetDeadline(len(ips), 3*time.Duration(timeout)*time.Second))
+	defer cancel()
+
+	results := fleet.NewRunner(workers).Run(ctx, ips, grpcPort, "passwd", func(ctx context.Context, host string) (interface{}, error) {
+		return fleet.ChangePassword(ctx, opts, host, newPassword)
+	})
+	auditResults(results)
+
+	// Record the new passwords before printing anything, a lost password
+	// locks us out of the miner
+	if save {
+		store, err := credentials.Load(credentialsFile, key)
+		if err != nil {
+			return fmt.Errorf("passwords changed but not saved: %w", err)
+		}
+		changed := 0
+		for _, r := range results {
+			change, ok := r.Response.(*fleet.PasswordChange)
+			if r.Error != "" || !ok {
+				continue
+			}
+			if err := store.SetHostPassword(r.IP, change.Username, newPassword); err != nil {
+				return fmt.Errorf("passwords changed but not saved: %w", err)
+			}
+			changed++
+		}
+		if changed > 0 {
+			if err := store.Save(credentialsFile, key); err != nil {
+				return fmt.Errorf("passwords changed but not saved: %w", err)
+			}
+			if outputFormat != "json" {
+				fmt.Printf("Saved %d new passwords to %s\n", changed, credentialsFile)
+			}
+		}
+	}
+
+	return output.GetFormatter(outputFormat, verbose).Format(results)
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statusFollow     bool
+	statusMinBackoff time.Duration
+	statusMaxBackoff time.Duration
+)
+
+var bosStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show or follow the mining status of Braiins OS miners",
+	Long: `Show the mining status (normal, paused, suspended, ...) of each miner.
+
+With --follow a GetMinerStatus stream is held open to every miner and each
+status change is printed as it happens, with the host, old and new status.
+Lost streams are reported as "unreachable" and reconnected with exponential
+backoff. Use -o json for newline delimited JSON events. Stop with Ctrl-C.
+
+Examples:
+  miner-cli bos status -i 192.168.1.0/24
+  miner-cli bos status --follow -i 192.168.1.0/24
+  miner-cli bos status --follow -i 10.0.0.0/22 -o json >> status.ndjson`,
+	RunE: func(c *cobra.Command, args []string) error {
+		if statusFollow {
+			return followStatus()
+		}
+		opts, err := fleetOptions()
+		if err != nil {
+			return err
+		}
+		return runFleet("status", func(ctx context.Context, host string) (interface{}, error) {
+			return fleet.GetMinerStatus(ctx, opts, host)
+		})
+	},
+}
+
+func init() {
+	bosStatusCmd.Flags().BoolVarP(&statusFollow, "follow", "f", false, "Stream status changes until interrupted")
+	bosStatusCmd.Flags().DurationVar(&statusMinBackoff, "min-backoff", time.Second, "Initial reconnect delay")
+	bosStatusCmd.Flags().DurationVar(&statusMaxBackoff, "max-backoff", time.Minute, "Maximum reconnect delay")
+
+	bosCmd.AddCommand(bosStatusCmd)
+}
+
+func followStatus() error {
+	ips, err := targetIPs()
+	if err != nil {
+		return err
+	}
+
+	jsonOutput := outputFormat == "json"
+	if !jsonOutput {
+		fmt.Printf("Following status of %d hosts, press Ctrl-C to stop...\n", len(ips))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+
+	w := &output.StatusEventWriter{W: os.Stdout, JSON: jsonOutput}
+	fleet.FollowStatus(ctx, opts, ips, fleet.FollowOptions{
+		MinBackoff: statusMinBackoff,
+		MaxBackoff: statusMaxBackoff,
+	}, func(e fleet.StatusEvent) {
+		w.Write(e)
+	})
+	return nil
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/spf13/cobra"
+)
+
+var coolingSettings fleet.CoolingSettings
+
+var coolingCmd = &cobra.Command{
+	Use:   "cooling",
+	Short: "Manage fan and temperature settings on Braiins OS and vnish miners",
+	Long: `Read and configure cooling across Braiins OS and vnish miners with one
+set of options. Temperatures are in degrees Celsius and fan speeds in percent.
+
+Examples:
+  # Show the cooling configuration
+  miner-cli cooling get -i 192.168.1.0/24
+
+  # Automatic fan control for summer
+  miner-cli cooling set --firmware vnish --mode auto --target-temp 65 --hot 80 --dangerous 90 -i 192.168.1.0/24
+
+  # Fixed fan speed
+  miner-cli cooling set --firmware braiins --mode manual --fan-speed 70 -i 192.168.1.100`,
+	PersistentPreRunE: func(c *cobra.Command, args []string) error {
+		if len(ipRanges) == 0 {
+			return fmt.Errorf("no IP ranges specified, use -i flag")
+		}
+		return fleet.ValidateFirmware(firmware, fleet.FirmwareAuto, fleet.FirmwareBraiins, fleet.FirmwareVnish)
+	},
+}
+
+func init() {
+	getCmd := &cobra.Command{
+		Use:   "get",
+		Short: "Show the current cooling configuration",
+		RunE: func(c *cobra.Command, args []string) error {
+			opts, err := fleetOptions()
+			if err != nil {
+				return err
+			}
+			return runFleet("cooling get", func(ctx context.Context, host string) (interface{}, error) {
+				return fleet.GetCooling(ctx, opts, host)
+			})
+		},
+	}
+
+	setCmd := &cobra.Command{
+		Use:   "set",
+		Short: "Change the cooling mode and temperature thresholds",
+		PreRunE: func(c *cobra.Command, args []string) error {
+			return coolingSettings.Validate()
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			opts, err := fleetOptions()
+			if err != nil {
+				return err
+			}
+			settings := coolingSettings
+			return runFleet("cooling set", func(ctx context.Context, host string) (interface{}, error) {
+				return fleet.SetCooling(ctx, opts, host, settings)
+			})
+		},
+	}
+	setCmd.Flags().StringVar(&coolingSettings.Mode, "mode", "", "Cooling mode (auto, manual, immersion, hydro)")
+	setCmd.Flags().Float64Var(&coolingSettings.TargetTemp, "target-temp", 0, "Target temperature")
+	setCmd.Flags().Float64Var(&coolingSettings.HotTemp, "hot", 0, "Temperature at which fans run at 100%")
+	setCmd.Flags().Float64Var(&coolingSettings.DangerousTemp, "dangerous", 0, "Temperature at which mining shuts down")
+	setCmd.Flags().IntVar(&coolingSettings.FanSpeed, "fan-speed", 0, "Fixed fan speed for manual mode")
+	setCmd.Flags().IntVar(&coolingSettings.MinFanSpeed, "min-fan-speed", 0, "Minimum fan speed for auto mode")
+	setCmd.Flags().IntVar(&coolingSettings.MaxFanSpeed, "max-fan-speed", 0, "Maximum fan speed for auto mode")
+	setCmd.MarkFlagRequired("mode")
+
+	coolingCmd.AddCommand(getCmd, setCmd)
+	rootCmd.AddCommand(coolingCmd)
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sinkers/miner-cli/internal/credentials"
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	credEntry        credentials.Entry
+	credPasswordFile string
+	credAPIKeyFile   string
+)
+
+var credentialsCmd = &cobra.Command{
+	Use:   "credentials",
+	Short: "Manage the encrypted credential store",
+	Long: `Store Braiins OS logins and vnish API keys per host or per group of hosts
+(IPs, CIDRs or ranges). Every Braiins OS and vnish command looks up the most
+specific entry for each miner; --username, --password and --api-key on the
+command line take precedence over the store.
+
+The store is the --credentials file, encrypted with the passphrase in
+` + credentials.EnvKey + `. Alternatively the whole store can be provided as
+unencrypted JSON in ` + credentials.EnvJSON + `, which is then used instead of
+the file.
+
+Examples:
+  export ` + credentials.EnvKey + `=...
+  miner-cli credentials set --name container-a --hosts 10.0.1.0/24 --username root --password-file pw.txt
+  miner-cli credentials set --name default --username root --password-file default-pw.txt
+  miner-cli credentials list
+  miner-cli credentials remove --name container-a`,
+}
+
+func init() {
+	setCmd := &cobra.Command{
+		Use:   "set",
+		Short: "Add or replace a credential entry",
+		RunE:  runCredentialsSet,
+	}
+	setCmd.Flags().StringVar(&credEntry.Name, "name", "", "Entry name")
+	setCmd.Flags().StringSliceVar(&credEntry.Hosts, "hosts", nil, "Hosts the entry applies to (all hosts when empty)")
+	setCmd.Flags().StringVar(&credEntry.Username, "username", "", "Braiins OS username")
+	setCmd.Flags().StringVar(&credPasswordFile, "password-file", "", "File containing the password")
+	setCmd.Flags().StringVar(&credAPIKeyFile, "api-key-file", "", "File containing the vnish API key")
+	setCmd.MarkFlagRequired("name")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List credential entries with secrets hidden",
+		RunE: func(c *cobra.Command, args []string) error {
+			store, err := loadCredentials()
+			if err != nil {
+				return err
+			}
+			entries := store.Redacted()
+			if outputFormat == "json" {
+				return output.PrintJSON(entries, verbose)
+			}
+			if len(entries) == 0 {
+				fmt.Println("No credential entries")
+				return nil
+			}
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "Name\tHosts\tUsername\tPassword\tAPI Key")
+			for _, e := range entries {
+				hosts := strings.Join(e.Hosts, ",")
+				if hosts == "" {
+					hosts = "*"
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.Name, hosts, e.Username, e.Password, e.APIKey)
+			}
+			return tw.Flush()
+		},
+	}
+
+	removeCmd := &cobra.Command{
+		Use:   "remove",
+		Short: "Remove a credential entry",
+		RunE: func(c *cobra.Command, args []string) error {
+			return updateCredentials(func(store *credentials.Store) error {
+				return store.Remove(credEntry.Name)
+			})
+		},
+	}
+	removeCmd.Flags().StringVar(&credEntry.Name, "name", "", "Entry name")
+	removeCmd.MarkFlagRequired("name")
+
+	credentialsCmd.AddCommand(setCmd, listCmd, removeCmd)
+	rootCmd.AddCommand(credentialsCmd)
+}
+
+func runCredentialsSet(cmd *cobra.Command, args []string) error {
+	entry := credEntry
+	if credPasswordFile != "" {
+		pw, err := fleet.ReadPassword(credPasswordFile)
+		if err != nil {
+			return err
+		}
+		entry.Password = pw
+	}
+	if credAPIKeyFile != "" {
+		key, err := fleet.ReadPassword(credAPIKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read API key: %w", err)
+		}
+		entry.APIKey = key
+	}
+	if entry.Username == "" && entry.Password == "" && entry.APIKey == "" {
+		return fmt.Errorf("specify at least one of --username, --password-file or --api-key-file")
+	}
+
+	return updateCredentials(func(store *credentials.Store) error {
+		return store.Set(entry)
+	})
+}
+
+// updateCredentials loads the encrypted store, applies fn and saves it
+func updateCredentials(fn func(*credentials.Store) error) error {
+	if err := saveCredentials(fn); err != nil {
+		return err
+	}
+	fmt.Printf("Credentials saved to %s\n", credentialsFile)
+	return nil
+}
+
+// saveCredentials is updateCredentials without the confirmation message
+func saveCredentials(fn func(*credentials.Store) error) error {
+	if err := checkCredentialsWritable(); err != nil {
+		return err
+	}
+
+	key := os.Getenv(credentials.EnvKey)
+	store, err := credentials.Load(credentialsFile, key)
+	if err != nil {
+		return err
+	}
+	if err := fn(store); err != nil {
+		return err
+	}
+	return store.Save(credentialsFile, key)
+}
+
+// checkCredentialsWritable fails when the store cannot be saved, so
+// commands can check before changing miners
+func checkCredentialsWritable() error {
+	if os.Getenv(credentials.EnvJSON) != "" {
+		return fmt.Errorf("credentials are read from %s and cannot be changed", credentials.EnvJSON)
+	}
+	if os.Getenv(credentials.EnvKey) == "" {
+		return fmt.Errorf("a passphrase is required to save credentials, set %s", credentials.EnvKey)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var errorsCmd = &cobra.Command{
+	Use:   "errors",
+	Short: "Collect active miner errors and summarize them across the fleet",
+	Long: `Gather active errors from Braiins OS miners (GetErrors) and vnish miners
+(status errors and warnings), remove duplicates and report how many hosts are
+affected per error code and component. Miners whose firmware has no error
+reporting, such as CGMiner, are listed as unsupported rather than
+unreachable.
+
+Examples:
+  miner-cli errors -i 192.168.1.0/24
+  miner-cli errors -i 10.0.0.0/22 --firmware braiins -o json`,
+	PreRunE: func(c *cobra.Command, args []string) error {
+		if len(ipRanges) == 0 {
+			return fmt.Errorf("no IP ranges specified, use -i flag")
+		}
+		return nil
+	},
+	RunE: runErrors,
+}
+
+func init() {
+	rootCmd.AddCommand(errorsCmd)
+}
+
+func runErrors(cmd *cobra.Command, args []string) error {
+	ips, err := targetIPs()
+	if err != nil {
+		return err
+	}
+
+	if outputFormat != "json" {
+		fmt.Printf("Collecting errors from %d hosts...\n", len(ips))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fleetDeadline(len(ips), time.Duration(timeout)*time.Second))
+	defer cancel()
+
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+	results := fleet.NewRunner(workers).Run(ctx, ips, fleetPort(), "errors", func(ctx context.Context, host string) (interface{}, error) {
+		return fleet.GetErrors(ctx, opts, host)
+	})
+
+	report := fleet.SummarizeErrors(results)
+
+	if outputFormat == "json" {
+		return output.PrintJSON(report, verbose)
+	}
+
+	output.WriteErrorReport(os.Stdout, report, verbose)
+	return nil
+}
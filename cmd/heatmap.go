@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/inventory"
+	"github.com/sinkers/miner-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	heatmapInventory string
+	heatmapHTML      string
+	heatmapWarn      float64
+	heatmapHot       float64
+)
+
+var heatmapCmd = &cobra.Command{
+	Use:   "heatmap",
+	Short: "Render a rack by shelf temperature map of the facility",
+	Long: `Poll board and chip temperatures and lay them out by rack and shelf using
+an inventory CSV file with the columns ip, rack, shelf and optionally
+firmware (cgminer, braiins, vnish) and model.
+
+When -i is given only the inventory entries within those ranges are polled.
+Miners without a firmware column use --firmware, which detects the
+firmware of each miner by default.
+
+Examples:
+  miner-cli heatmap --inventory racks.csv
+  miner-cli heatmap --inventory racks.csv --html heatmap.html --warn 75 --hot 85`,
+	RunE: runHeatmap,
+}
+
+func init() {
+	heatmapCmd.Flags().StringVar(&heatmapInventory, "inventory", "", "Inventory CSV file (ip,rack,shelf[,firmware,model])")
+	heatmapCmd.Flags().StringVar(&heatmapHTML, "html", "", "Also write the heatmap as an HTML/SVG file")
+	heatmapCmd.Flags().Float64Var(&heatmapWarn, "warn", 75, "Temperature highlighted as warm")
+	heatmapCmd.Flags().Float64Var(&heatmapHot, "hot", 85, "Temperature reported as a hot spot")
+	heatmapCmd.MarkFlagRequired("inventory")
+
+	rootCmd.AddCommand(heatmapCmd)
+}
+
+func runHeatmap(cmd *cobra.Command, args []string) error {
+	if heatmapWarn >= heatmapHot {
+		return fmt.Errorf("--warn must be below --hot")
+	}
+
+	inv, err := inventory.Load(heatmapInventory)
+	if err != nil {
+		return err
+	}
+
+	hosts := inv.IPs()
+	if len(ipRanges) > 0 {
+		ips, err := targetIPs()
+		if err != nil {
+			return err
+		}
+		wanted := make(map[string]bool)
+		for _, ip := range ips {
+			wanted[ip] = true
+		}
+		hosts = hosts[:0]
+		for _, ip := range inv.IPs() {
+			if wanted[ip] {
+				hosts = append(hosts, ip)
+			}
+		}
+	}
+	if len(hosts) == 0 {
+		return fmt.Errorf("no inventory miners match the specified ranges")
+	}
+
+	if outputFormat != "json" {
+		fmt.Printf("Polling temperatures from %d hosts...\n", len(hosts))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fleetDeadline(len(hosts), time.Duration(timeout)*time.Second))
+	defer cancel()
+
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+	results := fleet.NewRunner(workers).Run(ctx, hosts, 0, "heatmap", func(ctx context.Context, host string) (interface{}, error) {
+		hostOpts := opts
+		if m, ok := inv.Lookup(host); ok && m.Firmware != "" {
+			hostOpts.Firmware = m.Firmware
+		}
+		return fleet.GetTemperatures(ctx, hostOpts, host)
+	})
+
+	heatmap := &output.Heatmap{Warn: heatmapWarn, Hot: heatmapHot}
+	for _, result := range results {
+		m, _ := inv.Lookup(result.IP)
+		cell := output.HeatmapCell{IP: result.IP, Rack: m.Rack, Shelf: m.Shelf, Error: result.Error}
+		if temps, ok := result.Response.(*fleet.Temperatures); ok {
+			cell.Temp = temps.Max
+		}
+		heatmap.Cells = append(heatmap.Cells, cell)
+	}
+
+	if heatmapHTML != "" {
+		f, err := os.Create(heatmapHTML)
+		if err != nil {
+			return fmt.Errorf("failed to create HTML file: %w", err)
+		}
+		defer f.Close()
+		if err := heatmap.WriteHTML(f); err != nil {
+			return fmt.Errorf("failed to write HTML heatmap: %w", err)
+		}
+	}
+
+	if outputFormat == "json" {
+		return output.PrintJSON(heatmap, verbose)
+	}
+
+	heatmap.WriteTerminal(os.Stdout)
+	if heatmapHTML != "" {
+		fmt.Printf("\nHTML heatmap written to %s\n", heatmapHTML)
+	}
+	return nil
+}
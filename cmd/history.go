@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/iprange"
+	"github.com/sinkers/miner-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyDir       string
+	historyInterval  time.Duration
+	historyHosts     []string
+	historySince     time.Duration
+	historyAggregate fleet.AggregateOptions
+	historyDropPct   float64
+	historyOlderThan time.Duration
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Record and query a local history of miner metrics",
+	Long: `Keep normalized hashrate, power, temperature and hardware error samples of
+every miner in a local append-only store (one JSON lines file per day in
+--history-dir), so questions like "when did this unit start dropping
+hashrate" can be answered without external infrastructure.`,
+	// show and prune work offline on the store
+	PersistentPreRunE: func(c *cobra.Command, args []string) error { return nil },
+}
+
+func init() {
+	recordCmd := &cobra.Command{
+		Use:   "record",
+		Short: "Sample every target miner into the history",
+		Long: `Read the current metrics of every miner and append them to the history.
+Run it from cron, or with --interval to keep sampling until interrupted.
+Unreachable miners are not recorded and show up as gaps.
+
+Examples:
+  miner-cli history record -i 192.168.1.0/24
+  miner-cli history record -i 192.168.1.0/24 --interval 5m
+  */5 * * * * miner-cli history record -i 192.168.1.0/24 >> /var/log/miner-history.log`,
+		PersistentPreRunE: func(c *cobra.Command, args []string) error {
+			if len(ipRanges) == 0 {
+				return fmt.Errorf("no IP ranges specified, use -i flag")
+			}
+			return fleet.ValidateFirmware(firmware, fleet.FirmwareAuto, fleet.FirmwareCGMiner, fleet.FirmwareBraiins, fleet.FirmwareVnish)
+		},
+		RunE: runHistoryRecord,
+	}
+	recordCmd.Flags().DurationVar(&historyInterval, "interval", 0, "Keep sampling at this interval until interrupted, 0 to sample once")
+
+	showCmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show the recorded history of miners",
+		Long: `Aggregate the recorded samples per miner: min/avg/max hashrate over the
+period, and with --bucket the same per time bucket along with power,
+temperature and new hardware errors. A miner whose recent buckets stay more
+than --drop percent below its best bucket is flagged with the time the drop
+started.
+
+Examples:
+  miner-cli history show --since 24h
+  miner-cli history show --host 192.168.1.42 --since 72h --bucket 1h
+  miner-cli history show --host 192.168.1.0/24 --since 168h --bucket 6h --drop 5 -o json`,
+		RunE: runHistoryShow,
+	}
+	showCmd.Flags().StringSliceVar(&historyHosts, "host", nil, "Only these miners (IP ranges), all when empty")
+	showCmd.Flags().DurationVar(&historySince, "since", 24*time.Hour, "Only samples newer than this, 0 for all")
+	showCmd.Flags().DurationVar(&historyAggregate.Bucket, "bucket", 0, "Aggregate per bucket of this size (e.g. 1h), 0 for one summary per miner")
+	showCmd.Flags().Float64Var(&historyDropPct, "drop", 10, "Hashrate drop in percent of the best bucket to flag, 0 to disable")
+
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete old history",
+		Long: `Delete the days of history older than --older-than.
+
+Examples:
+  miner-cli history prune --older-than 720h`,
+		RunE: runHistoryPrune,
+	}
+	pruneCmd.Flags().DurationVar(&historyOlderThan, "older-than", 30*24*time.Hour, "Delete days older than this")
+
+	historyCmd.PersistentFlags().StringVar(&historyDir, "history-dir", fleet.DefaultHistoryDir(), "History store directory")
+	historyCmd.AddCommand(recordCmd, showCmd, pruneCmd)
+	rootCmd.AddCommand(historyCmd)
+}
+
+func runHistoryRecord(cmd *cobra.Command, args []string) error {
+	ips, err := targetIPs()
+	if err != nil {
+		return err
+	}
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+	store := fleet.NewHistoryStore(historyDir)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if historyInterval > 0 && outputFormat != "json" {
+		fmt.Printf("Sampling %d hosts every %s into %s, press Ctrl-C to stop...\n", len(ips), historyInterval, historyDir)
+	}
+
+	for {
+		roundCtx, cancel := context.WithTimeout(ctx, fleetDeadline(len(ips), time.Duration(timeout)*time.Second))
+		results := fleet.NewRunner(workers).Run(roundCtx, ips, fleetPort(), "history record", func(ctx context.Context, host string) (interface{}, error) {
+			return fleet.CollectSample(ctx, opts, host)
+		})
+		cancel()
+
+		report, err := fleet.RecordSamples(store, results)
+		if err != nil {
+			return err
+		}
+		if outputFormat == "json" {
+			if err := output.PrintJSON(report, false); err != nil {
+				return err
+			}
+		} else {
+			output.WriteRecordReport(os.Stdout, report, verbose)
+		}
+
+		if historyInterval <= 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(historyInterval):
+		}
+	}
+}
+
+func runHistoryShow(cmd *cobra.Command, args []string) error {
+	if historyDropPct < 0 || historyDropPct >= 100 {
+		return fmt.Errorf("--drop must be between 0 and 100")
+	}
+
+	q := fleet.HistoryQuery{}
+	if historySince > 0 {
+		q.Since = time.Now().Add(-historySince)
+	}
+	if len(historyHosts) > 0 {
+		ipRange, err := iprange.ParseMultipleRanges(historyHosts)
+		if err != nil {
+			return fmt.Errorf("failed to parse --host: %w", err)
+		}
+		q.Hosts = make(map[string]bool)
+		for _, ip := range ipRange.GetIPs() {
+			q.Hosts[ip] = true
+		}
+	}
+
+	samples, err := fleet.NewHistoryStore(historyDir).Query(q)
+	if err != nil {
+		return err
+	}
+
+	a := historyAggregate
+	a.Drop = historyDropPct / 100
+	report := fleet.AggregateHistory(samples, a)
+	report.Since = q.Since
+
+	if outputFormat == "json" {
+		return output.PrintJSON(report, verbose)
+	}
+	output.WriteHistoryReport(os.Stdout, report, verbose)
+	return nil
+}
+
+func runHistoryPrune(cmd *cobra.Command, args []string) error {
+	removed, err := fleet.NewHistoryStore(historyDir).Prune(time.Now().Add(-historyOlderThan))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Removed %d days of history from %s\n", removed, historyDir)
+	return nil
+}
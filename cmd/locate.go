@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var locateDuration time.Duration
+
+var locateCmd = &cobra.Command{
+	Use:   "locate",
+	Short: "Blink the locate LED to find miners on the floor",
+	Long: `Turn the locate LED on or off. The mechanism is picked per miner from the
+detected firmware: Braiins OS locate device mode, vnish find-miner, or the
+CGMiner ascset LED command. With --duration the LEDs that came on are turned
+off again, and -o json prints the results of both runs as one list.
+
+Examples:
+  # Blink for ten minutes, then turn the LED off again
+  miner-cli locate on -i 192.168.1.100 --duration 10m
+
+  miner-cli locate off -i 192.168.1.100
+  miner-cli locate status -i 192.168.1.0/24 --firmware braiins`,
+	PersistentPreRunE: func(c *cobra.Command, args []string) error {
+		if len(ipRanges) == 0 {
+			return fmt.Errorf("no IP ranges specified, use -i flag")
+		}
+		return nil
+	},
+}
+
+func init() {
+	onCmd := &cobra.Command{
+		Use:   "on",
+		Short: "Start blinking the locate LED",
+		RunE:  runLocateOn,
+	}
+	onCmd.Flags().DurationVar(&locateDuration, "duration", 0, "Turn the LED off again after this long (e.g. 5m)")
+
+	offCmd := &cobra.Command{
+		Use:   "off",
+		Short: "Stop blinking the locate LED",
+		RunE: func(c *cobra.Command, args []string) error {
+			return runLocate(false)
+		},
+	}
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show whether the locate LED is on",
+		RunE: func(c *cobra.Command, args []string) error {
+			opts, err := fleetOptions()
+			if err != nil {
+				return err
+			}
+			return runFleet("locate status", func(ctx context.Context, host string) (interface{}, error) {
+				return fleet.GetLocate(ctx, opts, host)
+			})
+		},
+	}
+
+	locateCmd.AddCommand(onCmd, offCmd, statusCmd)
+	rootCmd.AddCommand(locateCmd)
+}
+
+// runLocateOn turns the LEDs on and with --duration off again on the miners
+// that took it. JSON output is one document with the results of both runs.
+func runLocateOn(cmd *cobra.Command, args []string) error {
+	if locateDuration <= 0 {
+		return runLocate(true)
+	}
+	ips, err := targetIPs()
+	if err != nil {
+		return err
+	}
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+
+	on := fleetResults("locate on", ips, func(ctx context.Context, host string) (interface{}, error) {
+		return fleet.SetLocate(ctx, opts, host, true)
+	})
+	var lit []string
+	for _, r := range on {
+		if r.Error == "" {
+			lit = append(lit, r.IP)
+		}
+	}
+
+	formatter := output.GetFormatter(outputFormat, verbose)
+	if outputFormat != "json" {
+		// the LEDs still go off when the results cannot be printed
+		err = formatter.Format(on)
+	}
+	if len(lit) == 0 {
+		if outputFormat == "json" {
+			return formatter.Format(on)
+		}
+		return err
+	}
+
+	if err == nil {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		if outputFormat != "json" {
+			fmt.Printf("\nLEDs turn off in %s (Ctrl+C to turn off now)...\n", locateDuration)
+		}
+		select {
+		case <-time.After(locateDuration):
+		case <-ctx.Done():
+		}
+		stop()
+	}
+
+	off := fleetResults("locate off", lit, func(ctx context.Context, host string) (interface{}, error) {
+		return fleet.SetLocate(ctx, opts, host, false)
+	})
+	if outputFormat == "json" {
+		return formatter.Format(append(on, off...))
+	}
+	if offErr := formatter.Format(off); err == nil {
+		err = offErr
+	}
+	return err
+}
+
+func runLocate(enable bool) error {
+	command := "locate off"
+	if enable {
+		command = "locate on"
+	}
+
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+	return runFleet(command, func(ctx context.Context, host string) (interface{}, error) {
+		return fleet.SetLocate(ctx, opts, host, enable)
+	})
+}
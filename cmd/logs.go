@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logOpts       fleet.LogOptions
+	logClearType  string
+	logDir        string
+	logGrepLevel  string
+	logGrepSince  time.Duration
+	logIgnoreCase bool
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Collect and search miner logs across the fleet",
+}
+
+func init() {
+	fetchCmd := &cobra.Command{
+		Use:   "fetch",
+		Short: "Gather logs from every miner",
+		Long: `Gather logs from vnish (logs API) and Braiins OS miners (log files in the
+support archive) concurrently. Without --dir the lines of all miners are
+printed as one stream ordered by time and tagged by host; with --dir each
+miner's lines are saved for logs grep.
+
+Examples:
+  miner-cli logs fetch -i 192.168.1.0/24 --type miner --since 1h
+  miner-cli logs fetch -i 192.168.1.0/24 --since 24h --dir logs/
+  miner-cli logs fetch -i 192.168.1.0/24 --firmware braiins --type all -o json`,
+		PersistentPreRunE: func(c *cobra.Command, args []string) error {
+			if len(ipRanges) == 0 {
+				return fmt.Errorf("no IP ranges specified, use -i flag")
+			}
+			return fleet.ValidateFirmware(firmware, fleet.FirmwareAuto, fleet.FirmwareBraiins, fleet.FirmwareVnish)
+		},
+		RunE: runLogsFetch,
+	}
+	fetchCmd.Flags().StringVar(&logOpts.Type, "type", "miner", "Log type (vnish) or log file name filter (Braiins OS), all for every Braiins OS log")
+	fetchCmd.Flags().DurationVar(&logOpts.Since, "since", 0, "Only lines newer than this (e.g. 1h), 0 for all")
+	fetchCmd.Flags().StringVar(&logDir, "dir", "", "Save the logs per host into this directory instead of printing them")
+
+	grepCmd := &cobra.Command{
+		Use:   "grep <pattern>",
+		Short: "Search a log collection saved by logs fetch --dir",
+		Long: `Search the logs saved by logs fetch --dir with a regular expression and
+print the matching lines of all miners ordered by time. -i limits the search
+to those miners.
+
+Examples:
+  miner-cli logs grep --dir logs/ "pool.*(disconnect|timeout)"
+  miner-cli logs grep --dir logs/ --level error --since 30m -i 192.168.1.10-192.168.1.20 stratum`,
+		Args: cobra.ExactArgs(1),
+		// the collection is searched offline
+		PersistentPreRunE: func(c *cobra.Command, args []string) error { return nil },
+		RunE:              runLogsGrep,
+	}
+	grepCmd.Flags().StringVar(&logDir, "dir", "", "Log collection directory")
+	grepCmd.Flags().StringVar(&logGrepLevel, "level", "", "Only lines of this level (error, warn, info, debug)")
+	grepCmd.Flags().DurationVar(&logGrepSince, "since", 0, "Only lines newer than this, 0 for all")
+	grepCmd.Flags().BoolVar(&logIgnoreCase, "ignore-case", false, "Case insensitive match")
+	grepCmd.MarkFlagRequired("dir")
+
+	clearCmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Clear the logs of vnish miners",
+		Long: `Clear a log type on vnish miners, e.g. after collecting it with logs fetch.
+Braiins OS logs cannot be cleared through its API.
+
+Examples:
+  miner-cli logs fetch -i 192.168.1.0/24 --type miner --dir logs/
+  miner-cli logs clear -i 192.168.1.0/24 --type miner`,
+		PersistentPreRunE: func(c *cobra.Command, args []string) error {
+			if len(ipRanges) == 0 {
+				return fmt.Errorf("no IP ranges specified, use -i flag")
+			}
+			return fleet.ValidateFirmware(firmware, fleet.FirmwareAuto, fleet.FirmwareVnish)
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			opts, err := fleetOptions()
+			if err != nil {
+				return err
+			}
+			logType := logClearType
+			return runFleet("logs clear", func(ctx context.Context, host string) (interface{}, error) {
+				return fleet.ClearLogs(ctx, opts, host, logType)
+			})
+		},
+	}
+	clearCmd.Flags().StringVar(&logClearType, "type", "miner", "Log type to clear")
+
+	logsCmd.AddCommand(fetchCmd, grepCmd, clearCmd)
+	rootCmd.AddCommand(logsCmd)
+}
+
+func runLogsFetch(cmd *cobra.Command, args []string) error {
+	ips, err := targetIPs()
+	if err != nil {
+		return err
+	}
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+
+	if outputFormat != "json" {
+		fmt.Fprintf(os.Stderr, "Fetching %s logs from %d hosts...\n", logOpts.Type, len(ips))
+	}
+
+	// Braiins OS logs arrive inside the support archive, which needs more
+	// than a single request timeout
+	ctx, cancel := context.WithTimeout(context.Background(), fleetDeadline(len(ips), 10*time.Duration(timeout)*time.Second))
+	defer cancel()
+
+	results := fleet.NewRunner(workers).Run(ctx, ips, fleetPort(), "logs fetch", func(ctx context.Context, host string) (interface{}, error) {
+		lines, err := fleet.FetchLogs(ctx, opts, host, logOpts)
+		if err != nil {
+			return nil, err
+		}
+		if logDir != "" {
+			if err := fleet.SaveLogs(logDir, host, lines); err != nil {
+				return nil, err
+			}
+		}
+		return lines, nil
+	})
+
+	var lines []fleet.LogLine
+	failed := make(map[string]string)
+	for _, r := range results {
+		if hostLines, ok := r.Response.([]fleet.LogLine); ok && r.Error == "" {
+			lines = append(lines, hostLines...)
+			continue
+		}
+		failed[r.IP] = r.Error
+	}
+
+	if logDir == "" {
+		w := &output.LogWriter{W: os.Stdout, JSON: outputFormat == "json"}
+		for _, line := range fleet.MergeLogs(lines) {
+			if err := w.Write(line); err != nil {
+				return err
+			}
+		}
+	} else {
+		fmt.Fprintf(os.Stderr, "Saved %d lines from %d hosts to %s\n", len(lines), len(results)-len(failed), logDir)
+	}
+
+	reportLogFailures(failed)
+	return nil
+}
+
+// reportLogFailures lists the miners whose logs could not be fetched on
+// stderr, keeping stdout a clean log stream
+func reportLogFailures(failed map[string]string) {
+	if len(failed) == 0 {
+		return
+	}
+	hosts := make([]string, 0, len(failed))
+	for host := range failed {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	fmt.Fprintf(os.Stderr, "Failed to fetch logs from %d hosts:\n", len(hosts))
+	for _, host := range hosts {
+		fmt.Fprintf(os.Stderr, "  %s: %s\n", host, failed[host])
+	}
+}
+
+func runLogsGrep(cmd *cobra.Command, args []string) error {
+	pattern := args[0]
+	if logIgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	g := fleet.GrepOptions{Pattern: re, Level: strings.ToLower(logGrepLevel), Since: logGrepSince}
+	if len(ipRanges) > 0 {
+		ips, err := targetIPs()
+		if err != nil {
+			return err
+		}
+		g.Hosts = make(map[string]bool, len(ips))
+		for _, ip := range ips {
+			g.Hosts[ip] = true
+		}
+	}
+
+	lines, err := fleet.LoadLogs(logDir)
+	if err != nil {
+		return err
+	}
+
+	w := &output.LogWriter{W: os.Stdout, JSON: outputFormat == "json"}
+	for _, line := range fleet.GrepLogs(lines, g) {
+		if err := w.Write(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/alert"
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	monitorRules    string
+	monitorInterval time.Duration
+	monitorRecord   bool
+)
+
+var monitorCmd = &cobra.Command{
+	Use:   "monitor",
+	Short: "Poll the fleet and send alerts when rules fire",
+	Long: `Poll every miner at --interval and evaluate the alert rules of --rules
+against its normalized metrics (up, hash_rate, power, temp, hardware_errors,
+accepted, rejected, stale). A rule fires once its condition has held for its
+"for" duration and notifies once, again every "repeat" if set, and once more
+when it resolves. Silences mute notifications for some rules or miners in a
+time window. Notifiers post to generic webhooks, Slack compatible webhooks,
+the Telegram Bot API or mail through SMTP. Stop with Ctrl-C.
+
+The rules file is YAML or JSON:
+
+  {
+    "rules": [
+      {"name": "miner-down", "condition": "up == 0", "for": "3m", "severity": "critical"},
+      {"name": "low-hashrate", "condition": "hash_rate < 80", "for": "15m", "model": "Antminer S19", "repeat": "6h"},
+      {"name": "hot", "condition": "temp > 85", "for": "5m", "notify": ["ops-mail"]}
+    ],
+    "notifiers": [
+      {"name": "chat", "type": "slack", "url": "$SLACK_WEBHOOK_URL"},
+      {"name": "tg", "type": "telegram", "token": "$TELEGRAM_TOKEN", "chat_id": "-100123"},
+      {"name": "hook", "type": "webhook", "url": "https://ops.example.com/miner-alerts"},
+      {"name": "ops-mail", "type": "smtp", "smtp_addr": "mail.example.com:587",
+       "username": "alerts", "password": "$SMTP_PASSWORD", "from": "alerts@example.com", "to": ["ops@example.com"]}
+    ],
+    "silences": [
+      {"hosts": ["10.0.0.0/28"], "start": "2024-05-01T08:00:00Z", "end": "2024-05-01T12:00:00Z", "comment": "rack 1 maintenance"}
+    ]
+  }
+
+Examples:
+  miner-cli monitor -i 10.0.0.0/22 --rules alerts.json
+  miner-cli monitor -i 10.0.0.0/22 --rules alerts.json --interval 30s -o json >> alerts.ndjson
+  miner-cli monitor test-notify --rules alerts.json`,
+	PersistentPreRunE: func(c *cobra.Command, args []string) error {
+		if len(ipRanges) == 0 {
+			return fmt.Errorf("no IP ranges specified, use -i flag")
+		}
+		return fleet.ValidateFirmware(firmware, fleet.FirmwareAuto, fleet.FirmwareCGMiner, fleet.FirmwareBraiins, fleet.FirmwareVnish)
+	},
+	RunE: runMonitor,
+}
+
+func init() {
+	testCmd := &cobra.Command{
+		Use:   "test-notify",
+		Short: "Send a test notification through every notifier",
+		// only the notifiers are contacted
+		PersistentPreRunE: func(c *cobra.Command, args []string) error { return nil },
+		RunE:              runMonitorTest,
+	}
+
+	monitorCmd.PersistentFlags().StringVar(&monitorRules, "rules", "", "Alert rules file")
+	monitorCmd.MarkPersistentFlagRequired("rules")
+	monitorCmd.Flags().DurationVar(&monitorInterval, "interval", time.Minute, "Polling interval")
+	monitorCmd.Flags().BoolVar(&monitorRecord, "record", true, "Also record every poll into the history store")
+	monitorCmd.Flags().StringVar(&historyDir, "history-dir", fleet.DefaultHistoryDir(), "History store directory")
+
+	monitorCmd.AddCommand(testCmd)
+	rootCmd.AddCommand(monitorCmd)
+}
+
+// loadAlerting reads the rules file and builds its notifiers
+func loadAlerting() (*alert.Config, []alert.Notifier, error) {
+	config, err := alert.Load(monitorRules)
+	if err != nil {
+		return nil, nil, err
+	}
+	notifiers, err := alert.NewNotifiers(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return config, notifiers, nil
+}
+
+func runMonitor(cmd *cobra.Command, args []string) error {
+	if monitorInterval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+	config, notifiers, err := loadAlerting()
+	if err != nil {
+		return err
+	}
+	ips, err := targetIPs()
+	if err != nil {
+		return err
+	}
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	jsonOutput := outputFormat == "json"
+	if !jsonOutput {
+		fmt.Fprintf(os.Stderr, "Monitoring %d hosts with %d rules and %d notifiers every %s, press Ctrl-C to stop...\n",
+			len(ips), len(config.Rules), len(notifiers), monitorInterval)
+	}
+
+	engine := alert.NewEngine(config)
+	store := fleet.NewHistoryStore(historyDir)
+	w := &output.AlertEventWriter{W: os.Stdout, JSON: jsonOutput}
+	for {
+		roundCtx, cancel := context.WithTimeout(ctx, fleetDeadline(len(ips), time.Duration(timeout)*time.Second))
+		results := fleet.NewRunner(workers).Run(roundCtx, ips, fleetPort(), "monitor", func(ctx context.Context, host string) (interface{}, error) {
+			return fleet.CollectSample(ctx, opts, host)
+		})
+		cancel()
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if monitorRecord {
+			if _, err := fleet.RecordSamples(store, results); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to record history: %v\n", err)
+			}
+		}
+
+		for _, e := range engine.Evaluate(time.Now(), alert.Observe(results)) {
+			if err := w.Write(e); err != nil {
+				return err
+			}
+			reportNotifyFailures(alert.Dispatch(ctx, notifiers, e))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(monitorInterval):
+		}
+	}
+}
+
+// reportNotifyFailures prints failed deliveries on stderr; monitoring
+// carries on
+func reportNotifyFailures(failed map[string]error) {
+	names := make([]string, 0, len(failed))
+	for name := range failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "Notifier %s failed: %v\n", name, failed[name])
+	}
+}
+
+func runMonitorTest(cmd *cobra.Command, args []string) error {
+	_, notifiers, err := loadAlerting()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	e := alert.Event{
+		Time: now, Status: alert.StatusFiring, Rule: "test-notify", Severity: alert.SeverityInfo,
+		Host: "0.0.0.0", Condition: "up == 1", Value: 1, Since: now,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	failed := alert.Dispatch(ctx, notifiers, e)
+	for _, n := range notifiers {
+		if failed[n.Name()] == nil {
+			fmt.Printf("%s: sent\n", n.Name())
+		}
+	}
+	reportNotifyFailures(failed)
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d notifiers failed", len(failed), len(notifiers))
+	}
+	return nil
+}
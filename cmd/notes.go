@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	noteEntry   fleet.MaintenanceNote
+	notesDir    string
+	notesExport string
+	noteFilter  fleet.NoteFilter
+)
+
+var notesCmd = &cobra.Command{
+	Use:   "notes",
+	Short: "Record and search maintenance notes per miner",
+	Long: `Keep a maintenance log on the miners themselves. vnish miners store the
+notes through their notes API; Braiins OS and CGMiner miners have no notes
+API and use a local file per host in --notes-dir, so every command works the
+same across the fleet. With --firmware auto, a note for a miner that cannot
+be reached goes to the local file with a warning, and is listed with the
+miner's own notes later.`,
+	PersistentPreRunE: func(c *cobra.Command, args []string) error {
+		if len(ipRanges) == 0 {
+			return fmt.Errorf("no IP ranges specified, use -i flag")
+		}
+		return fleet.ValidateFirmware(firmware, fleet.FirmwareAuto, fleet.FirmwareCGMiner, fleet.FirmwareBraiins, fleet.FirmwareVnish)
+	},
+}
+
+func init() {
+	addCmd := &cobra.Command{
+		Use:   "add <text>",
+		Short: "Add a note to every target miner",
+		Long: `Add a maintenance note with the current time, author and category.
+
+Examples:
+  miner-cli notes add -i 192.168.1.42 --category repair "Replaced hashboard 2, serial HB1234"
+  miner-cli notes add -i 192.168.1.0/24 --category firmware --author ops "Upgraded to 1.2.6"`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runNotesAdd,
+	}
+	addCmd.Flags().StringVar(&noteEntry.Category, "category", "", "Note category (e.g. repair, fan, psu, firmware)")
+	addCmd.Flags().StringVar(&noteEntry.Author, "author", os.Getenv("USER"), "Note author")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the notes of every target miner",
+		Long: `List the maintenance notes of the fleet, oldest first. --export writes the
+fleet-wide history to a CSV (.csv) or JSON file.
+
+Examples:
+  miner-cli notes list -i 192.168.1.0/24
+  miner-cli notes list -i 192.168.1.0/24 --export maintenance.csv`,
+		RunE: func(c *cobra.Command, args []string) error {
+			return runNotesList(nil)
+		},
+	}
+
+	searchCmd := &cobra.Command{
+		Use:   "search [pattern]",
+		Short: "Search the notes of every target miner",
+		Long: `List the notes matching a regular expression (case insensitive) on the
+text, category or author, and the --category, --author and --since filters.
+
+Examples:
+  miner-cli notes search -i 192.168.1.0/24 hashboard
+  miner-cli notes search -i 192.168.1.0/24 --category repair --since 720h --export repairs.csv`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			filter := noteFilter
+			if len(args) == 1 {
+				re, err := regexp.Compile("(?i)" + args[0])
+				if err != nil {
+					return fmt.Errorf("invalid pattern: %w", err)
+				}
+				filter.Pattern = re
+			}
+			return runNotesList(&filter)
+		},
+	}
+	searchCmd.Flags().StringVar(&noteFilter.Category, "category", "", "Only notes of this category")
+	searchCmd.Flags().StringVar(&noteFilter.Author, "author", "", "Only notes by this author")
+	searchCmd.Flags().DurationVar(&noteFilter.Since, "since", 0, "Only notes newer than this (e.g. 720h), 0 for all")
+
+	for _, c := range []*cobra.Command{listCmd, searchCmd} {
+		c.Flags().StringVar(&notesExport, "export", "", "Write the notes to this CSV (.csv) or JSON file")
+	}
+
+	notesCmd.PersistentFlags().StringVar(&notesDir, "notes-dir", fleet.DefaultNotesDir(), "Local notes for miners without a notes API")
+	notesCmd.AddCommand(addCmd, listCmd, searchCmd)
+	rootCmd.AddCommand(notesCmd)
+}
+
+func runNotesAdd(cmd *cobra.Command, args []string) error {
+	note := noteEntry
+	note.Text = strings.Join(args, " ")
+	note.Time = time.Now().UTC()
+	if err := note.Validate(); err != nil {
+		return err
+	}
+
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+	dir := notesDir
+	return runFleet("notes add", func(ctx context.Context, host string) (interface{}, error) {
+		return fleet.AddNote(ctx, opts, host, dir, note)
+	})
+}
+
+// runNotesList collects the notes of the fleet, filtered when filter is set
+func runNotesList(filter *fleet.NoteFilter) error {
+	ips, err := targetIPs()
+	if err != nil {
+		return err
+	}
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+
+	if outputFormat != "json" {
+		fmt.Printf("Reading notes from %d hosts...\n", len(ips))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fleetDeadline(len(ips), time.Duration(timeout)*time.Second))
+	defer cancel()
+
+	dir := notesDir
+	results := fleet.NewRunner(workers).Run(ctx, ips, fleetPort(), "notes list", func(ctx context.Context, host string) (interface{}, error) {
+		return fleet.ListNotes(ctx, opts, host, dir)
+	})
+
+	history := fleet.CollectNotes(results)
+	if filter != nil {
+		history.Notes = fleet.FilterNotes(history.Notes, *filter)
+	}
+
+	if notesExport != "" {
+		if err := fleet.ExportNotes(notesExport, history.Notes); err != nil {
+			return err
+		}
+	}
+
+	if outputFormat == "json" {
+		return output.PrintJSON(history, verbose)
+	}
+
+	output.WriteNotes(os.Stdout, history, verbose)
+	if notesExport != "" {
+		fmt.Printf("\n%d notes exported to %s\n", len(history.Notes), notesExport)
+	}
+	return nil
+}
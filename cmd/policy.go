@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/sinkers/miner-cli/internal/audit"
+	"github.com/sinkers/miner-cli/internal/output"
+	"github.com/sinkers/miner-cli/internal/policy"
+	"github.com/spf13/cobra"
+)
+
+var readOnly bool
+
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Show which write commands the current operator may run",
+	Long: `Show the policy in effect: where it was found, the operator and role, and
+whether each write command is allowed.
+
+Every command is classified as a read or a write (pool, restart and quit
+CGMiner commands, Braiins OS config/license/network/password writes,
+cooling set, locate, logs clear, notes add, remediate, serve, the vnish
+settings, firmware, lock, API key and autotune rollout commands, credential
+changes and history prune). custom is a read when all its API commands are known reports such
+as summary, stats or pools. --read-only blocks every write. A policy file
+gives operators roles, found in ` + policy.SystemPath + `, which always wins
+when it exists, else in $` + policy.EnvPath + ` or miner-cli/policy.json in the
+user config directory:
+
+  {
+    "default_role": "junior",
+    "roles": {
+      "junior": {"read_only": true, "allow": ["locate"], "custom_allow": ["summary", "stats", "pools", "devs"]},
+      "operator": {"deny": ["vnish firmware", "quit"], "custom_deny": ["quit", "restart"]},
+      "admin": {}
+    },
+    "users": {"alice": "operator", "root": "admin"},
+    "audit_log": "/var/log/miner-cli/audit.jsonl",
+    "audit_syslog": true
+  }
+
+Commands in allow and deny match by path or path prefix. Operators without
+a role and no default role may only read. Refused commands are recorded in
+the audit log; audit_log and audit_syslog override --audit-log and
+--audit-syslog.
+
+Examples:
+  miner-cli policy
+  miner-cli summary -i 10.0.0.0/24 --read-only`,
+	// only reads the policy
+	PersistentPreRunE: func(c *cobra.Command, args []string) error { return nil },
+	RunE:              runPolicy,
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&readOnly, "read-only", false, "Refuse every command that changes miners or local state")
+	rootCmd.AddCommand(policyCmd)
+	cobra.OnInitialize(authorize)
+}
+
+// authorize runs once the flags are parsed: it refuses the command about
+// to run when --read-only or the policy forbid it, or the policy cannot be
+// read, then makes sure a write can be audited
+func authorize() {
+	c, _, err := rootCmd.Find(os.Args[1:])
+	if err != nil {
+		return
+	}
+	if err := checkPolicy(c); err != nil {
+		var denied *policy.DeniedError
+		if errors.As(err, &denied) {
+			if logErr := auditLogger().Log(newAuditRecord(c, nil, err)); logErr != nil {
+				fmt.Fprintf(os.Stderr, "Failed to write the audit record: %v\n", logErr)
+			}
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	checkAuditLog(c)
+}
+
+// checkPolicy applies --read-only and the operator's role to c
+func checkPolicy(c *cobra.Command) error {
+	p, _, err := policy.Find()
+	if err != nil {
+		return err
+	}
+	var role policy.Role
+	if p != nil {
+		_, role = p.RoleFor(audit.CurrentUser())
+		if p.AuditLog != "" {
+			auditLog = p.AuditLog
+		}
+		auditSyslog = auditSyslog || p.AuditSyslog
+	}
+
+	ro := readOnly
+	if readsOnly(c) {
+		// deny lists still apply
+		ro, role.ReadOnly = false, false
+	}
+	return role.Check(commandName(c), customCmd, ro)
+}
+
+func runPolicy(cmd *cobra.Command, args []string) error {
+	p, path, err := policy.Find()
+	if err != nil {
+		return err
+	}
+	report := policy.Summarize(p, path, audit.CurrentUser(), readOnly)
+	if outputFormat == "json" {
+		return output.PrintJSON(report, verbose)
+	}
+	output.WritePolicyReport(os.Stdout, report)
+	return nil
+}
@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/alert"
+	"github.com/sinkers/miner-cli/internal/client"
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/iprange"
+	"github.com/sinkers/miner-cli/internal/output"
+	"github.com/sinkers/miner-cli/internal/remediate"
+	"github.com/spf13/cobra"
+)
+
+var (
+	remediatePlaybooks string
+	remediateInterval  time.Duration
+	remediateDryRun    bool
+	remediateDir       string
+	remediateHosts     []string
+	remediateSince     time.Duration
+)
+
+var remediateCmd = &cobra.Command{
+	Use:   "remediate",
+	Short: "Run remediation playbooks against unhealthy miners",
+	Long: `Check every miner at --interval and run the playbooks of --playbooks
+against the ones in a known bad condition:
+
+  unreachable    the miner API does not answer
+  zero_hashrate  the miner answers but does not hash
+  pool_dead      no enabled pool is alive
+  over_temp      the hottest chip or board is above max_temp (default 90°C)
+
+A playbook escalates through its steps while the condition holds: restart
+(mining process restart), reboot (Braiins OS and VNish), disable-board
+(disable the Braiins OS hashboards without hashrate, keeping at least one)
+and notify (send an alert through the notifiers, which take the same form
+as in monitor rules). A miner gets at most one action per round and none
+within the cooldown of its last one. After max_attempts the playbook stops
+and notifies once; it starts over when the condition clears.
+
+Every step, give-up and recovery is appended to the audit trail in
+--remediation-dir along with the playbook state, so attempts and cooldowns
+survive restarts. With --dry-run nothing is executed, notified or saved.
+Without --interval a single round runs, for cron. Stop with Ctrl-C.
+
+The playbooks file is JSON (valid YAML as well):
+
+  {
+    "max_temp": 88,
+    "playbooks": [
+      {"name": "no-hashrate", "condition": "zero_hashrate",
+       "steps": ["restart", "reboot", "disable-board", "notify"], "cooldown": "15m", "max_attempts": 4},
+      {"name": "dead-pool", "condition": "pool_dead", "steps": ["restart", "notify"], "cooldown": "10m"},
+      {"name": "offline", "condition": "unreachable", "steps": ["notify"], "max_attempts": 1}
+    ],
+    "notifiers": [{"name": "chat", "type": "slack", "url": "$SLACK_WEBHOOK_URL"}]
+  }
+
+Examples:
+  miner-cli remediate -i 10.0.0.0/22 --playbooks playbooks.json --dry-run
+  miner-cli remediate -i 10.0.0.0/22 --playbooks playbooks.json --interval 2m
+  miner-cli remediate log --host 10.0.0.17 --since 168h`,
+	PersistentPreRunE: func(c *cobra.Command, args []string) error {
+		if len(ipRanges) == 0 {
+			return fmt.Errorf("no IP ranges specified, use -i flag")
+		}
+		return fleet.ValidateFirmware(firmware, fleet.FirmwareAuto, fleet.FirmwareCGMiner, fleet.FirmwareBraiins, fleet.FirmwareVnish)
+	},
+	RunE: runRemediate,
+}
+
+func init() {
+	logCmd := &cobra.Command{
+		Use:   "log",
+		Short: "Show the remediation audit trail",
+		// reads the audit trail only
+		PersistentPreRunE: func(c *cobra.Command, args []string) error { return nil },
+		RunE:              runRemediateLog,
+	}
+	logCmd.Flags().StringSliceVar(&remediateHosts, "host", nil, "Only these miners (IP ranges), all when empty")
+	logCmd.Flags().DurationVar(&remediateSince, "since", 24*time.Hour, "Only entries newer than this, 0 for all")
+
+	remediateCmd.Flags().StringVar(&remediatePlaybooks, "playbooks", "", "Playbooks file")
+	remediateCmd.MarkFlagRequired("playbooks")
+	remediateCmd.Flags().DurationVar(&remediateInterval, "interval", 0, "Keep checking at this interval until interrupted, 0 for one round")
+	remediateCmd.Flags().BoolVar(&remediateDryRun, "dry-run", false, "Only show what would be done")
+	remediateCmd.PersistentFlags().StringVar(&remediateDir, "remediation-dir", remediate.DefaultDir(), "Remediation state and audit trail directory")
+
+	remediateCmd.AddCommand(logCmd)
+	rootCmd.AddCommand(remediateCmd)
+}
+
+func runRemediate(cmd *cobra.Command, args []string) error {
+	if remediateInterval < 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+	config, err := remediate.Load(remediatePlaybooks)
+	if err != nil {
+		return err
+	}
+	notifiers, err := config.NewNotifiers()
+	if err != nil {
+		return err
+	}
+	ips, err := targetIPs()
+	if err != nil {
+		return err
+	}
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+
+	store := remediate.NewStore(remediateDir)
+	states, err := store.LoadStates()
+	if err != nil {
+		return err
+	}
+	engine := remediate.NewEngine(config, states)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	jsonOutput := outputFormat == "json"
+	if !jsonOutput && remediateInterval > 0 {
+		fmt.Fprintf(os.Stderr, "Remediating %d hosts with %d playbooks every %s, press Ctrl-C to stop...\n",
+			len(ips), len(config.Playbooks), remediateInterval)
+	}
+
+	w := &output.RemediationWriter{W: os.Stdout, JSON: jsonOutput}
+	for {
+		roundCtx, cancel := context.WithTimeout(ctx, fleetDeadline(len(ips), time.Duration(timeout)*time.Second))
+		results := fleet.NewRunner(workers).Run(roundCtx, ips, fleetPort(), "remediate", func(ctx context.Context, host string) (interface{}, error) {
+			return fleet.CheckHealth(ctx, opts, host)
+		})
+		cancel()
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		entries := engine.Evaluate(time.Now(), healthOf(results))
+		applyRemediation(ctx, opts, notifiers, entries)
+
+		for _, e := range entries {
+			if err := w.Write(e); err != nil {
+				return err
+			}
+		}
+		if !remediateDryRun {
+			if err := store.Append(entries...); err != nil {
+				return err
+			}
+			if err := store.SaveStates(engine.States()); err != nil {
+				return err
+			}
+		}
+		if err := flushAudit(); err != nil {
+			return err
+		}
+
+		if remediateInterval == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(remediateInterval):
+		}
+	}
+}
+
+// healthOf turns health check results into health states; a check that
+// did not finish counts as unreachable
+func healthOf(results []client.Result) []*fleet.Health {
+	health := make([]*fleet.Health, 0, len(results))
+	for _, r := range results {
+		if h, ok := r.Response.(*fleet.Health); ok && r.Error == "" {
+			health = append(health, h)
+			continue
+		}
+		health = append(health, &fleet.Health{Host: r.IP, Error: r.Error})
+	}
+	return health
+}
+
+// applyRemediation runs the actions of a round in parallel and sends its
+// notifications, recording the outcome in the entries
+func applyRemediation(ctx context.Context, opts fleet.Options, notifiers []alert.Notifier, entries []remediate.Entry) {
+	actions := make(map[string]*remediate.Entry)
+	var hosts []string
+	for i := range entries {
+		e := &entries[i]
+		notify := e.Event == remediate.EventExhausted || (e.Event == remediate.EventAction && e.Action == fleet.ActionNotify)
+		switch {
+		case remediateDryRun:
+			e.DryRun = e.Event == remediate.EventAction
+		case notify:
+			failed := alert.Dispatch(ctx, notifiers, e.Alert())
+			var errs []string
+			for name, err := range failed {
+				errs = append(errs, fmt.Sprintf("notifier %s: %v", name, err))
+			}
+			sort.Strings(errs)
+			e.Error = strings.Join(errs, "; ")
+		case e.Event == remediate.EventAction:
+			actions[e.Host] = e
+			hosts = append(hosts, e.Host)
+		}
+	}
+	if len(hosts) == 0 {
+		return
+	}
+
+	actionCtx, cancel := context.WithTimeout(ctx, fleetDeadline(len(hosts), time.Duration(timeout)*time.Second))
+	defer cancel()
+	results := fleet.NewRunner(workers).Run(actionCtx, hosts, fleetPort(), "remediate", func(ctx context.Context, host string) (interface{}, error) {
+		return nil, fleet.Remediate(ctx, opts, host, actions[host].Action)
+	})
+	for _, r := range results {
+		actions[r.IP].Error = r.Error
+	}
+	auditResults(results)
+}
+
+func runRemediateLog(cmd *cobra.Command, args []string) error {
+	var since time.Time
+	if remediateSince > 0 {
+		since = time.Now().Add(-remediateSince)
+	}
+	var hosts map[string]bool
+	if len(remediateHosts) > 0 {
+		ipRange, err := iprange.ParseMultipleRanges(remediateHosts)
+		if err != nil {
+			return fmt.Errorf("failed to parse --host: %w", err)
+		}
+		hosts = make(map[string]bool)
+		for _, ip := range ipRange.GetIPs() {
+			hosts[ip] = true
+		}
+	}
+
+	entries, err := remediate.NewStore(remediateDir).Entries(hosts, since)
+	if err != nil {
+		return err
+	}
+	if outputFormat == "json" {
+		return output.PrintJSON(entries, verbose)
+	}
+	if len(entries) == 0 {
+		fmt.Println("No remediation entries")
+		return nil
+	}
+	w := &output.RemediationWriter{W: os.Stdout}
+	for _, e := range entries {
+		if err := w.Write(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
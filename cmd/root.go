@@ -9,7 +9,10 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	braiins "github.com/sinkers/miner-cli/internal/braiins/client"
 	"github.com/sinkers/miner-cli/internal/client"
+	"github.com/sinkers/miner-cli/internal/credentials"
+	"github.com/sinkers/miner-cli/internal/fleet"
 	"github.com/sinkers/miner-cli/internal/iprange"
 	"github.com/sinkers/miner-cli/internal/output"
 )
@@ -32,6 +35,17 @@ var (
 	zeroAll    bool
 	customCmd  string
 	customArgs string
+
+	firmware string
+	username string
+	password string
+	apiKey   string
+	grpcPort int
+
+	credentialsFile string
+
+	// braiinsPool shares Braiins OS connections for the whole command
+	braiinsPool = braiins.NewPool()
 )
 
 const Version = "1.0.0"
@@ -58,7 +72,12 @@ Examples:
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	c, err := rootCmd.ExecuteC()
+	if auditErr := finishAudit(c, err); auditErr != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write the audit record: %v\n", auditErr)
+	}
+	braiinsPool.Close()
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
@@ -71,6 +90,12 @@ func init() {
 	rootCmd.PersistentFlags().IntVarP(&workers, "workers", "w", 255, "Number of concurrent workers")
 	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "color", "Output format (color, json, table)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
+	rootCmd.PersistentFlags().StringVar(&firmware, "firmware", fleet.FirmwareAuto, "Miner firmware (auto, cgminer, braiins, vnish)")
+	rootCmd.PersistentFlags().StringVar(&username, "username", "root", "Braiins OS login username")
+	rootCmd.PersistentFlags().StringVar(&password, "password", "root", "Braiins OS login password")
+	rootCmd.PersistentFlags().StringVar(&apiKey, "api-key", "", "vnish API key")
+	rootCmd.PersistentFlags().IntVar(&grpcPort, "grpc-port", 50051, "Braiins OS gRPC API port")
+	rootCmd.PersistentFlags().StringVar(&credentialsFile, "credentials", credentials.DefaultPath(), "Encrypted credential store (key in "+credentials.EnvKey+")")
 
 	commands := client.GetAvailableCommands()
 	for _, cmd := range commands {
@@ -170,6 +195,7 @@ func executeCommand(command string) error {
 
 	cgClient := client.NewClient(time.Duration(timeout)*time.Second, workers)
 	results := cgClient.ExecuteCommand(ctx, ips, port, command, params)
+	auditResults(results)
 
 	// Use summary formatter for summary command unless explicitly overridden
 	formatToUse := outputFormat
@@ -227,6 +253,103 @@ func scanMiners(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// targetIPs expands the -i flags into the list of hosts to operate on
+func targetIPs() ([]string, error) {
+	if len(ipRanges) == 0 {
+		return nil, fmt.Errorf("no IP ranges specified")
+	}
+
+	ipRange, err := iprange.ParseMultipleRanges(ipRanges)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse IP ranges: %w", err)
+	}
+
+	ips := ipRange.GetIPs()
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no valid IPs in specified ranges")
+	}
+
+	return ips, nil
+}
+
+// fleetOptions collects the Braiins/vnish connection flags and the
+// credential store. Each credential given on the command line overrides the
+// store, the others still come from it.
+func fleetOptions() (fleet.Options, error) {
+	store, err := loadCredentials()
+	if err != nil {
+		return fleet.Options{}, err
+	}
+
+	flags := rootCmd.PersistentFlags()
+	return fleet.Options{
+		Firmware:    firmware,
+		Port:        port,
+		Username:    username,
+		Password:    password,
+		APIKey:      apiKey,
+		GRPCPort:    grpcPort,
+		Timeout:     time.Duration(timeout) * time.Second,
+		Credentials: store,
+		Override: fleet.CredentialOverrides{
+			Username: flags.Changed("username"),
+			Password: flags.Changed("password"),
+			APIKey:   flags.Changed("api-key"),
+		},
+		Pool: braiinsPool,
+	}, nil
+}
+
+// loadCredentials reads the credential store from MINER_CLI_CREDENTIALS or
+// the encrypted --credentials file
+func loadCredentials() (*credentials.Store, error) {
+	store, err := credentials.FromEnv()
+	if err != nil || store != nil {
+		return store, err
+	}
+	return credentials.Load(credentialsFile, os.Getenv(credentials.EnvKey))
+}
+
+// fleetPort returns the API port reported in results for the selected
+// firmware; with auto the Runner reports that of the detected firmware
+func fleetPort() int {
+	return fleet.Options{Firmware: firmware, Port: port, GRPCPort: grpcPort}.ResultPort()
+}
+
+// fleetDeadline bounds a run over n hosts whose task takes up to perHost,
+// leaving each host time to detect its firmware with --firmware auto
+func fleetDeadline(n int, perHost time.Duration) time.Duration {
+	perHost += fleet.DetectBudget(firmware, time.Duration(timeout)*time.Second)
+	return perHost * time.Duration(n/workers+1)
+}
+
+// runFleet executes task on every target host and prints the results
+func runFleet(command string, task fleet.Task) error {
+	ips, err := targetIPs()
+	if err != nil {
+		return err
+	}
+
+	results := fleetResults(command, ips, task)
+
+	formatter := output.GetFormatter(outputFormat, verbose)
+	return formatter.Format(results)
+}
+
+// fleetResults runs task on ips and records the results for the audit log
+func fleetResults(command string, ips []string, task fleet.Task) []client.Result {
+	if outputFormat != "json" {
+		fmt.Printf("Executing '%s' on %d hosts...\n", command, len(ips))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fleetDeadline(len(ips), time.Duration(timeout)*time.Second))
+	defer cancel()
+
+	results := fleet.NewRunner(workers).Run(ctx, ips, fleetPort(), command, task)
+	auditResults(results)
+	return results
+}
+
 func parseIntParam(param string) (int, error) {
 	return strconv.Atoi(param)
 }
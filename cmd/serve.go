@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/audit"
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/inventory"
+	"github.com/sinkers/miner-cli/internal/policy"
+	"github.com/sinkers/miner-cli/internal/server"
+	"github.com/spf13/cobra"
+)
+
+// serveTokenEnv names the environment variable holding API tokens
+const serveTokenEnv = "MINER_CLI_API_TOKEN"
+
+var (
+	serveListen    string
+	serveTokens    []string
+	serveInventory string
+	serveTLSCert   string
+	serveTLSKey    string
+	serveMaxJobs   int
+	serveMaxHosts  int
+	serveRunning   int
+	serveWriters   []string
+	serveAdmins    []string
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the fleet commands as a REST API",
+	Long: `Run an HTTP server that starts commands against miners as jobs, so that
+dashboards can trigger fleet actions without running the CLI.
+
+Every request needs "Authorization: Bearer <token>". Tokens are given as
+name:secret with --token or, comma separated, in $` + serveTokenEnv + `; a
+token without a name is called "api". Clients appear as api:<name> in the
+policy and the audit log, so roles can be given to them as to operators.
+No client may run more than the role of the operator starting the server,
+which needs a role that allows serve unless --read-only is given.
+--read-only refuses every write job. Without a policy file clients may only
+read, except those named with --writer.
+
+Clients see their own jobs only, those named with --admin see every job.
+A client runs at most --max-running jobs at once; more are refused with 429.
+
+  GET  /api/v1/commands              commands and whether they write
+  POST /api/v1/jobs                  start a job, 202 with the job
+  GET  /api/v1/jobs                  the client's jobs, newest first
+  GET  /api/v1/jobs/{id}             the job with its results as -o json
+  GET  /api/v1/jobs/{id}/events      server-sent events, "result" per miner then "done"
+  GET  /api/v1/inventory[?rack=A1]   racks and miners of --inventory
+
+A job names a command, the miners as IP ranges and/or inventory racks, and
+the parameters of the command (pool; url, user, pass; cmd for custom):
+
+  {"command": "switchpool", "ips": ["10.0.0.0/24"], "racks": ["A1"],
+   "firmware": "cgminer", "params": {"pool": "1"}}
+
+The commands are the CGMiner API commands plus health, errors, cooling get,
+bos status, locate status/on/off, restart-mining and reboot, which use the
+Braiins OS and vnish APIs as their CLI counterparts. Stop with Ctrl-C.
+
+Examples:
+  miner-cli serve --listen :8080 --token dashboard:s3cret --inventory racks.csv
+  miner-cli serve --token ops:s3cret --token grafana:t0ken --writer ops --admin ops
+  MINER_CLI_API_TOKEN=s3cret miner-cli serve --tls-cert cert.pem --tls-key key.pem`,
+	PersistentPreRunE: func(c *cobra.Command, args []string) error {
+		// the miners come with each job, not -i
+		return fleet.ValidateFirmware(firmware, fleet.FirmwareAuto, fleet.FirmwareCGMiner, fleet.FirmwareBraiins, fleet.FirmwareVnish)
+	},
+	RunE: runServe,
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveListen, "listen", ":8080", "Address to listen on")
+	serveCmd.Flags().StringArrayVar(&serveTokens, "token", nil, "API token as name:secret (repeatable)")
+	serveCmd.Flags().StringVar(&serveInventory, "inventory", "", "Inventory CSV file for racks and GET /api/v1/inventory")
+	serveCmd.Flags().StringVar(&serveTLSCert, "tls-cert", "", "TLS certificate file, serves HTTPS with --tls-key")
+	serveCmd.Flags().StringVar(&serveTLSKey, "tls-key", "", "TLS key file")
+	serveCmd.Flags().IntVar(&serveMaxJobs, "max-jobs", 100, "Finished jobs kept for polling")
+	serveCmd.Flags().IntVar(&serveMaxHosts, "max-hosts", server.DefaultMaxHosts, "Most miners a job may target")
+	serveCmd.Flags().IntVar(&serveRunning, "max-running", server.DefaultMaxRunning, "Most jobs a client runs at once")
+	serveCmd.Flags().StringSliceVar(&serveWriters, "writer", nil, "Clients that may run write jobs without a policy file")
+	serveCmd.Flags().StringSliceVar(&serveAdmins, "admin", nil, "Clients that see the jobs of every client")
+
+	rootCmd.AddCommand(serveCmd)
+}
+
+// parseTokens maps the secrets of name:secret tokens to their names
+func parseTokens(values []string) (map[string]string, error) {
+	tokens := make(map[string]string)
+	for _, v := range values {
+		name, secret := "api", v
+		if i := strings.Index(v, ":"); i >= 0 {
+			name, secret = v[:i], v[i+1:]
+		}
+		if name == "" || secret == "" {
+			return nil, fmt.Errorf("invalid API token %q, expected name:secret", v)
+		}
+		tokens[secret] = name
+	}
+	return tokens, nil
+}
+
+// clientSet collects client names
+func clientSet(names []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	if (serveTLSCert == "") != (serveTLSKey == "") {
+		return fmt.Errorf("--tls-cert and --tls-key must be given together")
+	}
+	values := serveTokens
+	if len(values) == 0 && os.Getenv(serveTokenEnv) != "" {
+		values = strings.Split(os.Getenv(serveTokenEnv), ",")
+	}
+	tokens, err := parseTokens(values)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return fmt.Errorf("no API tokens, use --token or $%s", serveTokenEnv)
+	}
+
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+	p, _, err := policy.Find()
+	if err != nil {
+		return err
+	}
+	var operator policy.Role
+	if p != nil {
+		_, operator = p.RoleFor(audit.CurrentUser())
+	}
+	var inv *inventory.Inventory
+	if serveInventory != "" {
+		if inv, err = inventory.Load(serveInventory); err != nil {
+			return err
+		}
+	}
+	logger := auditLogger()
+	if err := logger.Check(); err != nil {
+		return fmt.Errorf("refusing to serve unaudited write jobs: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	s := server.New(ctx, server.Config{
+		Options:    opts,
+		Workers:    workers,
+		Timeout:    time.Duration(timeout) * time.Second,
+		Tokens:     tokens,
+		Inventory:  inv,
+		Policy:     p,
+		ReadOnly:   readOnly,
+		Operator:   operator,
+		Audit:      logger,
+		MaxJobs:    serveMaxJobs,
+		MaxHosts:   serveMaxHosts,
+		MaxRunning: serveRunning,
+		Writers:    clientSet(serveWriters),
+		Admins:     clientSet(serveAdmins),
+	})
+	httpServer := &http.Server{Addr: serveListen, Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	errs := make(chan error, 1)
+	go func() {
+		if serveTLSCert != "" {
+			errs <- httpServer.ListenAndServeTLS(serveTLSCert, serveTLSKey)
+		} else {
+			errs <- httpServer.ListenAndServe()
+		}
+	}()
+	fmt.Fprintf(os.Stderr, "Serving the API on %s with %d tokens, press Ctrl-C to stop...\n", serveListen, len(tokens))
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("failed to serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("failed to stop the server: %w", err)
+	}
+	return nil
+}
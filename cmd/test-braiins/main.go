@@ -322,8 +322,9 @@ func (s *TestSuite) testReadOperations() {
 		}
 
 		license := models.ConvertLicenseState(resp)
-		if state, ok := license["state"]; ok {
-			printInfo("License State: %v", state)
+		printInfo("License State: %s", license.State)
+		if license.ContractName != "" {
+			printInfo("Contract: %s (dev fee %.2f%%)", license.ContractName, license.DevFee)
 		}
 		
 		return nil
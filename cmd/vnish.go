@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/spf13/cobra"
+)
+
+var vnishCmd = &cobra.Command{
+	Use:   "vnish",
+	Short: "vnish specific operations",
+	Long: `Commands that use the vnish REST API without an equivalent on other
+firmware. The firmware is assumed to be vnish unless --firmware is set.`,
+	PersistentPreRunE: func(c *cobra.Command, args []string) error {
+		if len(ipRanges) == 0 {
+			return fmt.Errorf("no IP ranges specified, use -i flag")
+		}
+		if firmware == fleet.FirmwareAuto {
+			firmware = fleet.FirmwareVnish
+		}
+		return fleet.ValidateFirmware(firmware, fleet.FirmwareVnish)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(vnishCmd)
+}
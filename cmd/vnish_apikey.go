@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/credentials"
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var apiKeyOpts fleet.APIKeyOptions
+
+var vnishAPIKeyCmd = &cobra.Command{
+	Use:   "apikey",
+	Short: "Provision, rotate and revoke vnish API keys",
+	Long: `Manage the API keys automation uses to talk to vnish miners. Each miner is
+logged in to once with its password from the credential store, and new keys
+are saved to the credential store as an entry for the miner's IP, named
+vnish-apikey/<ip> or vnish-apikey/<mac> with --by-mac. Entries are looked up
+by IP either way; a rotation with --by-mac also replaces the entry of a
+miner whose IP changed. Old keys are deleted from the miners only after the
+new ones are saved. Keys are never printed. The store must be writable
+(` + credentials.EnvKey + ` set).
+
+Examples:
+  miner-cli vnish apikey provision -i 192.168.1.0/24
+  miner-cli vnish apikey rotate -i 192.168.1.0/24 --by-mac
+  miner-cli vnish apikey revoke -i 192.168.1.42`,
+}
+
+func init() {
+	for _, a := range []struct{ action, short string }{
+		{fleet.APIKeyProvision, "Create a key on miners that have none stored"},
+		{fleet.APIKeyRotate, "Replace the key of every miner"},
+		{fleet.APIKeyRevoke, "Delete the key from miners and the credential store"},
+	} {
+		action := a.action
+		vnishAPIKeyCmd.AddCommand(&cobra.Command{
+			Use:   action,
+			Short: a.short,
+			RunE: func(c *cobra.Command, args []string) error {
+				return runAPIKey(action)
+			},
+		})
+	}
+
+	vnishAPIKeyCmd.PersistentFlags().StringVar(&apiKeyOpts.Name, "name", "miner-cli", "Key name on the miners")
+	vnishAPIKeyCmd.PersistentFlags().BoolVar(&apiKeyOpts.ByMAC, "by-mac", false, "Name credential entries after the miner MAC address")
+	vnishCmd.AddCommand(vnishAPIKeyCmd)
+}
+
+func runAPIKey(action string) error {
+	a := apiKeyOpts
+	a.Action = action
+	if err := a.Validate(); err != nil {
+		return err
+	}
+	// keys created on the miners must reach the store
+	if err := checkCredentialsWritable(); err != nil {
+		return err
+	}
+
+	ips, err := targetIPs()
+	if err != nil {
+		return err
+	}
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+
+	if outputFormat != "json" {
+		fmt.Printf("Running API key %s on %d hosts...\n", action, len(ips))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fleetDeadline(len(ips), time.Duration(timeout)*time.Second))
+	defer cancel()
+
+	results := fleet.NewRunner(workers).Run(ctx, ips, fleetPort(), "vnish apikey "+action, func(ctx context.Context, host string) (interface{}, error) {
+		return fleet.ManageAPIKey(ctx, opts, host, a)
+	})
+	auditResults(results)
+
+	report := fleet.SummarizeAPIKeys(action, results)
+	if len(report.Changed) > 0 {
+		if err := saveCredentials(func(store *credentials.Store) error {
+			return fleet.StoreAPIKeys(store, report)
+		}); err != nil {
+			return fmt.Errorf("keys changed on the miners but the credential store was not updated: %w", err)
+		}
+		// the old keys go only once the new ones are saved
+		fleet.RetireAPIKeys(ctx, fleet.NewRunner(workers), fleetPort(), report)
+	}
+
+	if outputFormat == "json" {
+		return output.PrintJSON(report, verbose)
+	}
+
+	output.WriteAPIKeyReport(os.Stdout, report, verbose)
+	if len(report.Changed) > 0 {
+		fmt.Printf("\nCredentials saved to %s\n", credentialsFile)
+	}
+	return nil
+}
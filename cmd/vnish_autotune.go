@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/client"
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rolloutOpts   fleet.RolloutOptions
+	rolloutReport string
+)
+
+var vnishAutotuneCmd = &cobra.Command{
+	Use:   "autotune",
+	Short: "List, roll out and compare autotune presets",
+}
+
+func init() {
+	presetsCmd := &cobra.Command{
+		Use:   "presets",
+		Short: "List the autotune presets available per model",
+		Long: `Read the autotune presets offered by every miner and group them by model,
+with the number of miners running each preset.
+
+Examples:
+  miner-cli vnish autotune presets -i 192.168.1.0/24`,
+		RunE: runAutotunePresets,
+	}
+
+	rolloutCmd := &cobra.Command{
+		Use:   "rollout",
+		Short: "Switch miners to a preset in batches and measure the result",
+		Long: `Switch miners to an autotune preset, kept in the overclock settings, one
+batch at a time. Each miner's performance (GetPerfSummary) is recorded before
+the change and again once the batch has settled; batches that changed no
+miner do not wait. Miners of another model (--model), already on the preset
+or without it are skipped. A batch with
+failures stops the rollout unless --continue-on-error is set.
+
+Examples:
+  miner-cli vnish autotune rollout -i 192.168.1.0/24 --preset 3400w --model "Antminer S19" --batch-size 10 --settle 20m --report s19-3400w.json`,
+		RunE: runAutotuneRollout,
+	}
+	rolloutCmd.Flags().StringVar(&rolloutOpts.Preset, "preset", "", "Preset ID to apply")
+	rolloutCmd.Flags().StringVar(&rolloutOpts.Model, "model", "", "Only change miners of this model")
+	rolloutCmd.Flags().IntVar(&rolloutOpts.BatchSize, "batch-size", 10, "Miners changed per batch")
+	rolloutCmd.Flags().DurationVar(&rolloutOpts.Settle, "settle", 15*time.Minute, "Time for autotune to settle before measuring")
+	rolloutCmd.Flags().BoolVar(&rolloutOpts.ContinueOnError, "continue-on-error", false, "Keep rolling out after a batch with failures")
+	rolloutCmd.Flags().StringVar(&rolloutReport, "report", "", "Write the rollout report as JSON to this file")
+	rolloutCmd.MarkFlagRequired("preset")
+
+	compareCmd := &cobra.Command{
+		Use:   "compare report.json...",
+		Short: "Compare presets from one or more rollout reports",
+		Long: `Combine rollout reports and rank the presets per model by efficiency
+after the change, with the average hashrate, power and efficiency change.
+
+Examples:
+  miner-cli vnish autotune compare s19-3400w.json s19-3000w.json`,
+		Args: cobra.MinimumNArgs(1),
+		// reports are compared offline
+		PersistentPreRunE: func(c *cobra.Command, args []string) error { return nil },
+		RunE:              runAutotuneCompare,
+	}
+
+	vnishAutotuneCmd.AddCommand(presetsCmd, rolloutCmd, compareCmd)
+	vnishCmd.AddCommand(vnishAutotuneCmd)
+}
+
+func runAutotunePresets(cmd *cobra.Command, args []string) error {
+	ips, err := targetIPs()
+	if err != nil {
+		return err
+	}
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+
+	if outputFormat != "json" {
+		fmt.Printf("Reading autotune presets from %d hosts...\n", len(ips))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fleetDeadline(len(ips), time.Duration(timeout)*time.Second))
+	defer cancel()
+
+	results := fleet.NewRunner(workers).Run(ctx, ips, fleetPort(), "autotune presets", func(ctx context.Context, host string) (interface{}, error) {
+		return fleet.GetPresets(ctx, opts, host)
+	})
+
+	report := fleet.SummarizePresets(results)
+
+	if outputFormat == "json" {
+		return output.PrintJSON(report, verbose)
+	}
+
+	output.WritePresetReport(os.Stdout, report, verbose)
+	return nil
+}
+
+func runAutotuneRollout(cmd *cobra.Command, args []string) error {
+	if rolloutOpts.BatchSize <= 0 {
+		return fmt.Errorf("batch size must be positive")
+	}
+	ips, err := targetIPs()
+	if err != nil {
+		return err
+	}
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+
+	batches := (len(ips) + rolloutOpts.BatchSize - 1) / rolloutOpts.BatchSize
+	if outputFormat != "json" {
+		fmt.Printf("Rolling out preset %s to %d hosts in %d batches, settling %s each...\n",
+			rolloutOpts.Preset, len(ips), batches, rolloutOpts.Settle)
+	}
+
+	// The rollout takes at least batches * settle, so it is only bounded by
+	// Ctrl-C; each request still uses the global timeout.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	results := fleet.RolloutPreset(ctx, opts, fleet.NewRunner(workers), ips, rolloutOpts, func(batch int, results []client.Result) {
+		if outputFormat == "json" {
+			return
+		}
+		failed := 0
+		for _, r := range results {
+			if r.Error != "" {
+				failed++
+			}
+		}
+		fmt.Printf("Batch %d/%d done: %d hosts, %d failed\n", batch, batches, len(results), failed)
+	})
+	auditResults(results)
+
+	report := fleet.SummarizeRollout(rolloutOpts.Preset, results)
+	if rolloutReport != "" {
+		if err := report.Save(rolloutReport); err != nil {
+			return err
+		}
+	}
+
+	if outputFormat == "json" {
+		return output.PrintJSON(report, verbose)
+	}
+
+	output.WriteRolloutReport(os.Stdout, report, verbose)
+	if rolloutReport != "" {
+		fmt.Printf("\nReport written to %s\n", rolloutReport)
+	}
+	return nil
+}
+
+func runAutotuneCompare(cmd *cobra.Command, args []string) error {
+	var results []fleet.RolloutResult
+	for _, path := range args {
+		report, err := fleet.LoadRolloutReport(path)
+		if err != nil {
+			return err
+		}
+		results = append(results, report.Results...)
+	}
+
+	comparisons := fleet.ComparePresets(results)
+
+	if outputFormat == "json" {
+		return output.PrintJSON(comparisons, verbose)
+	}
+
+	if len(comparisons) == 0 {
+		fmt.Println("No miners measured before and after a preset change")
+		return nil
+	}
+	output.WritePresetComparisons(os.Stdout, comparisons)
+	return nil
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	upgradeOpts        fleet.UpgradeOptions
+	upgradeImage       string
+	upgradeConcurrency int
+	upgradeListen      string
+	upgradeAdvertise   string
+)
+
+var vnishFirmwareCmd = &cobra.Command{
+	Use:   "firmware",
+	Short: "Manage vnish firmware",
+}
+
+func init() {
+	upgradeCmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrade miners to a firmware image and verify the new version",
+		Long: `Upgrade vnish miners to a firmware image. Miners already running --version
+are skipped unless --force is set. Each miner is told to download the image,
+then polled (CheckAuth, then GetInfo) until it is back and reports the new
+version. Failed upgrades are retried --retries times, and the report lists
+the failed hosts for a later run.
+
+--image is a URL the miners can reach, or a local file which is served over
+HTTP from this machine (--listen, --advertise) for the duration of the run.
+
+Examples:
+  miner-cli vnish firmware upgrade -i 192.168.1.0/24 --image http://files.local/vnish-1.2.6.tar --version 1.2.6
+  miner-cli vnish firmware upgrade -i 192.168.1.0/24 --image ./vnish-1.2.6.tar --version 1.2.6 --concurrency 10 --retries 1`,
+		RunE: runFirmwareUpgrade,
+	}
+	upgradeCmd.Flags().StringVar(&upgradeImage, "image", "", "Firmware image URL or local file")
+	upgradeCmd.Flags().StringVar(&upgradeOpts.Version, "version", "", "Firmware version expected after the upgrade")
+	upgradeCmd.Flags().BoolVar(&upgradeOpts.Force, "force", false, "Upgrade miners already running the version")
+	upgradeCmd.Flags().IntVar(&upgradeOpts.Retries, "retries", 0, "Extra attempts for a failed upgrade")
+	upgradeCmd.Flags().IntVar(&upgradeConcurrency, "concurrency", 5, "Miners upgraded at the same time")
+	upgradeCmd.Flags().DurationVar(&upgradeOpts.RebootDelay, "reboot-delay", 30*time.Second, "Wait after starting the update before polling")
+	upgradeCmd.Flags().DurationVar(&upgradeOpts.RebootTimeout, "reboot-timeout", 15*time.Minute, "Time allowed for a miner to come back")
+	upgradeCmd.Flags().DurationVar(&upgradeOpts.PollInterval, "poll-interval", 10*time.Second, "Interval between checks while a miner reboots")
+	upgradeCmd.Flags().StringVar(&upgradeListen, "listen", ":8089", "Address to serve a local image on")
+	upgradeCmd.Flags().StringVar(&upgradeAdvertise, "advertise", "", "Address miners use to fetch a local image (default: detected)")
+	upgradeCmd.MarkFlagRequired("image")
+	upgradeCmd.MarkFlagRequired("version")
+
+	vnishFirmwareCmd.AddCommand(upgradeCmd)
+	vnishCmd.AddCommand(vnishFirmwareCmd)
+}
+
+func runFirmwareUpgrade(cmd *cobra.Command, args []string) error {
+	if upgradeConcurrency <= 0 {
+		return fmt.Errorf("concurrency must be positive")
+	}
+	ips, err := targetIPs()
+	if err != nil {
+		return err
+	}
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+
+	upgradeOpts.ImageURL = upgradeImage
+	if !fleet.IsImageURL(upgradeImage) && upgradeImage != "" {
+		server, err := fleet.ServeImage(upgradeImage, upgradeListen, upgradeAdvertise, ips[0])
+		if err != nil {
+			return err
+		}
+		defer server.Close()
+		upgradeOpts.ImageURL = server.URL
+		if outputFormat != "json" {
+			fmt.Printf("Serving %s at %s\n", upgradeImage, server.URL)
+		}
+	}
+	if err := upgradeOpts.Validate(); err != nil {
+		return err
+	}
+
+	if outputFormat != "json" {
+		fmt.Printf("Upgrading %d hosts to %s, %d at a time...\n", len(ips), upgradeOpts.Version, upgradeConcurrency)
+	}
+
+	// Upgrades wait for reboots, so the run is only bounded by Ctrl-C and
+	// --reboot-timeout; each request still uses the global timeout.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	results := fleet.NewRunner(upgradeConcurrency).Run(ctx, ips, fleetPort(), "firmware upgrade", func(ctx context.Context, host string) (interface{}, error) {
+		return fleet.UpgradeFirmware(ctx, opts, host, upgradeOpts)
+	})
+	auditResults(results)
+
+	report := fleet.SummarizeUpgrades(results)
+
+	if outputFormat == "json" {
+		return output.PrintJSON(report, verbose)
+	}
+
+	output.WriteUpgradeReport(os.Stdout, report, verbose)
+	return nil
+}
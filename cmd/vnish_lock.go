@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var lockOthers bool
+
+func init() {
+	lockCmd := &cobra.Command{
+		Use:   "lock",
+		Short: "Lock miners, or drop their other sessions",
+		Long: `Lock the web interface and API of vnish miners. The password of each miner
+is taken from the credential store, falling back to --password. While a
+miner is locked, write commands of this tool refuse it with a per-host error.
+
+Examples:
+  miner-cli vnish lock -i 192.168.1.0/24
+  miner-cli vnish lock --others -i 192.168.1.0/24
+  miner-cli vnish lock status -i 192.168.1.0/24`,
+		RunE: func(c *cobra.Command, args []string) error {
+			opts, err := fleetOptions()
+			if err != nil {
+				return err
+			}
+			others := lockOthers
+			return runFleet("vnish lock", func(ctx context.Context, host string) (interface{}, error) {
+				return fleet.LockMiner(ctx, opts, host, others)
+			})
+		},
+	}
+	lockCmd.Flags().BoolVar(&lockOthers, "others", false, "Drop the other sessions instead of locking")
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Report which miners are locked",
+		RunE:  runLockStatus,
+	}
+	lockCmd.AddCommand(statusCmd)
+
+	unlockCmd := &cobra.Command{
+		Use:   "unlock",
+		Short: "Unlock miners with their password from the credential store",
+		RunE: func(c *cobra.Command, args []string) error {
+			opts, err := fleetOptions()
+			if err != nil {
+				return err
+			}
+			return runFleet("vnish unlock", func(ctx context.Context, host string) (interface{}, error) {
+				return fleet.UnlockMiner(ctx, opts, host)
+			})
+		},
+	}
+
+	vnishCmd.AddCommand(lockCmd, unlockCmd)
+}
+
+func runLockStatus(cmd *cobra.Command, args []string) error {
+	ips, err := targetIPs()
+	if err != nil {
+		return err
+	}
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+
+	if outputFormat != "json" {
+		fmt.Printf("Reading lock status from %d hosts...\n", len(ips))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fleetDeadline(len(ips), time.Duration(timeout)*time.Second))
+	defer cancel()
+
+	results := fleet.NewRunner(workers).Run(ctx, ips, fleetPort(), "vnish lock status", func(ctx context.Context, host string) (interface{}, error) {
+		return fleet.GetLockState(ctx, opts, host)
+	})
+
+	report := fleet.SummarizeLocks(results)
+
+	if outputFormat == "json" {
+		return output.PrintJSON(report, verbose)
+	}
+
+	output.WriteLockReport(os.Stdout, report, verbose)
+	return nil
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	settingsDir          string
+	settingsOnDevice     bool
+	settingsDeviceBackup string
+	settingsGolden       string
+	settingsSections     []string
+	settingsDryRun       bool
+)
+
+var vnishSettingsCmd = &cobra.Command{
+	Use:   "settings",
+	Short: "Back up, restore, compare and apply vnish settings",
+}
+
+func init() {
+	backupCmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Save the settings of every miner",
+		Long: `Save the vnish settings (pools, fan, temperature, advanced and network)
+of every miner as one JSON file per host. The files include pool passwords
+and are readable by the owner only. With --on-device the miner also keeps a
+backup that can be restored with restore --device-backup.
+
+Examples:
+  miner-cli vnish settings backup -i 192.168.1.0/24 --dir backups/`,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := os.MkdirAll(settingsDir, 0700); err != nil {
+				return fmt.Errorf("failed to create backup directory: %w", err)
+			}
+			opts, err := fleetOptions()
+			if err != nil {
+				return err
+			}
+			dir, onDevice := settingsDir, settingsOnDevice
+			return runFleet("settings backup", func(ctx context.Context, host string) (interface{}, error) {
+				return fleet.BackupSettings(ctx, opts, host, dir, onDevice)
+			})
+		},
+	}
+	backupCmd.Flags().StringVar(&settingsDir, "dir", "./backups", "Backup directory")
+	backupCmd.Flags().BoolVar(&settingsOnDevice, "on-device", false, "Also create a backup on each miner")
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Write saved settings back to every miner",
+		Long: `Restore the settings saved by backup, matching files to miners by IP,
+or restore a backup kept on the miners with --device-backup.
+
+Examples:
+  miner-cli vnish settings restore -i 192.168.1.0/24 --dir backups/
+  miner-cli vnish settings restore -i 192.168.1.50 --device-backup settings_backup.tar`,
+		RunE: func(c *cobra.Command, args []string) error {
+			opts, err := fleetOptions()
+			if err != nil {
+				return err
+			}
+			dir, deviceBackup := settingsDir, settingsDeviceBackup
+			return runFleet("settings restore", func(ctx context.Context, host string) (interface{}, error) {
+				if deviceBackup != "" {
+					return fleet.RestoreDeviceBackup(ctx, opts, host, deviceBackup)
+				}
+				return fleet.RestoreSettings(ctx, opts, host, dir)
+			})
+		},
+	}
+	restoreCmd.Flags().StringVar(&settingsDir, "dir", "./backups", "Backup directory")
+	restoreCmd.Flags().StringVar(&settingsDeviceBackup, "device-backup", "", "Restore this backup file stored on the miner instead")
+
+	diffCmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Compare the settings of every miner with a golden file",
+		Long: `Compare the settings of every miner with a golden settings file, such as
+a backup of a known-good unit, and list the differing values. Passwords are
+shown as *** unless --verbose is set.
+
+Examples:
+  miner-cli vnish settings diff -i 192.168.1.0/24 --golden golden.json`,
+		RunE: runSettingsDiff,
+	}
+	diffCmd.Flags().StringVar(&settingsGolden, "golden", "", "Golden settings file")
+	diffCmd.MarkFlagRequired("golden")
+
+	applyCmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Apply the differing sections of a golden file",
+		Long: `Send each miner the values of a golden settings file that differ from
+its own settings, in the selected sections, and leave the rest unchanged.
+Lists such as pools are sent whole when they differ. Network settings are only
+applied when selected with --sections, and a static address can only be
+applied to a single miner.
+
+Examples:
+  miner-cli vnish settings apply -i 192.168.1.0/24 --golden golden.json --dry-run
+  miner-cli vnish settings apply -i 192.168.1.50 --golden golden.json --sections pools,fan`,
+		RunE: runSettingsApply,
+	}
+	applyCmd.Flags().StringVar(&settingsGolden, "golden", "", "Golden settings file")
+	applyCmd.Flags().StringSliceVar(&settingsSections, "sections", fleet.SettingsSections, "Sections to apply (pools, fan, temperature, advanced, network)")
+	applyCmd.Flags().BoolVar(&settingsDryRun, "dry-run", false, "Show the differences without applying them")
+	applyCmd.MarkFlagRequired("golden")
+
+	vnishSettingsCmd.AddCommand(backupCmd, restoreCmd, diffCmd, applyCmd)
+	vnishCmd.AddCommand(vnishSettingsCmd)
+}
+
+func runSettingsDiff(cmd *cobra.Command, args []string) error {
+	golden, err := fleet.LoadSettings(settingsGolden)
+	if err != nil {
+		return err
+	}
+	ips, err := targetIPs()
+	if err != nil {
+		return err
+	}
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+
+	if outputFormat != "json" {
+		fmt.Printf("Comparing %d hosts with %s...\n", len(ips), settingsGolden)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fleetDeadline(len(ips), time.Duration(timeout)*time.Second))
+	defer cancel()
+
+	results := fleet.NewRunner(workers).Run(ctx, ips, fleetPort(), "settings diff", func(ctx context.Context, host string) (interface{}, error) {
+		return fleet.DiffSettingsHost(ctx, opts, host, golden)
+	})
+
+	report := fleet.SummarizeConfigDiffs(results)
+	if !verbose {
+		report.Redact()
+	}
+
+	if outputFormat == "json" {
+		return output.PrintJSON(report, verbose)
+	}
+
+	output.WriteConfigDiffReport(os.Stdout, report, verbose)
+	return nil
+}
+
+func runSettingsApply(cmd *cobra.Command, args []string) error {
+	if err := fleet.ValidateSettingsSections(settingsSections); err != nil {
+		return err
+	}
+	golden, err := fleet.LoadSettings(settingsGolden)
+	if err != nil {
+		return err
+	}
+	ips, err := targetIPs()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range settingsSections {
+		if s == fleet.SettingsNetwork && !golden.Network.DHCP && len(ips) > 1 {
+			return fmt.Errorf("golden file has a static address, network settings can only be applied to a single miner")
+		}
+	}
+
+	opts, err := fleetOptions()
+	if err != nil {
+		return err
+	}
+	sections, dryRun := settingsSections, settingsDryRun
+	return runFleet("settings apply", func(ctx context.Context, host string) (interface{}, error) {
+		result, err := fleet.ApplySettings(ctx, opts, host, golden, sections, dryRun)
+		if err == nil && !verbose {
+			result.Redact()
+		}
+		return result, err
+	})
+}
@@ -0,0 +1,216 @@
+package alert
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/client"
+	"github.com/sinkers/miner-cli/internal/fleet"
+)
+
+// Alert statuses
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+)
+
+// Observation is the polled state of one miner
+type Observation struct {
+	Host    string
+	Model   string
+	Up      bool
+	Metrics map[string]float64
+	Error   string // why the miner is down
+}
+
+// Observe turns the results of a fleet.CollectSample run into observations
+func Observe(results []client.Result) []Observation {
+	obs := make([]Observation, 0, len(results))
+	for _, r := range results {
+		s, ok := r.Response.(*fleet.Sample)
+		if r.Error != "" || !ok {
+			obs = append(obs, Observation{Host: r.IP, Error: r.Error})
+			continue
+		}
+		obs = append(obs, Observation{Host: r.IP, Model: s.Model, Up: true, Metrics: SampleMetrics(s)})
+	}
+	return obs
+}
+
+// SampleMetrics returns the rule metrics of a sample
+func SampleMetrics(s *fleet.Sample) map[string]float64 {
+	return map[string]float64{
+		"hash_rate":       s.HashRate,
+		"power":           s.Power,
+		"temp":            s.Temp,
+		"hardware_errors": float64(s.HardwareErrors),
+		"accepted":        float64(s.Accepted),
+		"rejected":        float64(s.Rejected),
+		"stale":           float64(s.Stale),
+	}
+}
+
+// Event is a notification: an alert started firing, is still firing after
+// the rule's repeat interval, or resolved
+type Event struct {
+	Time      time.Time `json:"time"`
+	Status    string    `json:"status"`
+	Rule      string    `json:"rule"`
+	Severity  string    `json:"severity"`
+	Host      string    `json:"host"`
+	Model     string    `json:"model,omitempty"`
+	Condition string    `json:"condition"`
+	Value     float64   `json:"value"`
+	Since     time.Time `json:"since"` // when the condition started to hold
+	Error     string    `json:"error,omitempty"`
+
+	notify []string
+}
+
+// Text is a one line summary of the event for chat and mail
+func (e Event) Text() string {
+	switch e.Status {
+	case StatusResolved:
+		return fmt.Sprintf("[RESOLVED] %s on %s after %s", e.Rule, e.Host, e.Time.Sub(e.Since).Round(time.Second))
+	default:
+		text := fmt.Sprintf("[%s] %s on %s: %s (value %g) since %s", severityLabel(e.Severity), e.Rule, e.Host, e.Condition, e.Value, e.Since.Local().Format("2006-01-02 15:04:05"))
+		if e.Error != "" {
+			text += ": " + e.Error
+		}
+		return text
+	}
+}
+
+func severityLabel(severity string) string {
+	switch severity {
+	case SeverityCritical:
+		return "CRITICAL"
+	case SeverityInfo:
+		return "INFO"
+	default:
+		return "WARNING"
+	}
+}
+
+// alertState tracks one rule on one miner while its condition holds
+type alertState struct {
+	since    time.Time
+	firing   bool
+	notified bool
+	lastSent time.Time
+}
+
+// Engine evaluates rules on successive observations and decides which
+// notifications to send. An alert notifies once when it starts firing,
+// again every Repeat if set, and once more when it resolves.
+type Engine struct {
+	rules    []Rule
+	silences []Silence
+	state    map[string]*alertState // rule and host
+	Silenced int                    // notifications suppressed by silences
+}
+
+// NewEngine returns an engine for the rules and silences of c
+func NewEngine(c *Config) *Engine {
+	return &Engine{rules: c.Rules, silences: c.Silences, state: make(map[string]*alertState)}
+}
+
+// Evaluate applies the rules to one round of observations and returns the
+// notifications to send. Miners missing from obs keep their alert state.
+func (e *Engine) Evaluate(now time.Time, obs []Observation) []Event {
+	var events []Event
+	for i := range e.rules {
+		r := &e.rules[i]
+		for _, o := range obs {
+			if !r.applies(o.Host, o.Model) {
+				continue
+			}
+
+			var value float64
+			switch {
+			case r.metric == MetricUp:
+				if o.Up {
+					value = 1
+				}
+			case !o.Up:
+				// metrics of a down miner are unknown, the up rule covers it
+				continue
+			default:
+				v, ok := o.Metrics[r.metric]
+				if !ok {
+					continue
+				}
+				value = v
+			}
+
+			key := r.Name + "|" + o.Host
+			st := e.state[key]
+			ev := Event{
+				Time: now, Rule: r.Name, Severity: r.Severity, Host: o.Host, Model: o.Model,
+				Condition: r.Condition, Value: value, Error: o.Error, notify: r.Notify,
+			}
+
+			if !r.holds(value) {
+				if st != nil && st.firing && st.notified {
+					ev.Status = StatusResolved
+					ev.Since = st.since
+					events = append(events, ev)
+				}
+				delete(e.state, key)
+				continue
+			}
+
+			if st == nil {
+				st = &alertState{since: now}
+				e.state[key] = st
+			}
+			if !st.firing && now.Sub(st.since) < time.Duration(r.For) {
+				continue
+			}
+			st.firing = true
+
+			due := !st.notified || (r.Repeat > 0 && now.Sub(st.lastSent) >= time.Duration(r.Repeat))
+			if !due {
+				continue
+			}
+			if e.silenced(r.Name, o.Host, now) {
+				e.Silenced++
+				continue
+			}
+			ev.Status = StatusFiring
+			ev.Since = st.since
+			st.notified = true
+			st.lastSent = now
+			events = append(events, ev)
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Host != events[j].Host {
+			return events[i].Host < events[j].Host
+		}
+		return events[i].Rule < events[j].Rule
+	})
+	return events
+}
+
+func (e *Engine) silenced(rule, host string, t time.Time) bool {
+	for i := range e.silences {
+		if e.silences[i].mutes(rule, host, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Firing returns the number of alerts currently firing
+func (e *Engine) Firing() int {
+	n := 0
+	for _, st := range e.state {
+		if st.firing {
+			n++
+		}
+	}
+	return n
+}
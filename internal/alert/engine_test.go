@@ -0,0 +1,183 @@
+package alert
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/client"
+	"github.com/sinkers/miner-cli/internal/fleet"
+)
+
+const testRules = `{
+  "rules": [
+    {"name": "down", "condition": "up == 0", "for": "2m", "severity": "critical"},
+    {"name": "low-hashrate", "condition": "hash_rate < 80", "model": "S19", "repeat": "1h", "notify": ["hook"]},
+    {"name": "hot", "condition": "temp > 85", "hosts": ["10.0.0.1"]}
+  ],
+  "notifiers": [{"name": "hook", "type": "webhook", "url": "http://127.0.0.1/hook"}],
+  "silences": [{"rule": "down", "hosts": ["10.0.0.3"], "start": "2024-05-01T00:00:00Z", "end": "2024-05-01T01:00:00Z"}]
+}`
+
+const testYAMLRules = `
+rules:
+  - name: down
+    condition: up == 0
+    for: 2m
+    severity: critical
+  - name: low-hashrate
+    condition: hash_rate < 80
+    notify: [hook]
+notifiers:
+  - name: hook
+    type: webhook
+    url: http://127.0.0.1/hook
+silences:
+  - rule: down
+    start: 2024-05-01T00:00:00Z
+    end: 2024-05-01T01:00:00Z
+`
+
+func up(host, model string, hashRate, temp float64) Observation {
+	return Observation{Host: host, Model: model, Up: true, Metrics: map[string]float64{"hash_rate": hashRate, "temp": temp}}
+}
+
+func down(host string) Observation {
+	return Observation{Host: host, Error: "connection refused"}
+}
+
+func TestParseRules(t *testing.T) {
+	c, err := Parse([]byte(testRules))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(c.Rules) != 3 || c.Rules[1].Severity != SeverityWarning || time.Duration(c.Rules[0].For) != 2*time.Minute {
+		t.Errorf("unexpected rules: %+v", c.Rules)
+	}
+
+	c, err = Parse([]byte(testYAMLRules))
+	if err != nil {
+		t.Fatalf("Parse of YAML failed: %v", err)
+	}
+	if len(c.Rules) != 2 || time.Duration(c.Rules[0].For) != 2*time.Minute || c.Rules[1].Notify[0] != "hook" ||
+		len(c.Silences) != 1 || c.Silences[0].End.Hour() != 1 {
+		t.Errorf("unexpected YAML rules: %+v", c)
+	}
+
+	invalid := map[string]string{
+		"metric":    `{"rules": [{"name": "x", "condition": "hashrate < 1"}]}`,
+		"operator":  `{"rules": [{"name": "x", "condition": "temp => 1"}]}`,
+		"value":     `{"rules": [{"name": "x", "condition": "temp > hot"}]}`,
+		"severity":  `{"rules": [{"name": "x", "condition": "temp > 1", "severity": "page"}]}`,
+		"duration":  `{"rules": [{"name": "x", "condition": "temp > 1", "for": 5}]}`,
+		"notifier":  `{"rules": [{"name": "x", "condition": "temp > 1", "notify": ["none"]}]}`,
+		"duplicate": `{"rules": [{"name": "x", "condition": "temp > 1"}, {"name": "x", "condition": "up == 0"}]}`,
+		"silence":   `{"rules": [], "silences": [{"start": "2024-05-01T01:00:00Z", "end": "2024-05-01T00:00:00Z"}]}`,
+		"yaml":      "rules:\n  - name: x\n    condition: temp > 1\n    for: [5m]\n",
+	}
+	for name, rules := range invalid {
+		if _, err := Parse([]byte(rules)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestEngine(t *testing.T) {
+	c, err := Parse([]byte(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEngine(c)
+	start := time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC)
+	at := func(m int) time.Time { return start.Add(time.Duration(m) * time.Minute) }
+
+	// 10.0.0.1 goes down, fires after 2 minutes, once
+	if events := e.Evaluate(at(0), []Observation{down("10.0.0.1"), up("10.0.0.2", "S19", 100, 70)}); len(events) != 0 {
+		t.Fatalf("expected nothing before the duration, got %+v", events)
+	}
+	if events := e.Evaluate(at(1), []Observation{down("10.0.0.1")}); len(events) != 0 {
+		t.Fatalf("expected nothing before the duration, got %+v", events)
+	}
+	events := e.Evaluate(at(2), []Observation{down("10.0.0.1")})
+	if len(events) != 1 || events[0].Rule != "down" || events[0].Status != StatusFiring || !events[0].Since.Equal(at(0)) || events[0].Error != "connection refused" {
+		t.Fatalf("expected down to fire, got %+v", events)
+	}
+	if events := e.Evaluate(at(3), []Observation{down("10.0.0.1")}); len(events) != 0 {
+		t.Fatalf("expected no duplicate, got %+v", events)
+	}
+	if e.Firing() != 1 {
+		t.Errorf("expected 1 firing alert, got %d", e.Firing())
+	}
+
+	// it comes back hot: down resolves and hot fires at once
+	events = e.Evaluate(at(10), []Observation{up("10.0.0.1", "S19", 100, 90)})
+	if len(events) != 2 || events[0].Rule != "down" || events[0].Status != StatusResolved || events[1].Rule != "hot" {
+		t.Fatalf("expected down resolved and hot firing, got %+v", events)
+	}
+
+	// low hashrate repeats after an hour and only for the S19 model
+	events = e.Evaluate(at(20), []Observation{up("10.0.0.2", "S19", 70, 70), up("10.0.0.4", "S9", 10, 70)})
+	if len(events) != 1 || events[0].Rule != "low-hashrate" || events[0].Host != "10.0.0.2" || len(events[0].notify) != 1 {
+		t.Fatalf("expected low-hashrate on 10.0.0.2, got %+v", events)
+	}
+	if events := e.Evaluate(at(50), []Observation{up("10.0.0.2", "S19", 70, 70)}); len(events) != 0 {
+		t.Fatalf("expected no repeat yet, got %+v", events)
+	}
+	if events := e.Evaluate(at(80), []Observation{up("10.0.0.2", "S19", 72, 70)}); len(events) != 1 || events[0].Value != 72 {
+		t.Fatalf("expected a repeat, got %+v", events)
+	}
+}
+
+func TestEngineSilence(t *testing.T) {
+	c, err := Parse([]byte(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEngine(c)
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	// silenced while down, fires when the silence ends
+	e.Evaluate(start, []Observation{down("10.0.0.3")})
+	if events := e.Evaluate(start.Add(5*time.Minute), []Observation{down("10.0.0.3")}); len(events) != 0 {
+		t.Fatalf("expected the silence to mute, got %+v", events)
+	}
+	if e.Silenced != 1 {
+		t.Errorf("expected 1 silenced notification, got %d", e.Silenced)
+	}
+	if events := e.Evaluate(start.Add(time.Hour), []Observation{down("10.0.0.3")}); len(events) != 1 {
+		t.Fatalf("expected firing after the silence, got %+v", events)
+	}
+
+	// a silenced alert that resolves before the silence ends stays quiet
+	e = NewEngine(c)
+	e.Evaluate(start, []Observation{down("10.0.0.3")})
+	e.Evaluate(start.Add(5*time.Minute), []Observation{down("10.0.0.3")})
+	if events := e.Evaluate(start.Add(10*time.Minute), []Observation{up("10.0.0.3", "", 100, 70)}); len(events) != 0 {
+		t.Fatalf("expected no recovery for an unnotified alert, got %+v", events)
+	}
+}
+
+func TestObserve(t *testing.T) {
+	obs := Observe([]client.Result{
+		{IP: "10.0.0.1", Response: &fleet.Sample{Host: "10.0.0.1", Model: "S19", HashRate: 95, HardwareErrors: 3}},
+		{IP: "10.0.0.2", Error: "timeout"},
+	})
+	if len(obs) != 2 || !obs[0].Up || obs[0].Metrics["hash_rate"] != 95 || obs[0].Metrics["hardware_errors"] != 3 {
+		t.Errorf("unexpected observation: %+v", obs[0])
+	}
+	if obs[1].Up || obs[1].Error != "timeout" {
+		t.Errorf("unexpected observation: %+v", obs[1])
+	}
+}
+
+func TestEventText(t *testing.T) {
+	since := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	firing := Event{Status: StatusFiring, Rule: "down", Severity: SeverityCritical, Host: "10.0.0.1", Condition: "up == 0", Since: since, Time: since}
+	if text := firing.Text(); !strings.HasPrefix(text, "[CRITICAL] down on 10.0.0.1: up == 0 (value 0)") {
+		t.Errorf("unexpected text %q", text)
+	}
+	resolved := Event{Status: StatusResolved, Rule: "down", Host: "10.0.0.1", Since: since, Time: since.Add(90 * time.Second)}
+	if text := resolved.Text(); text != "[RESOLVED] down on 10.0.0.1 after 1m30s" {
+		t.Errorf("unexpected text %q", text)
+	}
+}
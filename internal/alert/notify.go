@@ -0,0 +1,275 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultTelegramAPI is the Bot API base used when a telegram notifier has
+// no URL
+const DefaultTelegramAPI = "https://api.telegram.org"
+
+// smtpTimeout bounds a whole mail delivery, the context may end it sooner
+const smtpTimeout = 30 * time.Second
+
+// Notifier delivers events to one destination
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, e Event) error
+}
+
+// NewNotifier builds the notifier described by c
+func NewNotifier(c NotifierConfig) (Notifier, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	switch c.Type {
+	case "webhook":
+		if c.URL == "" {
+			return nil, fmt.Errorf("notifier %q: url is required", c.Name)
+		}
+		return &webhookNotifier{name: c.Name, url: c.URL, client: client}, nil
+	case "slack":
+		if c.URL == "" {
+			return nil, fmt.Errorf("notifier %q: url is required", c.Name)
+		}
+		return &slackNotifier{name: c.Name, url: c.URL, client: client}, nil
+	case "telegram":
+		if c.Token == "" || c.ChatID == "" {
+			return nil, fmt.Errorf("notifier %q: token and chat_id are required", c.Name)
+		}
+		base := c.URL
+		if base == "" {
+			base = DefaultTelegramAPI
+		}
+		return &telegramNotifier{name: c.Name, url: strings.TrimSuffix(base, "/") + "/bot" + c.Token + "/sendMessage", chatID: c.ChatID, client: client}, nil
+	case "smtp":
+		if c.SMTPAddr == "" || c.From == "" || len(c.To) == 0 {
+			return nil, fmt.Errorf("notifier %q: smtp_addr, from and to are required", c.Name)
+		}
+		return &smtpNotifier{config: c, send: sendMail}, nil
+	default:
+		return nil, fmt.Errorf("notifier %q: unknown type %q (webhook, slack, telegram, smtp)", c.Name, c.Type)
+	}
+}
+
+// NewNotifiers builds every notifier of c
+func NewNotifiers(c *Config) ([]Notifier, error) {
+	notifiers := make([]Notifier, 0, len(c.Notifiers))
+	for _, nc := range c.Notifiers {
+		n, err := NewNotifier(nc)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, n)
+	}
+	return notifiers, nil
+}
+
+// Dispatch sends e to the notifiers its rule names, or to all of them, and
+// returns the failures by notifier name
+func Dispatch(ctx context.Context, notifiers []Notifier, e Event) map[string]error {
+	failed := make(map[string]error)
+	for _, n := range notifiers {
+		if len(e.notify) > 0 && !contains(e.notify, n.Name()) {
+			continue
+		}
+		if err := n.Notify(ctx, e); err != nil {
+			failed[n.Name()] = err
+		}
+	}
+	return failed
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// postJSON posts body as JSON and fails on a non-2xx answer. Errors leave
+// out the URL, which holds the secret of Telegram and Slack notifiers.
+func postJSON(ctx context.Context, client *http.Client, url string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", withoutURL(err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", withoutURL(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("notification rejected with status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// withoutURL strips the URL from the errors of net/http
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
+
+// webhookNotifier posts the event as JSON
+type webhookNotifier struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+func (n *webhookNotifier) Name() string { return n.name }
+
+func (n *webhookNotifier) Notify(ctx context.Context, e Event) error {
+	return postJSON(ctx, n.client, n.url, e)
+}
+
+// slackNotifier posts to a Slack compatible incoming webhook
+type slackNotifier struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+func (n *slackNotifier) Name() string { return n.name }
+
+func (n *slackNotifier) Notify(ctx context.Context, e Event) error {
+	return postJSON(ctx, n.client, n.url, map[string]string{"text": e.Text()})
+}
+
+// telegramNotifier sends a message through the Telegram Bot API
+type telegramNotifier struct {
+	name   string
+	url    string
+	chatID string
+	client *http.Client
+}
+
+func (n *telegramNotifier) Name() string { return n.name }
+
+func (n *telegramNotifier) Notify(ctx context.Context, e Event) error {
+	return postJSON(ctx, n.client, n.url, map[string]string{"chat_id": n.chatID, "text": e.Text()})
+}
+
+// smtpNotifier mails the event
+type smtpNotifier struct {
+	config NotifierConfig
+	send   func(ctx context.Context, addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func (n *smtpNotifier) Name() string { return n.config.Name }
+
+func (n *smtpNotifier) Notify(ctx context.Context, e Event) error {
+	var auth smtp.Auth
+	if n.config.Username != "" {
+		host, _, _ := net.SplitHostPort(n.config.SMTPAddr)
+		auth = smtp.PlainAuth("", n.config.Username, n.config.Password, host)
+	}
+	if err := n.send(ctx, n.config.SMTPAddr, auth, n.config.From, n.config.To, mailMessage(n.config.From, n.config.To, e)); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return nil
+}
+
+// sendMail is smtp.SendMail bounded by smtpTimeout and ctx
+func sendMail(ctx context.Context, addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	// a cancelled context interrupts the exchange in flight
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
+	host, _, _ := net.SplitHostPort(addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if a != nil {
+		if err := c.Auth(a); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// mailMessage builds a plain text mail with the event as subject
+func mailMessage(from string, to []string, e Event) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", e.Text())
+	fmt.Fprintf(&b, "Date: %s\r\n", e.Time.Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&b, "Rule:      %s\r\n", e.Rule)
+	fmt.Fprintf(&b, "Status:    %s\r\n", e.Status)
+	fmt.Fprintf(&b, "Severity:  %s\r\n", e.Severity)
+	fmt.Fprintf(&b, "Miner:     %s %s\r\n", e.Host, e.Model)
+	fmt.Fprintf(&b, "Condition: %s (value %g)\r\n", e.Condition, e.Value)
+	fmt.Fprintf(&b, "Since:     %s\r\n", e.Since.Format(time.RFC3339))
+	if e.Error != "" {
+		fmt.Fprintf(&b, "Error:     %s\r\n", e.Error)
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,180 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// standIn records the requests of a local webhook stand-in
+type standIn struct {
+	server *httptest.Server
+	paths  []string
+	bodies []map[string]interface{}
+	status int
+}
+
+func newStandIn(t *testing.T) *standIn {
+	s := &standIn{status: http.StatusOK}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		s.paths = append(s.paths, r.URL.Path)
+		s.bodies = append(s.bodies, body)
+		w.WriteHeader(s.status)
+	}))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func testEvent() Event {
+	since := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	return Event{Time: since.Add(3 * time.Minute), Status: StatusFiring, Rule: "down", Severity: SeverityCritical,
+		Host: "10.0.0.1", Condition: "up == 0", Since: since, Error: "connection refused"}
+}
+
+func TestWebhookNotifiers(t *testing.T) {
+	s := newStandIn(t)
+	t.Setenv("TEST_TG_TOKEN", "123:abc")
+	c, err := Parse([]byte(fmt.Sprintf(`{"notifiers": [
+		{"name": "hook", "type": "webhook", "url": "%[1]s/hook"},
+		{"name": "chat", "type": "slack", "url": "%[1]s/slack"},
+		{"name": "tg", "type": "telegram", "url": "%[1]s", "token": "$TEST_TG_TOKEN", "chat_id": "-100"}
+	]}`, s.server.URL)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifiers, err := NewNotifiers(c)
+	if err != nil {
+		t.Fatalf("NewNotifiers failed: %v", err)
+	}
+
+	if failed := Dispatch(context.Background(), notifiers, testEvent()); len(failed) != 0 {
+		t.Fatalf("unexpected failures: %v", failed)
+	}
+	if len(s.paths) != 3 || s.paths[0] != "/hook" || s.paths[1] != "/slack" || s.paths[2] != "/bot123:abc/sendMessage" {
+		t.Fatalf("unexpected requests: %v", s.paths)
+	}
+	if s.bodies[0]["rule"] != "down" || s.bodies[0]["status"] != StatusFiring || s.bodies[0]["error"] != "connection refused" {
+		t.Errorf("unexpected webhook body: %v", s.bodies[0])
+	}
+	if text, _ := s.bodies[1]["text"].(string); !strings.HasPrefix(text, "[CRITICAL] down on 10.0.0.1") {
+		t.Errorf("unexpected slack body: %v", s.bodies[1])
+	}
+	if s.bodies[2]["chat_id"] != "-100" || s.bodies[2]["text"] != s.bodies[1]["text"] {
+		t.Errorf("unexpected telegram body: %v", s.bodies[2])
+	}
+
+	// a rule naming notifiers only reaches those
+	e := testEvent()
+	e.notify = []string{"chat"}
+	Dispatch(context.Background(), notifiers, e)
+	if len(s.paths) != 4 || s.paths[3] != "/slack" {
+		t.Errorf("expected only the slack notifier, got %v", s.paths)
+	}
+
+	s.status = http.StatusBadRequest
+	failed := Dispatch(context.Background(), notifiers, testEvent())
+	if len(failed) != 3 || !strings.Contains(failed["hook"].Error(), "status 400") {
+		t.Errorf("expected every notifier to fail, got %v", failed)
+	}
+}
+
+func TestNotifierEnv(t *testing.T) {
+	t.Setenv("TEST_HOOK_URL", "http://127.0.0.1:9/hook")
+	c, err := Parse([]byte(`{"notifiers": [{"type": "webhook", "url": "$TEST_HOOK_URL"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Notifiers[0].URL != "http://127.0.0.1:9/hook" || c.Notifiers[0].Name != "webhook" {
+		t.Errorf("unexpected notifier: %+v", c.Notifiers[0])
+	}
+}
+
+func TestNewNotifierInvalid(t *testing.T) {
+	for _, c := range []NotifierConfig{
+		{Name: "a", Type: "webhook"},
+		{Name: "b", Type: "telegram", Token: "t"},
+		{Name: "c", Type: "smtp", SMTPAddr: "mail:25", From: "a@example.com"},
+		{Name: "d", Type: "pager"},
+	} {
+		if _, err := NewNotifier(c); err == nil {
+			t.Errorf("expected an error for %+v", c)
+		}
+	}
+}
+
+func TestTelegramErrorHidesToken(t *testing.T) {
+	n, err := NewNotifier(NotifierConfig{Name: "tg", Type: "telegram", URL: "http://127.0.0.1:9", Token: "123:s3cret-token", ChatID: "-100"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = n.Notify(context.Background(), Event{})
+	if err == nil || strings.Contains(err.Error(), "s3cret-token") {
+		t.Errorf("expected a delivery error without the token, got %v", err)
+	}
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	n, err := NewNotifier(NotifierConfig{Name: "mail", Type: "smtp", SMTPAddr: "mail.example.com:587",
+		Username: "alerts", Password: "secret", From: "alerts@example.com", To: []string{"ops@example.com", "oncall@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotMsg []byte
+	var gotAuth smtp.Auth
+	n.(*smtpNotifier).send = func(ctx context.Context, addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotAuth, gotFrom, gotTo, gotMsg = addr, a, from, to, msg
+		return nil
+	}
+
+	if err := n.Notify(context.Background(), testEvent()); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if gotAddr != "mail.example.com:587" || gotFrom != "alerts@example.com" || len(gotTo) != 2 || gotAuth == nil {
+		t.Errorf("unexpected envelope: %s %s %v %v", gotAddr, gotFrom, gotTo, gotAuth)
+	}
+	msg := string(gotMsg)
+	for _, want := range []string{"To: ops@example.com, oncall@example.com\r\n", "Subject: [CRITICAL] down on 10.0.0.1", "Error:     connection refused"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message misses %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestSendMailRespectsContext(t *testing.T) {
+	// a server that accepts but never greets
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			defer conn.Close()
+			io.Copy(io.Discard, conn)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := sendMail(ctx, ln.Addr().String(), nil, "a@example.com", []string{"b@example.com"}, []byte("hi")); err == nil {
+		t.Fatal("expected an error from a silent server")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("sendMail took %s despite the context", elapsed)
+	}
+}
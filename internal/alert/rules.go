@@ -0,0 +1,294 @@
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/iprange"
+	"gopkg.in/yaml.v3"
+)
+
+// Severities
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+)
+
+// MetricUp is 1 for a miner that answered the last poll and 0 otherwise.
+// It is the only metric of an unreachable miner.
+const MetricUp = "up"
+
+// Metrics lists the metric names rules can use, the fields of a history
+// sample and up
+var Metrics = []string{MetricUp, "hash_rate", "power", "temp", "hardware_errors", "accepted", "rejected", "stale"}
+
+// Duration is a time.Duration written as a string such as "5m" in rule files
+type Duration time.Duration
+
+// UnmarshalJSON reads a duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"5m\"")
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalJSON writes the duration string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Rule fires for a miner once Condition has held for For
+type Rule struct {
+	Name      string   `json:"name"`
+	Condition string   `json:"condition"` // "<metric> <op> <value>", e.g. "hash_rate < 80"
+	For       Duration `json:"for,omitempty"`
+	Severity  string   `json:"severity,omitempty"`
+	Hosts     []string `json:"hosts,omitempty"`  // IP ranges, all miners when empty
+	Model     string   `json:"model,omitempty"`  // only miners of this model
+	Repeat    Duration `json:"repeat,omitempty"` // notify again while firing, 0 for once
+	Notify    []string `json:"notify,omitempty"` // notifier names, all when empty
+
+	metric string
+	op     string
+	value  float64
+	hosts  map[string]bool
+}
+
+// Silence mutes notifications of a rule, or of all rules, for some miners
+// or all of them between Start and End. Alerts keep their state while
+// silenced; a recovery is only sent for an alert that was notified.
+type Silence struct {
+	Rule    string    `json:"rule,omitempty"`
+	Hosts   []string  `json:"hosts,omitempty"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Comment string    `json:"comment,omitempty"`
+
+	hosts map[string]bool
+}
+
+// NotifierConfig configures one notifier. Type is webhook, slack, telegram
+// or smtp; the other fields depend on it. $VAR references in the string
+// fields are read from the environment so secrets can stay out of the file.
+type NotifierConfig struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	URL  string `json:"url,omitempty"` // webhook and slack, telegram API base for a stand-in
+
+	Token  string `json:"token,omitempty"` // telegram bot token
+	ChatID string `json:"chat_id,omitempty"`
+
+	SMTPAddr string   `json:"smtp_addr,omitempty"` // host:port
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+}
+
+// Config is a rules file, written in YAML or JSON
+type Config struct {
+	Rules     []Rule           `json:"rules"`
+	Notifiers []NotifierConfig `json:"notifiers"`
+	Silences  []Silence        `json:"silences,omitempty"`
+}
+
+// Load reads and validates a rules file
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse reads and validates rules in their YAML or JSON form
+func Parse(data []byte) (*Config, error) {
+	c := &Config{}
+	if err := unmarshalYAML(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse rules: %w", err)
+	}
+	if err := c.compile(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// unmarshalYAML decodes YAML, of which JSON is a subset, into v through its
+// JSON form so that the json tags and Duration apply to both
+func unmarshalYAML(data []byte, v interface{}) error {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func (c *Config) compile() error {
+	names := make(map[string]bool)
+	for i := range c.Notifiers {
+		n := &c.Notifiers[i]
+		if n.Name == "" {
+			n.Name = n.Type
+		}
+		if names[n.Name] {
+			return fmt.Errorf("duplicate notifier %q", n.Name)
+		}
+		names[n.Name] = true
+		expandEnv(n)
+	}
+
+	rules := make(map[string]bool)
+	for i := range c.Rules {
+		r := &c.Rules[i]
+		if r.Name == "" {
+			return fmt.Errorf("rule %d has no name", i+1)
+		}
+		if rules[r.Name] {
+			return fmt.Errorf("duplicate rule %q", r.Name)
+		}
+		rules[r.Name] = true
+
+		if err := r.compile(); err != nil {
+			return fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+		for _, name := range r.Notify {
+			if !names[name] {
+				return fmt.Errorf("rule %q: unknown notifier %q", r.Name, name)
+			}
+		}
+	}
+
+	for i := range c.Silences {
+		s := &c.Silences[i]
+		if !s.End.After(s.Start) {
+			return fmt.Errorf("silence %d ends before it starts", i+1)
+		}
+		if s.Rule != "" && !rules[s.Rule] {
+			return fmt.Errorf("silence %d: unknown rule %q", i+1, s.Rule)
+		}
+		hosts, err := hostSet(s.Hosts)
+		if err != nil {
+			return fmt.Errorf("silence %d: %w", i+1, err)
+		}
+		s.hosts = hosts
+	}
+	return nil
+}
+
+func (r *Rule) compile() error {
+	fields := strings.Fields(r.Condition)
+	if len(fields) != 3 {
+		return fmt.Errorf("condition %q must be \"<metric> <op> <value>\"", r.Condition)
+	}
+	r.metric, r.op = fields[0], fields[1]
+
+	known := false
+	for _, m := range Metrics {
+		known = known || m == r.metric
+	}
+	if !known {
+		return fmt.Errorf("unknown metric %q, use one of %s", r.metric, strings.Join(Metrics, ", "))
+	}
+	switch r.op {
+	case "<", "<=", ">", ">=", "==", "!=":
+	default:
+		return fmt.Errorf("unknown operator %q", r.op)
+	}
+	value, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil {
+		return fmt.Errorf("invalid value %q", fields[2])
+	}
+	r.value = value
+
+	switch r.Severity {
+	case "":
+		r.Severity = SeverityWarning
+	case SeverityCritical, SeverityWarning, SeverityInfo:
+	default:
+		return fmt.Errorf("unknown severity %q", r.Severity)
+	}
+
+	r.hosts, err = hostSet(r.Hosts)
+	return err
+}
+
+// hostSet expands IP ranges, nil for none
+func hostSet(ranges []string) (map[string]bool, error) {
+	if len(ranges) == 0 {
+		return nil, nil
+	}
+	parsed, err := iprange.ParseMultipleRanges(ranges)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse hosts: %w", err)
+	}
+	hosts := make(map[string]bool)
+	for _, ip := range parsed.GetIPs() {
+		hosts[ip] = true
+	}
+	return hosts, nil
+}
+
+func expandEnv(n *NotifierConfig) {
+	for _, f := range []*string{&n.URL, &n.Token, &n.ChatID, &n.SMTPAddr, &n.Username, &n.Password, &n.From} {
+		*f = os.ExpandEnv(*f)
+	}
+	for i := range n.To {
+		n.To[i] = os.ExpandEnv(n.To[i])
+	}
+}
+
+// applies reports whether the rule covers a miner
+func (r *Rule) applies(host, model string) bool {
+	if r.hosts != nil && !r.hosts[host] {
+		return false
+	}
+	return r.Model == "" || strings.EqualFold(r.Model, model)
+}
+
+// holds evaluates the condition against a metric value
+func (r *Rule) holds(v float64) bool {
+	switch r.op {
+	case "<":
+		return v < r.value
+	case "<=":
+		return v <= r.value
+	case ">":
+		return v > r.value
+	case ">=":
+		return v >= r.value
+	case "==":
+		return v == r.value
+	default:
+		return v != r.value
+	}
+}
+
+// mutes reports whether the silence covers an alert at t
+func (s *Silence) mutes(rule, host string, t time.Time) bool {
+	if t.Before(s.Start) || !t.Before(s.End) {
+		return false
+	}
+	if s.Rule != "" && s.Rule != rule {
+		return false
+	}
+	return s.hosts == nil || s.hosts[host]
+}
@@ -0,0 +1,130 @@
+// Package audit keeps an append-only record of the commands that change
+// miners or local state: who ran them, with what parameters and the outcome
+// on every host.
+package audit
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/policy"
+)
+
+// Redacted replaces secret values in records
+const Redacted = "[REDACTED]"
+
+// secretNames are the parameter name fragments whose values are redacted
+var secretNames = []string{"pass", "secret", "token", "key", "credential"}
+
+// Outcome is the result of a command on one host
+type Outcome struct {
+	Host  string `json:"host"`
+	Error string `json:"error,omitempty"`
+}
+
+// Record is one audited command run
+type Record struct {
+	Time      time.Time         `json:"time"`
+	User      string            `json:"user"`
+	Machine   string            `json:"machine,omitempty"` // where the CLI ran
+	Command   string            `json:"command"`
+	Args      []string          `json:"args,omitempty"`
+	Flags     map[string]string `json:"flags,omitempty"` // flags given, secrets redacted
+	Targets   []string          `json:"targets,omitempty"`
+	Hosts     []Outcome         `json:"hosts,omitempty"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Error     string            `json:"error,omitempty"` // the command itself failed
+}
+
+// NewRecord starts a record of command run now by the current user
+func NewRecord(command string) Record {
+	machine, _ := os.Hostname()
+	return Record{Time: time.Now(), User: CurrentUser(), Machine: machine, Command: command}
+}
+
+// CurrentUser names the operator: the user behind sudo when running as
+// root, else the OS user. The environment is trusted only under sudo as
+// anyone could set SUDO_USER or USER; empty when the user is unknown.
+func CurrentUser() string {
+	if os.Getuid() == 0 {
+		if name := os.Getenv("SUDO_USER"); name != "" {
+			return name
+		}
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+// SetFlag records a flag value, redacting secrets
+func (r *Record) SetFlag(name, value string) {
+	if r.Flags == nil {
+		r.Flags = make(map[string]string)
+	}
+	r.Flags[name] = Redact(name, value)
+}
+
+// AddOutcome records the result on one host
+func (r *Record) AddOutcome(host, err string) {
+	r.Hosts = append(r.Hosts, Outcome{Host: host, Error: err})
+	if err == "" {
+		r.Succeeded++
+	} else {
+		r.Failed++
+	}
+}
+
+// Redact hides the value of a secret parameter such as a password, token
+// or API key, and the pool password of addpool in custom commands. Files
+// holding secrets are named by path and kept.
+func Redact(name, value string) string {
+	name = strings.ToLower(name)
+	if value == "" || strings.HasSuffix(name, "-file") {
+		return value
+	}
+	if name == "cmd" {
+		return redactCustom(value)
+	}
+	for _, secret := range secretNames {
+		if strings.Contains(name, secret) {
+			return Redacted
+		}
+	}
+	return value
+}
+
+// redactCustom hides the password, the third argument, of addpool in a
+// custom CGMiner command such as "summary+addpool|url,user,pass"
+func redactCustom(command string) string {
+	parts := strings.Split(command, "+")
+	for i, part := range parts {
+		names := policy.APICommands(part)
+		if len(names) != 1 || names[0] != "addpool" || !strings.Contains(part, "|") {
+			continue
+		}
+		// pool URLs such as stratum+tcp:// contain the separator, so the
+		// rest of the command holds the arguments, the password being all
+		// after the user
+		name, args, _ := strings.Cut(strings.Join(parts[i:], "+"), "|")
+		fields := strings.SplitN(args, ",", 3)
+		if len(fields) < 3 {
+			return command
+		}
+		return strings.Join(append(parts[:i:i], name+"|"+fields[0]+","+fields[1]+","+Redacted), "+")
+	}
+	return command
+}
+
+// DefaultPath returns where the audit log is kept
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "audit.jsonl"
+	}
+	return filepath.Join(dir, "miner-cli", "audit.jsonl")
+}
@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedact(t *testing.T) {
+	for name, want := range map[string]string{
+		"pass":         Redacted,
+		"password":     Redacted,
+		"new-password": Redacted,
+		"api-key":      Redacted,
+		"token":        Redacted,
+		"url":          "stratum+tcp://pool:3333",
+		"key-file":     "stratum+tcp://pool:3333",
+	} {
+		if got := Redact(name, "stratum+tcp://pool:3333"); got != want {
+			t.Errorf("Redact(%q) = %q, want %q", name, got, want)
+		}
+	}
+	if got := Redact("password", ""); got != "" {
+		t.Errorf("expected an empty value to stay empty, got %q", got)
+	}
+
+	for value, want := range map[string]string{
+		"summary+pools":                          "summary+pools",
+		"addpool|stratum+tcp://pool:3333,w1,x,y": "addpool|stratum+tcp://pool:3333,w1," + Redacted,
+		"summary+ADDPOOL|pool:3333,w1,s3cret":    "summary+ADDPOOL|pool:3333,w1," + Redacted,
+		"addpool|pool:3333,w1":                   "addpool|pool:3333,w1",
+	} {
+		if got := Redact("cmd", value); got != want {
+			t.Errorf("Redact(cmd, %q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestLogAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	l := &Logger{Path: path}
+	if err := l.Check(); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	addpool := Record{Time: start, User: "alice", Command: "addpool", Targets: []string{"10.0.0.0/30"}}
+	addpool.SetFlag("url", "stratum+tcp://pool:3333")
+	addpool.SetFlag("pass", "hunter2")
+	addpool.AddOutcome("10.0.0.1", "")
+	addpool.AddOutcome("10.0.0.2", "connection refused")
+	apply := Record{Time: start.Add(time.Hour), User: "bob", Command: "vnish settings apply"}
+	apply.AddOutcome("10.0.0.3", "")
+	for _, r := range []Record{addpool, apply} {
+		if err := l.Log(r); err != nil {
+			t.Fatalf("Log failed: %v", err)
+		}
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "hunter2") {
+		t.Fatalf("secret written to the log:\n%s", data)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected a private log file, got %v %v", info, err)
+	}
+
+	all, err := Read(path, Filter{})
+	if err != nil || len(all) != 2 {
+		t.Fatalf("unexpected records: %+v %v", all, err)
+	}
+	if all[0].Succeeded != 1 || all[0].Failed != 1 || all[0].Flags["pass"] != Redacted || all[0].Flags["url"] == Redacted {
+		t.Errorf("unexpected record: %+v", all[0])
+	}
+
+	filters := map[string]struct {
+		f    Filter
+		want string
+	}{
+		"user":    {Filter{User: "bob"}, "vnish settings apply"},
+		"command": {Filter{Command: "vnish settings"}, "vnish settings apply"},
+		"host":    {Filter{Hosts: map[string]bool{"10.0.0.2": true}}, "addpool"},
+		"failed":  {Filter{Failed: true}, "addpool"},
+		"since":   {Filter{Since: start.Add(time.Minute)}, "vnish settings apply"},
+		"until":   {Filter{Until: start.Add(time.Minute)}, "addpool"},
+	}
+	for name, tc := range filters {
+		records, err := Read(path, tc.f)
+		if err != nil || len(records) != 1 || records[0].Command != tc.want {
+			t.Errorf("%s: unexpected records %+v %v", name, records, err)
+		}
+	}
+	if records, _ := Read(path, Filter{Command: "vnish"}); len(records) != 1 {
+		t.Errorf("expected the command prefix to match whole words, got %+v", records)
+	}
+	if records, _ := Read(path, Filter{Command: "vnish set"}); len(records) != 0 {
+		t.Errorf("expected no partial word match, got %+v", records)
+	}
+
+	if records, err := Read(filepath.Join(t.TempDir(), "none.jsonl"), Filter{}); err != nil || records != nil {
+		t.Errorf("expected no records for a missing log, got %v %v", records, err)
+	}
+}
+
+func TestLogWithoutPath(t *testing.T) {
+	l := &Logger{}
+	if err := l.Check(); !errors.Is(err, ErrNoPath) {
+		t.Errorf("expected Check to refuse an empty path, got %v", err)
+	}
+	if err := l.Log(NewRecord("restart")); !errors.Is(err, ErrNoPath) {
+		t.Errorf("expected Log to refuse an empty path, got %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNoPath is returned when a logger has no file to write to
+var ErrNoPath = errors.New("no audit log path")
+
+// Logger appends records to a JSON lines file and optionally to syslog.
+// The file is written by the operator running the CLI, who can also edit
+// or delete it; syslog keeps a copy out of their reach.
+type Logger struct {
+	Path   string
+	Syslog bool
+}
+
+// Check makes sure the log can be written before a command changes
+// anything, so a command is never run without its record
+func (l *Logger) Check() error {
+	if l.Path == "" {
+		return ErrNoPath
+	}
+	f, err := l.open()
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func (l *Logger) open() (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(l.Path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	f, err := os.OpenFile(l.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return f, nil
+}
+
+// Log appends r
+func (l *Logger) Log(r Record) error {
+	if l.Path == "" {
+		return ErrNoPath
+	}
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+
+	f, err := l.open()
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	if l.Syslog {
+		if err := writeSyslog(string(line), r.Failed > 0 || r.Error != ""); err != nil {
+			return fmt.Errorf("failed to write audit record to syslog: %w", err)
+		}
+	}
+	return nil
+}
+
+// Filter selects records
+type Filter struct {
+	Since   time.Time       // zero for all
+	Until   time.Time       // zero for now
+	User    string          // exact user name
+	Command string          // command or command prefix, e.g. "vnish settings"
+	Hosts   map[string]bool // records touching any of these hosts
+	Failed  bool            // only records with a failure
+}
+
+// Match reports whether r passes the filter
+func (f Filter) Match(r Record) bool {
+	if !f.Since.IsZero() && r.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && r.Time.After(f.Until) {
+		return false
+	}
+	if f.User != "" && r.User != f.User {
+		return false
+	}
+	if f.Command != "" && r.Command != f.Command && !strings.HasPrefix(r.Command, f.Command+" ") {
+		return false
+	}
+	if f.Failed && r.Failed == 0 && r.Error == "" {
+		return false
+	}
+	if len(f.Hosts) > 0 {
+		for _, o := range r.Hosts {
+			if f.Hosts[o.Host] {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Read returns the records of the log at path that pass f, oldest first.
+// Unreadable lines are skipped.
+func Read(path string, f Filter) ([]Record, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+	return read(file, f)
+}
+
+func read(r io.Reader, f Filter) ([]Record, error) {
+	var records []Record
+	reader := bufio.NewReader(r)
+	for {
+		// records with many hosts are longer than a scanner's default buffer
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var rec Record
+			if json.Unmarshal(line, &rec) == nil && f.Match(rec) {
+				records = append(records, rec)
+			}
+		}
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+	}
+}
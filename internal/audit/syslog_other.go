@@ -0,0 +1,9 @@
+//go:build windows || plan9
+
+package audit
+
+import "fmt"
+
+func writeSyslog(line string, failed bool) error {
+	return fmt.Errorf("syslog is not available on this platform")
+}
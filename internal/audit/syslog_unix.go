@@ -0,0 +1,18 @@
+//go:build !windows && !plan9
+
+package audit
+
+import "log/syslog"
+
+// writeSyslog sends one record to the local syslog daemon
+func writeSyslog(line string, failed bool) error {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_AUTH, "miner-cli")
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if failed {
+		return w.Warning(line)
+	}
+	return w.Info(line)
+}
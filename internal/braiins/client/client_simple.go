@@ -4,22 +4,37 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"sync"
 	"time"
 
+	bos "github.com/sinkers/miner-cli/internal/braiins/bos"
 	pb "github.com/sinkers/miner-cli/internal/braiins/bos/v1"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
-// SimpleBraiinsClient provides a simplified interface to Braiins OS+ API
+// DefaultKeepalive is the interval between keepalive pings on idle connections
+const DefaultKeepalive = 30 * time.Second
+
+// SimpleBraiinsClient provides a simplified interface to Braiins OS+ API.
+// The session token is attached to every call and renewed by logging in
+// again when the miner rejects it, so a client can be shared and kept open.
 type SimpleBraiinsClient struct {
-	conn      *grpc.ClientConn
+	conn     *grpc.ClientConn
+	host     string
+	port     int
+	timeout  time.Duration
+	username string
+	password string
+	release  func() // set by a Pool, Close hands the client back
+
+	mu        sync.Mutex
 	authToken string
-	host      string
-	port      int
-	timeout   time.Duration
 }
 
 // SimpleClientOptions contains configuration for the simple client
@@ -31,6 +46,7 @@ type SimpleClientOptions struct {
 	Timeout            time.Duration
 	UseTLS             bool
 	InsecureSkipVerify bool
+	Keepalive          time.Duration // ping interval, 0 for DefaultKeepalive, negative to disable
 }
 
 // NewSimpleClient creates a new simplified Braiins client
@@ -47,10 +63,25 @@ func NewSimpleClient(opts SimpleClientOptions) (*SimpleBraiinsClient, error) {
 		opts.Timeout = 30 * time.Second
 	}
 
+	if opts.Keepalive == 0 {
+		opts.Keepalive = DefaultKeepalive
+	}
+
+	client := &SimpleBraiinsClient{
+		host:     opts.Host,
+		port:     opts.Port,
+		timeout:  opts.Timeout,
+		username: opts.Username,
+		password: opts.Password,
+	}
+
 	// Create gRPC connection
 	address := fmt.Sprintf("%s:%d", opts.Host, opts.Port)
 
-	var dialOpts []grpc.DialOption
+	dialOpts := []grpc.DialOption{
+		grpc.WithUnaryInterceptor(client.unaryAuth),
+		grpc.WithStreamInterceptor(client.streamAuth),
+	}
 
 	if opts.UseTLS {
 		tlsConfig := &tls.Config{
@@ -62,53 +93,169 @@ func NewSimpleClient(opts SimpleClientOptions) (*SimpleBraiinsClient, error) {
 		dialOpts = append(dialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	}
 
+	if opts.Keepalive > 0 {
+		dialOpts = append(dialOpts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                opts.Keepalive,
+			Timeout:             opts.Timeout,
+			PermitWithoutStream: true,
+		}))
+	}
+
 	conn, err := grpc.Dial(address, dialOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect: %w", err)
 	}
-
-	client := &SimpleBraiinsClient{
-		conn:    conn,
-		host:    opts.Host,
-		port:    opts.Port,
-		timeout: opts.Timeout,
-	}
+	client.conn = conn
 
 	// Authenticate if credentials provided
-	if opts.Username != "" && opts.Password != "" {
-		authClient := pb.NewAuthenticationServiceClient(conn)
-		ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
-		defer cancel()
-
-		resp, err := authClient.Login(ctx, &pb.LoginRequest{
-			Username: opts.Username,
-			Password: opts.Password,
-		})
-		if err != nil {
+	if client.hasCredentials() {
+		if err := client.login(""); err != nil {
 			conn.Close()
 			return nil, fmt.Errorf("authentication failed: %w", err)
 		}
-		client.authToken = resp.Token
 	}
 
 	return client, nil
 }
 
-// Close closes the client connection
+// Close closes the client connection. Clients owned by a Pool stay open
+// until the pool is closed.
 func (c *SimpleBraiinsClient) Close() error {
+	if c.release != nil {
+		c.release()
+		return nil
+	}
+	return c.closeConn()
+}
+
+func (c *SimpleBraiinsClient) closeConn() error {
 	if c.conn != nil {
 		return c.conn.Close()
 	}
 	return nil
 }
 
-// getContext creates a context with timeout and auth
+// getContext creates a context with timeout, the token is added by the
+// auth interceptors
 func (c *SimpleBraiinsClient) getContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.timeout)
+}
+
+func (c *SimpleBraiinsClient) hasCredentials() bool {
+	return c.username != "" && c.password != ""
+}
+
+// token returns the current session token
+func (c *SimpleBraiinsClient) token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.authToken
+}
+
+// login obtains a new session token unless another caller already replaced
+// the stale one
+func (c *SimpleBraiinsClient) login(stale string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.authToken != stale {
+		return nil
+	}
+
+	authClient := pb.NewAuthenticationServiceClient(c.conn)
 	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
-	if c.authToken != "" {
-		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", c.authToken)
+	defer cancel()
+
+	resp, err := authClient.Login(ctx, &pb.LoginRequest{
+		Username: c.username,
+		Password: c.password,
+	})
+	if err != nil {
+		return err
+	}
+	c.authToken = resp.Token
+	return nil
+}
+
+// invalidate drops a token the miner rejected so the next call logs in
+func (c *SimpleBraiinsClient) invalidate(stale string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.authToken == stale {
+		c.authToken = ""
+	}
+}
+
+// authorize returns the token to use for a call, logging in first when the
+// previous token was invalidated
+func (c *SimpleBraiinsClient) authorize(ctx context.Context) (context.Context, string, error) {
+	token := c.token()
+	if token == "" && c.hasCredentials() {
+		if err := c.login(""); err != nil {
+			return nil, "", fmt.Errorf("authentication failed: %w", err)
+		}
+		token = c.token()
+	}
+	if token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", token)
 	}
-	return ctx, cancel
+	return ctx, token, nil
+}
+
+// unaryAuth attaches the session token and retries once with a fresh login
+// when the token has expired
+func (c *SimpleBraiinsClient) unaryAuth(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if method == pb.AuthenticationService_Login_FullMethodName {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	authCtx, token, err := c.authorize(ctx)
+	if err != nil {
+		return err
+	}
+	err = invoker(authCtx, method, req, reply, cc, opts...)
+	if status.Code(err) != codes.Unauthenticated || !c.hasCredentials() {
+		return err
+	}
+
+	if err := c.login(token); err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
+	}
+	authCtx, _, err = c.authorize(ctx)
+	if err != nil {
+		return err
+	}
+	return invoker(authCtx, method, req, reply, cc, opts...)
+}
+
+// streamAuth attaches the session token to streaming calls. A stream that
+// fails with Unauthenticated invalidates the token so reopening it logs in.
+func (c *SimpleBraiinsClient) streamAuth(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	authCtx, token, err := c.authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := streamer(authCtx, desc, cc, method, opts...)
+	if err != nil {
+		if status.Code(err) == codes.Unauthenticated {
+			c.invalidate(token)
+		}
+		return nil, err
+	}
+	return &authStream{ClientStream: stream, client: c, token: token}, nil
+}
+
+type authStream struct {
+	grpc.ClientStream
+	client *SimpleBraiinsClient
+	token  string
+}
+
+func (s *authStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if status.Code(err) == codes.Unauthenticated {
+		s.client.invalidate(s.token)
+	}
+	return err
 }
 
 // GetMinerDetails retrieves miner information
@@ -119,6 +266,12 @@ func (c *SimpleBraiinsClient) GetMinerDetails() (*pb.GetMinerDetailsResponse, er
 	return client.GetMinerDetails(ctx, &pb.GetMinerDetailsRequest{})
 }
 
+// GetMinerStatus opens a stream that sends the miner status whenever it changes
+func (c *SimpleBraiinsClient) GetMinerStatus(ctx context.Context) (pb.MinerService_GetMinerStatusClient, error) {
+	client := pb.NewMinerServiceClient(c.conn)
+	return client.GetMinerStatus(ctx, &pb.GetMinerStatusRequest{})
+}
+
 // GetMinerStats retrieves mining statistics
 func (c *SimpleBraiinsClient) GetMinerStats() (*pb.GetMinerStatsResponse, error) {
 	client := pb.NewMinerServiceClient(c.conn)
@@ -135,6 +288,31 @@ func (c *SimpleBraiinsClient) GetHashboards() (*pb.GetHashboardsResponse, error)
 	return client.GetHashboards(ctx, &pb.GetHashboardsRequest{})
 }
 
+// DisableHashboards disables the given hashboards and saves the change
+func (c *SimpleBraiinsClient) DisableHashboards(ids []string) (*pb.DisableHashboardsResponse, error) {
+	client := pb.NewMinerServiceClient(c.conn)
+	ctx, cancel := c.getContext()
+	defer cancel()
+	return client.DisableHashboards(ctx, &pb.DisableHashboardsRequest{
+		SaveAction:   pb.SaveAction_SAVE_ACTION_SAVE_AND_APPLY,
+		HashboardIds: ids,
+	})
+}
+
+// GetErrors retrieves the active miner errors
+func (c *SimpleBraiinsClient) GetErrors() (*pb.GetErrorsResponse, error) {
+	client := pb.NewMinerServiceClient(c.conn)
+	ctx, cancel := c.getContext()
+	defer cancel()
+	return client.GetErrors(ctx, &pb.GetErrorsRequest{})
+}
+
+// GetSupportArchive opens a stream of support archive chunks
+func (c *SimpleBraiinsClient) GetSupportArchive(ctx context.Context, format pb.SupportArchiveFormat) (pb.MinerService_GetSupportArchiveClient, error) {
+	client := pb.NewMinerServiceClient(c.conn)
+	return client.GetSupportArchive(ctx, &pb.GetSupportArchiveRequest{Format: format})
+}
+
 // GetPoolGroups retrieves pool configuration
 func (c *SimpleBraiinsClient) GetPoolGroups() (*pb.GetPoolGroupsResponse, error) {
 	client := pb.NewPoolServiceClient(c.conn)
@@ -143,6 +321,17 @@ func (c *SimpleBraiinsClient) GetPoolGroups() (*pb.GetPoolGroupsResponse, error)
 	return client.GetPoolGroups(ctx, &pb.GetPoolGroupsRequest{})
 }
 
+// SetPoolGroups replaces all pool groups and applies them
+func (c *SimpleBraiinsClient) SetPoolGroups(groups []*pb.PoolGroupConfiguration) (*pb.SetPoolGroupsResponse, error) {
+	client := pb.NewPoolServiceClient(c.conn)
+	ctx, cancel := c.getContext()
+	defer cancel()
+	return client.SetPoolGroups(ctx, &pb.SetPoolGroupsRequest{
+		SaveAction: pb.SaveAction_SAVE_ACTION_SAVE_AND_APPLY,
+		PoolGroups: groups,
+	})
+}
+
 // GetCoolingState retrieves cooling information
 func (c *SimpleBraiinsClient) GetCoolingState() (*pb.GetCoolingStateResponse, error) {
 	client := pb.NewCoolingServiceClient(c.conn)
@@ -162,6 +351,19 @@ func (c *SimpleBraiinsClient) SetImmersionMode(enabled bool) (*pb.SetImmersionMo
 	})
 }
 
+// SetCoolingMode configures the cooling mode and temperature thresholds.
+// The save action defaults to save-and-apply so the new mode takes effect
+// without restarting bosminer.
+func (c *SimpleBraiinsClient) SetCoolingMode(req *pb.SetCoolingModeRequest) (*pb.SetCoolingModeResponse, error) {
+	client := pb.NewCoolingServiceClient(c.conn)
+	ctx, cancel := c.getContext()
+	defer cancel()
+	if req.SaveAction == pb.SaveAction_SAVE_ACTION_UNSPECIFIED {
+		req.SaveAction = pb.SaveAction_SAVE_ACTION_SAVE_AND_APPLY
+	}
+	return client.SetCoolingMode(ctx, req)
+}
+
 // GetTunerState retrieves performance tuning state
 func (c *SimpleBraiinsClient) GetTunerState() (*pb.GetTunerStateResponse, error) {
 	client := pb.NewPerformanceServiceClient(c.conn)
@@ -196,6 +398,17 @@ func (c *SimpleBraiinsClient) SetHashrateTarget(thps float64) (*pb.SetHashrateTa
 	})
 }
 
+// SetPerformanceMode switches between manual and tuner mode and applies it
+func (c *SimpleBraiinsClient) SetPerformanceMode(mode *pb.PerformanceMode) (*pb.PerformanceMode, error) {
+	client := pb.NewPerformanceServiceClient(c.conn)
+	ctx, cancel := c.getContext()
+	defer cancel()
+	return client.SetPerformanceMode(ctx, &pb.SetPerformanceModeRequest{
+		SaveAction: pb.SaveAction_SAVE_ACTION_SAVE_AND_APPLY,
+		Mode:       mode,
+	})
+}
+
 // StartMining starts mining operation
 func (c *SimpleBraiinsClient) StartMining() error {
 	client := pb.NewActionsServiceClient(c.conn)
@@ -250,6 +463,30 @@ func (c *SimpleBraiinsClient) Reboot() error {
 	return err
 }
 
+// SetLocateDeviceStatus turns the locate device LED blinking on or off
+func (c *SimpleBraiinsClient) SetLocateDeviceStatus(enable bool) (*pb.LocateDeviceStatusResponse, error) {
+	client := pb.NewActionsServiceClient(c.conn)
+	ctx, cancel := c.getContext()
+	defer cancel()
+	return client.SetLocateDeviceStatus(ctx, &pb.SetLocateDeviceStatusRequest{Enable: enable})
+}
+
+// GetLocateDeviceStatus retrieves whether locate device mode is enabled
+func (c *SimpleBraiinsClient) GetLocateDeviceStatus() (*pb.LocateDeviceStatusResponse, error) {
+	client := pb.NewActionsServiceClient(c.conn)
+	ctx, cancel := c.getContext()
+	defer cancel()
+	return client.GetLocateDeviceStatus(ctx, &pb.GetLocateDeviceStatusRequest{})
+}
+
+// GetAPIVersion retrieves the API version, it does not require authentication
+func (c *SimpleBraiinsClient) GetAPIVersion() (*bos.ApiVersion, error) {
+	client := bos.NewApiVersionServiceClient(c.conn)
+	ctx, cancel := c.getContext()
+	defer cancel()
+	return client.GetApiVersion(ctx, &bos.ApiVersionRequest{})
+}
+
 // GetLicenseState retrieves license information
 func (c *SimpleBraiinsClient) GetLicenseState() (*pb.GetLicenseStateResponse, error) {
 	client := pb.NewLicenseServiceClient(c.conn)
@@ -258,10 +495,51 @@ func (c *SimpleBraiinsClient) GetLicenseState() (*pb.GetLicenseStateResponse, er
 	return client.GetLicenseState(ctx, &pb.GetLicenseStateRequest{})
 }
 
+// ApplyContractKey installs a license contract key
+func (c *SimpleBraiinsClient) ApplyContractKey(key string) (*pb.ApplyContractKeyResponse, error) {
+	client := pb.NewLicenseServiceClient(c.conn)
+	ctx, cancel := c.getContext()
+	defer cancel()
+	return client.ApplyContractKey(ctx, &pb.ApplyContractKeyRequest{ContractKey: key})
+}
+
 // GetMinerConfiguration retrieves miner configuration
 func (c *SimpleBraiinsClient) GetMinerConfiguration() (*pb.GetMinerConfigurationResponse, error) {
 	client := pb.NewConfigurationServiceClient(c.conn)
 	ctx, cancel := c.getContext()
 	defer cancel()
 	return client.GetMinerConfiguration(ctx, &pb.GetMinerConfigurationRequest{})
-}
\ No newline at end of file
+}
+
+// GetNetworkConfiguration retrieves the hostname and DHCP/static settings
+func (c *SimpleBraiinsClient) GetNetworkConfiguration() (*pb.GetNetworkConfigurationResponse, error) {
+	client := pb.NewNetworkServiceClient(c.conn)
+	ctx, cancel := c.getContext()
+	defer cancel()
+	return client.GetNetworkConfiguration(ctx, &pb.GetNetworkConfigurationRequest{})
+}
+
+// SetNetworkConfiguration changes the hostname and DHCP/static settings
+func (c *SimpleBraiinsClient) SetNetworkConfiguration(req *pb.SetNetworkConfigurationRequest) (*pb.SetNetworkConfigurationResponse, error) {
+	client := pb.NewNetworkServiceClient(c.conn)
+	ctx, cancel := c.getContext()
+	defer cancel()
+	return client.SetNetworkConfiguration(ctx, req)
+}
+
+// GetNetworkInfo retrieves the active network state including the MAC address
+func (c *SimpleBraiinsClient) GetNetworkInfo() (*pb.GetNetworkInfoResponse, error) {
+	client := pb.NewNetworkServiceClient(c.conn)
+	ctx, cancel := c.getContext()
+	defer cancel()
+	return client.GetNetworkInfo(ctx, &pb.GetNetworkInfoRequest{})
+}
+
+// SetPassword changes the password of the logged in user
+func (c *SimpleBraiinsClient) SetPassword(ctx context.Context, password string) error {
+	client := pb.NewAuthenticationServiceClient(c.conn)
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	_, err := client.SetPassword(ctx, &pb.SetPasswordRequest{Password: &password})
+	return err
+}
@@ -0,0 +1,175 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultIdleTimeout is how long a pooled connection nobody holds stays open
+const DefaultIdleTimeout = 5 * time.Minute
+
+// Pool shares one authenticated connection per miner between callers. A
+// connection is dialled the first time a host is requested and kept open,
+// with its session renewed on demand, until nobody has held it for
+// IdleTimeout or the pool is closed.
+type Pool struct {
+	// IdleTimeout closes connections released this long ago, 0 keeps them
+	// until the pool is closed. Set it before the first Get.
+	IdleTimeout time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*poolEntry
+	closed  bool
+}
+
+type poolEntry struct {
+	once    sync.Once
+	key     string
+	opts    SimpleClientOptions
+	client  *SimpleBraiinsClient
+	err     error
+	refs    int         // callers holding the client, guarded by Pool.mu
+	retired bool        // replaced, closed when the last holder releases it
+	idle    *time.Timer // evicts the entry once released, guarded by Pool.mu
+	idleGen int         // invalidates an idle timer that already fired
+}
+
+// NewPool creates an empty connection pool that closes idle connections
+// after DefaultIdleTimeout
+func NewPool() *Pool {
+	return &Pool{IdleTimeout: DefaultIdleTimeout, entries: make(map[string]*poolEntry)}
+}
+
+// Get returns the pooled client for opts.Host, dialling it on first use.
+// Clients are keyed by host, port and username; a different password
+// replaces the pooled connection, which is closed once every caller holding
+// it has closed it. Closing the returned client only releases it.
+func (p *Pool) Get(opts SimpleClientOptions) (*SimpleBraiinsClient, error) {
+	if opts.Port == 0 {
+		opts.Port = 50051
+	}
+	key := fmt.Sprintf("%s:%d/%s", opts.Host, opts.Port, opts.Username)
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("connection pool is closed")
+	}
+	var stale *poolEntry
+	e, ok := p.entries[key]
+	if ok && e.opts != opts {
+		e.retired, ok = true, false
+		if e.refs == 0 {
+			stale = e
+		}
+	}
+	if !ok {
+		e = &poolEntry{key: key, opts: opts}
+		p.entries[key] = e
+	}
+	e.refs++
+	e.stopIdle()
+	p.mu.Unlock()
+
+	if stale != nil {
+		stale.close()
+	}
+
+	e.once.Do(func() {
+		e.client, e.err = NewSimpleClient(opts)
+		if e.err == nil {
+			e.client.release = func() { p.release(e) }
+		}
+	})
+	if e.err != nil {
+		// Drop the failed entry so the next caller dials again
+		p.mu.Lock()
+		e.refs--
+		if p.entries[key] == e {
+			delete(p.entries, key)
+		}
+		p.mu.Unlock()
+		return nil, e.err
+	}
+	return e.client, nil
+}
+
+// release hands back a client taken with Get and closes it when it was
+// replaced and this was its last holder. Otherwise the last holder starts
+// the idle timer.
+func (p *Pool) release(e *poolEntry) {
+	p.mu.Lock()
+	e.refs--
+	last := e.retired && e.refs == 0
+	if e.refs == 0 && !e.retired && !p.closed && p.IdleTimeout > 0 {
+		e.idleGen++
+		gen := e.idleGen
+		e.idle = time.AfterFunc(p.IdleTimeout, func() { p.evict(e, gen) })
+	}
+	p.mu.Unlock()
+
+	if last {
+		e.close()
+	}
+}
+
+// evict closes an entry whose idle timer fired, unless it was taken again
+// or replaced in the meantime
+func (p *Pool) evict(e *poolEntry, gen int) {
+	p.mu.Lock()
+	if e.refs != 0 || e.idleGen != gen || p.entries[e.key] != e {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.entries, e.key)
+	e.idle = nil
+	p.mu.Unlock()
+
+	e.close()
+}
+
+// stopIdle cancels the idle timer of an entry taken again, with Pool.mu held
+func (e *poolEntry) stopIdle() {
+	if e.idle != nil {
+		e.idle.Stop()
+		e.idle = nil
+	}
+	e.idleGen++
+}
+
+// Len returns the number of pooled connections
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.entries)
+}
+
+// Close closes every pooled connection. Later calls to Get fail.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	entries := p.entries
+	p.entries = make(map[string]*poolEntry)
+	p.closed = true
+	for _, e := range entries {
+		e.stopIdle()
+	}
+	p.mu.Unlock()
+
+	var firstErr error
+	for _, e := range entries {
+		if err := e.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// close waits for a pending dial and closes the connection
+func (e *poolEntry) close() error {
+	e.once.Do(func() { e.err = fmt.Errorf("connection pool is closed") })
+	if e.client == nil {
+		return nil
+	}
+	return e.client.closeConn()
+}
@@ -0,0 +1,286 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/sinkers/miner-cli/internal/braiins/bos/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// expiringMiner issues numbered session tokens and accepts only the latest
+type expiringMiner struct {
+	pb.UnimplementedAuthenticationServiceServer
+	pb.UnimplementedMinerServiceServer
+
+	mu     sync.Mutex
+	logins int
+	valid  string
+}
+
+func (m *expiringMiner) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if req.Username != "root" || req.Password != "root" {
+		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+	}
+	m.logins++
+	m.valid = fmt.Sprintf("token-%d", m.logins)
+	return &pb.LoginResponse{Token: m.valid}, nil
+}
+
+func (m *expiringMiner) GetMinerDetails(ctx context.Context, req *pb.GetMinerDetailsRequest) (*pb.GetMinerDetailsResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	md, _ := metadata.FromIncomingContext(ctx)
+	if tokens := md.Get("authorization"); len(tokens) != 1 || tokens[0] != m.valid {
+		return nil, status.Error(codes.Unauthenticated, "session expired")
+	}
+	return &pb.GetMinerDetailsResponse{Hostname: "miner"}, nil
+}
+
+// expire invalidates the current session token
+func (m *expiringMiner) expire() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.valid = "expired"
+}
+
+func (m *expiringMiner) loginCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.logins
+}
+
+func startMiner(t *testing.T, m *expiringMiner) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	pb.RegisterAuthenticationServiceServer(srv, m)
+	pb.RegisterMinerServiceServer(srv, m)
+	go srv.Serve(l)
+	t.Cleanup(srv.Stop)
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestClientReloginOnUnauthenticated(t *testing.T) {
+	m := &expiringMiner{}
+	port := startMiner(t, m)
+
+	c, err := NewSimpleClient(SimpleClientOptions{Host: "127.0.0.1", Port: port, Username: "root", Password: "root", Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if _, err := c.GetMinerDetails(); err != nil {
+		t.Fatalf("GetMinerDetails failed: %v", err)
+	}
+
+	m.expire()
+	details, err := c.GetMinerDetails()
+	if err != nil {
+		t.Fatalf("expected the call to succeed after re-login, got %v", err)
+	}
+	if details.GetHostname() != "miner" {
+		t.Errorf("unexpected response: %v", details)
+	}
+	if n := m.loginCount(); n != 2 {
+		t.Errorf("expected 2 logins, got %d", n)
+	}
+}
+
+func TestClientWithoutCredentialsDoesNotRetry(t *testing.T) {
+	m := &expiringMiner{}
+	port := startMiner(t, m)
+
+	c, err := NewSimpleClient(SimpleClientOptions{Host: "127.0.0.1", Port: port, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if _, err := c.GetMinerDetails(); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated, got %v", err)
+	}
+	if n := m.loginCount(); n != 0 {
+		t.Errorf("expected no logins, got %d", n)
+	}
+}
+
+func TestPoolReusesConnections(t *testing.T) {
+	m := &expiringMiner{}
+	port := startMiner(t, m)
+	opts := SimpleClientOptions{Host: "127.0.0.1", Port: port, Username: "root", Password: "root", Timeout: 5 * time.Second}
+
+	pool := NewPool()
+	defer pool.Close()
+
+	var wg sync.WaitGroup
+	clients := make([]*SimpleBraiinsClient, 8)
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c, err := pool.Get(opts)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer c.Close()
+			if _, err := c.GetMinerDetails(); err != nil {
+				t.Error(err)
+			}
+			clients[i] = c
+		}(i)
+	}
+	wg.Wait()
+
+	for _, c := range clients[1:] {
+		if c != clients[0] {
+			t.Fatal("expected every caller to share one client")
+		}
+	}
+	if n := m.loginCount(); n != 1 {
+		t.Errorf("expected a single login, got %d", n)
+	}
+
+	// closing a pooled client keeps the connection usable
+	if _, err := clients[0].GetMinerDetails(); err != nil {
+		t.Errorf("pooled client closed by caller: %v", err)
+	}
+	if pool.Len() != 1 {
+		t.Errorf("expected 1 pooled connection, got %d", pool.Len())
+	}
+}
+
+func TestPoolReplacesChangedCredentials(t *testing.T) {
+	m := &expiringMiner{}
+	port := startMiner(t, m)
+	opts := SimpleClientOptions{Host: "127.0.0.1", Port: port, Username: "root", Password: "wrong", Timeout: 5 * time.Second}
+
+	pool := NewPool()
+	defer pool.Close()
+
+	if _, err := pool.Get(opts); err == nil {
+		t.Fatal("expected login failure")
+	}
+	if pool.Len() != 0 {
+		t.Errorf("failed dial should not be pooled, got %d entries", pool.Len())
+	}
+
+	opts.Password = "root"
+	c, err := pool.Get(opts)
+	if err != nil {
+		t.Fatalf("expected login with the new password, got %v", err)
+	}
+	if _, err := c.GetMinerDetails(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPoolKeepsReplacedClientUntilReleased(t *testing.T) {
+	m := &expiringMiner{}
+	port := startMiner(t, m)
+	opts := SimpleClientOptions{Host: "127.0.0.1", Port: port, Username: "root", Password: "root", Timeout: 5 * time.Second}
+
+	pool := NewPool()
+	defer pool.Close()
+
+	held, err := pool.Get(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Timeout = 6 * time.Second
+	c, err := pool.Get(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if c == held {
+		t.Fatal("expected changed options to replace the pooled client")
+	}
+
+	if _, err := held.GetMinerDetails(); err != nil {
+		t.Errorf("replaced client closed while held: %v", err)
+	}
+	held.Close()
+	if _, err := held.GetMinerDetails(); err == nil {
+		t.Error("expected the replaced client to close with its last holder")
+	}
+	if _, err := c.GetMinerDetails(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPoolClosesIdleConnections(t *testing.T) {
+	m := &expiringMiner{}
+	port := startMiner(t, m)
+	opts := SimpleClientOptions{Host: "127.0.0.1", Port: port, Username: "root", Password: "root", Timeout: 5 * time.Second}
+
+	pool := NewPool()
+	pool.IdleTimeout = 50 * time.Millisecond
+	defer pool.Close()
+
+	held, err := pool.Get(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if _, err := held.GetMinerDetails(); err != nil || pool.Len() != 1 {
+		t.Fatalf("held connection evicted: %v, %d entries", err, pool.Len())
+	}
+
+	held.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for pool.Len() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if pool.Len() != 0 {
+		t.Fatal("expected the idle connection to be closed")
+	}
+	if _, err := held.GetMinerDetails(); err == nil {
+		t.Error("expected the evicted connection to be closed")
+	}
+
+	// a host used again is dialled afresh
+	c, err := pool.Get(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := c.GetMinerDetails(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPoolClose(t *testing.T) {
+	m := &expiringMiner{}
+	port := startMiner(t, m)
+	opts := SimpleClientOptions{Host: "127.0.0.1", Port: port, Username: "root", Password: "root", Timeout: 5 * time.Second}
+
+	pool := NewPool()
+	c, err := pool.Get(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.GetMinerDetails(); err == nil {
+		t.Error("expected the connection to be closed")
+	}
+	if _, err := pool.Get(opts); err == nil {
+		t.Error("expected Get to fail on a closed pool")
+	}
+}
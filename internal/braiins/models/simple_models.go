@@ -182,6 +182,11 @@ func ConvertHashboards(resp *pb.GetHashboardsResponse) []SimpleHashboard {
 			hb.Frequency = float64(board.CurrentFrequency.Hertz) / 1e6 // Convert Hz to MHz
 		}
 
+		// Convert board temperature
+		if board.BoardTemp != nil {
+			hb.Temperature = board.BoardTemp.DegreeC
+		}
+
 		boards = append(boards, hb)
 	}
 
@@ -276,28 +281,52 @@ func ConvertTunerState(resp *pb.GetTunerStateResponse) map[string]interface{} {
 	return result
 }
 
+// SimpleLicense represents the Braiins OS+ license state
+type SimpleLicense struct {
+	State            string // none, limited, valid or expired
+	Type             string // standard or custom
+	ContractName     string
+	DevFee           float64       // percent
+	TimeToRestricted time.Duration // until mining continues in restricted mode
+}
+
 // ConvertLicenseState converts GetLicenseStateResponse to license information
-func ConvertLicenseState(resp *pb.GetLicenseStateResponse) map[string]interface{} {
+func ConvertLicenseState(resp *pb.GetLicenseStateResponse) *SimpleLicense {
 	if resp == nil {
 		return nil
 	}
 
-	result := make(map[string]interface{})
+	license := &SimpleLicense{}
 
-	// Get license state based on the type
-	switch resp.State.(type) {
+	switch state := resp.State.(type) {
 	case *pb.GetLicenseStateResponse_None:
-		result["state"] = "none"
+		license.State = "none"
+		license.TimeToRestricted = time.Duration(state.None.GetTimeToRestricted()) * time.Second
 	case *pb.GetLicenseStateResponse_Limited:
-		result["state"] = "limited"
-		// Limited license details would be in the structure if available
+		license.State = "limited"
 	case *pb.GetLicenseStateResponse_Valid:
-		result["state"] = "valid"
-		// Valid license details would be in the structure if available
+		license.State = "valid"
+		license.Type = licenseType(state.Valid.GetType())
+		license.ContractName = state.Valid.GetContractName()
+		license.DevFee = float64(state.Valid.GetDevFee().GetBsp()) / 100
+		license.TimeToRestricted = time.Duration(state.Valid.GetTimeToRestricted()) * time.Second
 	case *pb.GetLicenseStateResponse_Expired:
-		result["state"] = "expired"
-		// Expired license details would be in the structure if available
+		license.State = "expired"
+		license.Type = licenseType(state.Expired.GetType())
+		license.ContractName = state.Expired.GetContractName()
+		license.DevFee = float64(state.Expired.GetDevFee().GetBsp()) / 100
 	}
 
-	return result
+	return license
+}
+
+func licenseType(t pb.LicenseType) string {
+	switch t {
+	case pb.LicenseType_LICENSE_TYPE_STANDARD:
+		return "standard"
+	case pb.LicenseType_LICENSE_TYPE_CUSTOM:
+		return "custom"
+	default:
+		return ""
+	}
 }
\ No newline at end of file
@@ -17,6 +17,7 @@ type Result struct {
 	Response interface{} `json:"response,omitempty"`
 	Error    string      `json:"error,omitempty"`
 	Duration string      `json:"duration"`
+	Err      error       `json:"-"` // the error behind Error, for telling failures apart
 }
 
 type Client struct {
@@ -66,6 +67,16 @@ func (c *Client) ExecuteCommand(ctx context.Context, ips []string, port int, com
 	return allResults
 }
 
+// Query executes a single command against one miner without the worker pool
+func (c *Client) Query(ip string, port int, command string, params map[string]interface{}) Result {
+	return c.executeJob(job{
+		ip:      ip,
+		port:    port,
+		command: command,
+		params:  params,
+	})
+}
+
 type job struct {
 	ip      string
 	port    int
@@ -0,0 +1,190 @@
+package credentials
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/sinkers/miner-cli/internal/iprange"
+)
+
+// Entry holds the credentials for a host or a group of hosts
+type Entry struct {
+	Name     string   `json:"name"`
+	Hosts    []string `json:"hosts,omitempty"` // IPs, CIDRs or ranges; empty matches every host
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	APIKey   string   `json:"api_key,omitempty"`
+	MAC      string   `json:"mac,omitempty"` // hardware address the entry was provisioned for
+}
+
+// Store is a set of credential entries. The most specific entry matching a
+// host wins: a single host beats a range, a smaller range beats a larger
+// one and entries without hosts apply to everything else.
+type Store struct {
+	Entries []Entry `json:"entries"`
+
+	index []compiled
+}
+
+type compiled struct {
+	entry Entry
+	hosts *iprange.Set // nil matches every host
+}
+
+// Parse reads a store from its JSON form
+func Parse(data []byte) (*Store, error) {
+	s := &Store{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials: %w", err)
+	}
+	if err := s.reindex(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Lookup returns the most specific entry matching host
+func (s *Store) Lookup(host string) (Entry, bool) {
+	var best *compiled
+	for i := range s.index {
+		c := &s.index[i]
+		if c.hosts != nil && !c.hosts.Contains(host) {
+			continue
+		}
+		if best == nil || moreSpecific(c, best) {
+			best = c
+		}
+	}
+	if best == nil {
+		return Entry{}, false
+	}
+	return best.entry, true
+}
+
+// Resolve returns the most specific entry matching host with the fields it
+// leaves empty taken from less specific matching entries, so a per-host API
+// key keeps the password of the host's group
+func (s *Store) Resolve(host string) (Entry, bool) {
+	var matches []*compiled
+	for i := range s.index {
+		if c := &s.index[i]; c.hosts == nil || c.hosts.Contains(host) {
+			matches = append(matches, c)
+		}
+	}
+	if len(matches) == 0 {
+		return Entry{}, false
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return moreSpecific(matches[i], matches[j])
+	})
+
+	e := matches[0].entry
+	for _, c := range matches[1:] {
+		if e.Username == "" {
+			e.Username = c.entry.Username
+		}
+		if e.Password == "" {
+			e.Password = c.entry.Password
+		}
+		if e.APIKey == "" {
+			e.APIKey = c.entry.APIKey
+		}
+	}
+	return e, true
+}
+
+func moreSpecific(a, b *compiled) bool {
+	if b.hosts == nil {
+		return a.hosts != nil
+	}
+	return a.hosts != nil && a.hosts.Size() < b.hosts.Size()
+}
+
+// Set adds the entry, replacing an existing entry with the same name
+func (s *Store) Set(e Entry) error {
+	if e.Name == "" {
+		return fmt.Errorf("credential entry name is required")
+	}
+
+	replaced := false
+	for i := range s.Entries {
+		if s.Entries[i].Name == e.Name {
+			s.Entries[i] = e
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		s.Entries = append(s.Entries, e)
+	}
+	return s.reindex()
+}
+
+// SetHostPassword records a changed password of host. Every entry for host
+// alone is updated, as any of them can win a lookup; without one an entry
+// called host is added, which is more specific than any range.
+func (s *Store) SetHostPassword(host, username, password string) error {
+	updated := false
+	for i, c := range s.index {
+		if c.hosts == nil || c.hosts.Size() != 1 || !c.hosts.Contains(host) {
+			continue
+		}
+		s.Entries[i].Username = username
+		s.Entries[i].Password = password
+		updated = true
+	}
+	if !updated {
+		s.Entries = append(s.Entries, Entry{Name: host, Hosts: []string{host}, Username: username, Password: password})
+	}
+	return s.reindex()
+}
+
+// Remove deletes the entry called name
+func (s *Store) Remove(name string) error {
+	for i := range s.Entries {
+		if s.Entries[i].Name == name {
+			s.Entries = append(s.Entries[:i], s.Entries[i+1:]...)
+			return s.reindex()
+		}
+	}
+	return fmt.Errorf("no credential entry named %q", name)
+}
+
+// Redacted returns the entries sorted by name with secrets masked
+func (s *Store) Redacted() []Entry {
+	entries := make([]Entry, len(s.Entries))
+	for i, e := range s.Entries {
+		e.Password = mask(e.Password)
+		e.APIKey = mask(e.APIKey)
+		entries[i] = e
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+func mask(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "********"
+}
+
+func (s *Store) reindex() error {
+	index := make([]compiled, 0, len(s.Entries))
+	for _, e := range s.Entries {
+		c := compiled{entry: e}
+		if len(e.Hosts) > 0 {
+			hosts, err := iprange.ParseSet(e.Hosts)
+			if err != nil {
+				return fmt.Errorf("invalid hosts in credential entry %q: %w", e.Name, err)
+			}
+			c.hosts = hosts
+		}
+		index = append(index, c)
+	}
+	s.index = index
+	return nil
+}
@@ -0,0 +1,193 @@
+package credentials
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	s, err := Parse([]byte(`{"entries": [
+		{"name": "default", "username": "root", "password": "root"},
+		{"name": "site", "hosts": ["10.0.0.0/8"], "password": "site-pass"},
+		{"name": "container-a", "hosts": ["10.0.1.0/24"], "password": "a-pass", "api_key": "key-a"},
+		{"name": "special", "hosts": ["10.0.1.5"], "password": "special-pass"}
+	]}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"10.0.1.5", "special"},
+		{"10.0.1.6", "container-a"},
+		{"10.0.2.1", "site"},
+		{"10.200.0.1", "site"},
+		{"192.168.1.1", "default"},
+	}
+	for _, tt := range tests {
+		e, ok := s.Lookup(tt.host)
+		if !ok || e.Name != tt.want {
+			t.Errorf("Lookup(%s) = %q, want %q", tt.host, e.Name, tt.want)
+		}
+	}
+
+	empty := &Store{}
+	if _, ok := empty.Lookup("10.0.0.1"); ok {
+		t.Error("expected no match in empty store")
+	}
+}
+
+func TestSetRemove(t *testing.T) {
+	s := &Store{}
+	if err := s.Set(Entry{Name: "a", Hosts: []string{"10.0.0.1"}, Password: "one"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set(Entry{Name: "a", Hosts: []string{"10.0.0.1"}, Password: "two"}); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := s.Lookup("10.0.0.1"); e.Password != "two" || len(s.Entries) != 1 {
+		t.Errorf("expected entry to be replaced, got %+v", s.Entries)
+	}
+
+	if err := s.Set(Entry{Name: "bad", Hosts: []string{"not-an-ip"}}); err == nil {
+		t.Error("expected error for invalid hosts")
+	}
+	s.Entries = s.Entries[:1]
+
+	if err := s.Remove("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Lookup("10.0.0.1"); ok {
+		t.Error("expected entry to be removed")
+	}
+	if err := s.Remove("a"); err == nil {
+		t.Error("expected error removing missing entry")
+	}
+}
+
+func TestSetHostPassword(t *testing.T) {
+	s, err := Parse([]byte(`{"entries": [
+		{"name": "site", "hosts": ["10.0.0.0/16"], "username": "root", "password": "site-pass"},
+		{"name": "vnish-apikey/10.0.1.5", "hosts": ["10.0.1.5"], "api_key": "key-5"},
+		{"name": "rack-3", "hosts": ["10.0.1.5-10.0.1.5"], "username": "root", "password": "old"}
+	]}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if err := s.SetHostPassword("10.0.1.5", "root", "new"); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Entries) != 3 {
+		t.Errorf("expected the single-host entries to be updated in place, got %+v", s.Entries)
+	}
+	if e, _ := s.Resolve("10.0.1.5"); e.Password != "new" || e.APIKey != "key-5" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if e, _ := s.Lookup("10.0.1.5"); e.Password != "new" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if e, _ := s.Lookup("10.0.1.6"); e.Password != "site-pass" {
+		t.Errorf("range entry changed: %+v", e)
+	}
+
+	// a host only covered by a range gets its own entry
+	if err := s.SetHostPassword("10.0.2.7", "root", "other"); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := s.Lookup("10.0.2.7"); e.Name != "10.0.2.7" || e.Password != "other" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	s := &Store{Entries: []Entry{{Name: "b", Password: "secret"}, {Name: "a", APIKey: "key"}}}
+	r := s.Redacted()
+	if r[0].Name != "a" || r[0].APIKey == "key" || r[1].Password == "secret" {
+		t.Errorf("expected sorted masked entries, got %+v", r)
+	}
+	if s.Entries[0].Password != "secret" {
+		t.Error("Redacted must not modify the store")
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "creds", "credentials.enc")
+
+	s := &Store{}
+	s.Set(Entry{Name: "site", Hosts: []string{"10.0.0.0/24"}, Password: "hunter2"})
+	if err := s.Save(path, "passphrase"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := Load(path, "passphrase")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if e, ok := loaded.Lookup("10.0.0.7"); !ok || e.Password != "hunter2" {
+		t.Errorf("unexpected entry after round trip: %+v", e)
+	}
+
+	if _, err := Load(path, "wrong"); err == nil || !strings.Contains(err.Error(), "wrong key") {
+		t.Errorf("expected decryption error, got %v", err)
+	}
+	if _, err := Load(path, ""); err == nil {
+		t.Error("expected error without passphrase")
+	}
+
+	missing, err := Load(filepath.Join(t.TempDir(), "none.enc"), "")
+	if err != nil || len(missing.Entries) != 0 {
+		t.Errorf("expected empty store for missing file, got %+v, %v", missing, err)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv(EnvJSON, "")
+	if s, err := FromEnv(); s != nil || err != nil {
+		t.Errorf("expected nil store, got %+v, %v", s, err)
+	}
+
+	t.Setenv(EnvJSON, `{"entries": [{"name": "all", "password": "env-pass"}]}`)
+	s, err := FromEnv()
+	if err != nil {
+		t.Fatalf("FromEnv failed: %v", err)
+	}
+	if e, _ := s.Lookup("10.0.0.1"); e.Password != "env-pass" {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+}
+
+func TestLoadRejectsIterations(t *testing.T) {
+	for _, iterations := range []int{0, 1, 1 << 40} {
+		path := filepath.Join(t.TempDir(), "credentials.enc")
+		raw, _ := json.Marshal(envelope{Version: 1, KDF: kdfName, Iterations: iterations, Salt: []byte("salt"), Nonce: make([]byte, 12)})
+		os.WriteFile(path, raw, 0600)
+		if _, err := Load(path, "secret"); err == nil || !strings.Contains(err.Error(), "iterations") {
+			t.Errorf("%d iterations: expected an error, got %v", iterations, err)
+		}
+	}
+}
+
+func TestResolveFillsFromLessSpecificEntries(t *testing.T) {
+	s, err := Parse([]byte(`{"entries": [
+		{"name": "default", "username": "root", "password": "root"},
+		{"name": "site", "hosts": ["10.0.0.0/16"], "password": "site-pass"},
+		{"name": "vnish-apikey/10.0.1.5", "hosts": ["10.0.1.5"], "api_key": "key-5"}
+	]}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	e, ok := s.Resolve("10.0.1.5")
+	if !ok || e.Name != "vnish-apikey/10.0.1.5" || e.APIKey != "key-5" || e.Password != "site-pass" || e.Username != "root" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if _, ok := (&Store{}).Resolve("10.0.1.5"); ok {
+		t.Error("expected no match in empty store")
+	}
+}
@@ -0,0 +1,153 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Environment variables consulted for credentials
+const (
+	EnvFile = "MINER_CLI_CREDENTIALS_FILE" // path of the encrypted store
+	EnvKey  = "MINER_CLI_CREDENTIALS_KEY"  // passphrase of the encrypted store
+	EnvJSON = "MINER_CLI_CREDENTIALS"      // unencrypted store as JSON
+)
+
+const (
+	kdfName       = "pbkdf2-sha256"
+	kdfIterations = 200000
+	keyLength     = 32
+
+	// bounds of the iterations accepted from a file
+	minIterations = 10000
+	maxIterations = 10000000
+)
+
+// envelope is the on-disk form of an encrypted store
+type envelope struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Data       []byte `json:"data"`
+}
+
+// Load decrypts the store at path. A missing file is an empty store.
+func Load(path, passphrase string) (*Store, error) {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Store{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+	if passphrase == "" {
+		return nil, fmt.Errorf("credentials file %s is encrypted, set %s", path, EnvKey)
+	}
+
+	var env envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials file: %w", err)
+	}
+	if env.Version != 1 || env.KDF != kdfName {
+		return nil, fmt.Errorf("unsupported credentials file format")
+	}
+
+	gcm, err := newGCM(passphrase, env.Salt, env.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	data, err := gcm.Open(nil, env.Nonce, env.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt credentials: wrong key or corrupted file")
+	}
+
+	return Parse(data)
+}
+
+// Save encrypts the store and writes it to path, readable by the owner only
+func (s *Store) Save(path, passphrase string) error {
+	if passphrase == "" {
+		return fmt.Errorf("a passphrase is required to save credentials, set %s", EnvKey)
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to marshal credentials: %w", err)
+	}
+
+	env := envelope{Version: 1, KDF: kdfName, Iterations: kdfIterations, Salt: make([]byte, 16)}
+	if _, err := rand.Read(env.Salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	gcm, err := newGCM(passphrase, env.Salt, env.Iterations)
+	if err != nil {
+		return err
+	}
+	env.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(env.Nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	env.Data = gcm.Seal(nil, env.Nonce, data, nil)
+
+	raw, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal credentials file: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create credentials directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+	return nil
+}
+
+// FromEnv returns the store given as JSON in MINER_CLI_CREDENTIALS, or nil
+// when the variable is not set
+func FromEnv() (*Store, error) {
+	data := os.Getenv(EnvJSON)
+	if data == "" {
+		return nil, nil
+	}
+	return Parse([]byte(data))
+}
+
+// DefaultPath returns the store location used when no path is given
+func DefaultPath() string {
+	if path := os.Getenv(EnvFile); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "credentials.enc"
+	}
+	return filepath.Join(dir, "miner-cli", "credentials.enc")
+}
+
+func newGCM(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	// the iterations come from the file, a huge count would hang the CLI
+	if iterations < minIterations || iterations > maxIterations {
+		return nil, fmt.Errorf("invalid key derivation parameters: %d iterations", iterations)
+	}
+	block, err := aes.NewCipher(pbkdf2.Key([]byte(passphrase), salt, iterations, keyLength, sha256.New))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
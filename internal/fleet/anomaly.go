@@ -0,0 +1,284 @@
+package fleet
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Anomaly kinds
+const (
+	AnomalyHashrateDrop = "hashrate_drop"
+	AnomalyBelowPeers   = "below_peers"
+	AnomalyErrorRate    = "hw_error_rate"
+	AnomalyRejects      = "reject_spike"
+	AnomalyTempDrift    = "temp_drift"
+)
+
+// minErrorRate is the hardware error rate per hour below which a rise is
+// noise rather than a failing board
+const minErrorRate = 10
+
+// AnomalyOptions sets the thresholds of DetectAnomalies
+type AnomalyOptions struct {
+	Window      time.Duration // recent period judged against the baseline before it
+	Drop        float64       // hashrate fraction below baseline or peers to flag
+	ErrorFactor float64       // hardware error rate multiple of the baseline to flag
+	RejectRate  float64       // rejected and stale fraction of shares to flag
+	TempDrift   float64       // °C above the baseline temperature to flag
+	MinPeers    int           // miners of a model needed for a peer comparison
+}
+
+// DefaultAnomalyOptions returns the thresholds used by the anomalies command
+func DefaultAnomalyOptions() AnomalyOptions {
+	return AnomalyOptions{
+		Window:      time.Hour,
+		Drop:        0.1,
+		ErrorFactor: 2,
+		RejectRate:  0.01,
+		TempDrift:   5,
+		MinPeers:    3,
+	}
+}
+
+// Anomaly is one finding on a miner. Score grows with how far the metric
+// is off, from 1 to 100.
+type Anomaly struct {
+	Kind     string  `json:"kind"`
+	Score    int     `json:"score"`
+	Current  float64 `json:"current"`
+	Expected float64 `json:"expected"`
+	Message  string  `json:"message"`
+}
+
+// MinerAnomalies are the findings on one miner. Score is the sum of the
+// finding scores, capped at 100.
+type MinerAnomalies struct {
+	Host      string    `json:"host"`
+	Model     string    `json:"model,omitempty"`
+	Score     int       `json:"score"`
+	Severity  string    `json:"severity"`
+	Anomalies []Anomaly `json:"anomalies"`
+}
+
+// AnomalyReport lists the flagged miners, worst first
+type AnomalyReport struct {
+	Window     string           `json:"window"`
+	Analyzed   int              `json:"analyzed"`
+	Flagged    []MinerAnomalies `json:"flagged"`
+	Healthy    []string         `json:"healthy"`
+	NoBaseline []string         `json:"no_baseline,omitempty"` // only compared to peers
+}
+
+// sampleStats condenses the samples of one miner over a period
+type sampleStats struct {
+	hashRate   float64 // average
+	hashMax    float64
+	temp       float64 // average of the samples with a reading
+	errorRate  float64 // hardware errors per hour
+	hasErrors  bool
+	rejectRate float64 // rejected and stale fraction of shares
+	hasRejects bool
+}
+
+// statsOf condenses time ordered samples. Counter rates come from the
+// increase between consecutive samples, a counter that went down is taken
+// as a restart from zero. A single sample has no error rate and gives the
+// reject rate since the miner started.
+func statsOf(samples []Sample) sampleStats {
+	var st sampleStats
+	if len(samples) == 0 {
+		return st
+	}
+
+	var hashSum, tempSum float64
+	temps := 0
+	for _, s := range samples {
+		hashSum += s.HashRate
+		if s.HashRate > st.hashMax {
+			st.hashMax = s.HashRate
+		}
+		if s.Temp > 0 {
+			tempSum += s.Temp
+			temps++
+		}
+	}
+	st.hashRate = hashSum / float64(len(samples))
+	if temps > 0 {
+		st.temp = tempSum / float64(temps)
+	}
+
+	var errors, good, bad int64
+	for i := 1; i < len(samples); i++ {
+		prev, cur := samples[i-1], samples[i]
+		errors += increase(prev.HardwareErrors, cur.HardwareErrors)
+		good += increase(prev.Accepted, cur.Accepted)
+		bad += increase(prev.Rejected, cur.Rejected) + increase(prev.Stale, cur.Stale)
+	}
+	if hours := samples[len(samples)-1].Time.Sub(samples[0].Time).Hours(); hours > 0 {
+		st.errorRate = float64(errors) / hours
+		st.hasErrors = true
+	}
+	if len(samples) == 1 {
+		last := samples[0]
+		good, bad = last.Accepted, last.Rejected+last.Stale
+	}
+	if good+bad > 0 {
+		st.rejectRate = float64(bad) / float64(good+bad)
+		st.hasRejects = true
+	}
+	return st
+}
+
+func increase(prev, cur int64) int64 {
+	if cur < prev {
+		return cur
+	}
+	return cur - prev
+}
+
+func scoreOf(v float64) int {
+	return int(math.Max(1, math.Min(100, math.Round(v))))
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// DetectAnomalies judges the samples in the window before now against each
+// miner's own baseline, the samples before the window, and against the
+// median of the miners of the same model. Miners without samples in the
+// window are not analyzed.
+func DetectAnomalies(samples []Sample, now time.Time, a AnomalyOptions) *AnomalyReport {
+	windowStart := now.Add(-a.Window)
+	recent := make(map[string][]Sample)
+	baseline := make(map[string][]Sample)
+	models := make(map[string]string)
+	for _, s := range samples {
+		if s.Time.After(now) {
+			continue
+		}
+		if s.Time.Before(windowStart) {
+			baseline[s.Host] = append(baseline[s.Host], s)
+		} else {
+			recent[s.Host] = append(recent[s.Host], s)
+		}
+		if s.Model != "" {
+			models[s.Host] = s.Model
+		}
+	}
+
+	current := make(map[string]sampleStats, len(recent))
+	peerRates := make(map[string][]float64)
+	for host, hostSamples := range recent {
+		sortSamples(hostSamples)
+		st := statsOf(hostSamples)
+		current[host] = st
+		if model := models[host]; model != "" {
+			peerRates[model] = append(peerRates[model], st.hashRate)
+		}
+	}
+
+	report := &AnomalyReport{Window: a.Window.String(), Analyzed: len(current)}
+	for host, cur := range current {
+		m := MinerAnomalies{Host: host, Model: models[host]}
+
+		if hostBaseline := baseline[host]; len(hostBaseline) > 0 {
+			sortSamples(hostBaseline)
+			m.Anomalies = append(m.Anomalies, compareBaseline(cur, statsOf(hostBaseline), a)...)
+		} else {
+			report.NoBaseline = append(report.NoBaseline, host)
+		}
+
+		if peers := peerRates[m.Model]; m.Model != "" && len(peers) >= a.MinPeers && len(peers) > 1 {
+			if peer := median(peers); peer > 0 && cur.hashRate < peer*(1-a.Drop) {
+				d := 1 - cur.hashRate/peer
+				m.Anomalies = append(m.Anomalies, Anomaly{
+					Kind: AnomalyBelowPeers, Score: scoreOf(d * 150), Current: cur.hashRate, Expected: peer,
+					Message: fmt.Sprintf("hashrate %.2f TH/s is %.0f%% below the %d %s miners' median of %.2f TH/s", cur.hashRate, d*100, len(peers), m.Model, peer),
+				})
+			}
+		}
+
+		if len(m.Anomalies) == 0 {
+			report.Healthy = append(report.Healthy, host)
+			continue
+		}
+		total := 0
+		for _, an := range m.Anomalies {
+			total += an.Score
+		}
+		m.Score = scoreOf(float64(total))
+		m.Severity = SeverityWarning
+		if m.Score >= 50 {
+			m.Severity = SeverityError
+		}
+		report.Flagged = append(report.Flagged, m)
+	}
+
+	sort.Slice(report.Flagged, func(i, j int) bool {
+		if report.Flagged[i].Score != report.Flagged[j].Score {
+			return report.Flagged[i].Score > report.Flagged[j].Score
+		}
+		return report.Flagged[i].Host < report.Flagged[j].Host
+	})
+	sort.Strings(report.Healthy)
+	sort.Strings(report.NoBaseline)
+	return report
+}
+
+// compareBaseline returns the findings of a miner's recent stats against
+// its own baseline
+func compareBaseline(cur, base sampleStats, a AnomalyOptions) []Anomaly {
+	var found []Anomaly
+
+	// sustained: even the best recent sample is below the limit
+	if base.hashRate > 0 && cur.hashMax < base.hashRate*(1-a.Drop) {
+		d := 1 - cur.hashRate/base.hashRate
+		found = append(found, Anomaly{
+			Kind: AnomalyHashrateDrop, Score: scoreOf(d * 200), Current: cur.hashRate, Expected: base.hashRate,
+			Message: fmt.Sprintf("hashrate %.2f TH/s is %.0f%% below its baseline of %.2f TH/s", cur.hashRate, d*100, base.hashRate),
+		})
+	}
+
+	if cur.hasErrors {
+		expected := math.Max(minErrorRate, base.errorRate)
+		if cur.errorRate > expected*a.ErrorFactor {
+			found = append(found, Anomaly{
+				Kind: AnomalyErrorRate, Score: scoreOf(15 * cur.errorRate / expected), Current: cur.errorRate, Expected: base.errorRate,
+				Message: fmt.Sprintf("%.0f hardware errors per hour, baseline %.0f", cur.errorRate, base.errorRate),
+			})
+		}
+	}
+
+	if cur.hasRejects {
+		limit := math.Max(a.RejectRate, 2*base.rejectRate)
+		if cur.rejectRate > limit {
+			found = append(found, Anomaly{
+				Kind: AnomalyRejects, Score: scoreOf(cur.rejectRate * 1000), Current: cur.rejectRate * 100, Expected: base.rejectRate * 100,
+				Message: fmt.Sprintf("%.1f%% of shares rejected or stale, baseline %.1f%%", cur.rejectRate*100, base.rejectRate*100),
+			})
+		}
+	}
+
+	if cur.temp > 0 && base.temp > 0 {
+		if drift := cur.temp - base.temp; drift >= a.TempDrift {
+			found = append(found, Anomaly{
+				Kind: AnomalyTempDrift, Score: scoreOf(drift * 5), Current: cur.temp, Expected: base.temp,
+				Message: fmt.Sprintf("running %.1f°C hotter than its baseline of %.1f°C", drift, base.temp),
+			})
+		}
+	}
+	return found
+}
+
+func sortSamples(samples []Sample) {
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+}
@@ -0,0 +1,126 @@
+package fleet
+
+import (
+	"testing"
+	"time"
+)
+
+// series returns samples of host every 10 minutes from start with rate
+// TH/s and counters growing by errs hardware errors and acc/rej shares
+// per sample
+func series(host, model string, start time.Time, n int, rate, temp float64, errs, acc, rej int64) []Sample {
+	var samples []Sample
+	for i := 0; i < n; i++ {
+		samples = append(samples, Sample{
+			Time:           start.Add(time.Duration(i) * 10 * time.Minute),
+			Host:           host,
+			Model:          model,
+			HashRate:       rate,
+			Temp:           temp,
+			HardwareErrors: int64(i) * errs,
+			Accepted:       int64(i) * acc,
+			Rejected:       int64(i) * rej,
+		})
+	}
+	return samples
+}
+
+func TestDetectAnomalies(t *testing.T) {
+	now := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	base := now.Add(-25 * time.Hour)
+	recent := now.Add(-55 * time.Minute)
+
+	var samples []Sample
+	for _, host := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"} {
+		samples = append(samples, series(host, "S19", base, 144, 100, 70, 2, 100, 0)...)
+	}
+	// healthy
+	samples = append(samples, series("10.0.0.1", "S19", recent, 6, 101, 71, 2, 100, 0)...)
+	samples = append(samples, series("10.0.0.2", "S19", recent, 6, 99, 70, 2, 100, 0)...)
+	// hashrate dropped by 30% and is running hot
+	samples = append(samples, series("10.0.0.3", "S19", recent, 6, 70, 82, 2, 100, 0)...)
+	// failing board: errors and rejects
+	samples = append(samples, series("10.0.0.4", "S19", recent, 6, 98, 70, 50, 90, 10)...)
+	// new miner, peers only
+	samples = append(samples, series("10.0.0.5", "S19", recent, 6, 60, 70, 0, 100, 0)...)
+	// not seen in the window
+	samples = append(samples, series("10.0.0.6", "S19", base, 6, 100, 70, 0, 100, 0)...)
+
+	report := DetectAnomalies(samples, now, DefaultAnomalyOptions())
+
+	if report.Analyzed != 5 {
+		t.Errorf("expected 5 analyzed miners, got %d", report.Analyzed)
+	}
+	if len(report.Healthy) != 2 || report.Healthy[0] != "10.0.0.1" || report.Healthy[1] != "10.0.0.2" {
+		t.Errorf("unexpected healthy miners: %v", report.Healthy)
+	}
+	if len(report.NoBaseline) != 1 || report.NoBaseline[0] != "10.0.0.5" {
+		t.Errorf("unexpected miners without baseline: %v", report.NoBaseline)
+	}
+
+	flagged := make(map[string]MinerAnomalies)
+	for _, m := range report.Flagged {
+		flagged[m.Host] = m
+	}
+	kinds := func(host string) map[string]int {
+		found := make(map[string]int)
+		for _, a := range flagged[host].Anomalies {
+			found[a.Kind] = a.Score
+		}
+		return found
+	}
+
+	k := kinds("10.0.0.3")
+	if k[AnomalyHashrateDrop] != 60 || k[AnomalyTempDrift] != 60 || k[AnomalyBelowPeers] == 0 {
+		t.Errorf("unexpected findings on 10.0.0.3: %+v", flagged["10.0.0.3"].Anomalies)
+	}
+	if m := flagged["10.0.0.3"]; m.Score != 100 || m.Severity != SeverityError {
+		t.Errorf("expected 10.0.0.3 capped at 100, got %d %s", m.Score, m.Severity)
+	}
+
+	k = kinds("10.0.0.4")
+	if k[AnomalyErrorRate] == 0 || k[AnomalyRejects] != 100 || k[AnomalyHashrateDrop] != 0 {
+		t.Errorf("unexpected findings on 10.0.0.4: %+v", flagged["10.0.0.4"].Anomalies)
+	}
+
+	k = kinds("10.0.0.5")
+	if len(k) != 1 || k[AnomalyBelowPeers] == 0 {
+		t.Errorf("expected 10.0.0.5 only below peers: %+v", flagged["10.0.0.5"].Anomalies)
+	}
+
+	for i := 1; i < len(report.Flagged); i++ {
+		if report.Flagged[i].Score > report.Flagged[i-1].Score {
+			t.Errorf("flagged miners not sorted by score: %+v", report.Flagged)
+		}
+	}
+}
+
+func TestDetectAnomaliesNotSustained(t *testing.T) {
+	now := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	samples := series("10.0.0.1", "", now.Add(-24*time.Hour), 100, 100, 70, 0, 0, 0)
+	// one bad reading among good ones is not a drop
+	for i, rate := range []float64{60, 100, 99} {
+		samples = append(samples, Sample{Time: now.Add(time.Duration(i-3) * 10 * time.Minute), Host: "10.0.0.1", HashRate: rate})
+	}
+
+	report := DetectAnomalies(samples, now, DefaultAnomalyOptions())
+	if len(report.Flagged) != 0 {
+		t.Errorf("expected no anomalies, got %+v", report.Flagged)
+	}
+}
+
+func TestStatsOfCounterReset(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	st := statsOf([]Sample{
+		{Time: start, HardwareErrors: 1000, Accepted: 5000, Rejected: 50},
+		{Time: start.Add(30 * time.Minute), HardwareErrors: 1010, Accepted: 5090, Rejected: 60},
+		// the miner restarted
+		{Time: start.Add(time.Hour), HardwareErrors: 10, Accepted: 100, Rejected: 0},
+	})
+	if !st.hasErrors || st.errorRate != 20 {
+		t.Errorf("expected 20 errors per hour, got %v", st.errorRate)
+	}
+	if st.rejectRate != 10.0/200 {
+		t.Errorf("unexpected reject rate %v", st.rejectRate)
+	}
+}
@@ -0,0 +1,261 @@
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/sinkers/miner-cli/internal/client"
+	"github.com/sinkers/miner-cli/internal/credentials"
+	vnish "github.com/sinkers/miner-cli/internal/vnish/client"
+)
+
+// API key actions
+const (
+	APIKeyProvision = "provision"
+	APIKeyRotate    = "rotate"
+	APIKeyRevoke    = "revoke"
+)
+
+// APIKeyEntryPrefix starts the names of credential entries written by
+// APIKeyResult.Entry
+const APIKeyEntryPrefix = "vnish-apikey/"
+
+// APIKeyOptions controls API key provisioning
+type APIKeyOptions struct {
+	Action string
+	Name   string // key name on the miner
+	ByMAC  bool   // name the credential entry after the MAC instead of the host
+}
+
+// Validate checks the action and key name
+func (a APIKeyOptions) Validate() error {
+	switch a.Action {
+	case APIKeyProvision, APIKeyRotate, APIKeyRevoke:
+	default:
+		return fmt.Errorf("invalid API key action %q", a.Action)
+	}
+	if a.Name == "" {
+		return fmt.Errorf("API key name is required")
+	}
+	return nil
+}
+
+// APIKeyResult is the outcome of an API key action on one miner. Key is the
+// new secret and is never printed; it only goes to the credential store.
+type APIKeyResult struct {
+	Host    string `json:"host"`
+	MAC     string `json:"mac,omitempty"`
+	Action  string `json:"action"`
+	Created bool   `json:"created"`
+	Removed int    `json:"removed"` // keys of that name deleted from the miner
+	Skipped string `json:"skipped,omitempty"`
+	Warning string `json:"warning,omitempty"`
+	Key     string `json:"-"`
+	byMAC   bool
+	stale   []string      // old keys to delete once the new one is stored
+	session *vnish.Client // logged in client for deleting them
+}
+
+// EntryName returns the credential entry of the miner, named after its MAC
+// when requested and known
+func (r *APIKeyResult) EntryName() string {
+	if r.byMAC && r.MAC != "" {
+		return APIKeyEntryPrefix + r.MAC
+	}
+	return APIKeyEntryPrefix + hostIP(r.Host)
+}
+
+// Entry returns the credential entry holding the new key
+func (r *APIKeyResult) Entry() credentials.Entry {
+	return credentials.Entry{Name: r.EntryName(), Hosts: []string{hostIP(r.Host)}, APIKey: r.Key, MAC: r.MAC}
+}
+
+// hostIP strips a port from host; credential entries match IPs only
+func hostIP(host string) string {
+	if ip, _, err := net.SplitHostPort(host); err == nil {
+		return ip
+	}
+	return host
+}
+
+// ManageAPIKey logs in to host once with its password and provisions,
+// rotates or revokes the API key called a.Name. Provisioning skips miners
+// that already have the key and a stored secret for it. Provisioning and
+// rotation only create the new key; its old keys are deleted by
+// RetireAPIKeys once the new key is in the credential store.
+func ManageAPIKey(ctx context.Context, opts Options, host string, a APIKeyOptions) (*APIKeyResult, error) {
+	password, err := lockPassword(opts, host)
+	if err != nil {
+		return nil, err
+	}
+	token, err := opts.Vnish(host).Login(ctx, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to log in: %w", err)
+	}
+	vc := opts.vnishSession(host, token)
+
+	result := &APIKeyResult{Host: host, Action: a.Action, byMAC: a.ByMAC}
+	if info, err := vc.GetInfo(ctx); err == nil && info.System != nil {
+		result.MAC = normalizeMAC(info.System.NetworkStatus.MAC)
+	}
+	if a.ByMAC && result.MAC == "" {
+		return nil, fmt.Errorf("miner did not report its MAC address")
+	}
+
+	keys, err := vc.GetAPIKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	var existing []string
+	for _, k := range keys {
+		if k.Name == a.Name {
+			existing = append(existing, k.ID)
+		}
+	}
+
+	if a.Action == APIKeyProvision && len(existing) > 0 && hasStoredAPIKey(opts.Credentials, host) {
+		result.Skipped = "key already provisioned"
+		return result, nil
+	}
+
+	if a.Action != APIKeyRevoke {
+		added, err := vc.AddAPIKey(ctx, a.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add API key: %w", err)
+		}
+		if !added.Status.Success || added.Key == "" {
+			return nil, fmt.Errorf("miner rejected API key: %s", added.Status.Message)
+		}
+		result.Created = true
+		result.Key = added.Key
+		result.stale, result.session = existing, vc
+		return result, nil
+	}
+
+	for _, id := range existing {
+		if err := vc.DeleteAPIKey(ctx, id); err != nil {
+			return nil, fmt.Errorf("failed to delete API key %s: %w", id, err)
+		}
+		result.Removed++
+	}
+	return result, nil
+}
+
+// RetireAPIKeys deletes the old keys of the miners that got a new one in
+// report, which must be in the credential store by then. When provisioning
+// they are keys whose secret was never stored. The new key works, so failing
+// to delete an old one is only a warning.
+func RetireAPIKeys(ctx context.Context, runner *Runner, port int, report *APIKeyReport) {
+	pending := make(map[string]*APIKeyResult)
+	var hosts []string
+	for i := range report.Changed {
+		if r := &report.Changed[i]; len(r.stale) > 0 {
+			pending[r.Host] = r
+			hosts = append(hosts, r.Host)
+		}
+	}
+	runner.Run(ctx, hosts, port, "vnish apikey "+report.Action, func(ctx context.Context, host string) (interface{}, error) {
+		r := pending[host]
+		for _, id := range r.stale {
+			if err := r.session.DeleteAPIKey(ctx, id); err != nil {
+				r.Warning = fmt.Sprintf("old key %s not deleted: %v", id, err)
+				continue
+			}
+			r.Removed++
+		}
+		r.stale = nil
+		return r, nil
+	})
+}
+
+// hasStoredAPIKey reports whether the store holds a provisioned key for host
+func hasStoredAPIKey(store *credentials.Store, host string) bool {
+	if store == nil {
+		return false
+	}
+	for _, e := range store.Entries {
+		if isAPIKeyEntryFor(e, host, "") && e.APIKey != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// isAPIKeyEntryFor reports whether e is a provisioned key entry of the
+// miner at host or with the MAC address mac
+func isAPIKeyEntryFor(e credentials.Entry, host, mac string) bool {
+	if !strings.HasPrefix(e.Name, APIKeyEntryPrefix) {
+		return false
+	}
+	if mac != "" && e.MAC == mac {
+		return true
+	}
+	return len(e.Hosts) == 1 && e.Hosts[0] == hostIP(host)
+}
+
+// vnishSession returns a vnish client authenticated with a Login token
+// instead of an API key
+func (o Options) vnishSession(host, token string) *vnish.Client {
+	opts := []vnish.Option{vnish.WithToken(token)}
+	if o.Timeout > 0 {
+		opts = append(opts, vnish.WithTimeout(o.Timeout))
+	}
+	return vnish.NewClient(host, opts...)
+}
+
+// APIKeyReport summarizes an API key run
+type APIKeyReport struct {
+	Action  string            `json:"action"`
+	Scanned int               `json:"scanned"`
+	Changed []APIKeyResult    `json:"changed"`
+	Skipped []string          `json:"skipped"`
+	Failed  map[string]string `json:"failed,omitempty"` // host to error
+}
+
+// SummarizeAPIKeys builds the report of a ManageAPIKey run
+func SummarizeAPIKeys(action string, results []client.Result) *APIKeyReport {
+	report := &APIKeyReport{Action: action, Scanned: len(results), Failed: make(map[string]string)}
+	for _, r := range results {
+		k, ok := r.Response.(*APIKeyResult)
+		if r.Error != "" || !ok {
+			report.Failed[r.IP] = r.Error
+			continue
+		}
+		if k.Skipped != "" {
+			report.Skipped = append(report.Skipped, r.IP)
+		} else {
+			report.Changed = append(report.Changed, *k)
+		}
+	}
+
+	sort.Slice(report.Changed, func(i, j int) bool {
+		return report.Changed[i].Host < report.Changed[j].Host
+	})
+	sort.Strings(report.Skipped)
+	return report
+}
+
+// StoreAPIKeys records the outcome of a run in the credential store. The
+// key entries of each changed miner, by host or MAC, are replaced by the
+// created key, or just removed when the key was revoked.
+func StoreAPIKeys(store *credentials.Store, report *APIKeyReport) error {
+	for i := range report.Changed {
+		r := &report.Changed[i]
+		for _, e := range append([]credentials.Entry(nil), store.Entries...) {
+			if isAPIKeyEntryFor(e, r.Host, r.MAC) {
+				if err := store.Remove(e.Name); err != nil {
+					return err
+				}
+			}
+		}
+		if r.Created {
+			if err := store.Set(r.Entry()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
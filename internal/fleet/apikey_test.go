@@ -0,0 +1,153 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sinkers/miner-cli/internal/client"
+	"github.com/sinkers/miner-cli/internal/credentials"
+	vmodels "github.com/sinkers/miner-cli/internal/vnish/models"
+)
+
+// fakeKeyMiner is a vnish miner whose API keys need a login token
+type fakeKeyMiner struct {
+	mu     sync.Mutex
+	keys   []vmodels.ApiKeysJsonItem
+	next   int
+	logins int
+}
+
+func (f *fakeKeyMiner) start(t *testing.T) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if r.URL.Path == "/api/v1/unlock" {
+			var req vmodels.UnlockRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.Password != "admin" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			f.logins++
+			json.NewEncoder(w).Encode(vmodels.UnlockSuccess{Token: "session"})
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer session" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v1/info":
+			json.NewEncoder(w).Encode(vmodels.SystemInfo{System: &vmodels.InfoSystem{NetworkStatus: vmodels.NetworkStatus{MAC: "AA-BB-CC-00-11-22"}}})
+		case "/api/v1/apikeys":
+			if r.Method == http.MethodPost {
+				var req vmodels.AddApikeyQuery
+				json.NewDecoder(r.Body).Decode(&req)
+				f.next++
+				f.keys = append(f.keys, vmodels.ApiKeysJsonItem{ID: fmt.Sprint(f.next), Name: req.Name})
+				json.NewEncoder(w).Encode(vmodels.AddApiKeyRes{Status: vmodels.AddApiKeyStatus{Success: true}, Key: fmt.Sprintf("secret-%d", f.next)})
+				return
+			}
+			json.NewEncoder(w).Encode(f.keys)
+		case "/api/v1/apikeys/delete":
+			var req vmodels.DeleteApikeyQuery
+			json.NewDecoder(r.Body).Decode(&req)
+			for i, k := range f.keys {
+				if k.ID == req.ID {
+					f.keys = append(f.keys[:i], f.keys[i+1:]...)
+					break
+				}
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+// runAPIKey runs one action against host and stores the outcome
+func runAPIKey(t *testing.T, store *credentials.Store, host string, a APIKeyOptions) *APIKeyReport {
+	t.Helper()
+	opts := Options{Firmware: FirmwareVnish, Password: "admin", Credentials: store}
+	r, err := ManageAPIKey(context.Background(), opts, host, a)
+	results := []client.Result{{IP: host, Response: r}}
+	if err != nil {
+		results[0] = client.Result{IP: host, Error: err.Error()}
+	}
+	report := SummarizeAPIKeys(a.Action, results)
+	if err := StoreAPIKeys(store, report); err != nil {
+		t.Fatal(err)
+	}
+	RetireAPIKeys(context.Background(), NewRunner(1), 0, report)
+	return report
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	m := &fakeKeyMiner{keys: []vmodels.ApiKeysJsonItem{{ID: "other", Name: "dashboard"}}}
+	host := m.start(t)
+	store := &credentials.Store{}
+
+	report := runAPIKey(t, store, host, APIKeyOptions{Action: APIKeyProvision, Name: "miner-cli", ByMAC: true})
+	if len(report.Changed) != 1 || len(report.Failed) != 0 {
+		t.Fatalf("unexpected provision report %+v", report)
+	}
+	if e, ok := store.Resolve("127.0.0.1"); !ok || e.Name != "vnish-apikey/aa:bb:cc:00:11:22" || e.APIKey != "secret-1" || e.MAC != "aa:bb:cc:00:11:22" {
+		t.Fatalf("key not stored by MAC: %+v", store.Entries)
+	}
+
+	report = runAPIKey(t, store, host, APIKeyOptions{Action: APIKeyProvision, Name: "miner-cli"})
+	if len(report.Skipped) != 1 || m.next != 1 {
+		t.Errorf("expected provisioned miner to be skipped, got %+v", report)
+	}
+
+	report = runAPIKey(t, store, host, APIKeyOptions{Action: APIKeyRotate, Name: "miner-cli", ByMAC: true})
+	if len(report.Changed) != 1 || report.Changed[0].Removed != 1 {
+		t.Fatalf("unexpected rotate report %+v", report)
+	}
+	if len(m.keys) != 2 || m.keys[1].ID != "2" || store.Entries[0].APIKey != "secret-2" || len(store.Entries) != 1 {
+		t.Errorf("rotation left keys %+v and entries %+v", m.keys, store.Entries)
+	}
+
+	runAPIKey(t, store, host, APIKeyOptions{Action: APIKeyRevoke, Name: "miner-cli"})
+	if len(m.keys) != 1 || m.keys[0].Name != "dashboard" || len(store.Entries) != 0 {
+		t.Errorf("revoke left keys %+v and entries %+v", m.keys, store.Entries)
+	}
+	if m.logins != 4 {
+		t.Errorf("expected one login per run, got %d", m.logins)
+	}
+}
+
+func TestAPIKeyRotateKeepsOldKeysUntilRetired(t *testing.T) {
+	m := &fakeKeyMiner{keys: []vmodels.ApiKeysJsonItem{{ID: "old", Name: "miner-cli"}}}
+	host := m.start(t)
+	opts := Options{Firmware: FirmwareVnish, Password: "admin"}
+
+	r, err := ManageAPIKey(context.Background(), opts, host, APIKeyOptions{Action: APIKeyRotate, Name: "miner-cli"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.keys) != 2 || r.Removed != 0 {
+		t.Fatalf("old key deleted before the new one was stored: %+v", m.keys)
+	}
+
+	report := SummarizeAPIKeys(APIKeyRotate, []client.Result{{IP: host, Response: r}})
+	RetireAPIKeys(context.Background(), NewRunner(1), 0, report)
+	if len(m.keys) != 1 || m.keys[0].ID != "1" || report.Changed[0].Removed != 1 || m.logins != 1 {
+		t.Errorf("retire left keys %+v, report %+v", m.keys, report.Changed)
+	}
+}
+
+func TestAPIKeyWrongPassword(t *testing.T) {
+	host := (&fakeKeyMiner{}).start(t)
+	_, err := ManageAPIKey(context.Background(), Options{Firmware: FirmwareVnish, Password: "nope"}, host, APIKeyOptions{Action: APIKeyProvision, Name: "miner-cli"})
+	if err == nil || !strings.Contains(err.Error(), "failed to log in") {
+		t.Errorf("expected a login error, got %v", err)
+	}
+}
@@ -0,0 +1,265 @@
+package fleet
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	pb "github.com/sinkers/miner-cli/internal/braiins/bos/v1"
+	"github.com/sinkers/miner-cli/internal/client"
+)
+
+// ManifestFile is the name of the manifest written next to the archives
+const ManifestFile = "manifest.json"
+
+// Support archive formats accepted by ArchiveOptions
+var archiveFormats = map[string]pb.SupportArchiveFormat{
+	"zip":           pb.SupportArchiveFormat_SUPPORT_ARCHIVE_FORMAT_ZIP,
+	"bos":           pb.SupportArchiveFormat_SUPPORT_ARCHIVE_FORMAT_BOS,
+	"zip-encrypted": pb.SupportArchiveFormat_SUPPORT_ARCHIVE_FORMAT_ZIP_ENCRYPTED,
+}
+
+// ArchiveOptions controls where and how support archives are downloaded
+type ArchiveOptions struct {
+	Dir     string
+	Format  string // zip, bos or zip-encrypted
+	MaxSize int64  // bytes, 0 for no limit
+	Force   bool   // download again even when the archive exists
+}
+
+// Validate checks the format and output directory
+func (a ArchiveOptions) Validate() error {
+	if _, ok := archiveFormats[a.Format]; !ok {
+		return fmt.Errorf("invalid archive format %q (expected zip, bos or zip-encrypted)", a.Format)
+	}
+	if a.Dir == "" {
+		return fmt.Errorf("output directory is required")
+	}
+	if a.MaxSize < 0 {
+		return fmt.Errorf("max size must not be negative")
+	}
+	return nil
+}
+
+// Path returns the archive file for host. Each format has its own suffix
+// so that resuming in another format does not skip the host.
+func (a ArchiveOptions) Path(host string) string {
+	ext := ".zip"
+	switch a.Format {
+	case "bos":
+		ext = ".bos"
+	case "zip-encrypted":
+		ext = ".enc.zip"
+	}
+	name := strings.NewReplacer(":", "_", "/", "_").Replace(host)
+	return filepath.Join(a.Dir, name+ext)
+}
+
+// ArchiveResult describes one downloaded support archive
+type ArchiveResult struct {
+	Host       string    `json:"host"`
+	File       string    `json:"file,omitempty"`
+	Format     string    `json:"format"`
+	Size       int64     `json:"size"`
+	SHA256     string    `json:"sha256,omitempty"`
+	Skipped    bool      `json:"skipped,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Downloaded time.Time `json:"downloaded,omitempty"`
+}
+
+// archiveStream is the receiving side of GetSupportArchive
+type archiveStream interface {
+	Recv() (*pb.GetSupportArchiveResponse, error)
+}
+
+// DownloadSupportArchive streams the Braiins OS support archive of host into
+// the output directory. Existing archives are skipped unless Force is set so
+// an interrupted run can be resumed.
+func DownloadSupportArchive(ctx context.Context, opts Options, host string, a ArchiveOptions) (*ArchiveResult, error) {
+	path := a.Path(host)
+	result := &ArchiveResult{Host: host, File: filepath.Base(path), Format: a.Format}
+
+	if !a.Force {
+		if info, err := os.Stat(path); err == nil && info.Size() > 0 {
+			sum, err := fileSHA256(path)
+			if err != nil {
+				return nil, err
+			}
+			result.Size = info.Size()
+			result.SHA256 = sum
+			result.Skipped = true
+			result.Downloaded = info.ModTime().UTC()
+			return result, nil
+		}
+	}
+
+	c, err := opts.Braiins(host)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	stream, err := c.GetSupportArchive(ctx, archiveFormats[a.Format])
+	if err != nil {
+		return nil, fmt.Errorf("failed to request support archive: %w", err)
+	}
+
+	size, sum, err := writeArchive(stream, path, a.MaxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Size = size
+	result.SHA256 = sum
+	result.Downloaded = time.Now().UTC()
+	return result, nil
+}
+
+// writeArchive writes the stream to a temporary file and renames it into
+// place once complete, so a partial download is never mistaken for a
+// finished archive.
+func writeArchive(stream archiveStream, path string, maxSize int64) (int64, string, error) {
+	tmp := path + ".part"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to create archive file: %w", err)
+	}
+
+	size, sum, err := copyArchive(f, stream, maxSize)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write archive: %w", closeErr)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return 0, "", err
+	}
+	if size == 0 {
+		os.Remove(tmp)
+		return 0, "", fmt.Errorf("miner returned an empty support archive")
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return 0, "", fmt.Errorf("failed to save archive: %w", err)
+	}
+	return size, sum, nil
+}
+
+func copyArchive(w io.Writer, stream archiveStream, maxSize int64) (int64, string, error) {
+	hash := sha256.New()
+	out := io.MultiWriter(w, hash)
+	var size int64
+
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, "", fmt.Errorf("failed to receive archive: %w", err)
+		}
+
+		size += int64(len(chunk.GetChunkData()))
+		if maxSize > 0 && size > maxSize {
+			return 0, "", fmt.Errorf("support archive exceeds size limit of %d bytes", maxSize)
+		}
+		if _, err := out.Write(chunk.GetChunkData()); err != nil {
+			return 0, "", fmt.Errorf("failed to write archive: %w", err)
+		}
+	}
+
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", fmt.Errorf("failed to read archive: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// ArchiveManifest lists the archives in an output directory
+type ArchiveManifest struct {
+	Updated  time.Time       `json:"updated"`
+	Archives []ArchiveResult `json:"archives"`
+}
+
+// LoadArchiveManifest reads the manifest in dir. A missing manifest is empty.
+func LoadArchiveManifest(dir string) (*ArchiveManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return &ArchiveManifest{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var m ArchiveManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// Merge records the results of a run, replacing earlier entries per host
+// and format; the archives of a host in other formats are kept. A failed
+// retry does not replace an archive downloaded by an earlier run.
+func (m *ArchiveManifest) Merge(format string, results []client.Result) {
+	type key struct{ host, format string }
+	entries := make(map[key]ArchiveResult)
+	for _, a := range m.Archives {
+		entries[key{a.Host, a.Format}] = a
+	}
+
+	for _, r := range results {
+		k := key{r.IP, format}
+		if a, ok := r.Response.(*ArchiveResult); ok && r.Error == "" {
+			entries[k] = *a
+			continue
+		}
+		if prev, ok := entries[k]; ok && prev.Error == "" {
+			continue
+		}
+		entries[k] = ArchiveResult{Host: r.IP, Format: format, Error: r.Error}
+	}
+
+	m.Archives = m.Archives[:0]
+	for _, a := range entries {
+		m.Archives = append(m.Archives, a)
+	}
+	sort.Slice(m.Archives, func(i, j int) bool {
+		if m.Archives[i].Host != m.Archives[j].Host {
+			return m.Archives[i].Host < m.Archives[j].Host
+		}
+		return m.Archives[i].Format < m.Archives[j].Format
+	})
+	m.Updated = time.Now().UTC()
+}
+
+// Save writes the manifest into dir
+func (m *ArchiveManifest) Save(dir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
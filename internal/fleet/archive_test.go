@@ -0,0 +1,152 @@
+package fleet
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pb "github.com/sinkers/miner-cli/internal/braiins/bos/v1"
+	"github.com/sinkers/miner-cli/internal/client"
+)
+
+type fakeArchiveStream struct {
+	chunks [][]byte
+}
+
+func (s *fakeArchiveStream) Recv() (*pb.GetSupportArchiveResponse, error) {
+	if len(s.chunks) == 0 {
+		return nil, io.EOF
+	}
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return &pb.GetSupportArchiveResponse{ChunkData: chunk}, nil
+}
+
+func TestArchiveOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    ArchiveOptions
+		wantErr bool
+	}{
+		{"zip", ArchiveOptions{Dir: "out", Format: "zip"}, false},
+		{"bos", ArchiveOptions{Dir: "out", Format: "bos", MaxSize: 1024}, false},
+		{"bad format", ArchiveOptions{Dir: "out", Format: "tar"}, true},
+		{"no dir", ArchiveOptions{Format: "zip"}, true},
+		{"negative size", ArchiveOptions{Dir: "out", Format: "zip", MaxSize: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWriteArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "10.0.0.1.zip")
+	stream := &fakeArchiveStream{chunks: [][]byte{[]byte("hello "), []byte("world")}}
+
+	size, sum, err := writeArchive(stream, path, 0)
+	if err != nil {
+		t.Fatalf("writeArchive failed: %v", err)
+	}
+	if size != 11 {
+		t.Errorf("expected size 11, got %d", size)
+	}
+	// sha256("hello world")
+	if sum != "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9" {
+		t.Errorf("unexpected checksum %s", sum)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "hello world" {
+		t.Errorf("unexpected contents %q", data)
+	}
+}
+
+func TestWriteArchiveSizeLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "10.0.0.1.zip")
+	stream := &fakeArchiveStream{chunks: [][]byte{make([]byte, 8), make([]byte, 8)}}
+
+	_, _, err := writeArchive(stream, path, 10)
+	if err == nil || !strings.Contains(err.Error(), "size limit") {
+		t.Fatalf("expected size limit error, got %v", err)
+	}
+	for _, p := range []string{path, path + ".part"} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed", p)
+		}
+	}
+}
+
+func TestDownloadSupportArchiveSkipsExisting(t *testing.T) {
+	a := ArchiveOptions{Dir: t.TempDir(), Format: "zip"}
+	if err := os.WriteFile(a.Path("10.0.0.1"), []byte("archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// No miner is listening; the existing file must be used without dialing
+	result, err := DownloadSupportArchive(context.Background(), Options{GRPCPort: closedPort(t)}, "10.0.0.1", a)
+	if err != nil {
+		t.Fatalf("DownloadSupportArchive failed: %v", err)
+	}
+	if !result.Skipped || result.Size != 7 || result.File != "10.0.0.1.zip" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	// an encrypted archive of the same host is another file
+	a.Format = "zip-encrypted"
+	if path := a.Path("10.0.0.1"); filepath.Base(path) != "10.0.0.1.enc.zip" {
+		t.Errorf("unexpected encrypted archive path %s", path)
+	}
+}
+
+func TestArchiveManifest(t *testing.T) {
+	dir := t.TempDir()
+
+	m, err := LoadArchiveManifest(dir)
+	if err != nil || len(m.Archives) != 0 {
+		t.Fatalf("expected empty manifest, got %+v, %v", m, err)
+	}
+
+	m.Merge("zip", []client.Result{
+		{IP: "10.0.0.2", Response: &ArchiveResult{Host: "10.0.0.2", Format: "zip", Size: 10}},
+		{IP: "10.0.0.1", Error: "connection refused"},
+	})
+	if err := m.Save(dir); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	m, err = LoadArchiveManifest(dir)
+	if err != nil {
+		t.Fatalf("LoadArchiveManifest failed: %v", err)
+	}
+	m.Merge("zip", []client.Result{
+		{IP: "10.0.0.1", Response: &ArchiveResult{Host: "10.0.0.1", Format: "zip", Size: 20}},
+		{IP: "10.0.0.2", Error: "context cancelled"},
+	})
+
+	if len(m.Archives) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", m.Archives)
+	}
+	if m.Archives[0].Host != "10.0.0.1" || m.Archives[0].Size != 20 || m.Archives[0].Error != "" {
+		t.Errorf("expected retried host to be recorded, got %+v", m.Archives[0])
+	}
+	if m.Archives[1].Size != 10 || m.Archives[1].Error != "" {
+		t.Errorf("expected earlier download to be kept, got %+v", m.Archives[1])
+	}
+
+	m.Merge("zip-encrypted", []client.Result{
+		{IP: "10.0.0.2", Response: &ArchiveResult{Host: "10.0.0.2", Format: "zip-encrypted", Size: 30}},
+	})
+	if len(m.Archives) != 3 {
+		t.Fatalf("expected an entry per host and format, got %+v", m.Archives)
+	}
+	if m.Archives[1].Format != "zip" || m.Archives[1].Size != 10 || m.Archives[2].Format != "zip-encrypted" || m.Archives[2].Size != 30 {
+		t.Errorf("expected both formats of 10.0.0.2, got %+v", m.Archives[1:])
+	}
+}
@@ -0,0 +1,395 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/client"
+	vmodels "github.com/sinkers/miner-cli/internal/vnish/models"
+)
+
+// PresetInventory is the autotune state of one vnish miner
+type PresetInventory struct {
+	Model   string                   `json:"model"`
+	Current string                   `json:"current,omitempty"`
+	Presets []vmodels.AutotunePreset `json:"presets"`
+}
+
+// GetPresets reads the model, active preset and available presets of a
+// vnish miner
+func GetPresets(ctx context.Context, opts Options, host string) (*PresetInventory, error) {
+	vc := opts.Vnish(host)
+
+	model, err := vc.GetModel(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get model: %w", err)
+	}
+	presets, err := vc.GetAutotunePresets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get autotune presets: %w", err)
+	}
+	current, err := vc.GetPreset(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+
+	return &PresetInventory{Model: model.Model, Current: current, Presets: presets.Presets}, nil
+}
+
+// ModelPresets lists the presets offered for one model
+type ModelPresets struct {
+	Model   string                   `json:"model"`
+	Hosts   int                      `json:"hosts"`
+	Current map[string]int           `json:"current"` // hosts per active preset
+	Presets []vmodels.AutotunePreset `json:"presets"`
+}
+
+// PresetReport summarizes autotune presets by model
+type PresetReport struct {
+	Scanned     int            `json:"scanned"`
+	Models      []ModelPresets `json:"models"`
+	Unreachable []string       `json:"unreachable,omitempty"`
+}
+
+// SummarizePresets groups GetPresets results by model
+func SummarizePresets(results []client.Result) *PresetReport {
+	report := &PresetReport{Scanned: len(results)}
+	byModel := make(map[string]*ModelPresets)
+	seen := make(map[string]map[string]bool)
+
+	for _, r := range results {
+		inv, ok := r.Response.(*PresetInventory)
+		if r.Error != "" || !ok {
+			report.Unreachable = append(report.Unreachable, r.IP)
+			continue
+		}
+
+		m, ok := byModel[inv.Model]
+		if !ok {
+			m = &ModelPresets{Model: inv.Model, Current: make(map[string]int)}
+			byModel[inv.Model] = m
+			seen[inv.Model] = make(map[string]bool)
+		}
+		m.Hosts++
+		current := inv.Current
+		if current == "" {
+			current = "none"
+		}
+		m.Current[current]++
+		for _, p := range inv.Presets {
+			if !seen[inv.Model][p.ID] {
+				seen[inv.Model][p.ID] = true
+				m.Presets = append(m.Presets, p)
+			}
+		}
+	}
+
+	for _, m := range byModel {
+		sort.Slice(m.Presets, func(i, j int) bool {
+			return m.Presets[i].ID < m.Presets[j].ID
+		})
+		report.Models = append(report.Models, *m)
+	}
+	sort.Slice(report.Models, func(i, j int) bool {
+		return report.Models[i].Model < report.Models[j].Model
+	})
+	sort.Strings(report.Unreachable)
+	return report
+}
+
+// PerfSample is a hashrate/power reading from GetPerfSummary
+type PerfSample struct {
+	HashRate     float64 `json:"hash_rate"`
+	HashRateUnit string  `json:"hash_rate_unit,omitempty"`
+	Power        float64 `json:"power"`
+	Efficiency   float64 `json:"efficiency"`
+}
+
+// MeasurePerf reads the current performance of a vnish miner
+func MeasurePerf(ctx context.Context, opts Options, host string) (*PerfSample, error) {
+	perf, err := opts.Vnish(host).GetPerfSummary(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get performance summary: %w", err)
+	}
+	return &PerfSample{HashRate: perf.HashRate, HashRateUnit: perf.HashRateUnit, Power: perf.PowerUsage, Efficiency: perf.Efficiency}, nil
+}
+
+// RolloutOptions controls a batched preset rollout
+type RolloutOptions struct {
+	Preset          string
+	Model           string // only miners of this model, empty for all
+	BatchSize       int
+	Settle          time.Duration // wait after applying before measuring
+	ContinueOnError bool
+}
+
+// RolloutResult is the outcome of a preset change on one miner
+type RolloutResult struct {
+	Host     string      `json:"host"`
+	Model    string      `json:"model"`
+	Preset   string      `json:"preset"`
+	Previous string      `json:"previous,omitempty"`
+	Batch    int         `json:"batch"`
+	Skipped  string      `json:"skipped,omitempty"`
+	Before   *PerfSample `json:"before,omitempty"`
+	After    *PerfSample `json:"after,omitempty"`
+}
+
+// ApplyPreset records the performance of a vnish miner and switches it to
+// preset through a settings update. Miners of another model or without the
+// preset are skipped.
+func ApplyPreset(ctx context.Context, opts Options, host, preset, model string) (*RolloutResult, error) {
+	inv, err := GetPresets(ctx, opts, host)
+	if err != nil {
+		return nil, err
+	}
+	result := &RolloutResult{Host: host, Model: inv.Model, Preset: preset, Previous: inv.Current}
+
+	switch {
+	case model != "" && inv.Model != model:
+		result.Skipped = fmt.Sprintf("model %s", inv.Model)
+		return result, nil
+	case inv.Current == preset:
+		result.Skipped = "preset already active"
+		return result, nil
+	case !hasPreset(inv.Presets, preset):
+		result.Skipped = "preset not available"
+		return result, nil
+	}
+
+	if result.Before, err = MeasurePerf(ctx, opts, host); err != nil {
+		return nil, err
+	}
+
+	if err := ensureUnlocked(ctx, opts, host); err != nil {
+		return nil, err
+	}
+	if err := opts.Vnish(host).SetPreset(ctx, preset); err != nil {
+		return nil, fmt.Errorf("failed to update settings: %w", err)
+	}
+	return result, nil
+}
+
+func hasPreset(presets []vmodels.AutotunePreset, id string) bool {
+	for _, p := range presets {
+		if p.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// RolloutPreset applies ro.Preset to hosts in batches. After a batch that
+// changed miners it waits ro.Settle and measures them again, then calls
+// batchDone.
+// A batch with failures stops the rollout unless ContinueOnError is set;
+// hosts not attempted are reported with an error.
+func RolloutPreset(ctx context.Context, opts Options, runner *Runner, hosts []string, ro RolloutOptions, batchDone func(batch int, results []client.Result)) []client.Result {
+	if ro.BatchSize <= 0 {
+		ro.BatchSize = len(hosts)
+	}
+
+	var all []client.Result
+	start := 0
+	for batch := 1; start < len(hosts); batch++ {
+		end := start + ro.BatchSize
+		if end > len(hosts) {
+			end = len(hosts)
+		}
+
+		results := runner.Run(ctx, hosts[start:end], 80, "autotune rollout", func(ctx context.Context, host string) (interface{}, error) {
+			r, err := ApplyPreset(ctx, opts, host, ro.Preset, ro.Model)
+			if r != nil {
+				r.Batch = batch
+			}
+			return r, err
+		})
+		start = end
+
+		if changed := changedHosts(results); len(changed) > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(ro.Settle):
+			}
+			measureAfter(ctx, opts, runner, changed)
+		}
+
+		all = append(all, results...)
+		if batchDone != nil {
+			batchDone(batch, results)
+		}
+
+		if ctx.Err() != nil || (!ro.ContinueOnError && rolloutFailed(results)) {
+			break
+		}
+	}
+
+	for _, host := range hosts[start:] {
+		all = append(all, client.Result{IP: host, Port: 80, Command: "autotune rollout", Error: "not attempted, rollout stopped"})
+	}
+	return all
+}
+
+// changedHosts returns the rollout results of the miners whose preset
+// was changed, by host
+func changedHosts(results []client.Result) map[string]*RolloutResult {
+	changed := make(map[string]*RolloutResult)
+	for _, r := range results {
+		if res, ok := r.Response.(*RolloutResult); ok && r.Error == "" && res.Skipped == "" {
+			changed[r.IP] = res
+		}
+	}
+	return changed
+}
+
+// measureAfter fills in the performance after the preset change
+func measureAfter(ctx context.Context, opts Options, runner *Runner, changed map[string]*RolloutResult) {
+	hosts := make([]string, 0, len(changed))
+	for host := range changed {
+		hosts = append(hosts, host)
+	}
+
+	for _, m := range runner.Run(ctx, hosts, 80, "autotune measure", func(ctx context.Context, host string) (interface{}, error) {
+		return MeasurePerf(ctx, opts, host)
+	}) {
+		if sample, ok := m.Response.(*PerfSample); ok {
+			changed[m.IP].After = sample
+		}
+	}
+}
+
+func rolloutFailed(results []client.Result) bool {
+	for _, r := range results {
+		if r.Error != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// PresetComparison is the average performance change of one preset on one
+// model. Changes are percentages of the value before the rollout.
+type PresetComparison struct {
+	Model            string     `json:"model"`
+	Preset           string     `json:"preset"`
+	Hosts            int        `json:"hosts"`
+	Before           PerfSample `json:"before"`
+	After            PerfSample `json:"after"`
+	HashRateChange   float64    `json:"hash_rate_change"`
+	PowerChange      float64    `json:"power_change"`
+	EfficiencyChange float64    `json:"efficiency_change"`
+}
+
+// RolloutReport records a rollout for later comparison
+type RolloutReport struct {
+	Preset      string             `json:"preset"`
+	Finished    time.Time          `json:"finished"`
+	Results     []RolloutResult    `json:"results"`
+	Failed      map[string]string  `json:"failed,omitempty"` // host to error
+	Comparisons []PresetComparison `json:"comparisons"`
+}
+
+// SummarizeRollout builds the report of a RolloutPreset run
+func SummarizeRollout(preset string, results []client.Result) *RolloutReport {
+	report := &RolloutReport{Preset: preset, Finished: time.Now().UTC(), Failed: make(map[string]string)}
+	for _, r := range results {
+		if res, ok := r.Response.(*RolloutResult); ok && r.Error == "" {
+			report.Results = append(report.Results, *res)
+			continue
+		}
+		report.Failed[r.IP] = r.Error
+	}
+	sort.Slice(report.Results, func(i, j int) bool {
+		return report.Results[i].Host < report.Results[j].Host
+	})
+	report.Comparisons = ComparePresets(report.Results)
+	return report
+}
+
+// ComparePresets averages before and after performance per model and
+// preset over miners measured on both sides, best efficiency first
+func ComparePresets(results []RolloutResult) []PresetComparison {
+	type key struct{ model, preset string }
+	groups := make(map[key]*PresetComparison)
+
+	for _, r := range results {
+		if r.Before == nil || r.After == nil {
+			continue
+		}
+		k := key{r.Model, r.Preset}
+		c, ok := groups[k]
+		if !ok {
+			c = &PresetComparison{Model: r.Model, Preset: r.Preset}
+			groups[k] = c
+		}
+		c.Hosts++
+		c.Before.HashRateUnit = r.Before.HashRateUnit
+		c.After.HashRateUnit = r.After.HashRateUnit
+		addSample(&c.Before, r.Before)
+		addSample(&c.After, r.After)
+	}
+
+	comparisons := make([]PresetComparison, 0, len(groups))
+	for _, c := range groups {
+		n := float64(c.Hosts)
+		for _, s := range []*PerfSample{&c.Before, &c.After} {
+			s.HashRate /= n
+			s.Power /= n
+			s.Efficiency /= n
+		}
+		c.HashRateChange = percentChange(c.Before.HashRate, c.After.HashRate)
+		c.PowerChange = percentChange(c.Before.Power, c.After.Power)
+		c.EfficiencyChange = percentChange(c.Before.Efficiency, c.After.Efficiency)
+		comparisons = append(comparisons, *c)
+	}
+
+	sort.Slice(comparisons, func(i, j int) bool {
+		if comparisons[i].Model != comparisons[j].Model {
+			return comparisons[i].Model < comparisons[j].Model
+		}
+		return comparisons[i].After.Efficiency < comparisons[j].After.Efficiency
+	})
+	return comparisons
+}
+
+func addSample(sum *PerfSample, s *PerfSample) {
+	sum.HashRate += s.HashRate
+	sum.Power += s.Power
+	sum.Efficiency += s.Efficiency
+}
+
+func percentChange(before, after float64) float64 {
+	if before == 0 {
+		return 0
+	}
+	return (after - before) / before * 100
+}
+
+// Save writes the report as JSON to path
+func (r *RolloutReport) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal rollout report: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write rollout report: %w", err)
+	}
+	return nil
+}
+
+// LoadRolloutReport reads a report written by Save
+func LoadRolloutReport(path string) (*RolloutReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rollout report: %w", err)
+	}
+	var r RolloutReport
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse rollout report %s: %w", path, err)
+	}
+	return &r, nil
+}
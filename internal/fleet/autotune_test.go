@@ -0,0 +1,201 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/client"
+	vmodels "github.com/sinkers/miner-cli/internal/vnish/models"
+)
+
+// fakeAutotuneMiner is a vnish miner whose performance follows its preset
+type fakeAutotuneMiner struct {
+	mu        sync.Mutex
+	model     string
+	overclock map[string]interface{}         // miner overclock settings
+	perf      map[string]vmodels.PerfSummary // by preset
+	fail      bool
+}
+
+func (f *fakeAutotuneMiner) start(t *testing.T) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v1/model":
+			json.NewEncoder(w).Encode(vmodels.ModelInfo{Model: f.model})
+		case "/api/v1/autotune/presets":
+			json.NewEncoder(w).Encode(vmodels.AutotunePresets{Presets: []vmodels.AutotunePreset{{ID: "low"}, {ID: "high"}}})
+		case "/api/v1/settings":
+			var settings struct {
+				Miner struct {
+					Overclock map[string]interface{} `json:"overclock"`
+				} `json:"miner"`
+			}
+			if r.Method == http.MethodPost {
+				json.NewDecoder(r.Body).Decode(&settings)
+				f.overclock = settings.Miner.Overclock
+				return
+			}
+			settings.Miner.Overclock = f.overclock
+			json.NewEncoder(w).Encode(settings)
+		case "/api/v1/perf-summary":
+			json.NewEncoder(w).Encode(f.perf[f.overclock["preset"].(string)])
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+func newAutotuneMiner(model, preset string) *fakeAutotuneMiner {
+	return &fakeAutotuneMiner{
+		model:     model,
+		overclock: map[string]interface{}{"preset": preset, "modded_psu": true},
+		perf: map[string]vmodels.PerfSummary{
+			"low":  {HashRate: 80, HashRateUnit: "TH/s", PowerUsage: 2400, Efficiency: 30},
+			"high": {HashRate: 100, HashRateUnit: "TH/s", PowerUsage: 3400, Efficiency: 34},
+		},
+	}
+}
+
+func TestSummarizePresets(t *testing.T) {
+	results := []client.Result{
+		{IP: "10.0.0.1", Response: &PresetInventory{Model: "S19", Current: "low", Presets: []vmodels.AutotunePreset{{ID: "low"}, {ID: "high"}}}},
+		{IP: "10.0.0.2", Response: &PresetInventory{Model: "S19", Presets: []vmodels.AutotunePreset{{ID: "low"}}}},
+		{IP: "10.0.0.3", Response: &PresetInventory{Model: "L7", Current: "eco", Presets: []vmodels.AutotunePreset{{ID: "eco"}}}},
+		{IP: "10.0.0.4", Error: "timeout"},
+	}
+
+	report := SummarizePresets(results)
+	if len(report.Models) != 2 || report.Models[0].Model != "L7" || len(report.Unreachable) != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	s19 := report.Models[1]
+	if s19.Hosts != 2 || len(s19.Presets) != 2 || s19.Current["low"] != 1 || s19.Current["none"] != 1 {
+		t.Errorf("unexpected S19 presets %+v", s19)
+	}
+}
+
+func TestRolloutPreset(t *testing.T) {
+	miners := []*fakeAutotuneMiner{
+		newAutotuneMiner("S19", "low"),
+		newAutotuneMiner("S19", "low"),
+		newAutotuneMiner("S19", "high"), // already on the preset
+		newAutotuneMiner("L7", "low"),   // other model
+	}
+	var hosts []string
+	for _, m := range miners {
+		hosts = append(hosts, m.start(t))
+	}
+
+	var batches []int
+	ro := RolloutOptions{Preset: "high", Model: "S19", BatchSize: 2}
+	results := RolloutPreset(context.Background(), Options{}, NewRunner(4), hosts, ro, func(batch int, results []client.Result) {
+		batches = append(batches, batch)
+	})
+
+	if len(batches) != 2 || len(results) != 4 {
+		t.Fatalf("expected 2 batches and 4 results, got %v and %d", batches, len(results))
+	}
+	for _, m := range miners[:2] {
+		if m.overclock["preset"] != "high" || m.overclock["modded_psu"] != true {
+			t.Errorf("preset not applied: %+v", m.overclock)
+		}
+	}
+	if miners[3].overclock["preset"] != "low" {
+		t.Error("miner of another model was changed")
+	}
+
+	report := SummarizeRollout("high", results)
+	if len(report.Failed) != 0 || len(report.Results) != 4 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	skipped := 0
+	for _, r := range report.Results {
+		if r.Skipped != "" {
+			skipped++
+		}
+	}
+	if skipped != 2 {
+		t.Errorf("expected 2 skipped miners, got %d", skipped)
+	}
+
+	if len(report.Comparisons) != 1 {
+		t.Fatalf("expected one comparison, got %+v", report.Comparisons)
+	}
+	c := report.Comparisons[0]
+	if c.Hosts != 2 || c.Before.HashRate != 80 || c.After.HashRate != 100 || c.HashRateChange != 25 {
+		t.Errorf("unexpected comparison %+v", c)
+	}
+
+	// reports round trip for compare
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := report.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadRolloutReport(path)
+	if err != nil || len(loaded.Results) != 4 {
+		t.Errorf("LoadRolloutReport: %v %+v", err, loaded)
+	}
+}
+
+func TestRolloutPresetStopsOnFailure(t *testing.T) {
+	broken := newAutotuneMiner("S19", "low")
+	broken.fail = true
+	miners := []*fakeAutotuneMiner{broken, newAutotuneMiner("S19", "low")}
+	hosts := []string{miners[0].start(t), miners[1].start(t)}
+
+	results := RolloutPreset(context.Background(), Options{}, NewRunner(4), hosts, RolloutOptions{Preset: "high", BatchSize: 1}, nil)
+
+	report := SummarizeRollout("high", results)
+	if len(report.Failed) != 2 || !strings.Contains(report.Failed[hosts[1]], "not attempted") {
+		t.Errorf("expected the second batch to be skipped, got %+v", report.Failed)
+	}
+	if miners[1].overclock["preset"] != "low" {
+		t.Error("second batch should not have been changed")
+	}
+}
+
+func TestRolloutPresetSettlesOnlyAfterChanges(t *testing.T) {
+	hosts := []string{newAutotuneMiner("S19", "high").start(t), newAutotuneMiner("L7", "low").start(t)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	results := RolloutPreset(ctx, Options{}, NewRunner(4), hosts, RolloutOptions{Preset: "high", Model: "S19", Settle: time.Hour}, nil)
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("waited %s for a batch without changes", elapsed)
+	}
+	for _, r := range SummarizeRollout("high", results).Results {
+		if r.Skipped == "" || r.After != nil {
+			t.Errorf("unexpected result %+v", r)
+		}
+	}
+}
+
+func TestComparePresetsRanksByEfficiency(t *testing.T) {
+	results := []RolloutResult{
+		{Model: "S19", Preset: "high", Before: &PerfSample{Efficiency: 30}, After: &PerfSample{Efficiency: 34}},
+		{Model: "S19", Preset: "low", Before: &PerfSample{Efficiency: 34}, After: &PerfSample{Efficiency: 30}},
+		{Model: "S19", Preset: "low", Skipped: "preset already active"},
+	}
+
+	comparisons := ComparePresets(results)
+	if len(comparisons) != 2 || comparisons[0].Preset != "low" {
+		t.Errorf("expected low first, got %+v", comparisons)
+	}
+}
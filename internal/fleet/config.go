@@ -0,0 +1,457 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	pb "github.com/sinkers/miner-cli/internal/braiins/bos/v1"
+	"github.com/sinkers/miner-cli/internal/client"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Configuration sections that ApplyConfig can push back to a miner
+const (
+	ConfigPools   = "pools"
+	ConfigTuner   = "tuner"
+	ConfigCooling = "cooling"
+)
+
+// ConfigSections lists the sections restored by default
+var ConfigSections = []string{ConfigPools, ConfigTuner, ConfigCooling}
+
+// configSectionFields maps GetMinerConfiguration fields to section names
+var configSectionFields = map[string]string{
+	"pool_groups":      ConfigPools,
+	"tuner":            ConfigTuner,
+	"temperature":      ConfigCooling,
+	"dps":              "dps",
+	"hashboard_config": "hashboards",
+}
+
+// ValidateConfigSections checks that every section can be applied
+func ValidateConfigSections(sections []string) error {
+	if len(sections) == 0 {
+		return fmt.Errorf("no configuration sections selected")
+	}
+	for _, s := range sections {
+		switch s {
+		case ConfigPools, ConfigTuner, ConfigCooling:
+		default:
+			return fmt.Errorf("invalid configuration section %q (expected pools, tuner or cooling)", s)
+		}
+	}
+	return nil
+}
+
+// ConfigSnapshot is the configuration of one miner as written to disk
+type ConfigSnapshot struct {
+	Host   string          `json:"host"`
+	Taken  time.Time       `json:"taken"`
+	Config json.RawMessage `json:"config"` // GetMinerConfiguration response in protobuf JSON
+}
+
+// ConfigExport reports where a snapshot was written
+type ConfigExport struct {
+	Host string `json:"host"`
+	File string `json:"file"`
+}
+
+// ExportConfig writes the configuration of a Braiins OS miner to a snapshot
+// file in dir
+func ExportConfig(ctx context.Context, opts Options, host, dir string) (*ConfigExport, error) {
+	cfg, err := GetConfig(ctx, opts, host)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal configuration: %w", err)
+	}
+	snapshot := ConfigSnapshot{Host: host, Taken: time.Now().UTC(), Config: raw}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	path := SnapshotPath(dir, host)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return &ConfigExport{Host: host, File: path}, nil
+}
+
+// SnapshotPath returns the snapshot file for host in dir
+func SnapshotPath(dir, host string) string {
+	name := strings.NewReplacer(":", "_", "/", "_").Replace(host)
+	return filepath.Join(dir, name+".json")
+}
+
+// GetConfig reads the full configuration of a Braiins OS miner
+func GetConfig(ctx context.Context, opts Options, host string) (*pb.GetMinerConfigurationResponse, error) {
+	c, err := opts.Braiins(host)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	cfg, err := c.GetMinerConfiguration()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// LoadConfig reads a snapshot written by ExportConfig or a bare
+// GetMinerConfiguration JSON document used as a golden template
+func LoadConfig(path string) (*pb.GetMinerConfigurationResponse, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration: %w", err)
+	}
+
+	var snapshot ConfigSnapshot
+	if err := json.Unmarshal(data, &snapshot); err == nil && len(snapshot.Config) > 0 {
+		data = snapshot.Config
+	}
+
+	cfg := &pb.GetMinerConfigurationResponse{}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse configuration %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// ConfigChange is one differing configuration value, Old from the first and
+// New from the second configuration. An empty side means the value is not set.
+type ConfigChange struct {
+	Section string `json:"section"`
+	Path    string `json:"path"`
+	Old     string `json:"old,omitempty"`
+	New     string `json:"new,omitempty"`
+}
+
+// secretKeys are the keys whose values RedactChanges masks
+var secretKeys = map[string]bool{"password": true, "pass": true}
+
+// RedactChanges returns changes with the values of password fields, such as
+// pool passwords, replaced by *** so diffs can be shared
+func RedactChanges(changes []ConfigChange) []ConfigChange {
+	out := make([]ConfigChange, len(changes))
+	for i, ch := range changes {
+		if secretKeys[lastField(ch.Path)] {
+			ch.Old, ch.New = mask(ch.Old), mask(ch.New)
+		}
+		out[i] = ch
+	}
+	return out
+}
+
+// lastField returns the last key of a flattened path without list indexes
+func lastField(path string) string {
+	path = strings.TrimRight(path, "]0123456789[")
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
+
+func mask(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "***"
+}
+
+// DiffConfig compares two configurations value by value. Pool and group
+// UIDs are generated per miner and ignored.
+func DiffConfig(from, to *pb.GetMinerConfigurationResponse) ([]ConfigChange, error) {
+	a, err := flattenConfig(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := flattenConfig(to)
+	if err != nil {
+		return nil, err
+	}
+	return diffValues(a, b, configSection), nil
+}
+
+// flattenConfig turns the configuration into path/value pairs such as
+// pool_groups[0].pools[1].url
+func flattenConfig(cfg *pb.GetMinerConfigurationResponse) (map[string]string, error) {
+	raw, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal configuration: %w", err)
+	}
+	return flattenJSON(raw)
+}
+
+func configSection(path string) string {
+	field := topField(path)
+	if section, ok := configSectionFields[field]; ok {
+		return section
+	}
+	return field
+}
+
+// diffValues lists the paths whose values differ between two flattened
+// documents, sorted by path
+func diffValues(a, b map[string]string, section func(string) string) []ConfigChange {
+	paths := make(map[string]bool)
+	for p := range a {
+		paths[p] = true
+	}
+	for p := range b {
+		paths[p] = true
+	}
+
+	var changes []ConfigChange
+	for p := range paths {
+		if a[p] != b[p] {
+			changes = append(changes, ConfigChange{Section: section(p), Path: p, Old: a[p], New: b[p]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+// flattenJSON turns a JSON document into path/value pairs. Keys named uid
+// are skipped.
+func flattenJSON(raw []byte) (map[string]string, error) {
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, fmt.Errorf("failed to decode configuration: %w", err)
+	}
+
+	out := make(map[string]string)
+	flatten("", v, out)
+	return out, nil
+}
+
+func flatten(prefix string, v interface{}, out map[string]string) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if k == "uid" {
+				continue
+			}
+			path := k
+			if prefix != "" {
+				path = prefix + "." + k
+			}
+			flatten(path, child, out)
+		}
+	case []interface{}:
+		for i, child := range v {
+			flatten(fmt.Sprintf("%s[%d]", prefix, i), child, out)
+		}
+	case string:
+		out[prefix] = v
+	case nil:
+	default:
+		data, _ := json.Marshal(v)
+		out[prefix] = string(data)
+	}
+}
+
+// topField returns the first element of a flattened path
+func topField(path string) string {
+	if i := strings.IndexAny(path, ".["); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
+
+// ConfigDiff lists the differences between a miner and a template
+type ConfigDiff struct {
+	Host    string         `json:"host"`
+	Changes []ConfigChange `json:"changes"`
+}
+
+// DiffConfigHost compares the configuration of a miner with template. Old
+// values are the miner's, new values the template's.
+func DiffConfigHost(ctx context.Context, opts Options, host string, template *pb.GetMinerConfigurationResponse) (*ConfigDiff, error) {
+	cfg, err := GetConfig(ctx, opts, host)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := DiffConfig(cfg, template)
+	if err != nil {
+		return nil, err
+	}
+	return &ConfigDiff{Host: host, Changes: changes}, nil
+}
+
+// ConfigDiffReport summarizes template drift across the fleet
+type ConfigDiffReport struct {
+	Scanned     int          `json:"scanned"`
+	Matching    []string     `json:"matching"`
+	Differing   []ConfigDiff `json:"differing"`
+	Unreachable []string     `json:"unreachable,omitempty"`
+}
+
+// Redact masks the password values in the differences of every miner
+func (r *ConfigDiffReport) Redact() {
+	for i := range r.Differing {
+		r.Differing[i].Changes = RedactChanges(r.Differing[i].Changes)
+	}
+}
+
+// SummarizeConfigDiffs groups DiffConfigHost results into matching and
+// differing miners
+func SummarizeConfigDiffs(results []client.Result) *ConfigDiffReport {
+	report := &ConfigDiffReport{Scanned: len(results)}
+
+	for _, r := range results {
+		d, ok := r.Response.(*ConfigDiff)
+		switch {
+		case r.Error != "" || !ok:
+			report.Unreachable = append(report.Unreachable, r.IP)
+		case len(d.Changes) == 0:
+			report.Matching = append(report.Matching, r.IP)
+		default:
+			report.Differing = append(report.Differing, *d)
+		}
+	}
+
+	sort.Strings(report.Matching)
+	sort.Slice(report.Differing, func(i, j int) bool {
+		return report.Differing[i].Host < report.Differing[j].Host
+	})
+	sort.Strings(report.Unreachable)
+	return report
+}
+
+// ConfigApplyResult reports the sections pushed to a miner
+type ConfigApplyResult struct {
+	Applied []string       `json:"applied"`
+	Changes []ConfigChange `json:"changes"`
+	DryRun  bool           `json:"dry_run,omitempty"`
+}
+
+// Redact masks the password values in the reported changes
+func (r *ConfigApplyResult) Redact() {
+	r.Changes = RedactChanges(r.Changes)
+}
+
+// ApplyConfig pushes the selected sections of template to a miner. Only
+// sections that differ from the running configuration are sent; with dryRun
+// the differences are reported without changing the miner.
+func ApplyConfig(ctx context.Context, opts Options, host string, template *pb.GetMinerConfigurationResponse, sections []string, dryRun bool) (*ConfigApplyResult, error) {
+	c, err := opts.Braiins(host)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	current, err := c.GetMinerConfiguration()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get configuration: %w", err)
+	}
+	changes, err := DiffConfig(current, template)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ConfigApplyResult{Applied: []string{}, DryRun: dryRun}
+	for _, section := range sections {
+		differs := false
+		for _, ch := range changes {
+			if ch.Section == section {
+				result.Changes = append(result.Changes, ch)
+				differs = true
+			}
+		}
+		if !differs || dryRun {
+			continue
+		}
+
+		switch section {
+		case ConfigPools:
+			_, err = c.SetPoolGroups(templatePoolGroups(template))
+		case ConfigTuner:
+			var mode *pb.PerformanceMode
+			if mode, err = tunerPerformanceMode(template.GetTuner()); err == nil {
+				_, err = c.SetPerformanceMode(mode)
+			}
+		case ConfigCooling:
+			var req *pb.SetCoolingModeRequest
+			if req, err = coolingModeRequest(template.GetTemperature()); err == nil {
+				_, err = c.SetCoolingMode(req)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply %s configuration after applying %v: %w", section, result.Applied, err)
+		}
+		result.Applied = append(result.Applied, section)
+	}
+	return result, nil
+}
+
+// templatePoolGroups copies the template pool groups without their UIDs so
+// the miner creates them afresh
+func templatePoolGroups(template *pb.GetMinerConfigurationResponse) []*pb.PoolGroupConfiguration {
+	groups := make([]*pb.PoolGroupConfiguration, 0, len(template.GetPoolGroups()))
+	for _, g := range template.GetPoolGroups() {
+		g = proto.Clone(g).(*pb.PoolGroupConfiguration)
+		g.Uid = nil
+		for _, p := range g.Pools {
+			p.Uid = nil
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// tunerPerformanceMode converts a tuner configuration into the performance
+// mode that restores it
+func tunerPerformanceMode(t *pb.TunerConfiguration) (*pb.PerformanceMode, error) {
+	if t == nil {
+		return nil, fmt.Errorf("template has no tuner configuration")
+	}
+	if t.Enabled != nil && !*t.Enabled {
+		return nil, fmt.Errorf("restoring a disabled tuner is not supported")
+	}
+
+	tuner := &pb.TunerPerformanceMode{}
+	switch {
+	case t.GetTunerMode() == pb.TunerMode_TUNER_MODE_HASHRATE_TARGET && t.GetHashrateTarget() != nil:
+		tuner.Target = &pb.TunerPerformanceMode_HashrateTarget{HashrateTarget: &pb.HashrateTargetMode{HashrateTarget: t.GetHashrateTarget()}}
+	case t.GetPowerTarget() != nil:
+		tuner.Target = &pb.TunerPerformanceMode_PowerTarget{PowerTarget: &pb.PowerTargetMode{PowerTarget: t.GetPowerTarget()}}
+	default:
+		return nil, fmt.Errorf("template tuner has no power or hashrate target")
+	}
+	return &pb.PerformanceMode{Mode: &pb.PerformanceMode_TunerMode{TunerMode: tuner}}, nil
+}
+
+// coolingModeRequest converts a cooling configuration into the request
+// that restores it
+func coolingModeRequest(cfg *pb.CoolingConfiguration) (*pb.SetCoolingModeRequest, error) {
+	req := &pb.SetCoolingModeRequest{}
+	switch m := cfg.GetMode().(type) {
+	case *pb.CoolingConfiguration_Auto:
+		req.Mode = &pb.SetCoolingModeRequest_Auto{Auto: m.Auto}
+	case *pb.CoolingConfiguration_Manual:
+		req.Mode = &pb.SetCoolingModeRequest_Manual{Manual: m.Manual}
+	case *pb.CoolingConfiguration_Immersion:
+		req.Mode = &pb.SetCoolingModeRequest_Immersion{Immersion: m.Immersion}
+	case *pb.CoolingConfiguration_Hydro:
+		req.Mode = &pb.SetCoolingModeRequest_Hydro{Hydro: m.Hydro}
+	case *pb.CoolingConfiguration_Disabled:
+		return nil, fmt.Errorf("disabled cooling cannot be restored")
+	default:
+		return nil, fmt.Errorf("template has no cooling configuration")
+	}
+	return req, nil
+}
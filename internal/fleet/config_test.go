@@ -0,0 +1,263 @@
+package fleet
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/sinkers/miner-cli/internal/braiins/bos/v1"
+	"github.com/sinkers/miner-cli/internal/client"
+	"google.golang.org/grpc"
+)
+
+// fakeConfigMiner serves a configuration and records the setters called
+type fakeConfigMiner struct {
+	pb.UnimplementedConfigurationServiceServer
+	pb.UnimplementedPoolServiceServer
+	pb.UnimplementedPerformanceServiceServer
+	pb.UnimplementedCoolingServiceServer
+
+	mu     sync.Mutex
+	config *pb.GetMinerConfigurationResponse
+	calls  []string
+	groups []*pb.PoolGroupConfiguration
+}
+
+func (f *fakeConfigMiner) GetMinerConfiguration(ctx context.Context, req *pb.GetMinerConfigurationRequest) (*pb.GetMinerConfigurationResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.config, nil
+}
+
+func (f *fakeConfigMiner) SetPoolGroups(ctx context.Context, req *pb.SetPoolGroupsRequest) (*pb.SetPoolGroupsResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, ConfigPools)
+	f.groups = req.GetPoolGroups()
+	return &pb.SetPoolGroupsResponse{PoolGroups: req.GetPoolGroups()}, nil
+}
+
+func (f *fakeConfigMiner) SetPerformanceMode(ctx context.Context, req *pb.SetPerformanceModeRequest) (*pb.PerformanceMode, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, ConfigTuner)
+	return req.GetMode(), nil
+}
+
+func (f *fakeConfigMiner) SetCoolingMode(ctx context.Context, req *pb.SetCoolingModeRequest) (*pb.SetCoolingModeResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, ConfigCooling)
+	return &pb.SetCoolingModeResponse{}, nil
+}
+
+func startConfigMiner(t *testing.T, cfg *pb.GetMinerConfigurationResponse) (*fakeConfigMiner, Options) {
+	f := &fakeConfigMiner{config: cfg}
+	port := fakeBraiins(t, func(s *grpc.Server) {
+		pb.RegisterConfigurationServiceServer(s, f)
+		pb.RegisterPoolServiceServer(s, f)
+		pb.RegisterPerformanceServiceServer(s, f)
+		pb.RegisterCoolingServiceServer(s, f)
+	})
+	return f, Options{GRPCPort: port, Timeout: 5 * time.Second}
+}
+
+func testConfig(uid, url string, watts uint64) *pb.GetMinerConfigurationResponse {
+	enabled := true
+	mode := pb.TunerMode_TUNER_MODE_POWER_TARGET
+	return &pb.GetMinerConfigurationResponse{
+		PoolGroups: []*pb.PoolGroupConfiguration{{
+			Uid:   &uid,
+			Name:  "default",
+			Pools: []*pb.PoolConfiguration{{Uid: &uid, Url: url, User: "worker"}},
+		}},
+		Tuner: &pb.TunerConfiguration{Enabled: &enabled, TunerMode: &mode, PowerTarget: &pb.Power{Watt: watts}},
+		Temperature: &pb.CoolingConfiguration{Mode: &pb.CoolingConfiguration_Auto{Auto: &pb.CoolingAutoMode{
+			TargetTemperature: &pb.Temperature{DegreeC: 70},
+		}}},
+	}
+}
+
+func TestDiffConfig(t *testing.T) {
+	a := testConfig("uid-a", "stratum+tcp://pool:3333", 3000)
+	b := testConfig("uid-b", "stratum+tcp://backup:3333", 3000)
+
+	changes, err := DiffConfig(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("expected only the pool URL to differ, got %+v", changes)
+	}
+	want := ConfigChange{Section: ConfigPools, Path: "pool_groups[0].pools[0].url", Old: "stratum+tcp://pool:3333", New: "stratum+tcp://backup:3333"}
+	if changes[0] != want {
+		t.Errorf("got %+v, want %+v", changes[0], want)
+	}
+
+	// a value missing on one side
+	b.Tuner.PowerTarget = nil
+	changes, err = DiffConfig(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, ch := range changes {
+		if ch.Path == "tuner.power_target.watt" {
+			found = ch.Section == ConfigTuner && ch.Old == "3000" && ch.New == ""
+		}
+	}
+	if !found {
+		t.Errorf("expected removed power target in %+v", changes)
+	}
+}
+
+func TestRedactChanges(t *testing.T) {
+	changes := []ConfigChange{
+		{Path: "pool_groups[0].pools[0].password", Old: "secret", New: ""},
+		{Path: "pools[1].pass", Old: "x", New: "y"},
+		{Path: "pool_groups[0].pools[0].url", Old: "stratum+tcp://pool:3333", New: "stratum+tcp://backup:3333"},
+	}
+	redacted := RedactChanges(changes)
+	if redacted[0].Old != "***" || redacted[0].New != "" {
+		t.Errorf("password not masked: %+v", redacted[0])
+	}
+	if redacted[1].Old != "***" || redacted[1].New != "***" {
+		t.Errorf("pass not masked: %+v", redacted[1])
+	}
+	if redacted[2] != changes[2] {
+		t.Errorf("URL should be kept: %+v", redacted[2])
+	}
+	if changes[0].Old != "secret" {
+		t.Error("RedactChanges must not modify its argument")
+	}
+}
+
+func TestExportAndLoadConfig(t *testing.T) {
+	cfg := testConfig("uid-a", "stratum+tcp://pool:3333", 3200)
+	_, opts := startConfigMiner(t, cfg)
+	dir := t.TempDir()
+
+	export, err := ExportConfig(context.Background(), opts, "127.0.0.1", dir)
+	if err != nil {
+		t.Fatalf("ExportConfig failed: %v", err)
+	}
+	if export.File != SnapshotPath(dir, "127.0.0.1") {
+		t.Errorf("unexpected snapshot file %s", export.File)
+	}
+	if info, err := os.Stat(export.File); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("snapshot must be readable by the owner only: %v %v", info.Mode(), err)
+	}
+
+	loaded, err := LoadConfig(export.File)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if changes, _ := DiffConfig(cfg, loaded); len(changes) != 0 {
+		t.Errorf("snapshot does not round trip: %+v", changes)
+	}
+
+	// a bare configuration document works as a template
+	bare := dir + "/golden.json"
+	if err := os.WriteFile(bare, []byte(`{"tuner": {"enabled": true, "power_target": {"watt": "3000"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	golden, err := LoadConfig(bare)
+	if err != nil {
+		t.Fatalf("LoadConfig of a bare template failed: %v", err)
+	}
+	if golden.GetTuner().GetPowerTarget().GetWatt() != 3000 {
+		t.Errorf("unexpected template %v", golden)
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	template := testConfig("uid-t", "stratum+tcp://backup:3333", 3000)
+
+	tests := []struct {
+		name      string
+		current   *pb.GetMinerConfigurationResponse
+		sections  []string
+		dryRun    bool
+		wantCalls []string
+	}{
+		{
+			name:      "only differing sections are sent",
+			current:   testConfig("uid-m", "stratum+tcp://pool:3333", 3000),
+			sections:  ConfigSections,
+			wantCalls: []string{ConfigPools},
+		},
+		{
+			name:      "selected sections only",
+			current:   testConfig("uid-m", "stratum+tcp://pool:3333", 2500),
+			sections:  []string{ConfigTuner},
+			wantCalls: []string{ConfigTuner},
+		},
+		{
+			name:     "dry run changes nothing",
+			current:  testConfig("uid-m", "stratum+tcp://pool:3333", 2500),
+			sections: ConfigSections,
+			dryRun:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, opts := startConfigMiner(t, tt.current)
+
+			result, err := ApplyConfig(context.Background(), opts, "127.0.0.1", template, tt.sections, tt.dryRun)
+			if err != nil {
+				t.Fatalf("ApplyConfig failed: %v", err)
+			}
+			if strings.Join(f.calls, ",") != strings.Join(tt.wantCalls, ",") {
+				t.Errorf("calls = %v, want %v", f.calls, tt.wantCalls)
+			}
+			if !tt.dryRun && strings.Join(result.Applied, ",") != strings.Join(tt.wantCalls, ",") {
+				t.Errorf("applied = %v, want %v", result.Applied, tt.wantCalls)
+			}
+			if len(result.Changes) == 0 {
+				t.Error("expected the differences to be reported")
+			}
+		})
+	}
+}
+
+func TestApplyConfigClearsUIDs(t *testing.T) {
+	template := testConfig("uid-t", "stratum+tcp://backup:3333", 3000)
+	f, opts := startConfigMiner(t, testConfig("uid-m", "stratum+tcp://pool:3333", 3000))
+
+	if _, err := ApplyConfig(context.Background(), opts, "127.0.0.1", template, []string{ConfigPools}, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.groups) != 1 || f.groups[0].Uid != nil || f.groups[0].Pools[0].Uid != nil {
+		t.Errorf("expected pool groups without UIDs, got %v", f.groups)
+	}
+	if template.PoolGroups[0].Uid == nil {
+		t.Error("template was modified")
+	}
+}
+
+func TestCoolingModeRequestRejectsDisabled(t *testing.T) {
+	cfg := &pb.CoolingConfiguration{Mode: &pb.CoolingConfiguration_Disabled{Disabled: &pb.CoolingDisabledMode{}}}
+	if _, err := coolingModeRequest(cfg); err == nil {
+		t.Error("expected disabled cooling to be rejected")
+	}
+}
+
+func TestSummarizeConfigDiffs(t *testing.T) {
+	results := []client.Result{
+		{IP: "10.0.0.2", Response: &ConfigDiff{Host: "10.0.0.2", Changes: []ConfigChange{{Path: "tuner.enabled"}}}},
+		{IP: "10.0.0.1", Response: &ConfigDiff{Host: "10.0.0.1"}},
+		{IP: "10.0.0.3", Error: "connection refused"},
+	}
+
+	report := SummarizeConfigDiffs(results)
+	if report.Scanned != 3 || len(report.Matching) != 1 || len(report.Differing) != 1 || len(report.Unreachable) != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if report.Differing[0].Host != "10.0.0.2" {
+		t.Errorf("unexpected differing host %s", report.Differing[0].Host)
+	}
+}
@@ -0,0 +1,376 @@
+package fleet
+
+import (
+	"context"
+	"fmt"
+
+	pb "github.com/sinkers/miner-cli/internal/braiins/bos/v1"
+	vmodels "github.com/sinkers/miner-cli/internal/vnish/models"
+)
+
+// Cooling modes understood by SetCooling
+const (
+	CoolingAuto      = "auto"
+	CoolingManual    = "manual"
+	CoolingImmersion = "immersion"
+	CoolingHydro     = "hydro"
+	CoolingDisabled  = "disabled"
+)
+
+// CoolingSettings is the firmware independent view of a miner's fan and
+// temperature configuration. Zero values mean "leave unchanged" when used
+// as input to SetCooling.
+type CoolingSettings struct {
+	Mode          string  `json:"mode"`
+	TargetTemp    float64 `json:"target_temp,omitempty"`
+	HotTemp       float64 `json:"hot_temp,omitempty"`
+	DangerousTemp float64 `json:"dangerous_temp,omitempty"`
+	FanSpeed      int     `json:"fan_speed,omitempty"` // percent, manual mode only
+	MinFanSpeed   int     `json:"min_fan_speed,omitempty"`
+	MaxFanSpeed   int     `json:"max_fan_speed,omitempty"`
+}
+
+// Validate checks the settings for obviously invalid combinations
+func (s CoolingSettings) Validate() error {
+	switch s.Mode {
+	case CoolingAuto, CoolingManual, CoolingImmersion, CoolingHydro:
+	case CoolingDisabled:
+		// reported by GetCooling, but no firmware lets it be set
+		return fmt.Errorf("cooling mode %q cannot be set", s.Mode)
+	default:
+		return fmt.Errorf("invalid cooling mode %q", s.Mode)
+	}
+
+	if s.TargetTemp != 0 && s.HotTemp != 0 && s.TargetTemp >= s.HotTemp {
+		return fmt.Errorf("target temperature must be below hot temperature")
+	}
+	if s.HotTemp != 0 && s.DangerousTemp != 0 && s.HotTemp >= s.DangerousTemp {
+		return fmt.Errorf("hot temperature must be below dangerous temperature")
+	}
+	for _, speed := range []int{s.FanSpeed, s.MinFanSpeed, s.MaxFanSpeed} {
+		if speed < 0 || speed > 100 {
+			return fmt.Errorf("fan speed must be between 0 and 100")
+		}
+	}
+	if s.MinFanSpeed != 0 && s.MaxFanSpeed != 0 && s.MinFanSpeed >= s.MaxFanSpeed {
+		return fmt.Errorf("min fan speed must be less than max fan speed")
+	}
+	if s.Mode == CoolingManual && s.FanSpeed == 0 {
+		return fmt.Errorf("manual mode requires a fan speed")
+	}
+
+	return nil
+}
+
+// GetCooling reads the cooling configuration of host
+func GetCooling(ctx context.Context, opts Options, host string) (*CoolingSettings, error) {
+	opts, err := opts.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	switch opts.Firmware {
+	case FirmwareBraiins:
+		c, err := opts.Braiins(host)
+		if err != nil {
+			return nil, err
+		}
+		defer c.Close()
+
+		cfg, err := c.GetMinerConfiguration()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get configuration: %w", err)
+		}
+		return coolingFromBraiins(cfg.Temperature), nil
+	case FirmwareVnish:
+		settings, err := opts.Vnish(host).GetSettings(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get settings: %w", err)
+		}
+		return coolingFromVnish(settings), nil
+	default:
+		return nil, fmt.Errorf("cooling is not supported for firmware %q", opts.Firmware)
+	}
+}
+
+// SetCooling applies the cooling settings to host and returns the resulting
+// configuration.
+func SetCooling(ctx context.Context, opts Options, host string, s CoolingSettings) (*CoolingSettings, error) {
+	opts, err := opts.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	switch opts.Firmware {
+	case FirmwareBraiins:
+		c, err := opts.Braiins(host)
+		if err != nil {
+			return nil, err
+		}
+		defer c.Close()
+
+		// Braiins OS resets what the request leaves out to its defaults
+		cfg, err := c.GetMinerConfiguration()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get configuration: %w", err)
+		}
+		merged := mergeBraiinsCooling(cfg.Temperature, s)
+		if err := merged.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid cooling settings with the current ones: %w", err)
+		}
+		req, err := braiinsCoolingRequest(merged)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := c.SetCoolingMode(req); err != nil {
+			return nil, fmt.Errorf("failed to set cooling mode: %w", err)
+		}
+		cfg, err = c.GetMinerConfiguration()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get configuration: %w", err)
+		}
+		return coolingFromBraiins(cfg.Temperature), nil
+	case FirmwareVnish:
+		vc := opts.Vnish(host)
+		settings, err := vc.GetSettings(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get settings: %w", err)
+		}
+		before := *settings
+		if err := applyVnishCooling(settings, s); err != nil {
+			return nil, err
+		}
+		// only the changed cooling values are sent, the miner keeps the rest
+		partial := vnishCoolingUpdate(&before, settings)
+		if len(partial) > 0 {
+			if err := ensureUnlocked(ctx, opts, host); err != nil {
+				return nil, err
+			}
+			if err := vc.UpdateSettingsPartial(ctx, partial); err != nil {
+				return nil, fmt.Errorf("failed to update settings: %w", err)
+			}
+		}
+		return coolingFromVnish(settings), nil
+	default:
+		return nil, fmt.Errorf("cooling is not supported for firmware %q", opts.Firmware)
+	}
+}
+
+// mergeBraiinsCooling fills the zero values of s from the current Braiins
+// cooling configuration. Fan speed limits only carry over between auto modes.
+func mergeBraiinsCooling(current *pb.CoolingConfiguration, s CoolingSettings) CoolingSettings {
+	cur := coolingFromBraiins(current)
+	if s.TargetTemp == 0 {
+		s.TargetTemp = cur.TargetTemp
+	}
+	if s.HotTemp == 0 {
+		s.HotTemp = cur.HotTemp
+	}
+	if s.DangerousTemp == 0 {
+		s.DangerousTemp = cur.DangerousTemp
+	}
+	if s.Mode == CoolingAuto && cur.Mode == CoolingAuto {
+		if s.MinFanSpeed == 0 {
+			s.MinFanSpeed = cur.MinFanSpeed
+		}
+		if s.MaxFanSpeed == 0 {
+			s.MaxFanSpeed = cur.MaxFanSpeed
+		}
+	}
+	return s
+}
+
+// braiinsCoolingRequest builds a SetCoolingMode request from the settings;
+// merge them with the current ones first as zero values are left unset
+func braiinsCoolingRequest(s CoolingSettings) (*pb.SetCoolingModeRequest, error) {
+	req := &pb.SetCoolingModeRequest{}
+
+	switch s.Mode {
+	case CoolingAuto:
+		mode := &pb.CoolingAutoMode{
+			TargetTemperature:    temperature(s.TargetTemp),
+			HotTemperature:       temperature(s.HotTemp),
+			DangerousTemperature: temperature(s.DangerousTemp),
+		}
+		if s.MinFanSpeed != 0 {
+			v := uint32(s.MinFanSpeed)
+			mode.MinFanSpeed = &v
+		}
+		if s.MaxFanSpeed != 0 {
+			v := uint32(s.MaxFanSpeed)
+			mode.MaxFanSpeed = &v
+		}
+		req.Mode = &pb.SetCoolingModeRequest_Auto{Auto: mode}
+	case CoolingManual:
+		ratio := float64(s.FanSpeed) / 100.0
+		req.Mode = &pb.SetCoolingModeRequest_Manual{Manual: &pb.CoolingManualMode{
+			FanSpeedRatio:        &ratio,
+			TargetTemperature:    temperature(s.TargetTemp),
+			HotTemperature:       temperature(s.HotTemp),
+			DangerousTemperature: temperature(s.DangerousTemp),
+		}}
+	case CoolingImmersion:
+		req.Mode = &pb.SetCoolingModeRequest_Immersion{Immersion: &pb.CoolingImmersionMode{
+			TargetTemperature:    temperature(s.TargetTemp),
+			HotTemperature:       temperature(s.HotTemp),
+			DangerousTemperature: temperature(s.DangerousTemp),
+		}}
+	case CoolingHydro:
+		req.Mode = &pb.SetCoolingModeRequest_Hydro{Hydro: &pb.CoolingHydroMode{
+			TargetTemperature:    temperature(s.TargetTemp),
+			HotTemperature:       temperature(s.HotTemp),
+			DangerousTemperature: temperature(s.DangerousTemp),
+		}}
+	case CoolingDisabled:
+		return nil, fmt.Errorf("disabling cooling is not supported by Braiins OS")
+	default:
+		return nil, fmt.Errorf("invalid cooling mode %q", s.Mode)
+	}
+
+	return req, nil
+}
+
+// coolingFromBraiins converts a Braiins cooling configuration
+func coolingFromBraiins(cfg *pb.CoolingConfiguration) *CoolingSettings {
+	s := &CoolingSettings{}
+	if cfg == nil {
+		return s
+	}
+
+	switch mode := cfg.Mode.(type) {
+	case *pb.CoolingConfiguration_Auto:
+		s.Mode = CoolingAuto
+		s.TargetTemp = mode.Auto.GetTargetTemperature().GetDegreeC()
+		s.HotTemp = mode.Auto.GetHotTemperature().GetDegreeC()
+		s.DangerousTemp = mode.Auto.GetDangerousTemperature().GetDegreeC()
+		s.MinFanSpeed = int(mode.Auto.GetMinFanSpeed())
+		s.MaxFanSpeed = int(mode.Auto.GetMaxFanSpeed())
+	case *pb.CoolingConfiguration_Manual:
+		s.Mode = CoolingManual
+		s.FanSpeed = int(mode.Manual.GetFanSpeedRatio()*100 + 0.5)
+		s.TargetTemp = mode.Manual.GetTargetTemperature().GetDegreeC()
+		s.HotTemp = mode.Manual.GetHotTemperature().GetDegreeC()
+		s.DangerousTemp = mode.Manual.GetDangerousTemperature().GetDegreeC()
+	case *pb.CoolingConfiguration_Immersion:
+		s.Mode = CoolingImmersion
+		s.TargetTemp = mode.Immersion.GetTargetTemperature().GetDegreeC()
+		s.HotTemp = mode.Immersion.GetHotTemperature().GetDegreeC()
+		s.DangerousTemp = mode.Immersion.GetDangerousTemperature().GetDegreeC()
+	case *pb.CoolingConfiguration_Hydro:
+		s.Mode = CoolingHydro
+		s.TargetTemp = mode.Hydro.GetTargetTemperature().GetDegreeC()
+		s.HotTemp = mode.Hydro.GetHotTemperature().GetDegreeC()
+		s.DangerousTemp = mode.Hydro.GetDangerousTemperature().GetDegreeC()
+	case *pb.CoolingConfiguration_Disabled:
+		s.Mode = CoolingDisabled
+		s.FanSpeed = int(mode.Disabled.GetFanSpeedRatio()*100 + 0.5)
+	}
+
+	return s
+}
+
+// applyVnishCooling merges the cooling settings into a vnish configuration
+func applyVnishCooling(settings *vmodels.Settings, s CoolingSettings) error {
+	wasManual := settings.Fan.Mode == CoolingManual
+	switch s.Mode {
+	case CoolingAuto, CoolingManual, CoolingImmersion:
+		settings.Fan.Mode = s.Mode
+	default:
+		return fmt.Errorf("cooling mode %q is not supported by vnish", s.Mode)
+	}
+
+	if s.TargetTemp != 0 {
+		settings.Temperature.TargetTemp = int(s.TargetTemp)
+		settings.Fan.TargetTemp = int(s.TargetTemp)
+	}
+	if s.HotTemp != 0 {
+		settings.Temperature.HotTemp = int(s.HotTemp)
+	}
+	if s.DangerousTemp != 0 {
+		settings.Temperature.DangerousTemp = int(s.DangerousTemp)
+	}
+
+	// vnish has no fixed fan speed setting, manual mode pins min and max.
+	// Leaving manual mode drops the pinned speeds.
+	if s.Mode == CoolingManual {
+		settings.Fan.MinSpeed = s.FanSpeed
+		settings.Fan.MaxSpeed = s.FanSpeed
+	} else {
+		if wasManual {
+			settings.Fan.MinSpeed, settings.Fan.MaxSpeed = 0, 0
+		}
+		if s.MinFanSpeed != 0 {
+			settings.Fan.MinSpeed = s.MinFanSpeed
+		}
+		if s.MaxFanSpeed != 0 {
+			settings.Fan.MaxSpeed = s.MaxFanSpeed
+		}
+	}
+
+	settings.Advanced.ImmersionMode = s.Mode == CoolingImmersion
+
+	return nil
+}
+
+// vnishCoolingUpdate returns the fan, temperature and immersion values that
+// differ between before and after as a partial settings document
+func vnishCoolingUpdate(before, after *vmodels.Settings) map[string]interface{} {
+	set := func(values map[string]interface{}, key string, old, new interface{}) {
+		if old != new {
+			values[key] = new
+		}
+	}
+
+	fan := make(map[string]interface{})
+	set(fan, "mode", before.Fan.Mode, after.Fan.Mode)
+	set(fan, "target_temp", before.Fan.TargetTemp, after.Fan.TargetTemp)
+	set(fan, "min_speed", before.Fan.MinSpeed, after.Fan.MinSpeed)
+	set(fan, "max_speed", before.Fan.MaxSpeed, after.Fan.MaxSpeed)
+
+	temp := make(map[string]interface{})
+	set(temp, "target_temp", before.Temperature.TargetTemp, after.Temperature.TargetTemp)
+	set(temp, "hot_temp", before.Temperature.HotTemp, after.Temperature.HotTemp)
+	set(temp, "dangerous_temp", before.Temperature.DangerousTemp, after.Temperature.DangerousTemp)
+
+	advanced := make(map[string]interface{})
+	set(advanced, "immersion_mode", before.Advanced.ImmersionMode, after.Advanced.ImmersionMode)
+
+	partial := make(map[string]interface{})
+	for section, values := range map[string]map[string]interface{}{SettingsFan: fan, SettingsTemperature: temp, SettingsAdvanced: advanced} {
+		if len(values) > 0 {
+			partial[section] = values
+		}
+	}
+	return partial
+}
+
+// coolingFromVnish converts vnish fan and temperature settings
+func coolingFromVnish(settings *vmodels.Settings) *CoolingSettings {
+	s := &CoolingSettings{
+		Mode:          settings.Fan.Mode,
+		TargetTemp:    float64(settings.Temperature.TargetTemp),
+		HotTemp:       float64(settings.Temperature.HotTemp),
+		DangerousTemp: float64(settings.Temperature.DangerousTemp),
+		MinFanSpeed:   settings.Fan.MinSpeed,
+		MaxFanSpeed:   settings.Fan.MaxSpeed,
+	}
+
+	if s.Mode == CoolingManual && settings.Fan.MinSpeed == settings.Fan.MaxSpeed {
+		s.FanSpeed = settings.Fan.MinSpeed
+		s.MinFanSpeed = 0
+		s.MaxFanSpeed = 0
+	}
+	if s.TargetTemp == 0 {
+		s.TargetTemp = float64(settings.Fan.TargetTemp)
+	}
+
+	return s
+}
+
+func temperature(degrees float64) *pb.Temperature {
+	if degrees == 0 {
+		return nil
+	}
+	return &pb.Temperature{DegreeC: degrees}
+}
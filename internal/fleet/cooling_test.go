@@ -0,0 +1,216 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	pb "github.com/sinkers/miner-cli/internal/braiins/bos/v1"
+	vmodels "github.com/sinkers/miner-cli/internal/vnish/models"
+)
+
+func TestCoolingSettingsValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings CoolingSettings
+		wantErr  bool
+	}{
+		{"auto", CoolingSettings{Mode: CoolingAuto, TargetTemp: 65, HotTemp: 80, DangerousTemp: 90}, false},
+		{"bad mode", CoolingSettings{Mode: "turbo"}, true},
+		{"target above hot", CoolingSettings{Mode: CoolingAuto, TargetTemp: 85, HotTemp: 80}, true},
+		{"hot above dangerous", CoolingSettings{Mode: CoolingAuto, HotTemp: 95, DangerousTemp: 90}, true},
+		{"manual without speed", CoolingSettings{Mode: CoolingManual}, true},
+		{"manual speed too high", CoolingSettings{Mode: CoolingManual, FanSpeed: 120}, true},
+		{"manual", CoolingSettings{Mode: CoolingManual, FanSpeed: 70}, false},
+		{"min above max", CoolingSettings{Mode: CoolingAuto, MinFanSpeed: 80, MaxFanSpeed: 50}, true},
+		{"disabled", CoolingSettings{Mode: CoolingDisabled}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.settings.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBraiinsCoolingRequest(t *testing.T) {
+	req, err := braiinsCoolingRequest(CoolingSettings{Mode: CoolingAuto, TargetTemp: 65, HotTemp: 80, DangerousTemp: 90, MinFanSpeed: 20})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	auto := req.GetAuto()
+	if auto == nil {
+		t.Fatal("expected auto mode")
+	}
+	if auto.GetTargetTemperature().GetDegreeC() != 65 || auto.GetHotTemperature().GetDegreeC() != 80 || auto.GetDangerousTemperature().GetDegreeC() != 90 {
+		t.Errorf("unexpected temperatures: %v", auto)
+	}
+	if auto.GetMinFanSpeed() != 20 || auto.MaxFanSpeed != nil {
+		t.Errorf("unexpected fan speeds: %v", auto)
+	}
+
+	req, err = braiinsCoolingRequest(CoolingSettings{Mode: CoolingManual, FanSpeed: 70})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.GetManual().GetFanSpeedRatio() != 0.7 {
+		t.Errorf("expected ratio 0.7, got %v", req.GetManual().GetFanSpeedRatio())
+	}
+	if req.GetManual().HotTemperature != nil {
+		t.Error("unset temperatures should be left nil")
+	}
+
+	if _, err := braiinsCoolingRequest(CoolingSettings{Mode: CoolingDisabled}); err == nil {
+		t.Error("expected error for disabled mode")
+	}
+}
+
+func TestMergeBraiinsCoolingKeepsTemperatures(t *testing.T) {
+	minSpeed := uint32(20)
+	current := &pb.CoolingConfiguration{
+		Mode: &pb.CoolingConfiguration_Auto{Auto: &pb.CoolingAutoMode{
+			TargetTemperature:    &pb.Temperature{DegreeC: 65},
+			HotTemperature:       &pb.Temperature{DegreeC: 80},
+			DangerousTemperature: &pb.Temperature{DegreeC: 90},
+			MinFanSpeed:          &minSpeed,
+		}},
+	}
+
+	req, err := braiinsCoolingRequest(mergeBraiinsCooling(current, CoolingSettings{Mode: CoolingImmersion}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	immersion := req.GetImmersion()
+	if immersion.GetTargetTemperature().GetDegreeC() != 65 || immersion.GetHotTemperature().GetDegreeC() != 80 || immersion.GetDangerousTemperature().GetDegreeC() != 90 {
+		t.Errorf("temperatures not kept: %v", immersion)
+	}
+
+	merged := mergeBraiinsCooling(current, CoolingSettings{Mode: CoolingAuto, HotTemp: 85})
+	if merged.TargetTemp != 65 || merged.HotTemp != 85 || merged.DangerousTemp != 90 || merged.MinFanSpeed != 20 {
+		t.Errorf("unexpected merged settings: %+v", merged)
+	}
+
+	if err := mergeBraiinsCooling(current, CoolingSettings{Mode: CoolingAuto, TargetTemp: 85}).Validate(); err == nil {
+		t.Error("expected a target above the current hot temperature to be refused")
+	}
+}
+
+func TestCoolingFromBraiins(t *testing.T) {
+	ratio := 0.55
+	cfg := &pb.CoolingConfiguration{
+		Mode: &pb.CoolingConfiguration_Manual{Manual: &pb.CoolingManualMode{
+			FanSpeedRatio:  &ratio,
+			HotTemperature: &pb.Temperature{DegreeC: 85},
+		}},
+	}
+
+	s := coolingFromBraiins(cfg)
+	if s.Mode != CoolingManual || s.FanSpeed != 55 || s.HotTemp != 85 {
+		t.Errorf("unexpected settings: %+v", s)
+	}
+
+	if s := coolingFromBraiins(nil); s.Mode != "" {
+		t.Errorf("expected empty settings, got %+v", s)
+	}
+}
+
+func TestApplyVnishCoolingLeavesManual(t *testing.T) {
+	settings := &vmodels.Settings{Fan: vmodels.FanSettings{Mode: "manual", MinSpeed: 100, MaxSpeed: 100}}
+	if err := applyVnishCooling(settings, CoolingSettings{Mode: CoolingAuto, MaxFanSpeed: 90}); err != nil {
+		t.Fatal(err)
+	}
+	if settings.Fan.MinSpeed != 0 || settings.Fan.MaxSpeed != 90 {
+		t.Errorf("pinned manual speeds kept: %+v", settings.Fan)
+	}
+
+	settings.Fan.MinSpeed = 30
+	if err := applyVnishCooling(settings, CoolingSettings{Mode: CoolingImmersion}); err != nil {
+		t.Fatal(err)
+	}
+	if settings.Fan.MinSpeed != 30 || settings.Fan.MaxSpeed != 90 {
+		t.Errorf("speeds outside manual mode changed: %+v", settings.Fan)
+	}
+}
+
+func TestVnishCooling(t *testing.T) {
+	stored := vmodels.Settings{
+		Fan:         vmodels.FanSettings{Mode: "manual", MinSpeed: 100, MaxSpeed: 100},
+		Temperature: vmodels.TempSettings{TargetTemp: 70, HotTemp: 85, DangerousTemp: 95},
+		Pools:       []vmodels.PoolConfig{{ID: 0, URL: "stratum+tcp://pool:3333"}},
+	}
+	var posted map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/settings" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodPost {
+			// vnish merges the posted settings into its own
+			data, _ := io.ReadAll(r.Body)
+			posted = nil
+			json.Unmarshal(data, &posted)
+			json.Unmarshal(data, &stored)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		json.NewEncoder(w).Encode(stored)
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	opts := Options{Firmware: FirmwareVnish}
+
+	got, err := GetCooling(context.Background(), opts, host)
+	if err != nil {
+		t.Fatalf("GetCooling failed: %v", err)
+	}
+	if got.Mode != CoolingManual || got.FanSpeed != 100 || got.TargetTemp != 70 {
+		t.Errorf("unexpected cooling: %+v", got)
+	}
+
+	got, err = SetCooling(context.Background(), opts, host, CoolingSettings{Mode: CoolingAuto, TargetTemp: 65, HotTemp: 80})
+	if err != nil {
+		t.Fatalf("SetCooling failed: %v", err)
+	}
+	if stored.Fan.Mode != "auto" || stored.Temperature.TargetTemp != 65 || stored.Temperature.HotTemp != 80 {
+		t.Errorf("settings not updated: %+v", stored)
+	}
+	if stored.Temperature.DangerousTemp != 95 {
+		t.Errorf("unset dangerous temp should be preserved, got %d", stored.Temperature.DangerousTemp)
+	}
+	if len(stored.Pools) != 1 {
+		t.Error("unrelated settings should be preserved")
+	}
+	want := map[string]interface{}{
+		"fan":         map[string]interface{}{"mode": "auto", "target_temp": float64(65), "min_speed": float64(0), "max_speed": float64(0)},
+		"temperature": map[string]interface{}{"target_temp": float64(65), "hot_temp": float64(80)},
+	}
+	if !reflect.DeepEqual(posted, want) {
+		t.Errorf("posted %v, want only the changed cooling values %v", posted, want)
+	}
+	if stored.Fan.MinSpeed != 0 || stored.Fan.MaxSpeed != 0 {
+		t.Errorf("pinned manual speeds kept: %+v", stored.Fan)
+	}
+	if got.Mode != CoolingAuto {
+		t.Errorf("unexpected result: %+v", got)
+	}
+
+	if _, err := SetCooling(context.Background(), opts, host, CoolingSettings{Mode: CoolingHydro}); err == nil {
+		t.Error("expected error for hydro mode on vnish")
+	}
+}
+
+func TestCoolingUnsupportedFirmware(t *testing.T) {
+	if _, err := GetCooling(context.Background(), Options{Firmware: FirmwareCGMiner}, "10.0.0.1"); err == nil {
+		t.Error("expected error for cgminer")
+	}
+}
@@ -0,0 +1,93 @@
+package fleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	vnish "github.com/sinkers/miner-cli/internal/vnish/client"
+)
+
+// FirmwareAuto detects the firmware of each host before running a task
+const FirmwareAuto = "auto"
+
+// detectProbes is how many APIs Detect tries at most, each bounded by the
+// timeout
+const detectProbes = 3
+
+// DetectBudget returns the time detecting the firmware may add to the task
+// of a host, none unless firmware is auto
+func DetectBudget(firmware string, timeout time.Duration) time.Duration {
+	if firmware != FirmwareAuto && firmware != "" {
+		return 0
+	}
+	return detectProbes * timeout
+}
+
+// Detect probes host and returns the firmware it runs. Braiins OS and vnish
+// also expose the CGMiner API, so their own APIs are tried first.
+func Detect(ctx context.Context, opts Options, host string) (string, error) {
+	if isBraiins(opts, host) {
+		return FirmwareBraiins, nil
+	}
+	if isVnish(ctx, opts, host) {
+		return FirmwareVnish, nil
+	}
+	if result := opts.CGMiner().Query(host, opts.Port, "version", nil); result.Error == "" {
+		return FirmwareCGMiner, nil
+	}
+	return "", fmt.Errorf("no supported miner API found on %s", host)
+}
+
+// isBraiins checks for the unauthenticated Braiins OS API version service
+func isBraiins(opts Options, host string) bool {
+	probe := opts
+	probe.Username = ""
+	probe.Password = ""
+	probe.Credentials = nil
+	probe.Pool = nil // most scanned hosts are not Braiins, keep them out of the pool
+
+	c, err := probe.Braiins(host)
+	if err != nil {
+		return false
+	}
+	defer c.Close()
+
+	_, err = c.GetAPIVersion()
+	return err == nil
+}
+
+// isVnish checks for the vnish REST API. Authentication errors still
+// identify the firmware when they come as a vnish JSON error; stock web
+// interfaces and other devices on port 80 ask for credentials too.
+func isVnish(ctx context.Context, opts Options, host string) bool {
+	_, err := opts.Vnish(host).GetInfo(ctx)
+	if err == nil {
+		return true
+	}
+
+	var apiErr *vnish.APIError
+	if errors.As(err, &apiErr) && apiErr.JSON {
+		return apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden
+	}
+	return false
+}
+
+// resolve returns the options with the firmware detected when it is auto
+func (o Options) resolve(ctx context.Context, host string) (Options, error) {
+	if o.Firmware != FirmwareAuto && o.Firmware != "" {
+		return o, nil
+	}
+
+	detected, err := Detect(ctx, o, host)
+	if err != nil {
+		return o, err
+	}
+	o.Firmware = detected
+	if port, ok := ctx.Value(resultPortKey{}).(*int); ok {
+		*port = o.ResultPort()
+	}
+	return o, nil
+}
@@ -0,0 +1,138 @@
+package fleet
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// closedPort returns a local port with nothing listening on it
+func closedPort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+	return port
+}
+
+// fakeCGMiner serves a canned response to every CGMiner API request
+func fakeCGMiner(t *testing.T, response string) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			buf := make([]byte, 1024)
+			conn.Read(buf)
+			conn.Write([]byte(response + "\x00"))
+			conn.Close()
+		}
+	}()
+
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestDetectVnish(t *testing.T) {
+	for _, status := range []int{http.StatusOK, http.StatusUnauthorized} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/v1/info" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(status)
+			w.Write([]byte(`{}`))
+		}))
+
+		opts := Options{GRPCPort: closedPort(t), Port: closedPort(t), Timeout: time.Second}
+		got, err := Detect(context.Background(), opts, strings.TrimPrefix(server.URL, "http://"))
+		server.Close()
+
+		if err != nil || got != FirmwareVnish {
+			t.Errorf("status %d: expected vnish, got %q (%v)", status, got, err)
+		}
+	}
+}
+
+func TestDetectHTTPAuthIsNotVnish(t *testing.T) {
+	// a stock web interface asking for credentials on every path
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Digest realm="antMiner Configuration"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`<html><body>401 Unauthorized</body></html>`))
+	}))
+	defer server.Close()
+
+	opts := Options{GRPCPort: closedPort(t), Port: closedPort(t), Timeout: time.Second}
+	if got, err := Detect(context.Background(), opts, strings.TrimPrefix(server.URL, "http://")); err == nil {
+		t.Errorf("expected no firmware for an HTTP authentication page, got %q", got)
+	}
+}
+
+func TestDetectCGMiner(t *testing.T) {
+	port := fakeCGMiner(t, `{"STATUS":[{"STATUS":"S","Msg":"CGMiner versions"}],"VERSION":[{"CGMiner":"4.11.1","API":"3.7"}],"id":1}`)
+
+	opts := Options{GRPCPort: closedPort(t), Port: port, Timeout: time.Second}
+	got, err := Detect(context.Background(), opts, "127.0.0.1")
+	if err != nil || got != FirmwareCGMiner {
+		t.Errorf("expected cgminer, got %q (%v)", got, err)
+	}
+}
+
+func TestDetectNothing(t *testing.T) {
+	opts := Options{GRPCPort: closedPort(t), Port: closedPort(t), Timeout: time.Second}
+	if _, err := Detect(context.Background(), opts, "127.0.0.1"); err == nil {
+		t.Error("expected error when no API responds")
+	}
+}
+
+func TestResolveKeepsExplicitFirmware(t *testing.T) {
+	opts, err := Options{Firmware: FirmwareVnish}.resolve(context.Background(), "10.0.0.1")
+	if err != nil || opts.Firmware != FirmwareVnish {
+		t.Errorf("unexpected resolve result: %q (%v)", opts.Firmware, err)
+	}
+}
+
+func TestRunnerReportsDetectedPort(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	vnishHost := strings.TrimPrefix(server.URL, "http://")
+	cgminerPort := fakeCGMiner(t, `{"STATUS":[{"STATUS":"S"}],"VERSION":[{"CGMiner":"4.11.1"}],"id":1}`)
+
+	opts := Options{Firmware: FirmwareAuto, GRPCPort: closedPort(t), Port: cgminerPort, Timeout: time.Second}
+	results := NewRunner(2).Run(context.Background(), []string{vnishHost, "127.0.0.1"}, opts.ResultPort(), "test", func(ctx context.Context, host string) (interface{}, error) {
+		return opts.resolve(ctx, host)
+	})
+
+	for _, r := range results {
+		want := cgminerPort
+		if r.IP == vnishHost {
+			want = 80
+		}
+		if r.Error != "" || r.Port != want {
+			t.Errorf("%s: port %d (%s), want %d", r.IP, r.Port, r.Error, want)
+		}
+	}
+}
+
+func TestDetectBudget(t *testing.T) {
+	for firmware, want := range map[string]time.Duration{FirmwareAuto: 3 * time.Second, "": 3 * time.Second, FirmwareVnish: 0, FirmwareCGMiner: 0} {
+		if got := DetectBudget(firmware, time.Second); got != want {
+			t.Errorf("DetectBudget(%q) = %v, want %v", firmware, got, want)
+		}
+	}
+}
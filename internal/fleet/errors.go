@@ -0,0 +1,248 @@
+package fleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	pb "github.com/sinkers/miner-cli/internal/braiins/bos/v1"
+	"github.com/sinkers/miner-cli/internal/client"
+)
+
+// Error severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// MinerError is an active error or warning reported by a miner
+type MinerError struct {
+	Severity  string `json:"severity"`
+	Code      string `json:"code,omitempty"`
+	Component string `json:"component,omitempty"`
+	Index     int    `json:"index,omitempty"` // component index, e.g. hashboard number
+	Message   string `json:"message"`
+	Hint      string `json:"hint,omitempty"`
+	Timestamp string `json:"timestamp,omitempty"`
+}
+
+// ErrErrorsUnsupported is returned by GetErrors for firmware without error
+// reporting, so that SummarizeErrors can tell those hosts from failed ones
+var ErrErrorsUnsupported = errors.New("error reporting is not supported")
+
+// GetErrors collects the active errors of host, without duplicates
+func GetErrors(ctx context.Context, opts Options, host string) ([]MinerError, error) {
+	opts, err := opts.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	switch opts.Firmware {
+	case FirmwareBraiins:
+		c, err := opts.Braiins(host)
+		if err != nil {
+			return nil, err
+		}
+		defer c.Close()
+
+		resp, err := c.GetErrors()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get errors: %w", err)
+		}
+		return dedupeErrors(errorsFromBraiins(resp)), nil
+	case FirmwareVnish:
+		status, err := opts.Vnish(host).GetStatus(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get status: %w", err)
+		}
+		var errs []MinerError
+		for _, msg := range status.Errors {
+			errs = append(errs, MinerError{Severity: SeverityError, Message: msg})
+		}
+		for _, msg := range status.Warnings {
+			errs = append(errs, MinerError{Severity: SeverityWarning, Message: msg})
+		}
+		return dedupeErrors(errs), nil
+	default:
+		return nil, fmt.Errorf("%w for firmware %q", ErrErrorsUnsupported, opts.Firmware)
+	}
+}
+
+// errorsFromBraiins flattens Braiins errors into one entry per error code
+// and component.
+func errorsFromBraiins(resp *pb.GetErrorsResponse) []MinerError {
+	var errs []MinerError
+
+	for _, e := range resp.GetErrors() {
+		codes := e.GetErrorCodes()
+		if len(codes) == 0 {
+			codes = []*pb.ErrorCode{{}}
+		}
+		components := e.GetComponents()
+		if len(components) == 0 {
+			components = []*pb.Component{{}}
+		}
+
+		for _, code := range codes {
+			for _, component := range components {
+				msg := e.GetMessage()
+				if msg == "" {
+					msg = code.GetReason()
+				}
+				errs = append(errs, MinerError{
+					Severity:  SeverityError,
+					Code:      code.GetCode(),
+					Component: component.GetName(),
+					Index:     int(component.GetIndex()),
+					Message:   msg,
+					Hint:      code.GetHint(),
+					Timestamp: e.GetTimestamp(),
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
+func dedupeErrors(errs []MinerError) []MinerError {
+	seen := make(map[string]bool)
+	var unique []MinerError
+	for _, e := range errs {
+		key := fmt.Sprintf("%s|%s|%s|%d|%s", e.Severity, e.Code, e.Component, e.Index, e.Message)
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, e)
+		}
+	}
+	return unique
+}
+
+// ErrorSummary counts the hosts affected by one kind of error
+type ErrorSummary struct {
+	Severity  string   `json:"severity"`
+	Code      string   `json:"code,omitempty"`
+	Component string   `json:"component,omitempty"`
+	Message   string   `json:"message"`
+	Hint      string   `json:"hint,omitempty"`
+	Count     int      `json:"count"`
+	Hosts     []string `json:"hosts"`
+}
+
+// ErrorReport is the fleet wide view of miner errors
+type ErrorReport struct {
+	Scanned     int               `json:"scanned"`
+	Affected    int               `json:"affected"`
+	Errors      []ErrorSummary    `json:"errors"`
+	ByCode      map[string]int    `json:"by_code"`
+	ByComponent map[string]int    `json:"by_component"`
+	Unsupported map[string]string `json:"unsupported,omitempty"` // host to reason
+	Unreachable []string          `json:"unreachable,omitempty"`
+	Failed      map[string]string `json:"failed,omitempty"` // host to error, e.g. refused credentials
+}
+
+// SummarizeErrors aggregates GetErrors results by error code and component.
+// Errors without a code are grouped by message.
+func SummarizeErrors(results []client.Result) *ErrorReport {
+	report := &ErrorReport{
+		Scanned:     len(results),
+		ByCode:      make(map[string]int),
+		ByComponent: make(map[string]int),
+	}
+
+	summaries := make(map[string]*ErrorSummary)
+	codeHosts := make(map[string]map[string]bool)
+	componentHosts := make(map[string]map[string]bool)
+
+	for _, result := range results {
+		switch {
+		case result.Error == "":
+		case errors.Is(result.Err, ErrErrorsUnsupported):
+			if report.Unsupported == nil {
+				report.Unsupported = make(map[string]string)
+			}
+			report.Unsupported[result.IP] = result.Error
+			continue
+		case Unreachable(result.Err):
+			report.Unreachable = append(report.Unreachable, result.IP)
+			continue
+		default:
+			if report.Failed == nil {
+				report.Failed = make(map[string]string)
+			}
+			report.Failed[result.IP] = result.Error
+			continue
+		}
+		errs, _ := result.Response.([]MinerError)
+		if len(errs) > 0 {
+			report.Affected++
+		}
+
+		for _, e := range errs {
+			key := e.Severity + "|" + e.Code + "|" + e.Component
+			if e.Code == "" {
+				key += "|" + e.Message
+			}
+
+			s, ok := summaries[key]
+			if !ok {
+				s = &ErrorSummary{Severity: e.Severity, Code: e.Code, Component: e.Component, Message: e.Message, Hint: e.Hint}
+				summaries[key] = s
+			}
+			if !containsString(s.Hosts, result.IP) {
+				s.Hosts = append(s.Hosts, result.IP)
+				s.Count++
+			}
+
+			codeKey := e.Code
+			if codeKey == "" {
+				codeKey = e.Message
+			}
+			addHost(codeHosts, codeKey, result.IP)
+			if e.Component != "" {
+				addHost(componentHosts, e.Component, result.IP)
+			}
+		}
+	}
+
+	for _, s := range summaries {
+		sort.Strings(s.Hosts)
+		report.Errors = append(report.Errors, *s)
+	}
+	sort.Slice(report.Errors, func(i, j int) bool {
+		if report.Errors[i].Count != report.Errors[j].Count {
+			return report.Errors[i].Count > report.Errors[j].Count
+		}
+		if report.Errors[i].Code != report.Errors[j].Code {
+			return report.Errors[i].Code < report.Errors[j].Code
+		}
+		return report.Errors[i].Message < report.Errors[j].Message
+	})
+
+	for code, hosts := range codeHosts {
+		report.ByCode[code] = len(hosts)
+	}
+	for component, hosts := range componentHosts {
+		report.ByComponent[component] = len(hosts)
+	}
+	sort.Strings(report.Unreachable)
+
+	return report
+}
+
+func addHost(m map[string]map[string]bool, key, host string) {
+	if m[key] == nil {
+		m[key] = make(map[string]bool)
+	}
+	m[key][host] = true
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
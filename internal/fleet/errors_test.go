@@ -0,0 +1,125 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pb "github.com/sinkers/miner-cli/internal/braiins/bos/v1"
+	"github.com/sinkers/miner-cli/internal/client"
+	vmodels "github.com/sinkers/miner-cli/internal/vnish/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorsFromBraiins(t *testing.T) {
+	resp := &pb.GetErrorsResponse{
+		Errors: []*pb.MinerError{
+			{
+				Timestamp:  "2024-01-01T00:00:00Z",
+				Message:    "Hashboard not detected",
+				ErrorCodes: []*pb.ErrorCode{{Code: "HB-001", Hint: "Check the cable"}},
+				Components: []*pb.Component{{Name: "hashboard", Index: 1}, {Name: "hashboard", Index: 2}},
+			},
+			{
+				ErrorCodes: []*pb.ErrorCode{{Code: "FAN-002", Reason: "Fan failure"}},
+			},
+		},
+	}
+
+	errs := errorsFromBraiins(resp)
+	if len(errs) != 3 {
+		t.Fatalf("expected 3 errors, got %d: %+v", len(errs), errs)
+	}
+	if errs[1].Code != "HB-001" || errs[1].Component != "hashboard" || errs[1].Index != 2 || errs[1].Hint != "Check the cable" {
+		t.Errorf("unexpected error: %+v", errs[1])
+	}
+	if errs[2].Message != "Fan failure" || errs[2].Component != "" {
+		t.Errorf("expected reason as message, got %+v", errs[2])
+	}
+}
+
+func TestDedupeErrors(t *testing.T) {
+	errs := []MinerError{
+		{Severity: SeverityError, Code: "A", Message: "x"},
+		{Severity: SeverityError, Code: "A", Message: "x"},
+		{Severity: SeverityWarning, Code: "A", Message: "x"},
+	}
+	if got := dedupeErrors(errs); len(got) != 2 {
+		t.Errorf("expected 2 unique errors, got %d", len(got))
+	}
+}
+
+func TestVnishErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(vmodels.Status{
+			Errors:   []string{"chain 2 lost", "chain 2 lost"},
+			Warnings: []string{"high temperature"},
+		})
+	}))
+	defer server.Close()
+
+	errs, err := GetErrors(context.Background(), Options{Firmware: FirmwareVnish}, strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("GetErrors failed: %v", err)
+	}
+	if len(errs) != 2 || errs[0].Severity != SeverityError || errs[1].Severity != SeverityWarning {
+		t.Errorf("unexpected errors: %+v", errs)
+	}
+}
+
+func resultWithError(host string, err error) client.Result {
+	return client.Result{IP: host, Error: err.Error(), Err: err}
+}
+
+func TestSummarizeErrors(t *testing.T) {
+	results := []client.Result{
+		{IP: "10.0.0.1", Response: []MinerError{
+			{Severity: SeverityError, Code: "HB-001", Component: "hashboard", Index: 1, Message: "Hashboard not detected"},
+			{Severity: SeverityError, Code: "HB-001", Component: "hashboard", Index: 2, Message: "Hashboard not detected"},
+		}},
+		{IP: "10.0.0.2", Response: []MinerError{
+			{Severity: SeverityError, Code: "HB-001", Component: "hashboard", Message: "Hashboard not detected"},
+			{Severity: SeverityWarning, Message: "high temperature"},
+		}},
+		{IP: "10.0.0.3", Response: []MinerError(nil)},
+		resultWithError("10.0.0.4", fmt.Errorf("request failed: %w", &net.OpError{Op: "dial", Err: errors.New("connection refused")})),
+		resultWithError("10.0.0.5", fmt.Errorf("%w for firmware %q", ErrErrorsUnsupported, "cgminer")),
+		resultWithError("10.0.0.6", fmt.Errorf("failed to get errors: %w", status.Error(codes.Unavailable, "connection refused"))),
+		resultWithError("10.0.0.7", fmt.Errorf("failed to get errors: %w", status.Error(codes.Unauthenticated, "invalid credentials"))),
+	}
+
+	report := SummarizeErrors(results)
+
+	if report.Scanned != 7 || report.Affected != 2 {
+		t.Errorf("unexpected totals: scanned %d affected %d", report.Scanned, report.Affected)
+	}
+	if strings.Join(report.Unreachable, ",") != "10.0.0.4,10.0.0.6" {
+		t.Errorf("unexpected unreachable: %v", report.Unreachable)
+	}
+	if len(report.Failed) != 1 || !strings.Contains(report.Failed["10.0.0.7"], "invalid credentials") {
+		t.Errorf("unexpected failed: %v", report.Failed)
+	}
+	if len(report.Unsupported) != 1 || !strings.Contains(report.Unsupported["10.0.0.5"], "cgminer") {
+		t.Errorf("unexpected unsupported: %v", report.Unsupported)
+	}
+	if len(report.Errors) != 2 {
+		t.Fatalf("expected 2 summaries, got %+v", report.Errors)
+	}
+	first := report.Errors[0]
+	if first.Code != "HB-001" || first.Count != 2 || strings.Join(first.Hosts, ",") != "10.0.0.1,10.0.0.2" {
+		t.Errorf("unexpected summary: %+v", first)
+	}
+	if report.ByCode["HB-001"] != 2 || report.ByCode["high temperature"] != 1 {
+		t.Errorf("unexpected by-code counts: %v", report.ByCode)
+	}
+	if report.ByComponent["hashboard"] != 2 {
+		t.Errorf("unexpected by-component counts: %v", report.ByComponent)
+	}
+}
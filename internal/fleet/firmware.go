@@ -0,0 +1,255 @@
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/client"
+	vmodels "github.com/sinkers/miner-cli/internal/vnish/models"
+)
+
+// Firmware upgrade outcomes
+const (
+	UpgradeDone    = "upgraded"
+	UpgradeSkipped = "up-to-date"
+)
+
+// UpgradeOptions controls a vnish firmware upgrade
+type UpgradeOptions struct {
+	ImageURL      string // image the miners download
+	Version       string // version expected after the upgrade
+	Force         bool   // upgrade miners already on Version
+	Retries       int    // extra attempts after a failed upgrade
+	RebootDelay   time.Duration
+	RebootTimeout time.Duration // time allowed for a miner to come back
+	PollInterval  time.Duration
+}
+
+// Validate checks the upgrade options
+func (u UpgradeOptions) Validate() error {
+	if u.ImageURL == "" {
+		return fmt.Errorf("firmware image is required")
+	}
+	if u.Version == "" {
+		return fmt.Errorf("target version is required")
+	}
+	if u.Retries < 0 {
+		return fmt.Errorf("retries must not be negative")
+	}
+	return nil
+}
+
+// UpgradeResult is the outcome of a firmware upgrade on one miner
+type UpgradeResult struct {
+	Host     string `json:"host"`
+	Status   string `json:"status"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Attempts int    `json:"attempts"`
+	Duration string `json:"duration"`
+}
+
+// UpgradeFirmware upgrades a vnish miner to u.Version, waits for it to come
+// back and verifies the running version. Failed attempts are retried
+// u.Retries times.
+func UpgradeFirmware(ctx context.Context, opts Options, host string, u UpgradeOptions) (*UpgradeResult, error) {
+	start := time.Now()
+	vc := opts.Vnish(host)
+
+	info, err := vc.GetInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current version: %w", err)
+	}
+	result := &UpgradeResult{Host: host, From: info.Version, To: info.Version}
+	if info.Version == u.Version && !u.Force {
+		result.Status = UpgradeSkipped
+		result.Duration = time.Since(start).Round(time.Second).String()
+		return result, nil
+	}
+
+	for {
+		result.Attempts++
+		err = upgradeOnce(ctx, opts, host, u)
+		if err == nil || result.Attempts > u.Retries || ctx.Err() != nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("upgrade failed after %d attempts: %w", result.Attempts, err)
+	}
+
+	result.Status = UpgradeDone
+	result.To = u.Version
+	result.Duration = time.Since(start).Round(time.Second).String()
+	return result, nil
+}
+
+// upgradeOnce triggers the update and waits until the miner answers with
+// the expected version
+func upgradeOnce(ctx context.Context, opts Options, host string, u UpgradeOptions) error {
+	if err := ensureUnlocked(ctx, opts, host); err != nil {
+		return err
+	}
+	vc := opts.Vnish(host)
+
+	resp, err := vc.UpdateFirmware(ctx, &vmodels.FirmwareUpdateRequest{URL: u.ImageURL, Version: u.Version})
+	if err != nil {
+		return fmt.Errorf("failed to start update: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("miner rejected update: %s", resp.Message)
+	}
+
+	if err := sleepCtx(ctx, u.RebootDelay); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(u.RebootTimeout)
+	lastVersion := ""
+	for {
+		// CheckAuth answers as soon as the API is up again
+		if _, err := vc.CheckAuth(ctx); err == nil {
+			if info, err := vc.GetInfo(ctx); err == nil {
+				if info.Version == u.Version {
+					return nil
+				}
+				lastVersion = info.Version
+			}
+		}
+
+		if time.Now().After(deadline) {
+			if lastVersion != "" {
+				return fmt.Errorf("miner runs version %s after upgrade, expected %s", lastVersion, u.Version)
+			}
+			return fmt.Errorf("miner did not come back within %s", u.RebootTimeout)
+		}
+		if err := sleepCtx(ctx, u.PollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// UpgradeReport summarizes a fleet firmware upgrade
+type UpgradeReport struct {
+	Scanned   int               `json:"scanned"`
+	Upgraded  []UpgradeResult   `json:"upgraded"`
+	Skipped   []string          `json:"skipped"`
+	Failed    map[string]string `json:"failed,omitempty"` // host to error
+	ByVersion map[string]int    `json:"by_version"`       // versions after the run
+}
+
+// FailedHosts returns the failed hosts, sorted, for a retry run
+func (r *UpgradeReport) FailedHosts() []string {
+	hosts := make([]string, 0, len(r.Failed))
+	for host := range r.Failed {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// SummarizeUpgrades builds the report of an UpgradeFirmware run
+func SummarizeUpgrades(results []client.Result) *UpgradeReport {
+	report := &UpgradeReport{
+		Scanned:   len(results),
+		Failed:    make(map[string]string),
+		ByVersion: make(map[string]int),
+	}
+
+	for _, r := range results {
+		u, ok := r.Response.(*UpgradeResult)
+		if r.Error != "" || !ok {
+			report.Failed[r.IP] = r.Error
+			continue
+		}
+		report.ByVersion[u.To]++
+		if u.Status == UpgradeSkipped {
+			report.Skipped = append(report.Skipped, r.IP)
+		} else {
+			report.Upgraded = append(report.Upgraded, *u)
+		}
+	}
+
+	sort.Slice(report.Upgraded, func(i, j int) bool {
+		return report.Upgraded[i].Host < report.Upgraded[j].Host
+	})
+	sort.Strings(report.Skipped)
+	return report
+}
+
+// ImageServer serves a local firmware image to the miners over HTTP
+type ImageServer struct {
+	URL string
+
+	server *http.Server
+}
+
+// ServeImage starts an HTTP server on listen that serves the file at path.
+// advertise is the address miners use to reach this host; when empty the
+// local address used to reach probe is taken.
+func ServeImage(path, listen, advertise, probe string) (*ImageServer, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("failed to open firmware image: %w", err)
+	}
+
+	l, err := net.Listen("tcp", listen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serve firmware image: %w", err)
+	}
+
+	if advertise == "" {
+		if advertise, err = localAddressFor(probe); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	name := filepath.Base(path)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/"+name, func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, path)
+	})
+	s := &ImageServer{
+		URL:    fmt.Sprintf("http://%s/%s", net.JoinHostPort(advertise, fmt.Sprint(port)), name),
+		server: &http.Server{Handler: mux},
+	}
+	go s.server.Serve(l)
+	return s, nil
+}
+
+// Close stops serving the image
+func (s *ImageServer) Close() error {
+	return s.server.Close()
+}
+
+// localAddressFor returns the local IP the system routes to host from
+func localAddressFor(host string) (string, error) {
+	conn, err := net.Dial("udp", net.JoinHostPort(host, "80"))
+	if err != nil {
+		return "", fmt.Errorf("failed to determine local address, use --advertise: %w", err)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
+
+// IsImageURL reports whether image is a URL rather than a local file
+func IsImageURL(image string) bool {
+	return strings.HasPrefix(image, "http://") || strings.HasPrefix(image, "https://")
+}
@@ -0,0 +1,165 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/client"
+	vmodels "github.com/sinkers/miner-cli/internal/vnish/models"
+)
+
+// fakeUpgradeMiner is a vnish miner that is down for a few polls after an
+// update and then reports the installed version
+type fakeUpgradeMiner struct {
+	mu       sync.Mutex
+	version  string
+	install  string // version installed by an update, defaults to the requested one
+	down     int    // polls left before the miner is back
+	updates  int
+	imageURL string
+}
+
+func (f *fakeUpgradeMiner) start(t *testing.T) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		switch r.URL.Path {
+		case "/api/v1/firmware/update":
+			var req vmodels.FirmwareUpdateRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			f.updates++
+			f.imageURL = req.URL
+			f.down = 2
+			f.version = req.Version
+			if f.install != "" {
+				f.version = f.install
+			}
+			json.NewEncoder(w).Encode(vmodels.FirmwareUpdateResponse{Success: true})
+		case "/api/v1/auth-check":
+			if f.down > 0 {
+				f.down--
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			json.NewEncoder(w).Encode(vmodels.AuthCheck{})
+		case "/api/v1/info":
+			json.NewEncoder(w).Encode(vmodels.SystemInfo{Version: f.version})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+func testUpgradeOptions() UpgradeOptions {
+	return UpgradeOptions{
+		ImageURL:      "http://files.local/vnish.tar",
+		Version:       "1.2.6",
+		RebootTimeout: 200 * time.Millisecond,
+		PollInterval:  5 * time.Millisecond,
+	}
+}
+
+func TestUpgradeFirmware(t *testing.T) {
+	m := &fakeUpgradeMiner{version: "1.2.5"}
+	host := m.start(t)
+
+	result, err := UpgradeFirmware(context.Background(), Options{}, host, testUpgradeOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != UpgradeDone || result.From != "1.2.5" || result.To != "1.2.6" || result.Attempts != 1 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if m.imageURL != "http://files.local/vnish.tar" {
+		t.Errorf("unexpected image URL %q", m.imageURL)
+	}
+}
+
+func TestUpgradeFirmwareSkipsUpToDate(t *testing.T) {
+	m := &fakeUpgradeMiner{version: "1.2.6"}
+	host := m.start(t)
+
+	result, err := UpgradeFirmware(context.Background(), Options{}, host, testUpgradeOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != UpgradeSkipped || m.updates != 0 {
+		t.Errorf("expected no update, got %+v after %d updates", result, m.updates)
+	}
+
+	u := testUpgradeOptions()
+	u.Force = true
+	if _, err := UpgradeFirmware(context.Background(), Options{}, host, u); err != nil || m.updates != 1 {
+		t.Errorf("forced upgrade: %v after %d updates", err, m.updates)
+	}
+}
+
+func TestUpgradeFirmwareRetriesVersionMismatch(t *testing.T) {
+	m := &fakeUpgradeMiner{version: "1.2.5", install: "1.2.5"}
+	host := m.start(t)
+
+	u := testUpgradeOptions()
+	u.RebootTimeout = 30 * time.Millisecond
+	u.Retries = 1
+	_, err := UpgradeFirmware(context.Background(), Options{}, host, u)
+	if err == nil || !strings.Contains(err.Error(), "after 2 attempts") || !strings.Contains(err.Error(), "runs version 1.2.5") {
+		t.Errorf("expected a version mismatch after 2 attempts, got %v", err)
+	}
+	if m.updates != 2 {
+		t.Errorf("expected 2 updates, got %d", m.updates)
+	}
+}
+
+func TestSummarizeUpgrades(t *testing.T) {
+	results := []client.Result{
+		{IP: "10.0.0.2", Response: &UpgradeResult{Host: "10.0.0.2", Status: UpgradeDone, From: "1.2.5", To: "1.2.6"}},
+		{IP: "10.0.0.1", Response: &UpgradeResult{Host: "10.0.0.1", Status: UpgradeSkipped, From: "1.2.6", To: "1.2.6"}},
+		{IP: "10.0.0.4", Error: "miner did not come back within 15m0s"},
+		{IP: "10.0.0.3", Error: "timeout"},
+	}
+
+	report := SummarizeUpgrades(results)
+	if len(report.Upgraded) != 1 || len(report.Skipped) != 1 || report.ByVersion["1.2.6"] != 2 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if hosts := report.FailedHosts(); len(hosts) != 2 || hosts[0] != "10.0.0.3" {
+		t.Errorf("unexpected failed hosts %v", hosts)
+	}
+}
+
+func TestServeImage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vnish.tar")
+	if err := os.WriteFile(path, []byte("image"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := ServeImage(path, "127.0.0.1:0", "127.0.0.1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	if !IsImageURL(server.URL) || !strings.HasSuffix(server.URL, "/vnish.tar") {
+		t.Fatalf("unexpected URL %s", server.URL)
+	}
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "image" {
+		t.Errorf("unexpected body %q", body)
+	}
+}
@@ -0,0 +1,247 @@
+package fleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	braiins "github.com/sinkers/miner-cli/internal/braiins/client"
+	"github.com/sinkers/miner-cli/internal/client"
+	"github.com/sinkers/miner-cli/internal/credentials"
+	vnish "github.com/sinkers/miner-cli/internal/vnish/client"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Supported firmware backends
+const (
+	FirmwareCGMiner = "cgminer"
+	FirmwareBraiins = "braiins"
+	FirmwareVnish   = "vnish"
+)
+
+// Options holds the connection settings shared by the firmware backends.
+type Options struct {
+	Firmware string
+	Port     int // CGMiner API port
+	Username string
+	Password string
+	APIKey   string
+	GRPCPort int
+	Timeout  time.Duration
+
+	// Credentials, when set, supplies per-host logins that take precedence
+	// over Username, Password and APIKey, except for the fields in Override.
+	Credentials *credentials.Store
+	Override    CredentialOverrides
+
+	// Pool, when set, shares Braiins OS connections between tasks
+	Pool *braiins.Pool
+}
+
+// CredentialOverrides names the credentials given explicitly, e.g. on the
+// command line, which the store does not replace
+type CredentialOverrides struct {
+	Username bool
+	Password bool
+	APIKey   bool
+}
+
+// ForHost returns the options with the credential store entries for host
+// applied. Overridden fields and fields missing from the entries keep their
+// configured value.
+func (o Options) ForHost(host string) Options {
+	if o.Credentials == nil {
+		return o
+	}
+	e, ok := o.Credentials.Resolve(host)
+	if !ok {
+		return o
+	}
+	if e.Username != "" && !o.Override.Username {
+		o.Username = e.Username
+	}
+	if e.Password != "" && !o.Override.Password {
+		o.Password = e.Password
+	}
+	if e.APIKey != "" && !o.Override.APIKey {
+		o.APIKey = e.APIKey
+	}
+	return o
+}
+
+// Braiins opens an authenticated Braiins OS+ client for host, or takes it
+// from the pool when one is configured. Callers always Close the client.
+func (o Options) Braiins(host string) (*braiins.SimpleBraiinsClient, error) {
+	o = o.ForHost(host)
+	opts := braiins.SimpleClientOptions{
+		Host:     host,
+		Port:     o.GRPCPort,
+		Username: o.Username,
+		Password: o.Password,
+		Timeout:  o.Timeout,
+	}
+	if o.Pool != nil {
+		return o.Pool.Get(opts)
+	}
+	return braiins.NewSimpleClient(opts)
+}
+
+// CGMiner creates a CGMiner API client using the configured timeout.
+func (o Options) CGMiner() *client.Client {
+	return client.NewClient(o.Timeout, 1)
+}
+
+// Vnish creates a vnish REST client for host.
+func (o Options) Vnish(host string) *vnish.Client {
+	o = o.ForHost(host)
+	opts := []vnish.Option{}
+	if o.APIKey != "" {
+		opts = append(opts, vnish.WithAPIKey(o.APIKey))
+	}
+	if o.Timeout > 0 {
+		opts = append(opts, vnish.WithTimeout(o.Timeout))
+	}
+	return vnish.NewClient(host, opts...)
+}
+
+// ResultPort returns the API port reported in results for the firmware:
+// the gRPC port of Braiins OS, 80 for vnish, else the CGMiner port
+func (o Options) ResultPort() int {
+	switch o.Firmware {
+	case FirmwareBraiins:
+		return o.GRPCPort
+	case FirmwareVnish:
+		return 80
+	default:
+		return o.Port
+	}
+}
+
+// resultPortKey holds where a task reports the port of the firmware it
+// detected, replacing the port given to the Runner
+type resultPortKey struct{}
+
+// Task is executed once per host by a Runner.
+type Task func(ctx context.Context, host string) (interface{}, error)
+
+// Runner executes tasks against many hosts with a bounded worker pool.
+type Runner struct {
+	workers int
+}
+
+// NewRunner creates a runner with the given number of workers
+func NewRunner(workers int) *Runner {
+	if workers <= 0 {
+		workers = 10
+	}
+	return &Runner{workers: workers}
+}
+
+// Run executes task on every host and returns one client.Result per host so
+// the results can be rendered by the output formatters.
+func (r *Runner) Run(ctx context.Context, hosts []string, port int, command string, task Task) []client.Result {
+	return r.Stream(ctx, hosts, port, command, task, nil)
+}
+
+// Stream is Run passing every result to emit, when set, as soon as its host
+// is done
+func (r *Runner) Stream(ctx context.Context, hosts []string, port int, command string, task Task, emit func(client.Result)) []client.Result {
+	jobs := make(chan string, len(hosts))
+	results := make(chan client.Result, len(hosts))
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < r.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for host := range jobs {
+				results <- r.execute(ctx, host, port, command, task)
+			}
+		}()
+	}
+
+	for _, host := range hosts {
+		jobs <- host
+	}
+	close(jobs)
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var allResults []client.Result
+	for res := range results {
+		if emit != nil {
+			emit(res)
+		}
+		allResults = append(allResults, res)
+	}
+
+	return allResults
+}
+
+func (r *Runner) execute(ctx context.Context, host string, port int, command string, task Task) client.Result {
+	result := client.Result{
+		IP:      host,
+		Port:    port,
+		Command: command,
+	}
+
+	select {
+	case <-ctx.Done():
+		result.Error = "context cancelled"
+		result.Err = ctx.Err()
+		return result
+	default:
+	}
+
+	detected := 0
+	start := time.Now()
+	response, err := task(context.WithValue(ctx, resultPortKey{}, &detected), host)
+	result.Duration = time.Since(start).String()
+	if detected != 0 {
+		result.Port = detected
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+		result.Err = err
+	} else {
+		result.Response = response
+	}
+
+	return result
+}
+
+// Unreachable reports whether err means the host could not be reached or
+// did not answer in time, as opposed to refusing or failing the request
+func Unreachable(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// ValidateFirmware checks that firmware is one of the allowed backends
+func ValidateFirmware(firmware string, allowed ...string) error {
+	for _, a := range allowed {
+		if firmware == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported firmware %q (expected one of %v)", firmware, allowed)
+}
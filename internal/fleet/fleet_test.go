@@ -0,0 +1,105 @@
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/client"
+)
+
+func TestRunnerRun(t *testing.T) {
+	hosts := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+
+	runner := NewRunner(2)
+	results := runner.Run(context.Background(), hosts, 50051, "test", func(ctx context.Context, host string) (interface{}, error) {
+		if host == "10.0.0.2" {
+			return nil, fmt.Errorf("boom")
+		}
+		return "ok " + host, nil
+	})
+
+	if len(results) != len(hosts) {
+		t.Fatalf("expected %d results, got %d", len(hosts), len(results))
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].IP < results[j].IP })
+
+	for _, r := range results {
+		if r.Port != 50051 || r.Command != "test" {
+			t.Errorf("unexpected port/command: %+v", r)
+		}
+		if r.Duration == "" {
+			t.Errorf("expected duration for %s", r.IP)
+		}
+	}
+
+	if results[0].Response != "ok 10.0.0.1" || results[0].Error != "" {
+		t.Errorf("unexpected result: %+v", results[0])
+	}
+	if results[1].Error != "boom" || results[1].Response != nil {
+		t.Errorf("expected error result, got %+v", results[1])
+	}
+}
+
+func TestRunnerCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := false
+	results := NewRunner(1).Run(ctx, []string{"10.0.0.1"}, 80, "test", func(ctx context.Context, host string) (interface{}, error) {
+		called = true
+		return nil, nil
+	})
+
+	if called {
+		t.Error("task should not run with a cancelled context")
+	}
+	if len(results) != 1 || results[0].Error != "context cancelled" {
+		t.Errorf("unexpected results: %+v", results)
+	}
+}
+
+func TestRunnerStream(t *testing.T) {
+	hosts := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+
+	var emitted []string
+	results := NewRunner(2).Stream(context.Background(), hosts, 80, "test", func(ctx context.Context, host string) (interface{}, error) {
+		return host, nil
+	}, func(r client.Result) {
+		emitted = append(emitted, r.IP)
+	})
+
+	if len(results) != 3 || len(emitted) != 3 {
+		t.Fatalf("expected 3 results and 3 emitted, got %d and %d", len(results), len(emitted))
+	}
+	for i := range results {
+		if results[i].IP != emitted[i] {
+			t.Errorf("expected results in emitted order, got %v and %+v", emitted, results)
+		}
+	}
+}
+
+func TestNewRunnerDefaults(t *testing.T) {
+	if r := NewRunner(0); r.workers != 10 {
+		t.Errorf("expected 10 workers, got %d", r.workers)
+	}
+}
+
+func TestValidateFirmware(t *testing.T) {
+	if err := ValidateFirmware(FirmwareVnish, FirmwareBraiins, FirmwareVnish); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateFirmware("", FirmwareBraiins); err == nil {
+		t.Error("expected error for empty firmware")
+	}
+}
+
+func TestOptionsVnish(t *testing.T) {
+	c := Options{APIKey: "key", Timeout: time.Second}.Vnish("10.0.0.1")
+	if c == nil {
+		t.Fatal("expected client")
+	}
+}
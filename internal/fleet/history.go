@@ -0,0 +1,528 @@
+package fleet
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/client"
+)
+
+// historyDay names the daily files of a history store
+const historyDay = "2006-01-02"
+
+// Sample is one normalized metric reading of a miner, the same for every
+// firmware
+type Sample struct {
+	Time           time.Time `json:"time"`
+	Host           string    `json:"host"`
+	Firmware       string    `json:"firmware,omitempty"`
+	Model          string    `json:"model,omitempty"`
+	HashRate       float64   `json:"hash_rate"`       // TH/s
+	Power          float64   `json:"power,omitempty"` // W
+	Temp           float64   `json:"temp,omitempty"`  // hottest sensor, °C
+	HardwareErrors int64     `json:"hardware_errors,omitempty"`
+	Accepted       int64     `json:"accepted,omitempty"` // shares since the miner started
+	Rejected       int64     `json:"rejected,omitempty"`
+	Stale          int64     `json:"stale,omitempty"`
+}
+
+// CollectSample reads the hashrate, power, temperature, hardware errors and
+// share counters of host. Only the hashrate is required; the other readings are left empty
+// when the firmware does not report them.
+func CollectSample(ctx context.Context, opts Options, host string) (*Sample, error) {
+	opts, err := opts.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Sample{Time: time.Now().UTC(), Host: host, Firmware: opts.Firmware}
+	switch opts.Firmware {
+	case FirmwareBraiins:
+		c, err := opts.Braiins(host)
+		if err != nil {
+			return nil, err
+		}
+		stats, err := c.GetMinerStats()
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("failed to get miner stats: %w", err)
+		}
+		s.HashRate = stats.GetMinerStats().GetRealHashrate().GetLast_5M().GetGigahashPerSecond() / 1000
+		s.Power = float64(stats.GetPowerStats().GetApproximatedConsumption().GetWatt())
+		s.Accepted = int64(stats.GetPoolStats().GetAcceptedShares())
+		s.Rejected = int64(stats.GetPoolStats().GetRejectedShares())
+		s.Stale = int64(stats.GetPoolStats().GetStaleShares())
+		if details, err := c.GetMinerDetails(); err == nil {
+			s.Model = details.GetMinerIdentity().GetMinerModel()
+		}
+		c.Close()
+	case FirmwareVnish:
+		vc := opts.Vnish(host)
+		perf, err := vc.GetPerfSummary(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get performance summary: %w", err)
+		}
+		s.HashRate = terahash(perf.HashRate, perf.HashRateUnit)
+		s.Power = perf.PowerUsage
+		s.HardwareErrors = perf.HardwareErrors
+		s.Accepted = perf.Accepted
+		s.Rejected = perf.Rejected
+		if model, err := vc.GetModel(ctx); err == nil {
+			s.Model = model.Model
+		}
+	case FirmwareCGMiner:
+		result := opts.CGMiner().Query(host, opts.Port, "summary", nil)
+		if result.Error != "" {
+			return nil, fmt.Errorf("failed to get summary: %s", result.Error)
+		}
+		summary := firstSection(result.Response, "SUMMARY")
+		if summary == nil {
+			return nil, fmt.Errorf("no summary in response")
+		}
+		counter := func(key string) int64 {
+			v, _ := summary[key].(float64)
+			return int64(v)
+		}
+		mhs, _ := summary["MHS 5s"].(float64)
+		s.HashRate = mhs / 1e6
+		s.HardwareErrors = counter("Hardware Errors")
+		s.Accepted = counter("Accepted")
+		s.Rejected = counter("Rejected")
+		s.Stale = counter("Stale")
+		if version := opts.CGMiner().Query(host, opts.Port, "version", nil); version.Error == "" {
+			if v := firstSection(version.Response, "VERSION"); v != nil {
+				s.Model, _ = v["Type"].(string)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported firmware %q", opts.Firmware)
+	}
+
+	if temps, err := GetTemperatures(ctx, opts, host); err == nil {
+		s.Temp = temps.Max
+	}
+	return s, nil
+}
+
+// terahash converts a hashrate in unit to TH/s
+func terahash(value float64, unit string) float64 {
+	switch strings.ToUpper(strings.TrimSuffix(strings.TrimSpace(unit), "/s")) {
+	case "MH":
+		return value / 1e6
+	case "GH":
+		return value / 1000
+	case "PH":
+		return value * 1000
+	default:
+		return value
+	}
+}
+
+// firstSection returns the first entry of a CGMiner response section such
+// as SUMMARY
+func firstSection(response interface{}, name string) map[string]interface{} {
+	respMap, ok := response.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	list, ok := respMap[name].([]interface{})
+	if !ok || len(list) == 0 {
+		return nil
+	}
+	section, _ := list[0].(map[string]interface{})
+	return section
+}
+
+// DefaultHistoryDir returns the directory of the local history store
+func DefaultHistoryDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "history"
+	}
+	return filepath.Join(dir, "miner-cli", "history")
+}
+
+// HistoryStore is an append-only log of samples, one JSON line per sample in
+// a file per UTC day, so old days can be dropped by deleting their files
+type HistoryStore struct {
+	Dir string
+	mu  sync.Mutex
+}
+
+// NewHistoryStore returns the store kept in dir
+func NewHistoryStore(dir string) *HistoryStore {
+	return &HistoryStore{Dir: dir}
+}
+
+func (h *HistoryStore) dayPath(day time.Time) string {
+	return filepath.Join(h.Dir, day.UTC().Format(historyDay)+".jsonl")
+}
+
+// Append adds samples to the store
+func (h *HistoryStore) Append(samples ...Sample) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := os.MkdirAll(h.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	byDay := make(map[string][]Sample)
+	for _, s := range samples {
+		path := h.dayPath(s.Time)
+		byDay[path] = append(byDay[path], s)
+	}
+	for path, day := range byDay {
+		if err := appendSamples(path, day); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appendSamples(path string, samples []Sample) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+
+	// one write per batch keeps concurrent appenders from interleaving lines
+	var buf []byte
+	for _, s := range samples {
+		line, err := json.Marshal(s)
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("failed to marshal sample: %w", err)
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+	_, err = f.Write(buf)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// HistoryQuery selects samples from a store
+type HistoryQuery struct {
+	Hosts map[string]bool // empty for all
+	Since time.Time       // zero for all
+	Until time.Time       // zero for now
+}
+
+// Query returns the matching samples, oldest first. Only the files of the
+// days in range are read, and unreadable lines are skipped.
+func (h *HistoryStore) Query(q HistoryQuery) ([]Sample, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(h.Dir, "*.jsonl"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list history: %w", err)
+	}
+
+	var samples []Sample
+	for _, path := range paths {
+		day, err := time.Parse(historyDay, strings.TrimSuffix(filepath.Base(path), ".jsonl"))
+		if err != nil {
+			continue
+		}
+		if !q.Since.IsZero() && day.Add(24*time.Hour).Before(q.Since) {
+			continue
+		}
+		if !q.Until.IsZero() && day.After(q.Until) {
+			continue
+		}
+		daySamples, err := readSamples(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range daySamples {
+			if len(q.Hosts) > 0 && !q.Hosts[s.Host] {
+				continue
+			}
+			if !q.Since.IsZero() && s.Time.Before(q.Since) {
+				continue
+			}
+			if !q.Until.IsZero() && s.Time.After(q.Until) {
+				continue
+			}
+			samples = append(samples, s)
+		}
+	}
+
+	sort.SliceStable(samples, func(i, j int) bool {
+		if !samples[i].Time.Equal(samples[j].Time) {
+			return samples[i].Time.Before(samples[j].Time)
+		}
+		return samples[i].Host < samples[j].Host
+	})
+	return samples, nil
+}
+
+func readSamples(path string) ([]Sample, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+
+	var samples []Sample
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var s Sample
+		// a line cut short by a crash must not hide the rest of the day
+		if err := json.Unmarshal(scanner.Bytes(), &s); err != nil || s.Host == "" {
+			continue
+		}
+		samples = append(samples, s)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return samples, nil
+}
+
+// Prune deletes the days that ended before cutoff and returns how many
+// files were removed
+func (h *HistoryStore) Prune(cutoff time.Time) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(h.Dir, "*.jsonl"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list history: %w", err)
+	}
+	removed := 0
+	for _, path := range paths {
+		day, err := time.Parse(historyDay, strings.TrimSuffix(filepath.Base(path), ".jsonl"))
+		if err != nil || !day.Add(24*time.Hour).Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return removed, fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// RecordReport summarizes a CollectSample run
+type RecordReport struct {
+	Time     time.Time         `json:"time"`
+	Recorded int               `json:"recorded"`
+	Failed   map[string]string `json:"failed,omitempty"` // host to error
+}
+
+// RecordSamples appends the samples of a CollectSample run to the store.
+// Unreachable miners are reported, not recorded, so a gap in the history
+// shows when a miner was down.
+func RecordSamples(store *HistoryStore, results []client.Result) (*RecordReport, error) {
+	report := &RecordReport{Time: time.Now().UTC(), Failed: make(map[string]string)}
+	var samples []Sample
+	for _, r := range results {
+		s, ok := r.Response.(*Sample)
+		if r.Error != "" || !ok {
+			report.Failed[r.IP] = r.Error
+			continue
+		}
+		samples = append(samples, *s)
+	}
+	if err := store.Append(samples...); err != nil {
+		return nil, err
+	}
+	report.Recorded = len(samples)
+	return report, nil
+}
+
+// Stat is the minimum, average and maximum of a metric
+type Stat struct {
+	Min float64 `json:"min"`
+	Avg float64 `json:"avg"`
+	Max float64 `json:"max"`
+}
+
+func statOf(values []float64) Stat {
+	if len(values) == 0 {
+		return Stat{}
+	}
+	s := Stat{Min: values[0], Max: values[0]}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+		if v < s.Min {
+			s.Min = v
+		}
+		if v > s.Max {
+			s.Max = v
+		}
+	}
+	s.Avg = sum / float64(len(values))
+	return s
+}
+
+// HistoryBucket aggregates the samples of one miner in a time bucket
+type HistoryBucket struct {
+	Start          time.Time `json:"start"`
+	Samples        int       `json:"samples"`
+	HashRate       Stat      `json:"hash_rate"`
+	Power          Stat      `json:"power"`
+	Temp           Stat      `json:"temp"`
+	HardwareErrors int64     `json:"hardware_errors"` // increase over the bucket
+}
+
+// HostHistory is the aggregated history of one miner
+type HostHistory struct {
+	Host      string          `json:"host"`
+	Model     string          `json:"model,omitempty"`
+	Samples   int             `json:"samples"`
+	First     time.Time       `json:"first"`
+	Last      time.Time       `json:"last"`
+	HashRate  Stat            `json:"hash_rate"`
+	Buckets   []HistoryBucket `json:"buckets,omitempty"`
+	DropSince *time.Time      `json:"drop_since,omitempty"` // start of the current hashrate drop
+}
+
+// HistoryReport is the aggregated history of the fleet
+type HistoryReport struct {
+	Since  time.Time     `json:"since"`
+	Bucket string        `json:"bucket,omitempty"`
+	Hosts  []HostHistory `json:"hosts"`
+}
+
+// AggregateOptions controls AggregateHistory
+type AggregateOptions struct {
+	Bucket time.Duration // 0 for a single summary per miner
+	Drop   float64       // fraction below the best bucket that counts as a drop, 0 to disable
+}
+
+// AggregateHistory groups samples per miner and time bucket. With Drop set
+// it also finds when each miner started its current hashrate drop: the
+// start of the run of trailing buckets whose average hashrate is below
+// (1 - Drop) times the best bucket average.
+func AggregateHistory(samples []Sample, a AggregateOptions) *HistoryReport {
+	report := &HistoryReport{}
+	if a.Bucket > 0 {
+		report.Bucket = a.Bucket.String()
+	}
+
+	byHost := make(map[string][]Sample)
+	for _, s := range samples {
+		byHost[s.Host] = append(byHost[s.Host], s)
+	}
+
+	for host, hostSamples := range byHost {
+		sortSamples(hostSamples)
+
+		h := HostHistory{
+			Host:    host,
+			Samples: len(hostSamples),
+			First:   hostSamples[0].Time,
+			Last:    hostSamples[len(hostSamples)-1].Time,
+		}
+		hashRates := make([]float64, 0, len(hostSamples))
+		for _, s := range hostSamples {
+			hashRates = append(hashRates, s.HashRate)
+			if s.Model != "" {
+				h.Model = s.Model
+			}
+		}
+		h.HashRate = statOf(hashRates)
+
+		buckets := bucketSamples(hostSamples, a.Bucket)
+		if a.Bucket > 0 {
+			h.Buckets = buckets
+		}
+		if a.Drop > 0 {
+			h.DropSince = dropSince(buckets, a.Drop)
+		}
+		report.Hosts = append(report.Hosts, h)
+	}
+
+	sort.Slice(report.Hosts, func(i, j int) bool {
+		return report.Hosts[i].Host < report.Hosts[j].Host
+	})
+	return report
+}
+
+// bucketSamples aggregates the time ordered samples of one miner. Without
+// a bucket size every sample is its own bucket.
+func bucketSamples(samples []Sample, size time.Duration) []HistoryBucket {
+	var buckets []HistoryBucket
+	var group []Sample
+	flush := func() {
+		if len(group) == 0 {
+			return
+		}
+		var hashRates, power, temps []float64
+		for _, s := range group {
+			hashRates = append(hashRates, s.HashRate)
+			if s.Power > 0 {
+				power = append(power, s.Power)
+			}
+			if s.Temp > 0 {
+				temps = append(temps, s.Temp)
+			}
+		}
+		b := HistoryBucket{
+			Start:    group[0].Time,
+			Samples:  len(group),
+			HashRate: statOf(hashRates),
+			Power:    statOf(power),
+			Temp:     statOf(temps),
+		}
+		if size > 0 {
+			b.Start = group[0].Time.Truncate(size)
+		}
+		// counters reset when the miner restarts
+		if errs := group[len(group)-1].HardwareErrors - group[0].HardwareErrors; errs > 0 {
+			b.HardwareErrors = errs
+		}
+		buckets = append(buckets, b)
+		group = nil
+	}
+
+	for _, s := range samples {
+		if len(group) > 0 && (size <= 0 || !s.Time.Truncate(size).Equal(group[0].Time.Truncate(size))) {
+			flush()
+		}
+		group = append(group, s)
+	}
+	flush()
+	return buckets
+}
+
+// dropSince returns the start of the trailing buckets whose average
+// hashrate is below (1 - drop) of the best bucket, nil when the last bucket
+// is not below it
+func dropSince(buckets []HistoryBucket, drop float64) *time.Time {
+	best := 0.0
+	for _, b := range buckets {
+		if b.HashRate.Avg > best {
+			best = b.HashRate.Avg
+		}
+	}
+	limit := best * (1 - drop)
+
+	var since *time.Time
+	for i := len(buckets) - 1; i >= 0 && buckets[i].HashRate.Avg < limit; i-- {
+		start := buckets[i].Start
+		since = &start
+	}
+	return since
+}
@@ -0,0 +1,172 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/client"
+	vmodels "github.com/sinkers/miner-cli/internal/vnish/models"
+)
+
+func TestCollectSampleVnish(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/perf-summary":
+			json.NewEncoder(w).Encode(vmodels.PerfSummary{HashRate: 98500, HashRateUnit: "GH/s", PowerUsage: 3250, HardwareErrors: 12})
+		case "/api/v1/model":
+			json.NewEncoder(w).Encode(vmodels.ModelInfo{Model: "Antminer S19 Pro"})
+		case "/api/v1/status":
+			json.NewEncoder(w).Encode(vmodels.Status{Temperature: vmodels.TempInfo{Chip: []float64{71, 76}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	s, err := CollectSample(context.Background(), Options{Firmware: FirmwareVnish}, host)
+	if err != nil {
+		t.Fatalf("CollectSample failed: %v", err)
+	}
+	if s.HashRate != 98.5 || s.Power != 3250 || s.Temp != 76 || s.HardwareErrors != 12 {
+		t.Errorf("unexpected sample: %+v", s)
+	}
+	if s.Model != "Antminer S19 Pro" || s.Host != host || s.Firmware != FirmwareVnish {
+		t.Errorf("unexpected identity: %+v", s)
+	}
+}
+
+func TestTerahash(t *testing.T) {
+	cases := map[string]float64{"TH/s": 100, "GH/s": 0.1, "gh": 0.1, "MH/s": 0.0001, "PH/s": 100000, "": 100}
+	for unit, want := range cases {
+		if got := terahash(100, unit); got != want {
+			t.Errorf("terahash(100, %q) = %v, want %v", unit, got, want)
+		}
+	}
+}
+
+func TestHistoryStore(t *testing.T) {
+	store := NewHistoryStore(t.TempDir())
+	day := time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC)
+
+	err := store.Append(
+		Sample{Time: day, Host: "10.0.0.1", HashRate: 100},
+		Sample{Time: day.Add(2 * time.Hour), Host: "10.0.0.1", HashRate: 90},
+		Sample{Time: day.Add(time.Hour), Host: "10.0.0.2", HashRate: 110},
+	)
+	if err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if err := store.Append(Sample{Time: day.Add(3 * time.Hour), Host: "10.0.0.2", HashRate: 111}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(store.Dir, "*.jsonl"))
+	if len(files) != 2 {
+		t.Fatalf("expected one file per day, got %v", files)
+	}
+
+	// a torn line must not hide the rest of the day
+	f, _ := os.OpenFile(files[1], os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"time":"2024-05-02T`)
+	f.Close()
+
+	all, err := store.Query(HistoryQuery{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(all) != 4 || all[0].Host != "10.0.0.1" || all[1].Host != "10.0.0.2" || all[3].HashRate != 111 {
+		t.Errorf("unexpected samples: %+v", all)
+	}
+
+	recent, _ := store.Query(HistoryQuery{Hosts: map[string]bool{"10.0.0.1": true}, Since: day.Add(30 * time.Minute)})
+	if len(recent) != 1 || recent[0].HashRate != 90 {
+		t.Errorf("unexpected filtered samples: %+v", recent)
+	}
+
+	removed, err := store.Prune(day.Add(2 * time.Hour))
+	if err != nil || removed != 1 {
+		t.Fatalf("expected to prune one day, got %d (%v)", removed, err)
+	}
+	left, _ := store.Query(HistoryQuery{})
+	if len(left) != 3 {
+		t.Errorf("expected the second day to remain, got %+v", left)
+	}
+}
+
+func TestRecordSamples(t *testing.T) {
+	store := NewHistoryStore(t.TempDir())
+	results := []client.Result{
+		{IP: "10.0.0.1", Response: &Sample{Time: time.Now(), Host: "10.0.0.1", HashRate: 95}},
+		{IP: "10.0.0.2", Error: "connection refused"},
+	}
+
+	report, err := RecordSamples(store, results)
+	if err != nil {
+		t.Fatalf("RecordSamples failed: %v", err)
+	}
+	if report.Recorded != 1 || report.Failed["10.0.0.2"] != "connection refused" {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	samples, _ := store.Query(HistoryQuery{})
+	if len(samples) != 1 || samples[0].Host != "10.0.0.1" {
+		t.Errorf("unexpected stored samples: %+v", samples)
+	}
+}
+
+func TestAggregateHistory(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	var samples []Sample
+	// 10.0.0.1 runs at 100 TH/s and drops to 80 from 03:00
+	for i, rate := range []float64{100, 102, 99, 101, 98, 100, 80, 79, 81, 80} {
+		samples = append(samples, Sample{
+			Time:           start.Add(time.Duration(i) * 30 * time.Minute),
+			Host:           "10.0.0.1",
+			Model:          "S19",
+			HashRate:       rate,
+			Power:          3000,
+			Temp:           70 + float64(i),
+			HardwareErrors: int64(i * 5),
+		})
+	}
+	// 10.0.0.2 dipped once but recovered
+	for i, rate := range []float64{100, 70, 100, 100} {
+		samples = append(samples, Sample{Time: start.Add(time.Duration(i) * time.Hour), Host: "10.0.0.2", HashRate: rate})
+	}
+
+	report := AggregateHistory(samples, AggregateOptions{Bucket: time.Hour, Drop: 0.1})
+	if len(report.Hosts) != 2 {
+		t.Fatalf("expected 2 hosts, got %+v", report.Hosts)
+	}
+
+	h := report.Hosts[0]
+	if h.Host != "10.0.0.1" || h.Model != "S19" || h.Samples != 10 || h.HashRate.Min != 79 || h.HashRate.Max != 102 {
+		t.Errorf("unexpected host summary: %+v", h)
+	}
+	if len(h.Buckets) != 5 {
+		t.Fatalf("expected 5 hourly buckets, got %d", len(h.Buckets))
+	}
+	b := h.Buckets[0]
+	if b.Samples != 2 || b.HashRate.Avg != 101 || b.Temp.Max != 71 || b.HardwareErrors != 5 {
+		t.Errorf("unexpected first bucket: %+v", b)
+	}
+	if h.DropSince == nil || !h.DropSince.Equal(start.Add(3*time.Hour)) {
+		t.Errorf("expected drop since 03:00, got %v", h.DropSince)
+	}
+
+	if report.Hosts[1].DropSince != nil {
+		t.Errorf("recovered miner flagged as dropping since %v", report.Hosts[1].DropSince)
+	}
+
+	summary := AggregateHistory(samples, AggregateOptions{})
+	if len(summary.Hosts[0].Buckets) != 0 || summary.Hosts[0].DropSince != nil {
+		t.Errorf("expected summaries only, got %+v", summary.Hosts[0])
+	}
+}
@@ -0,0 +1,170 @@
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/braiins/models"
+	"github.com/sinkers/miner-cli/internal/client"
+)
+
+// License issues flagged by SummarizeLicenses
+const (
+	LicenseOK       = "ok"
+	LicenseExpiring = "expiring"
+	LicenseMissing  = "missing"
+	LicenseLimited  = "limited"
+	LicenseExpired  = "expired"
+)
+
+// LicenseStatus is the license of one Braiins OS miner
+type LicenseStatus struct {
+	State            string  `json:"state"`
+	Type             string  `json:"type,omitempty"`
+	ContractName     string  `json:"contract_name,omitempty"`
+	DevFee           float64 `json:"dev_fee_percent"`
+	TimeToRestricted int64   `json:"time_to_restricted_seconds,omitempty"`
+}
+
+// GetLicense reads the license state of host
+func GetLicense(ctx context.Context, opts Options, host string) (*LicenseStatus, error) {
+	c, err := opts.Braiins(host)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	resp, err := c.GetLicenseState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get license state: %w", err)
+	}
+	return licenseStatus(models.ConvertLicenseState(resp)), nil
+}
+
+// ApplyContractKey installs a contract key on host and returns the new
+// license state
+func ApplyContractKey(ctx context.Context, opts Options, host, key string) (*LicenseStatus, error) {
+	c, err := opts.Braiins(host)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	resp, err := c.ApplyContractKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply contract key: %w", err)
+	}
+	if !resp.GetSuccessful() {
+		return nil, fmt.Errorf("miner rejected the contract key")
+	}
+
+	state, err := c.GetLicenseState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get license state: %w", err)
+	}
+	return licenseStatus(models.ConvertLicenseState(state)), nil
+}
+
+// ReadContractKey reads a contract key from a file
+func ReadContractKey(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read key file: %w", err)
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return "", fmt.Errorf("key file is empty")
+	}
+	return key, nil
+}
+
+func licenseStatus(l *models.SimpleLicense) *LicenseStatus {
+	return &LicenseStatus{
+		State:            l.State,
+		Type:             l.Type,
+		ContractName:     l.ContractName,
+		DevFee:           l.DevFee,
+		TimeToRestricted: int64(l.TimeToRestricted / time.Second),
+	}
+}
+
+// Issue classifies the license, treating valid licenses that enter
+// restricted mode within warn as expiring. Only a miner without any license
+// is missing one.
+func (s *LicenseStatus) Issue(warn time.Duration) string {
+	switch s.State {
+	case "valid":
+		remaining := time.Duration(s.TimeToRestricted) * time.Second
+		if remaining > 0 && remaining < warn {
+			return LicenseExpiring
+		}
+		return LicenseOK
+	case "expired":
+		return LicenseExpired
+	case "limited":
+		return LicenseLimited
+	default:
+		return LicenseMissing
+	}
+}
+
+// LicenseFlag is a miner whose license needs attention
+type LicenseFlag struct {
+	Host   string `json:"host"`
+	Issue  string `json:"issue"`
+	State  string `json:"state"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// LicenseReport summarizes licenses across the fleet
+type LicenseReport struct {
+	Scanned     int            `json:"scanned"`
+	ByState     map[string]int `json:"by_state"`
+	ByDevFee    map[string]int `json:"by_dev_fee"`
+	Flagged     []LicenseFlag  `json:"flagged"`
+	Unreachable []string       `json:"unreachable,omitempty"`
+}
+
+// SummarizeLicenses counts license states and dev fees and flags missing,
+// limited, expired and expiring licenses
+func SummarizeLicenses(results []client.Result, warn time.Duration) *LicenseReport {
+	report := &LicenseReport{
+		Scanned:  len(results),
+		ByState:  make(map[string]int),
+		ByDevFee: make(map[string]int),
+	}
+
+	for _, r := range results {
+		s, ok := r.Response.(*LicenseStatus)
+		if r.Error != "" || !ok {
+			report.Unreachable = append(report.Unreachable, r.IP)
+			continue
+		}
+
+		report.ByState[s.State]++
+		if s.State == "valid" || s.State == "expired" {
+			report.ByDevFee[fmt.Sprintf("%.2f%%", s.DevFee)]++
+		}
+
+		if issue := s.Issue(warn); issue != LicenseOK {
+			flag := LicenseFlag{Host: r.IP, Issue: issue, State: s.State}
+			if issue == LicenseExpiring {
+				flag.Detail = "restricted in " + (time.Duration(s.TimeToRestricted) * time.Second).String()
+			}
+			report.Flagged = append(report.Flagged, flag)
+		}
+	}
+
+	sort.Slice(report.Flagged, func(i, j int) bool {
+		if report.Flagged[i].Issue != report.Flagged[j].Issue {
+			return report.Flagged[i].Issue < report.Flagged[j].Issue
+		}
+		return report.Flagged[i].Host < report.Flagged[j].Host
+	})
+	sort.Strings(report.Unreachable)
+	return report
+}
@@ -0,0 +1,64 @@
+package fleet
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/sinkers/miner-cli/internal/braiins/bos/v1"
+	"github.com/sinkers/miner-cli/internal/braiins/models"
+	"github.com/sinkers/miner-cli/internal/client"
+)
+
+func TestLicenseStatusFromBraiins(t *testing.T) {
+	resp := &pb.GetLicenseStateResponse{State: &pb.GetLicenseStateResponse_Valid{Valid: &pb.ValidLicense{
+		Type:             pb.LicenseType_LICENSE_TYPE_CUSTOM,
+		ContractName:     "acme",
+		TimeToRestricted: 3600,
+		DevFee:           &pb.BasesPoints{Bsp: 250},
+	}}}
+
+	s := licenseStatus(models.ConvertLicenseState(resp))
+	if s.State != "valid" || s.Type != "custom" || s.ContractName != "acme" || s.DevFee != 2.5 || s.TimeToRestricted != 3600 {
+		t.Errorf("unexpected status: %+v", s)
+	}
+	if s.Issue(24*time.Hour) != LicenseExpiring {
+		t.Errorf("expected expiring, got %s", s.Issue(24*time.Hour))
+	}
+	if s.Issue(time.Minute) != LicenseOK {
+		t.Errorf("expected ok, got %s", s.Issue(time.Minute))
+	}
+}
+
+func TestSummarizeLicenses(t *testing.T) {
+	results := []client.Result{
+		{IP: "10.0.0.1", Response: &LicenseStatus{State: "valid", DevFee: 2, TimeToRestricted: 30 * 24 * 3600}},
+		{IP: "10.0.0.2", Response: &LicenseStatus{State: "valid", DevFee: 2, TimeToRestricted: 3600}},
+		{IP: "10.0.0.3", Response: &LicenseStatus{State: "none"}},
+		{IP: "10.0.0.4", Response: &LicenseStatus{State: "expired", DevFee: 0}},
+		{IP: "10.0.0.5", Error: "connection refused"},
+		{IP: "10.0.0.6", Response: &LicenseStatus{State: "limited"}},
+	}
+
+	report := SummarizeLicenses(results, 7*24*time.Hour)
+
+	if report.ByState["valid"] != 2 || report.ByState["none"] != 1 || report.ByDevFee["2.00%"] != 2 || report.ByDevFee["0.00%"] != 1 {
+		t.Errorf("unexpected counts: %v %v", report.ByState, report.ByDevFee)
+	}
+	want := []LicenseFlag{
+		{Host: "10.0.0.4", Issue: LicenseExpired, State: "expired"},
+		{Host: "10.0.0.2", Issue: LicenseExpiring, State: "valid", Detail: "restricted in 1h0m0s"},
+		{Host: "10.0.0.6", Issue: LicenseLimited, State: "limited"},
+		{Host: "10.0.0.3", Issue: LicenseMissing, State: "none"},
+	}
+	if len(report.Flagged) != len(want) {
+		t.Fatalf("expected %d flagged, got %+v", len(want), report.Flagged)
+	}
+	for i := range want {
+		if report.Flagged[i] != want[i] {
+			t.Errorf("flag %d: expected %+v, got %+v", i, want[i], report.Flagged[i])
+		}
+	}
+	if len(report.Unreachable) != 1 {
+		t.Errorf("unexpected unreachable: %v", report.Unreachable)
+	}
+}
@@ -0,0 +1,112 @@
+package fleet
+
+import (
+	"context"
+	"fmt"
+)
+
+// LocateStatus reports whether the locate LED of a miner is blinking
+type LocateStatus struct {
+	Firmware string `json:"firmware"`
+	Enabled  bool   `json:"enabled"`
+}
+
+// SetLocate turns the locate LED of host on or off using the mechanism of
+// its firmware.
+func SetLocate(ctx context.Context, opts Options, host string, enable bool) (*LocateStatus, error) {
+	opts, err := opts.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	switch opts.Firmware {
+	case FirmwareBraiins:
+		c, err := opts.Braiins(host)
+		if err != nil {
+			return nil, err
+		}
+		defer c.Close()
+
+		resp, err := c.SetLocateDeviceStatus(enable)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set locate status: %w", err)
+		}
+		return &LocateStatus{Firmware: opts.Firmware, Enabled: resp.Enabled}, nil
+	case FirmwareVnish:
+		if err := ensureUnlocked(ctx, opts, host); err != nil {
+			return nil, err
+		}
+		resp, err := opts.Vnish(host).FindMiner(ctx, enable)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set locate status: %w", err)
+		}
+		if !resp.Success {
+			return nil, fmt.Errorf("find miner failed: %s", resp.Message)
+		}
+		return &LocateStatus{Firmware: opts.Firmware, Enabled: enable}, nil
+	case FirmwareCGMiner:
+		// Antminer style CGMiner builds expose the LED through ascset
+		value := "0"
+		if enable {
+			value = "1"
+		}
+		result := opts.CGMiner().Query(host, opts.Port, "custom", map[string]interface{}{
+			"cmd":  "ascset",
+			"args": "0,led," + value,
+		})
+		if result.Error != "" {
+			return nil, fmt.Errorf("failed to set LED: %s", result.Error)
+		}
+		if err := cgminerStatusError(result.Response); err != nil {
+			return nil, fmt.Errorf("failed to set LED: %w", err)
+		}
+		return &LocateStatus{Firmware: opts.Firmware, Enabled: enable}, nil
+	default:
+		return nil, fmt.Errorf("unsupported firmware %q", opts.Firmware)
+	}
+}
+
+// GetLocate reads the locate LED state of host. Only Braiins OS reports it.
+func GetLocate(ctx context.Context, opts Options, host string) (*LocateStatus, error) {
+	opts, err := opts.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Firmware != FirmwareBraiins {
+		return nil, fmt.Errorf("locate status is not reported by %s firmware", opts.Firmware)
+	}
+
+	c, err := opts.Braiins(host)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	resp, err := c.GetLocateDeviceStatus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get locate status: %w", err)
+	}
+	return &LocateStatus{Firmware: opts.Firmware, Enabled: resp.Enabled}, nil
+}
+
+// cgminerStatusError returns the message of a CGMiner error status response
+func cgminerStatusError(response interface{}) error {
+	respMap, ok := response.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	statusList, ok := respMap["STATUS"].([]interface{})
+	if !ok || len(statusList) == 0 {
+		return nil
+	}
+	status, ok := statusList[0].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if code, _ := status["STATUS"].(string); code == "E" || code == "F" {
+		msg, _ := status["Msg"].(string)
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	vmodels "github.com/sinkers/miner-cli/internal/vnish/models"
+)
+
+func TestSetLocateVnish(t *testing.T) {
+	var got vmodels.FindMinerRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/find-miner" || r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		json.NewEncoder(w).Encode(vmodels.FindMinerResponse{Success: true})
+	}))
+	defer server.Close()
+
+	opts := Options{Firmware: FirmwareVnish}
+	status, err := SetLocate(context.Background(), opts, strings.TrimPrefix(server.URL, "http://"), true)
+	if err != nil {
+		t.Fatalf("SetLocate failed: %v", err)
+	}
+	if !got.Blink || !status.Enabled || status.Firmware != FirmwareVnish {
+		t.Errorf("unexpected result: request %+v, status %+v", got, status)
+	}
+}
+
+func TestSetLocateCGMiner(t *testing.T) {
+	port := fakeCGMiner(t, `{"STATUS":[{"STATUS":"E","Msg":"Invalid ascset"}],"id":1}`)
+
+	opts := Options{Firmware: FirmwareCGMiner, Port: port, Timeout: time.Second}
+	_, err := SetLocate(context.Background(), opts, "127.0.0.1", true)
+	if err == nil || !strings.Contains(err.Error(), "Invalid ascset") {
+		t.Errorf("expected CGMiner error message, got %v", err)
+	}
+}
+
+func TestGetLocateUnsupported(t *testing.T) {
+	if _, err := GetLocate(context.Background(), Options{Firmware: FirmwareVnish}, "10.0.0.1"); err == nil {
+		t.Error("expected error for vnish locate status")
+	}
+}
+
+func TestCGMinerStatusError(t *testing.T) {
+	ok := map[string]interface{}{"STATUS": []interface{}{map[string]interface{}{"STATUS": "S"}}}
+	if err := cgminerStatusError(ok); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := cgminerStatusError("text"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package fleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/sinkers/miner-cli/internal/client"
+	vnish "github.com/sinkers/miner-cli/internal/vnish/client"
+)
+
+// LockedError is returned for write operations against a locked vnish miner
+type LockedError struct {
+	Host string
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("miner %s is locked, unlock it first with: miner-cli vnish unlock -i %s", e.Host, e.Host)
+}
+
+// LockState is the screen lock state of a vnish miner
+type LockState struct {
+	Host          string `json:"host"`
+	Locked        bool   `json:"locked"`
+	UnlockTimeout int64  `json:"unlock_timeout,omitempty"` // seconds until the miner unlocks itself
+}
+
+// GetLockState reads the lock state of host from its status. Firmware that
+// does not report the state is treated as unlocked.
+func GetLockState(ctx context.Context, opts Options, host string) (*LockState, error) {
+	status, err := opts.Vnish(host).GetStatus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status: %w", err)
+	}
+
+	state := &LockState{Host: host, Locked: status.Unlocked != nil && !*status.Unlocked}
+	if status.UnlockTimeout != nil {
+		state.UnlockTimeout = *status.UnlockTimeout
+	}
+	return state, nil
+}
+
+// ensureUnlocked refuses writes to a locked vnish miner. A refused status
+// read is reported as such, as it usually means wrong credentials rather
+// than a lock; when the state cannot be read otherwise the write goes ahead
+// and reports its own error.
+func ensureUnlocked(ctx context.Context, opts Options, host string) error {
+	state, err := GetLockState(ctx, opts, host)
+	var apiErr *vnish.APIError
+	if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden) {
+		return fmt.Errorf("cannot read lock state: %w", err)
+	}
+	if err == nil && state.Locked {
+		return &LockedError{Host: host}
+	}
+	return nil
+}
+
+// lockPassword returns the password of host from the credential store, or
+// the configured password
+func lockPassword(opts Options, host string) (string, error) {
+	password := opts.ForHost(host).Password
+	if password == "" {
+		return "", fmt.Errorf("no password for %s, add one to the credential store", host)
+	}
+	return password, nil
+}
+
+// LockMiner locks host, or with others drops the other sessions of host
+// and keeps the current one
+func LockMiner(ctx context.Context, opts Options, host string, others bool) (*LockState, error) {
+	password, err := lockPassword(opts, host)
+	if err != nil {
+		return nil, err
+	}
+
+	vc := opts.Vnish(host)
+	if others {
+		err = vc.LockOthers(ctx, password)
+	} else {
+		err = vc.Lock(ctx, password)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock: %w", err)
+	}
+	return &LockState{Host: host, Locked: !others}, nil
+}
+
+// UnlockMiner unlocks host with its password
+func UnlockMiner(ctx context.Context, opts Options, host string) (*LockState, error) {
+	password, err := lockPassword(opts, host)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.Vnish(host).Unlock(ctx, password); err != nil {
+		return nil, fmt.Errorf("failed to unlock: %w", err)
+	}
+	return &LockState{Host: host}, nil
+}
+
+// LockReport lists which miners are locked
+type LockReport struct {
+	Scanned     int               `json:"scanned"`
+	Locked      []LockState       `json:"locked"`
+	Unlocked    []string          `json:"unlocked"`
+	Unreachable map[string]string `json:"unreachable,omitempty"` // host to error
+}
+
+// SummarizeLocks builds the report of a GetLockState run
+func SummarizeLocks(results []client.Result) *LockReport {
+	report := &LockReport{Scanned: len(results), Unreachable: make(map[string]string)}
+	for _, r := range results {
+		state, ok := r.Response.(*LockState)
+		if r.Error != "" || !ok {
+			report.Unreachable[r.IP] = r.Error
+			continue
+		}
+		if state.Locked {
+			report.Locked = append(report.Locked, *state)
+		} else {
+			report.Unlocked = append(report.Unlocked, r.IP)
+		}
+	}
+
+	sort.Slice(report.Locked, func(i, j int) bool {
+		return report.Locked[i].Host < report.Locked[j].Host
+	})
+	sort.Strings(report.Unlocked)
+	return report
+}
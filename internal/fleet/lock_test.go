@@ -0,0 +1,137 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sinkers/miner-cli/internal/client"
+	"github.com/sinkers/miner-cli/internal/credentials"
+	vmodels "github.com/sinkers/miner-cli/internal/vnish/models"
+)
+
+// fakeLockMiner is a vnish miner with a screen lock guarded by password
+type fakeLockMiner struct {
+	mu       sync.Mutex
+	password string
+	locked   bool
+	writes   int
+	// the status is refused, as with a wrong API key
+	statusDenied bool
+}
+
+func (f *fakeLockMiner) start(t *testing.T) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		switch r.URL.Path {
+		case "/api/v1/status":
+			if f.statusDenied {
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(vmodels.ErrDescr{Error: "locked"})
+				return
+			}
+			unlocked := !f.locked
+			timeout := int64(300)
+			json.NewEncoder(w).Encode(vmodels.Status{Unlocked: &unlocked, UnlockTimeout: &timeout})
+		case "/api/v1/lock", "/api/v1/unlock":
+			var req vmodels.LockRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.Password != f.password {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			f.locked = r.URL.Path == "/api/v1/lock"
+		case "/api/v1/settings":
+			if r.Method == http.MethodPost {
+				f.writes++
+				return
+			}
+			json.NewEncoder(w).Encode(vmodels.Settings{})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+func TestLockUnlockWithStoredPassword(t *testing.T) {
+	m := &fakeLockMiner{password: "stored"}
+	host := m.start(t)
+
+	store := &credentials.Store{}
+	store.Set(credentials.Entry{Name: "fleet", Password: "stored"})
+	opts := Options{Firmware: FirmwareVnish, Password: "wrong", Credentials: store}
+
+	if _, err := LockMiner(context.Background(), opts, host, false); err != nil {
+		t.Fatal(err)
+	}
+	state, err := GetLockState(context.Background(), opts, host)
+	if err != nil || !state.Locked || state.UnlockTimeout != 300 {
+		t.Fatalf("expected a locked miner, got %+v %v", state, err)
+	}
+
+	if _, err := UnlockMiner(context.Background(), opts, host); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := GetLockState(context.Background(), opts, host); state.Locked {
+		t.Error("miner still locked")
+	}
+
+	if _, err := LockMiner(context.Background(), Options{Firmware: FirmwareVnish}, host, false); err == nil {
+		t.Error("expected an error without a password")
+	}
+}
+
+func TestWritesRefusedOnLockedMiner(t *testing.T) {
+	m := &fakeLockMiner{locked: true}
+	host := m.start(t)
+	opts := Options{Firmware: FirmwareVnish}
+
+	_, err := ApplySettings(context.Background(), opts, host, &vmodels.Settings{Fan: vmodels.FanSettings{Mode: "manual"}}, []string{SettingsFan}, false)
+	var locked *LockedError
+	if !errors.As(err, &locked) || locked.Host != host || !strings.Contains(err.Error(), "vnish unlock -i "+host) {
+		t.Errorf("expected a LockedError, got %v", err)
+	}
+	if _, err := SetCooling(context.Background(), opts, host, CoolingSettings{Mode: CoolingAuto}); !errors.As(err, &locked) {
+		t.Errorf("expected a LockedError from SetCooling, got %v", err)
+	}
+	if m.writes != 0 {
+		t.Errorf("locked miner received %d writes", m.writes)
+	}
+
+	m.locked = false
+	if _, err := ApplySettings(context.Background(), opts, host, &vmodels.Settings{Fan: vmodels.FanSettings{Mode: "manual"}}, []string{SettingsFan}, false); err != nil || m.writes != 1 {
+		t.Errorf("unlocked miner: %v after %d writes", err, m.writes)
+	}
+}
+
+func TestWritesRefusedWhenLockStateDenied(t *testing.T) {
+	m := &fakeLockMiner{statusDenied: true}
+	host := m.start(t)
+
+	_, err := ApplySettings(context.Background(), Options{Firmware: FirmwareVnish}, host, &vmodels.Settings{Fan: vmodels.FanSettings{Mode: "manual"}}, []string{SettingsFan}, false)
+	var locked *LockedError
+	if err == nil || errors.As(err, &locked) || !strings.Contains(err.Error(), "cannot read lock state") || m.writes != 0 {
+		t.Errorf("expected a lock state error without writes, got %v after %d writes", err, m.writes)
+	}
+}
+
+func TestSummarizeLocks(t *testing.T) {
+	results := []client.Result{
+		{IP: "10.0.0.2", Response: &LockState{Host: "10.0.0.2", Locked: true}},
+		{IP: "10.0.0.1", Response: &LockState{Host: "10.0.0.1"}},
+		{IP: "10.0.0.3", Error: "timeout"},
+	}
+
+	report := SummarizeLocks(results)
+	if len(report.Locked) != 1 || len(report.Unlocked) != 1 || len(report.Unreachable) != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
@@ -0,0 +1,357 @@
+package fleet
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	pb "github.com/sinkers/miner-cli/internal/braiins/bos/v1"
+)
+
+// LogFileExt is the extension of the per-host files in a log collection
+const LogFileExt = ".jsonl"
+
+// maxLogArchiveSize bounds the Braiins OS support archive read for its logs
+const maxLogArchiveSize = 64 << 20
+
+// LogLine is one log entry tagged with the miner it came from
+type LogLine struct {
+	Time    time.Time `json:"time"`
+	Host    string    `json:"host"`
+	Type    string    `json:"type"`
+	Level   string    `json:"level,omitempty"`
+	Source  string    `json:"source,omitempty"`
+	Message string    `json:"message"`
+}
+
+// LogOptions selects the logs fetched from each miner
+type LogOptions struct {
+	Type  string        // vnish log type, or Braiins OS log file name filter; "all" for every file
+	Since time.Duration // only lines newer than this, 0 for all
+}
+
+// cutoff returns the oldest time kept, zero when everything is kept
+func (l LogOptions) cutoff() time.Time {
+	if l.Since <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(-l.Since)
+}
+
+// FetchLogs reads the logs of host. vnish logs come from the logs API;
+// Braiins OS logs are read from the log files in its support archive.
+func FetchLogs(ctx context.Context, opts Options, host string, l LogOptions) ([]LogLine, error) {
+	opts, err := opts.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []LogLine
+	switch opts.Firmware {
+	case FirmwareVnish:
+		entries, err := opts.Vnish(host).GetLogs(ctx, l.Type)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get logs: %w", err)
+		}
+		for _, e := range entries {
+			lines = append(lines, LogLine{
+				Time:    e.Timestamp.UTC(),
+				Host:    host,
+				Type:    l.Type,
+				Level:   e.Level,
+				Source:  e.Source,
+				Message: e.Message,
+			})
+		}
+
+	case FirmwareBraiins:
+		c, err := opts.Braiins(host)
+		if err != nil {
+			return nil, err
+		}
+		defer c.Close()
+
+		stream, err := c.GetSupportArchive(ctx, pb.SupportArchiveFormat_SUPPORT_ARCHIVE_FORMAT_ZIP)
+		if err != nil {
+			return nil, fmt.Errorf("failed to request support archive: %w", err)
+		}
+		var buf bytes.Buffer
+		if _, _, err := copyArchive(&buf, stream, maxLogArchiveSize); err != nil {
+			return nil, err
+		}
+		lines, err = ArchiveLogs(bytes.NewReader(buf.Bytes()), int64(buf.Len()), host, l.Type)
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("log collection is not supported for %s firmware", opts.Firmware)
+	}
+
+	return filterSince(lines, l.cutoff()), nil
+}
+
+// LogsCleared reports the logs emptied on a miner
+type LogsCleared struct {
+	Host string `json:"host"`
+	Type string `json:"type"`
+}
+
+// ClearLogs empties the logs of logType on a vnish miner, e.g. once they
+// were collected. Braiins OS logs cannot be cleared through its API.
+func ClearLogs(ctx context.Context, opts Options, host, logType string) (*LogsCleared, error) {
+	opts, err := opts.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Firmware != FirmwareVnish {
+		return nil, fmt.Errorf("clearing logs is not supported for %s firmware", opts.Firmware)
+	}
+	if err := ensureUnlocked(ctx, opts, host); err != nil {
+		return nil, err
+	}
+	if err := opts.Vnish(host).ClearLogs(ctx, logType); err != nil {
+		return nil, fmt.Errorf("failed to clear logs: %w", err)
+	}
+	return &LogsCleared{Host: host, Type: logType}, nil
+}
+
+// ArchiveLogs extracts the lines of the log files in a zip support archive.
+// Only files whose name contains logType are read unless it is "all" or
+// empty. Lines without a timestamp inherit the one of the line before.
+func ArchiveLogs(r io.ReaderAt, size int64, host, logType string) ([]LogLine, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open support archive: %w", err)
+	}
+
+	var lines []LogLine
+	for _, f := range zr.File {
+		if !isLogFile(f.Name, logType) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		fileLines, err := parseLogFile(rc, host, path.Base(f.Name), f.Modified)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		lines = append(lines, fileLines...)
+	}
+	return lines, nil
+}
+
+func isLogFile(name, logType string) bool {
+	if strings.HasSuffix(name, "/") || !strings.Contains(strings.ToLower(name), "log") {
+		return false
+	}
+	if logType == "" || logType == "all" {
+		return true
+	}
+	return strings.Contains(strings.ToLower(path.Base(name)), strings.ToLower(logType))
+}
+
+func parseLogFile(r io.Reader, host, source string, modified time.Time) ([]LogLine, error) {
+	var lines []LogLine
+	var last time.Time
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		t, message := parseLogTime(text, modified)
+		if t.IsZero() {
+			t = last
+		} else {
+			last = t
+		}
+		lines = append(lines, LogLine{
+			Time:    t,
+			Host:    host,
+			Type:    strings.TrimSuffix(source, path.Ext(source)),
+			Level:   logLevel(message),
+			Source:  source,
+			Message: message,
+		})
+	}
+	return lines, scanner.Err()
+}
+
+// Fixed width timestamp layouts found at the start of Braiins OS log lines,
+// besides RFC 3339
+var logTimeLayouts = []struct {
+	layout string
+	length int
+}{
+	{"2006-01-02 15:04:05", 19},
+	{time.Stamp, 15},
+}
+
+// parseLogTime splits a leading timestamp off a log line. Syslog stamps have
+// no year and take it from the file modification time.
+func parseLogTime(text string, modified time.Time) (time.Time, string) {
+	field := strings.Fields(text)[0]
+	if t, err := time.Parse(time.RFC3339Nano, field); err == nil {
+		return t.UTC(), strings.TrimSpace(text[len(field):])
+	}
+	for _, l := range logTimeLayouts {
+		if len(text) < l.length {
+			continue
+		}
+		t, err := time.Parse(l.layout, text[:l.length])
+		if err != nil {
+			continue
+		}
+		if t.Year() == 0 {
+			year := modified.Year()
+			if modified.IsZero() {
+				year = time.Now().Year()
+			}
+			t = t.AddDate(year, 0, 0)
+		}
+		return t.UTC(), strings.TrimSpace(text[l.length:])
+	}
+	return time.Time{}, text
+}
+
+// logLevel picks a level keyword out of a free-form log message
+func logLevel(message string) string {
+	upper := strings.ToUpper(message)
+	for _, level := range []string{"ERROR", "WARN", "INFO", "DEBUG"} {
+		if strings.Contains(upper, level) {
+			return strings.ToLower(level)
+		}
+	}
+	return ""
+}
+
+func filterSince(lines []LogLine, cutoff time.Time) []LogLine {
+	if cutoff.IsZero() {
+		return lines
+	}
+	kept := lines[:0]
+	for _, line := range lines {
+		if !line.Time.Before(cutoff) {
+			kept = append(kept, line)
+		}
+	}
+	return kept
+}
+
+// MergeLogs orders lines from several miners by time. Lines with the same
+// time keep their order within a host.
+func MergeLogs(lines []LogLine) []LogLine {
+	sort.SliceStable(lines, func(i, j int) bool {
+		if !lines[i].Time.Equal(lines[j].Time) {
+			return lines[i].Time.Before(lines[j].Time)
+		}
+		return lines[i].Host < lines[j].Host
+	})
+	return lines
+}
+
+// LogPath returns the collection file for host in dir
+func LogPath(dir, host string) string {
+	name := strings.NewReplacer(":", "_", "/", "_").Replace(host)
+	return filepath.Join(dir, name+LogFileExt)
+}
+
+// SaveLogs writes the lines of host into dir as newline delimited JSON,
+// replacing an earlier collection of the host
+func SaveLogs(dir, host string, lines []LogLine) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, line := range lines {
+		if err := enc.Encode(line); err != nil {
+			return fmt.Errorf("failed to marshal log line: %w", err)
+		}
+	}
+	if err := os.WriteFile(LogPath(dir, host), buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write logs: %w", err)
+	}
+	return nil
+}
+
+// LoadLogs reads every host file of a collection in dir, merged by time
+func LoadLogs(dir string) ([]LogLine, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+LogFileExt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list logs: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no logs found in %s, run logs fetch --dir first", dir)
+	}
+
+	var lines []LogLine
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open logs: %w", err)
+		}
+		dec := json.NewDecoder(f)
+		for {
+			var line LogLine
+			err := dec.Decode(&line)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				f.Close()
+				return nil, fmt.Errorf("failed to parse %s: %w", filepath.Base(file), err)
+			}
+			lines = append(lines, line)
+		}
+		f.Close()
+	}
+	return MergeLogs(lines), nil
+}
+
+// GrepOptions filters a log collection
+type GrepOptions struct {
+	Pattern *regexp.Regexp
+	Hosts   map[string]bool // empty for all hosts
+	Level   string
+	Since   time.Duration
+}
+
+// GrepLogs returns the lines matching g, keeping their order
+func GrepLogs(lines []LogLine, g GrepOptions) []LogLine {
+	cutoff := LogOptions{Since: g.Since}.cutoff()
+	var matched []LogLine
+	for _, line := range lines {
+		if len(g.Hosts) > 0 && !g.Hosts[line.Host] {
+			continue
+		}
+		if g.Level != "" && !strings.EqualFold(line.Level, g.Level) {
+			continue
+		}
+		if !cutoff.IsZero() && line.Time.Before(cutoff) {
+			continue
+		}
+		if g.Pattern != nil && !g.Pattern.MatchString(line.Message) && !g.Pattern.MatchString(line.Source) {
+			continue
+		}
+		matched = append(matched, line)
+	}
+	return matched
+}
@@ -0,0 +1,144 @@
+package fleet
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	vmodels "github.com/sinkers/miner-cli/internal/vnish/models"
+)
+
+func TestFetchLogsVnish(t *testing.T) {
+	now := time.Now().UTC()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/logs/miner" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode([]vmodels.LogEntry{
+			{Timestamp: now.Add(-2 * time.Hour), Level: "info", Message: "started"},
+			{Timestamp: now.Add(-10 * time.Minute), Level: "error", Message: "pool disconnected"},
+		})
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	lines, err := FetchLogs(context.Background(), Options{Firmware: FirmwareVnish}, host, LogOptions{Type: "miner", Since: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 1 || lines[0].Message != "pool disconnected" || lines[0].Host != host || lines[0].Type != "miner" {
+		t.Errorf("unexpected lines %+v", lines)
+	}
+}
+
+func TestClearLogs(t *testing.T) {
+	var cleared string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/clear") {
+			cleared = r.URL.Path
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	result, err := ClearLogs(context.Background(), Options{Firmware: FirmwareVnish}, host, "miner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cleared != "/api/v1/logs/miner/clear" || result.Host != host || result.Type != "miner" {
+		t.Errorf("cleared %q, result %+v", cleared, result)
+	}
+
+	if _, err := ClearLogs(context.Background(), Options{Firmware: FirmwareBraiins}, host, "miner"); err == nil {
+		t.Error("expected an error for Braiins OS")
+	}
+}
+
+func TestArchiveLogs(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := map[string]string{
+		"logs/bosminer.log": "2026-10-18T10:00:01Z INFO pool connected\n" +
+			"2026-10-18T10:05:00.5Z ERROR pool disconnected\n" +
+			"  caused by: timeout\n",
+		"logs/messages":   "Oct 18 10:02:00 miner kernel: eth0 link up\n",
+		"config/bos.toml": "[[group]]\n",
+	}
+	for name, content := range files {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Modified: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	zw.Close()
+
+	lines, err := ArchiveLogs(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "10.0.0.1", "all")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines, got %+v", lines)
+	}
+
+	lines = MergeLogs(lines)
+	if lines[1].Source != "messages" || lines[1].Time != time.Date(2026, 10, 18, 10, 2, 0, 0, time.UTC) {
+		t.Errorf("syslog line out of order: %+v", lines[1])
+	}
+	last := lines[3]
+	if last.Message != "caused by: timeout" || !last.Time.Equal(lines[2].Time) {
+		t.Errorf("continuation line should inherit the timestamp: %+v", last)
+	}
+	if lines[2].Level != "error" || lines[2].Type != "bosminer" {
+		t.Errorf("unexpected line %+v", lines[2])
+	}
+
+	miner, err := ArchiveLogs(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "10.0.0.1", "miner")
+	if err != nil || len(miner) != 3 {
+		t.Errorf("expected only bosminer.log, got %d lines: %v", len(miner), err)
+	}
+}
+
+func TestLogCollectionGrep(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Now().UTC().Add(-time.Hour)
+	if err := SaveLogs(dir, "10.0.0.1", []LogLine{
+		{Time: base, Host: "10.0.0.1", Level: "info", Message: "pool connected"},
+		{Time: base.Add(30 * time.Minute), Host: "10.0.0.1", Level: "error", Message: "pool disconnected"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveLogs(dir, "10.0.0.2", []LogLine{
+		{Time: base.Add(10 * time.Minute), Host: "10.0.0.2", Level: "error", Message: "Pool Disconnected"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := LoadLogs(dir)
+	if err != nil || len(lines) != 3 || lines[1].Host != "10.0.0.2" {
+		t.Fatalf("LoadLogs: %v %+v", err, lines)
+	}
+
+	matched := GrepLogs(lines, GrepOptions{Pattern: regexp.MustCompile("(?i)disconnect")})
+	if len(matched) != 2 {
+		t.Errorf("expected 2 matches, got %+v", matched)
+	}
+	matched = GrepLogs(lines, GrepOptions{Pattern: regexp.MustCompile("pool"), Hosts: map[string]bool{"10.0.0.1": true}, Since: 45 * time.Minute})
+	if len(matched) != 1 || matched[0].Level != "error" {
+		t.Errorf("unexpected filtered matches %+v", matched)
+	}
+
+	if _, err := LoadLogs(t.TempDir()); err == nil {
+		t.Error("expected an error for an empty collection")
+	}
+}
@@ -0,0 +1,294 @@
+package fleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+
+	pb "github.com/sinkers/miner-cli/internal/braiins/bos/v1"
+	"github.com/sinkers/miner-cli/internal/client"
+	"github.com/sinkers/miner-cli/internal/inventory"
+)
+
+// Network protocols
+const (
+	NetworkDHCP   = "dhcp"
+	NetworkStatic = "static"
+)
+
+// NetworkSettings is the network configuration and active address of a miner
+type NetworkSettings struct {
+	MAC      string   `json:"mac"`
+	Hostname string   `json:"hostname"`
+	Protocol string   `json:"protocol"`
+	Address  string   `json:"address,omitempty"`
+	Netmask  string   `json:"netmask,omitempty"`
+	Gateway  string   `json:"gateway,omitempty"`
+	DNS      []string `json:"dns,omitempty"`
+}
+
+// NetworkChange describes a network update. Empty fields are left unchanged.
+type NetworkChange struct {
+	Hostname string
+	DHCP     bool
+	Static   *inventory.NetworkMapping
+}
+
+// GetNetwork reads the network configuration of a Braiins OS miner
+func GetNetwork(ctx context.Context, opts Options, host string) (*NetworkSettings, error) {
+	c, err := opts.Braiins(host)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	info, err := c.GetNetworkInfo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network info: %w", err)
+	}
+	cfg, err := c.GetNetworkConfiguration()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network configuration: %w", err)
+	}
+
+	return networkFromBraiins(info, cfg.GetNetwork()), nil
+}
+
+// SetNetwork applies change to a Braiins OS miner and returns the resulting
+// configuration
+func SetNetwork(ctx context.Context, opts Options, host string, change NetworkChange) (*NetworkSettings, error) {
+	req, err := braiinsNetworkRequest(change)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := opts.Braiins(host)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	resp, err := c.SetNetworkConfiguration(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set network configuration: %w", err)
+	}
+	return networkFromBraiins(nil, resp.GetNetwork()), nil
+}
+
+func braiinsNetworkRequest(change NetworkChange) (*pb.SetNetworkConfigurationRequest, error) {
+	req := &pb.SetNetworkConfigurationRequest{}
+
+	switch {
+	case change.DHCP && change.Static != nil:
+		return nil, fmt.Errorf("cannot set both DHCP and static addressing")
+	case change.DHCP:
+		req.Protocol = &pb.SetNetworkConfigurationRequest_Dhcp{Dhcp: &pb.Dhcp{}}
+	case change.Static != nil:
+		if err := change.Static.Validate(); err != nil {
+			return nil, err
+		}
+		req.Protocol = &pb.SetNetworkConfigurationRequest_Static{Static: &pb.Static{
+			Address:    change.Static.IP,
+			Netmask:    change.Static.Netmask,
+			Gateway:    change.Static.Gateway,
+			DnsServers: change.Static.DNS,
+		}}
+		if change.Hostname == "" {
+			change.Hostname = change.Static.Hostname
+		}
+	}
+
+	if change.Hostname != "" {
+		hostname := change.Hostname
+		req.Hostname = &hostname
+	}
+	if req.Protocol == nil && req.Hostname == nil {
+		return nil, fmt.Errorf("no network change specified")
+	}
+	return req, nil
+}
+
+// networkFromBraiins combines the active network info, when available, with
+// the stored configuration
+func networkFromBraiins(info *pb.GetNetworkInfoResponse, cfg *pb.NetworkConfiguration) *NetworkSettings {
+	s := &NetworkSettings{Hostname: cfg.GetHostname()}
+
+	if info != nil {
+		s.MAC = normalizeMAC(info.GetMacAddress())
+		s.Gateway = info.GetDefaultGateway()
+		s.DNS = info.GetDnsServers()
+		if networks := info.GetNetworks(); len(networks) > 0 {
+			s.Address = networks[0].GetAddress()
+			s.Netmask = networks[0].GetNetmask()
+		}
+	}
+
+	switch p := cfg.GetProtocol().(type) {
+	case *pb.NetworkConfiguration_Dhcp:
+		s.Protocol = NetworkDHCP
+	case *pb.NetworkConfiguration_Static:
+		s.Protocol = NetworkStatic
+		s.Address = p.Static.GetAddress()
+		s.Netmask = p.Static.GetNetmask()
+		s.Gateway = p.Static.GetGateway()
+		s.DNS = p.Static.GetDnsServers()
+	}
+
+	return s
+}
+
+func normalizeMAC(mac string) string {
+	if hw, err := net.ParseMAC(mac); err == nil {
+		return hw.String()
+	}
+	return mac
+}
+
+// MigrationStep moves one miner to its planned static address
+type MigrationStep struct {
+	Host string                   `json:"host"`
+	MAC  string                   `json:"mac"`
+	To   inventory.NetworkMapping `json:"to"`
+}
+
+// MigrationPlan matches scanned miners to the network mapping by MAC address
+type MigrationPlan struct {
+	Steps     []MigrationStep `json:"steps"`
+	Unmatched []string        `json:"unmatched,omitempty"` // mapping MACs not found on the network
+	Unmapped  []string        `json:"unmapped,omitempty"`  // scanned hosts without a mapping
+	Conflicts []string        `json:"conflicts,omitempty"`
+}
+
+// PlanMigration builds a static addressing plan from GetNetwork results.
+// A target address that is the current address of another scanned miner is
+// reported as a conflict.
+func PlanMigration(results []client.Result, mappings []inventory.NetworkMapping) *MigrationPlan {
+	plan := &MigrationPlan{}
+
+	byMAC := make(map[string]string)
+	inUse := make(map[string]string)
+	for _, r := range results {
+		s, ok := r.Response.(*NetworkSettings)
+		if r.Error != "" || !ok {
+			continue
+		}
+		if other, dup := byMAC[s.MAC]; dup && s.MAC != "" {
+			plan.Conflicts = append(plan.Conflicts, fmt.Sprintf("MAC %s reported by both %s and %s", s.MAC, other, r.IP))
+			continue
+		}
+		byMAC[s.MAC] = r.IP
+		inUse[r.IP] = r.IP
+		if s.Address != "" {
+			inUse[s.Address] = r.IP
+		}
+	}
+
+	mapped := make(map[string]bool)
+	for _, m := range mappings {
+		host, ok := byMAC[m.MAC]
+		if !ok {
+			plan.Unmatched = append(plan.Unmatched, m.MAC)
+			continue
+		}
+		mapped[host] = true
+		if other, ok := inUse[m.IP]; ok && other != host {
+			plan.Conflicts = append(plan.Conflicts, fmt.Sprintf("%s for %s is in use by miner %s", m.IP, m.MAC, other))
+		}
+		plan.Steps = append(plan.Steps, MigrationStep{Host: host, MAC: m.MAC, To: m})
+	}
+
+	for _, r := range results {
+		if r.Error == "" && !mapped[r.IP] {
+			plan.Unmapped = append(plan.Unmapped, r.IP)
+		}
+	}
+
+	sort.Slice(plan.Steps, func(i, j int) bool {
+		return plan.Steps[i].Host < plan.Steps[j].Host
+	})
+	sort.Strings(plan.Unmapped)
+	return plan
+}
+
+// ProbeConflicts reports target addresses that already answer on the
+// network, excluding miners that keep their current address. The addresses
+// are probed in parallel by runner.
+func (p *MigrationPlan) ProbeConflicts(ctx context.Context, runner *Runner, ports []int, timeout time.Duration) {
+	var targets []string
+	for _, step := range p.Steps {
+		if step.To.IP != step.Host {
+			targets = append(targets, step.To.IP)
+		}
+	}
+	inUse := make(map[string]bool)
+	for _, r := range runner.Run(ctx, targets, 0, "network probe", func(ctx context.Context, ip string) (interface{}, error) {
+		return addressInUse(ctx, ip, ports, timeout), nil
+	}) {
+		inUse[r.IP] = r.Response == true
+	}
+
+	for _, step := range p.Steps {
+		if step.To.IP != step.Host && inUse[step.To.IP] {
+			p.Conflicts = append(p.Conflicts, fmt.Sprintf("%s for %s already answers on the network", step.To.IP, step.MAC))
+		}
+	}
+}
+
+// addressInUse reports whether a host answers on ip. A refused connection
+// still means the address is taken.
+func addressInUse(ctx context.Context, ip string, ports []int, timeout time.Duration) bool {
+	dialer := &net.Dialer{Timeout: timeout}
+	for _, port := range ports {
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+		if err == nil {
+			conn.Close()
+			return true
+		}
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			return true
+		}
+	}
+	return false
+}
+
+// MigrationResult reports the outcome of one migration step
+type MigrationResult struct {
+	MAC      string `json:"mac"`
+	Address  string `json:"address"`
+	Verified bool   `json:"verified"`
+}
+
+// Migrate switches a miner to its static address and confirms that it
+// answers on the new address with the same MAC. The miner may drop the
+// connection before replying, so a failed request is still verified.
+func Migrate(ctx context.Context, opts Options, step MigrationStep, verifyTimeout time.Duration) (*MigrationResult, error) {
+	to := step.To
+	_, setErr := SetNetwork(ctx, opts, step.Host, NetworkChange{Static: &to})
+
+	result := &MigrationResult{MAC: step.MAC, Address: to.IP}
+	deadline := time.Now().Add(verifyTimeout)
+	for {
+		if s, err := GetNetwork(ctx, opts, to.IP); err == nil && s.MAC == step.MAC {
+			result.Verified = true
+			return result, nil
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+
+	if setErr != nil {
+		return nil, setErr
+	}
+	return nil, fmt.Errorf("miner %s did not answer on %s within %s", step.MAC, to.IP, verifyTimeout)
+}
@@ -0,0 +1,137 @@
+package fleet
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/sinkers/miner-cli/internal/braiins/bos/v1"
+	"github.com/sinkers/miner-cli/internal/client"
+	"github.com/sinkers/miner-cli/internal/inventory"
+)
+
+func TestBraiinsNetworkRequest(t *testing.T) {
+	static := &inventory.NetworkMapping{
+		MAC: "aa:bb:cc:00:00:01", IP: "10.1.0.10", Netmask: "255.255.255.0",
+		Gateway: "10.1.0.1", DNS: []string{"1.1.1.1"}, Hostname: "r1-s1",
+	}
+
+	req, err := braiinsNetworkRequest(NetworkChange{Static: static})
+	if err != nil {
+		t.Fatalf("braiinsNetworkRequest failed: %v", err)
+	}
+	s := req.GetStatic()
+	if s.GetAddress() != "10.1.0.10" || s.GetGateway() != "10.1.0.1" || !reflect.DeepEqual(s.GetDnsServers(), []string{"1.1.1.1"}) {
+		t.Errorf("unexpected static config: %v", s)
+	}
+	if req.GetHostname() != "r1-s1" {
+		t.Errorf("expected hostname from mapping, got %q", req.GetHostname())
+	}
+
+	req, err = braiinsNetworkRequest(NetworkChange{DHCP: true})
+	if err != nil || req.GetDhcp() == nil || req.Hostname != nil {
+		t.Errorf("unexpected DHCP request: %v, %v", req, err)
+	}
+
+	if _, err := braiinsNetworkRequest(NetworkChange{}); err == nil {
+		t.Error("expected error for empty change")
+	}
+	if _, err := braiinsNetworkRequest(NetworkChange{DHCP: true, Static: static}); err == nil {
+		t.Error("expected error for DHCP and static")
+	}
+}
+
+func TestNetworkFromBraiins(t *testing.T) {
+	mac, gateway := "AA-BB-CC-00-00-01", "10.0.0.1"
+	info := &pb.GetNetworkInfoResponse{
+		MacAddress:     &mac,
+		DefaultGateway: &gateway,
+		DnsServers:     []string{"10.0.0.1"},
+		Networks:       []*pb.IpNetwork{{Address: "10.0.0.50", Netmask: "255.255.255.0"}},
+	}
+	cfg := &pb.NetworkConfiguration{
+		Hostname: "miner-1",
+		Protocol: &pb.NetworkConfiguration_Dhcp{Dhcp: &pb.Dhcp{}},
+	}
+
+	s := networkFromBraiins(info, cfg)
+	want := &NetworkSettings{
+		MAC: "aa:bb:cc:00:00:01", Hostname: "miner-1", Protocol: NetworkDHCP,
+		Address: "10.0.0.50", Netmask: "255.255.255.0", Gateway: "10.0.0.1", DNS: []string{"10.0.0.1"},
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("expected %+v, got %+v", want, s)
+	}
+}
+
+func TestPlanMigration(t *testing.T) {
+	results := []client.Result{
+		{IP: "10.0.0.50", Response: &NetworkSettings{MAC: "aa:bb:cc:00:00:01", Address: "10.0.0.50"}},
+		{IP: "10.0.0.51", Response: &NetworkSettings{MAC: "aa:bb:cc:00:00:02", Address: "10.0.0.51"}},
+		{IP: "10.0.0.52", Response: &NetworkSettings{MAC: "aa:bb:cc:00:00:03", Address: "10.0.0.52"}},
+		{IP: "10.0.0.53", Error: "connection refused"},
+	}
+	mappings := []inventory.NetworkMapping{
+		{MAC: "aa:bb:cc:00:00:01", IP: "10.1.0.10"},
+		{MAC: "aa:bb:cc:00:00:02", IP: "10.0.0.52"}, // taken by another miner
+		{MAC: "aa:bb:cc:00:00:09", IP: "10.1.0.19"},
+	}
+
+	plan := PlanMigration(results, mappings)
+
+	if len(plan.Steps) != 2 || plan.Steps[0].Host != "10.0.0.50" || plan.Steps[0].To.IP != "10.1.0.10" {
+		t.Errorf("unexpected steps: %+v", plan.Steps)
+	}
+	if !reflect.DeepEqual(plan.Unmatched, []string{"aa:bb:cc:00:00:09"}) {
+		t.Errorf("unexpected unmatched: %v", plan.Unmatched)
+	}
+	if !reflect.DeepEqual(plan.Unmapped, []string{"10.0.0.52"}) {
+		t.Errorf("unexpected unmapped: %v", plan.Unmapped)
+	}
+	if len(plan.Conflicts) != 1 || !strings.Contains(plan.Conflicts[0], "in use by miner 10.0.0.52") {
+		t.Errorf("unexpected conflicts: %v", plan.Conflicts)
+	}
+}
+
+func TestPlanMigrationKeepAddress(t *testing.T) {
+	results := []client.Result{
+		{IP: "10.0.0.50", Response: &NetworkSettings{MAC: "aa:bb:cc:00:00:01", Address: "10.0.0.50"}},
+	}
+	plan := PlanMigration(results, []inventory.NetworkMapping{{MAC: "aa:bb:cc:00:00:01", IP: "10.0.0.50"}})
+	if len(plan.Conflicts) != 0 || len(plan.Steps) != 1 {
+		t.Errorf("expected DHCP to static on the same address to be allowed: %+v", plan)
+	}
+
+	// the miner itself answers on its target address
+	plan.ProbeConflicts(context.Background(), NewRunner(2), []int{closedPort(t)}, 100*time.Millisecond)
+	if len(plan.Conflicts) != 0 {
+		t.Errorf("unexpected conflicts: %v", plan.Conflicts)
+	}
+}
+
+func TestAddressInUse(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if !addressInUse(context.Background(), "127.0.0.1", []int{l.Addr().(*net.TCPAddr).Port}, time.Second) {
+		t.Error("expected listening address to be in use")
+	}
+	if !addressInUse(context.Background(), "127.0.0.1", []int{closedPort(t)}, time.Second) {
+		t.Error("expected refused connection to count as in use")
+	}
+
+	plan := &MigrationPlan{Steps: []MigrationStep{
+		{Host: "10.0.0.5", MAC: "aa:bb:cc:00:00:05", To: inventory.NetworkMapping{IP: "127.0.0.1"}},
+		{Host: "127.0.0.2", MAC: "aa:bb:cc:00:00:06", To: inventory.NetworkMapping{IP: "127.0.0.2"}},
+	}}
+	plan.ProbeConflicts(context.Background(), NewRunner(2), []int{l.Addr().(*net.TCPAddr).Port}, time.Second)
+	if len(plan.Conflicts) != 1 || !strings.Contains(plan.Conflicts[0], "aa:bb:cc:00:00:05") {
+		t.Errorf("conflicts = %v", plan.Conflicts)
+	}
+}
@@ -0,0 +1,292 @@
+package fleet
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/client"
+)
+
+// Where a note is kept
+const (
+	NoteOnMiner = "miner"
+	NoteLocal   = "local"
+)
+
+// MaintenanceNote is a structured maintenance record. On vnish it is kept
+// as JSON in the content of a miner note; other firmware has no notes API
+// and uses a local file per host.
+type MaintenanceNote struct {
+	ID       string    `json:"id,omitempty"`
+	Host     string    `json:"host,omitempty"`
+	Time     time.Time `json:"time"`
+	Author   string    `json:"author,omitempty"`
+	Category string    `json:"category,omitempty"`
+	Text     string    `json:"text"`
+	Stored   string    `json:"stored,omitempty"`
+	Warning  string    `json:"warning,omitempty"` // reported when added, never stored
+}
+
+// Validate checks that the note has text
+func (n MaintenanceNote) Validate() error {
+	if strings.TrimSpace(n.Text) == "" {
+		return fmt.Errorf("note text is required")
+	}
+	return nil
+}
+
+// encode returns the content stored in a vnish note
+func (n MaintenanceNote) encode() (string, error) {
+	n.ID, n.Host, n.Stored = "", "", ""
+	data, err := json.Marshal(n)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal note: %w", err)
+	}
+	return string(data), nil
+}
+
+// decodeNote reads a vnish note. Notes written elsewhere, such as the web
+// interface, are plain text and keep the miner's creation time.
+func decodeNote(host, id, content string, created time.Time) MaintenanceNote {
+	var n MaintenanceNote
+	if err := json.Unmarshal([]byte(content), &n); err != nil || n.Text == "" {
+		n = MaintenanceNote{Time: created.UTC(), Text: content}
+	}
+	n.ID = id
+	n.Host = host
+	n.Stored = NoteOnMiner
+	return n
+}
+
+// DefaultNotesDir returns the directory of the local notes used for miners
+// without a notes API
+func DefaultNotesDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "notes"
+	}
+	return filepath.Join(dir, "miner-cli", "notes")
+}
+
+// AddNote records a note for host, on the miner for vnish and in dir
+// otherwise. A miner whose firmware cannot be detected gets the note in dir
+// with a warning saying so.
+func AddNote(ctx context.Context, opts Options, host, dir string, n MaintenanceNote) (*MaintenanceNote, error) {
+	if err := n.Validate(); err != nil {
+		return nil, err
+	}
+	opts, err := opts.resolve(ctx, host)
+	if err != nil {
+		added, lerr := addLocalNote(dir, host, n)
+		if lerr != nil {
+			return nil, lerr
+		}
+		added.Warning = fmt.Sprintf("kept in the local notes, firmware not detected: %v", err)
+		return added, nil
+	}
+
+	if opts.Firmware != FirmwareVnish {
+		return addLocalNote(dir, host, n)
+	}
+
+	if err := ensureUnlocked(ctx, opts, host); err != nil {
+		return nil, err
+	}
+	content, err := n.encode()
+	if err != nil {
+		return nil, err
+	}
+	created, err := opts.Vnish(host).CreateNote(ctx, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create note: %w", err)
+	}
+	added := decodeNote(host, created.ID, content, created.CreatedAt)
+	return &added, nil
+}
+
+// ListNotes returns the notes of host, oldest first. Notes of vnish miners
+// include those kept locally while the miner was unreachable.
+func ListNotes(ctx context.Context, opts Options, host, dir string) ([]MaintenanceNote, error) {
+	opts, err := opts.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Firmware != FirmwareVnish {
+		return loadLocalNotes(dir, host)
+	}
+
+	stored, err := opts.Vnish(host).GetNotes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notes: %w", err)
+	}
+	notes, err := loadLocalNotes(dir, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range stored {
+		notes = append(notes, decodeNote(host, s.ID, s.Content, s.CreatedAt))
+	}
+	sortNotes(notes)
+	return notes, nil
+}
+
+// localNotesPath returns the local notes file of host in dir
+func localNotesPath(dir, host string) string {
+	name := strings.NewReplacer(":", "_", "/", "_").Replace(host)
+	return filepath.Join(dir, name+".json")
+}
+
+func loadLocalNotes(dir, host string) ([]MaintenanceNote, error) {
+	data, err := os.ReadFile(localNotesPath(dir, host))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notes: %w", err)
+	}
+
+	var notes []MaintenanceNote
+	if err := json.Unmarshal(data, &notes); err != nil {
+		return nil, fmt.Errorf("failed to parse notes: %w", err)
+	}
+	for i := range notes {
+		notes[i].Host = host
+		notes[i].Stored = NoteLocal
+	}
+	sortNotes(notes)
+	return notes, nil
+}
+
+func addLocalNote(dir, host string, n MaintenanceNote) (*MaintenanceNote, error) {
+	notes, err := loadLocalNotes(dir, host)
+	if err != nil {
+		return nil, err
+	}
+
+	n.ID = fmt.Sprint(len(notes) + 1)
+	n.Host = host
+	n.Stored = NoteLocal
+	notes = append(notes, n)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create notes directory: %w", err)
+	}
+	data, err := json.MarshalIndent(notes, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal notes: %w", err)
+	}
+	if err := os.WriteFile(localNotesPath(dir, host), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write notes: %w", err)
+	}
+	return &n, nil
+}
+
+func sortNotes(notes []MaintenanceNote) {
+	sort.SliceStable(notes, func(i, j int) bool {
+		if !notes[i].Time.Equal(notes[j].Time) {
+			return notes[i].Time.Before(notes[j].Time)
+		}
+		return notes[i].Host < notes[j].Host
+	})
+}
+
+// NoteFilter selects notes for notes search
+type NoteFilter struct {
+	Pattern  *regexp.Regexp // matched against text, category and author
+	Category string
+	Author   string
+	Since    time.Duration
+}
+
+// FilterNotes returns the notes matching f, keeping their order
+func FilterNotes(notes []MaintenanceNote, f NoteFilter) []MaintenanceNote {
+	var cutoff time.Time
+	if f.Since > 0 {
+		cutoff = time.Now().Add(-f.Since)
+	}
+
+	var matched []MaintenanceNote
+	for _, n := range notes {
+		if f.Category != "" && !strings.EqualFold(n.Category, f.Category) {
+			continue
+		}
+		if f.Author != "" && !strings.EqualFold(n.Author, f.Author) {
+			continue
+		}
+		if !cutoff.IsZero() && n.Time.Before(cutoff) {
+			continue
+		}
+		if f.Pattern != nil && !f.Pattern.MatchString(n.Text) && !f.Pattern.MatchString(n.Category) && !f.Pattern.MatchString(n.Author) {
+			continue
+		}
+		matched = append(matched, n)
+	}
+	return matched
+}
+
+// MaintenanceHistory is the fleet-wide list of notes
+type MaintenanceHistory struct {
+	Scanned     int               `json:"scanned"`
+	Notes       []MaintenanceNote `json:"notes"`
+	Unreachable map[string]string `json:"unreachable,omitempty"` // host to error
+}
+
+// CollectNotes merges the ListNotes results of a fleet run, oldest first
+func CollectNotes(results []client.Result) *MaintenanceHistory {
+	history := &MaintenanceHistory{Scanned: len(results), Unreachable: make(map[string]string)}
+	for _, r := range results {
+		if r.Error != "" {
+			history.Unreachable[r.IP] = r.Error
+			continue
+		}
+		notes, _ := r.Response.([]MaintenanceNote)
+		history.Notes = append(history.Notes, notes...)
+	}
+	sortNotes(history.Notes)
+	return history
+}
+
+// ExportNotes writes notes to path as CSV when it ends in .csv, JSON
+// otherwise
+func ExportNotes(path string, notes []MaintenanceNote) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create export: %w", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		err = writeNotesCSV(f, notes)
+	} else {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(notes)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
+
+func writeNotesCSV(w io.Writer, notes []MaintenanceNote) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "host", "category", "author", "text", "stored"})
+	for _, n := range notes {
+		cw.Write([]string{n.Time.Format(time.RFC3339), n.Host, n.Category, n.Author, n.Text, n.Stored})
+	}
+	cw.Flush()
+	return cw.Error()
+}
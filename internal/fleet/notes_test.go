@@ -0,0 +1,148 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/client"
+	vmodels "github.com/sinkers/miner-cli/internal/vnish/models"
+)
+
+// fakeNotesMiner is a vnish notes API
+func fakeNotesMiner(t *testing.T, notes []vmodels.Note) string {
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path != "/api/v1/notes" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodPost {
+			var req vmodels.CreateNoteRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			note := vmodels.Note{ID: fmt.Sprintf("n%d", len(notes)), Content: req.Content, CreatedAt: time.Now()}
+			notes = append(notes, note)
+			json.NewEncoder(w).Encode(note)
+			return
+		}
+		json.NewEncoder(w).Encode(notes)
+	}))
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+func TestNotesVnish(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	host := fakeNotesMiner(t, []vmodels.Note{{ID: "n0", Content: "written in the web UI", CreatedAt: created}})
+	opts := Options{Firmware: FirmwareVnish}
+
+	note := MaintenanceNote{Time: time.Now().UTC(), Author: "alice", Category: "repair", Text: "replaced fan 2"}
+	added, err := AddNote(context.Background(), opts, host, t.TempDir(), note)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added.Stored != NoteOnMiner || added.ID != "n1" {
+		t.Errorf("unexpected note %+v", added)
+	}
+
+	notes, err := ListNotes(context.Background(), opts, host, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 2 {
+		t.Fatalf("expected 2 notes, got %+v", notes)
+	}
+	if notes[0].Text != "written in the web UI" || !notes[0].Time.Equal(created) {
+		t.Errorf("plain note not kept: %+v", notes[0])
+	}
+	if notes[1].Author != "alice" || notes[1].Category != "repair" || notes[1].Host != host {
+		t.Errorf("structured note not decoded: %+v", notes[1])
+	}
+}
+
+func TestNotesLocalFallback(t *testing.T) {
+	dir := t.TempDir()
+	opts := Options{Firmware: FirmwareCGMiner}
+
+	for _, text := range []string{"swapped PSU", "cleaned filters"} {
+		if _, err := AddNote(context.Background(), opts, "10.0.0.1", dir, MaintenanceNote{Time: time.Now().UTC(), Text: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := AddNote(context.Background(), opts, "10.0.0.1", dir, MaintenanceNote{Text: " "}); err == nil {
+		t.Error("expected an error for an empty note")
+	}
+
+	notes, err := ListNotes(context.Background(), opts, "10.0.0.1", dir)
+	if err != nil || len(notes) != 2 || notes[1].ID != "2" || notes[0].Stored != NoteLocal {
+		t.Fatalf("ListNotes: %v %+v", err, notes)
+	}
+	if notes, err := ListNotes(context.Background(), opts, "10.0.0.2", dir); err != nil || len(notes) != 0 {
+		t.Errorf("expected no notes for a new host: %v %+v", err, notes)
+	}
+}
+
+func TestNotesUnreachableAuto(t *testing.T) {
+	dir := t.TempDir()
+	host := fmt.Sprintf("127.0.0.1:%d", closedPort(t))
+	opts := Options{Firmware: FirmwareAuto, Port: closedPort(t), GRPCPort: closedPort(t), Timeout: time.Second}
+
+	added, err := AddNote(context.Background(), opts, host, dir, MaintenanceNote{Time: time.Now().UTC(), Text: "no power on PSU 1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added.Stored != NoteLocal || !strings.Contains(added.Warning, "firmware not detected") {
+		t.Errorf("unexpected note %+v", added)
+	}
+
+	notes, err := loadLocalNotes(dir, host)
+	if err != nil || len(notes) != 1 || notes[0].Warning != "" {
+		t.Errorf("local notes: %v %+v", err, notes)
+	}
+}
+
+func TestCollectFilterExportNotes(t *testing.T) {
+	now := time.Now().UTC()
+	results := []client.Result{
+		{IP: "10.0.0.2", Response: []MaintenanceNote{{Host: "10.0.0.2", Time: now.Add(-time.Hour), Category: "repair", Text: "hashboard 1 replaced"}}},
+		{IP: "10.0.0.1", Response: []MaintenanceNote{
+			{Host: "10.0.0.1", Time: now.Add(-48 * time.Hour), Category: "repair", Text: "Hashboard 3 reseated"},
+			{Host: "10.0.0.1", Time: now.Add(-2 * time.Hour), Category: "fan", Author: "bob", Text: "fan swapped"},
+		}},
+		{IP: "10.0.0.3", Error: "timeout"},
+	}
+
+	history := CollectNotes(results)
+	if len(history.Notes) != 3 || history.Notes[0].Text != "Hashboard 3 reseated" || len(history.Unreachable) != 1 {
+		t.Fatalf("unexpected history %+v", history)
+	}
+
+	matched := FilterNotes(history.Notes, NoteFilter{Pattern: regexp.MustCompile("(?i)hashboard"), Since: 24 * time.Hour})
+	if len(matched) != 1 || matched[0].Host != "10.0.0.2" {
+		t.Errorf("unexpected matches %+v", matched)
+	}
+	if matched := FilterNotes(history.Notes, NoteFilter{Category: "FAN"}); len(matched) != 1 {
+		t.Errorf("unexpected category matches %+v", matched)
+	}
+
+	path := filepath.Join(t.TempDir(), "history.csv")
+	if err := ExportNotes(path, history.Notes); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 4 || lines[0] != "time,host,category,author,text,stored" {
+		t.Errorf("unexpected CSV %q", data)
+	}
+}
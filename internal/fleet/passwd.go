@@ -0,0 +1,75 @@
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// PasswordChange reports a password rotation and whether a login with the
+// new password confirmed it
+type PasswordChange struct {
+	Username string `json:"username"`
+	Verified bool   `json:"verified"`
+	Warning  string `json:"warning,omitempty"`
+}
+
+// ReadPassword reads a new password from the first line of a file
+func ReadPassword(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password file: %w", err)
+	}
+
+	password := strings.TrimRight(string(data), "\r\n")
+	if strings.ContainsAny(password, "\r\n") {
+		return "", fmt.Errorf("password file must contain a single line")
+	}
+	if strings.TrimSpace(password) == "" {
+		return "", fmt.Errorf("password file is empty")
+	}
+	return password, nil
+}
+
+// ChangePassword sets a new Braiins OS password on host and confirms that a
+// fresh login with the new password succeeds. Once the miner took the new
+// password a failed confirmation is only a warning, so that the new password
+// is still recorded.
+func ChangePassword(ctx context.Context, opts Options, host, newPassword string) (*PasswordChange, error) {
+	current := opts.ForHost(host)
+	if current.Username == "" {
+		return nil, fmt.Errorf("a username is required to change the password")
+	}
+	if current.Password == newPassword {
+		return nil, fmt.Errorf("new password is the same as the current password")
+	}
+
+	c, err := opts.Braiins(host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to log in with current credentials: %w", err)
+	}
+	err = c.SetPassword(ctx, newPassword)
+	c.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to set password: %w", err)
+	}
+
+	change := &PasswordChange{Username: current.Username}
+	if err := ctx.Err(); err != nil {
+		change.Warning = fmt.Sprintf("password changed but not verified: %v", err)
+		return change, nil
+	}
+	verify := current
+	verify.Password = newPassword
+	verify.Credentials = nil
+	vc, err := verify.Braiins(host)
+	if err != nil {
+		change.Warning = fmt.Sprintf("password changed but login with the new password failed: %v", err)
+		return change, nil
+	}
+	vc.Close()
+
+	change.Verified = true
+	return change, nil
+}
@@ -0,0 +1,156 @@
+package fleet
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/sinkers/miner-cli/internal/braiins/bos/v1"
+	"github.com/sinkers/miner-cli/internal/credentials"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeAuth is a Braiins OS authentication service holding one password
+type fakeAuth struct {
+	pb.UnimplementedAuthenticationServiceServer
+
+	mu       sync.Mutex
+	password string
+	lockOut  bool // refuse every login once the password is set
+}
+
+func (f *fakeAuth) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if req.Username != "root" || req.Password != f.password || f.lockOut && f.password != "root" {
+		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+	}
+	return &pb.LoginResponse{Token: "token-" + f.password}, nil
+}
+
+func (f *fakeAuth) SetPassword(ctx context.Context, req *pb.SetPasswordRequest) (*pb.SetPasswordResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	md, _ := metadata.FromIncomingContext(ctx)
+	if tokens := md.Get("authorization"); len(tokens) == 0 || tokens[0] != "token-"+f.password {
+		return nil, status.Error(codes.Unauthenticated, "not logged in")
+	}
+	f.password = req.GetPassword()
+	return &pb.SetPasswordResponse{}, nil
+}
+
+// fakeBraiins starts a gRPC server on localhost and returns its port
+func fakeBraiins(t *testing.T, register func(*grpc.Server)) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	register(srv)
+	go srv.Serve(l)
+	t.Cleanup(srv.Stop)
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestChangePassword(t *testing.T) {
+	auth := &fakeAuth{password: "root"}
+	port := fakeBraiins(t, func(s *grpc.Server) { pb.RegisterAuthenticationServiceServer(s, auth) })
+	opts := Options{Username: "root", Password: "root", GRPCPort: port, Timeout: 5 * time.Second}
+
+	result, err := ChangePassword(context.Background(), opts, "127.0.0.1", "n3w-secret")
+	if err != nil {
+		t.Fatalf("ChangePassword failed: %v", err)
+	}
+	if !result.Verified || result.Username != "root" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if auth.password != "n3w-secret" {
+		t.Errorf("expected password to be changed, got %q", auth.password)
+	}
+
+	// the old password no longer works
+	if _, err := ChangePassword(context.Background(), opts, "127.0.0.1", "another"); err == nil || !strings.Contains(err.Error(), "current credentials") {
+		t.Errorf("expected login failure, got %v", err)
+	}
+}
+
+func TestChangePasswordUnverified(t *testing.T) {
+	auth := &fakeAuth{password: "root", lockOut: true}
+	port := fakeBraiins(t, func(s *grpc.Server) { pb.RegisterAuthenticationServiceServer(s, auth) })
+	opts := Options{Username: "root", Password: "root", GRPCPort: port, Timeout: 5 * time.Second}
+
+	result, err := ChangePassword(context.Background(), opts, "127.0.0.1", "n3w-secret")
+	if err != nil {
+		t.Fatalf("expected the change to be reported, got %v", err)
+	}
+	if result.Verified || !strings.Contains(result.Warning, "login with the new password failed") || auth.password != "n3w-secret" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestChangePasswordUsesCredentialStore(t *testing.T) {
+	auth := &fakeAuth{password: "stored"}
+	port := fakeBraiins(t, func(s *grpc.Server) { pb.RegisterAuthenticationServiceServer(s, auth) })
+
+	store := &credentials.Store{}
+	store.Set(credentials.Entry{Name: "local", Hosts: []string{"127.0.0.1"}, Password: "stored"})
+	opts := Options{Username: "root", Password: "root", GRPCPort: port, Timeout: 5 * time.Second, Credentials: store}
+
+	if _, err := ChangePassword(context.Background(), opts, "127.0.0.1", "rotated"); err != nil {
+		t.Fatalf("ChangePassword failed: %v", err)
+	}
+
+	opts.Override.Password = true
+	if _, err := ChangePassword(context.Background(), opts, "127.0.0.1", "again"); err == nil {
+		t.Error("expected the flag password to be used when overriding the store")
+	}
+}
+
+func TestForHost(t *testing.T) {
+	store := &credentials.Store{}
+	store.Set(credentials.Entry{Name: "a", Hosts: []string{"10.0.0.1"}, Password: "pw", APIKey: "key"})
+	opts := Options{Username: "root", Password: "root", Credentials: store}
+
+	got := opts.ForHost("10.0.0.1")
+	if got.Username != "root" || got.Password != "pw" || got.APIKey != "key" {
+		t.Errorf("unexpected options: %+v", got)
+	}
+
+	// only the API key given on the command line
+	opts.APIKey = "flag-key"
+	opts.Override = CredentialOverrides{APIKey: true}
+	got = opts.ForHost("10.0.0.1")
+	if got.Password != "pw" || got.APIKey != "flag-key" {
+		t.Errorf("expected the stored password and the flag API key, got %+v", got)
+	}
+	if got := opts.ForHost("10.0.0.2"); got.Password != "root" {
+		t.Errorf("expected flag password for unmatched host, got %q", got.Password)
+	}
+}
+
+func TestReadPassword(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(content), 0600)
+		return path
+	}
+
+	if pw, err := ReadPassword(write("ok", "s3cret\n")); err != nil || pw != "s3cret" {
+		t.Errorf("unexpected result %q, %v", pw, err)
+	}
+	if _, err := ReadPassword(write("empty", "\n")); err == nil {
+		t.Error("expected error for empty file")
+	}
+	if _, err := ReadPassword(write("multi", "a\nb\n")); err == nil {
+		t.Error("expected error for multiple lines")
+	}
+}
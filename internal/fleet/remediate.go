@@ -0,0 +1,246 @@
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Conditions a remediation playbook can react to
+const (
+	ConditionUnreachable  = "unreachable"
+	ConditionZeroHashrate = "zero_hashrate"
+	ConditionPoolDead     = "pool_dead"
+	ConditionOverTemp     = "over_temp"
+)
+
+// Remediation actions, mildest first
+const (
+	ActionRestart      = "restart"       // restart the mining process
+	ActionReboot       = "reboot"        // reboot the control board
+	ActionDisableBoard = "disable-board" // disable hashboards without hashrate (Braiins OS)
+	ActionNotify       = "notify"        // hand over to a human
+)
+
+// Health is what remediation knows about a miner after one check
+type Health struct {
+	Host       string  `json:"host"`
+	Firmware   string  `json:"firmware,omitempty"`
+	Model      string  `json:"model,omitempty"`
+	Reachable  bool    `json:"reachable"`
+	Error      string  `json:"error,omitempty"`
+	HashRate   float64 `json:"hash_rate"`
+	Temp       float64 `json:"temp,omitempty"`
+	PoolsAlive bool    `json:"pools_alive"`
+	PoolsKnown bool    `json:"pools_known"`
+}
+
+// CheckHealth samples host and checks its pools. An unreachable miner is
+// a health state, not an error.
+func CheckHealth(ctx context.Context, opts Options, host string) (*Health, error) {
+	h := &Health{Host: host}
+	s, err := CollectSample(ctx, opts, host)
+	if err != nil {
+		h.Error = err.Error()
+		return h, nil
+	}
+	h.Reachable = true
+	h.Firmware, h.Model, h.HashRate, h.Temp = s.Firmware, s.Model, s.HashRate, s.Temp
+
+	opts.Firmware = s.Firmware
+	if alive, err := PoolsAlive(ctx, opts, host); err == nil {
+		h.PoolsAlive, h.PoolsKnown = alive, true
+	}
+	return h, nil
+}
+
+// Conditions returns the remediation conditions host is in; maxTemp 0
+// disables the temperature check
+func (h *Health) Conditions(maxTemp float64) []string {
+	if !h.Reachable {
+		return []string{ConditionUnreachable}
+	}
+	var conditions []string
+	if h.HashRate <= 0 {
+		conditions = append(conditions, ConditionZeroHashrate)
+	}
+	if h.PoolsKnown && !h.PoolsAlive {
+		conditions = append(conditions, ConditionPoolDead)
+	}
+	if maxTemp > 0 && h.Temp > maxTemp {
+		conditions = append(conditions, ConditionOverTemp)
+	}
+	return conditions
+}
+
+// PoolsAlive reports whether any enabled pool of host is alive
+func PoolsAlive(ctx context.Context, opts Options, host string) (bool, error) {
+	opts, err := opts.resolve(ctx, host)
+	if err != nil {
+		return false, err
+	}
+
+	switch opts.Firmware {
+	case FirmwareBraiins:
+		c, err := opts.Braiins(host)
+		if err != nil {
+			return false, err
+		}
+		defer c.Close()
+		groups, err := c.GetPoolGroups()
+		if err != nil {
+			return false, fmt.Errorf("failed to get pools: %w", err)
+		}
+		for _, g := range groups.GetPoolGroups() {
+			for _, p := range g.GetPools() {
+				if p.GetEnabled() && p.GetAlive() {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	case FirmwareVnish:
+		summary, err := opts.Vnish(host).GetSummary(ctx)
+		if err != nil {
+			return false, fmt.Errorf("failed to get summary: %w", err)
+		}
+		for _, p := range summary.Pools {
+			switch strings.ToLower(p.Status) {
+			case "", "dead", "offline", "error", "disabled":
+			default:
+				return true, nil
+			}
+		}
+		return false, nil
+	case FirmwareCGMiner:
+		result := opts.CGMiner().Query(host, opts.Port, "pools", nil)
+		if result.Error != "" {
+			return false, fmt.Errorf("failed to get pools: %s", result.Error)
+		}
+		respMap, _ := result.Response.(map[string]interface{})
+		pools, _ := respMap["POOLS"].([]interface{})
+		for _, item := range pools {
+			if p, ok := item.(map[string]interface{}); ok && p["Status"] == "Alive" {
+				return true, nil
+			}
+		}
+		return false, nil
+	default:
+		return false, fmt.Errorf("unsupported firmware %q", opts.Firmware)
+	}
+}
+
+// Remediate runs one remediation action on host. notify is handled by the
+// caller; the other actions use the firmware's restart, reboot and
+// hashboard calls.
+func Remediate(ctx context.Context, opts Options, host, action string) error {
+	opts, err := opts.resolve(ctx, host)
+	if err != nil {
+		return err
+	}
+	if opts.Firmware == FirmwareVnish {
+		if err := ensureUnlocked(ctx, opts, host); err != nil {
+			return err
+		}
+	}
+
+	switch action {
+	case ActionRestart:
+		return restartMining(ctx, opts, host)
+	case ActionReboot:
+		return rebootMiner(ctx, opts, host)
+	case ActionDisableBoard:
+		return disableFailedBoards(opts, host)
+	default:
+		return fmt.Errorf("unknown remediation action %q", action)
+	}
+}
+
+func restartMining(ctx context.Context, opts Options, host string) error {
+	switch opts.Firmware {
+	case FirmwareBraiins:
+		c, err := opts.Braiins(host)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		if err := c.RestartMining(); err != nil {
+			return fmt.Errorf("failed to restart mining: %w", err)
+		}
+		return nil
+	case FirmwareVnish:
+		if err := opts.Vnish(host).RestartMining(ctx); err != nil {
+			return fmt.Errorf("failed to restart mining: %w", err)
+		}
+		return nil
+	case FirmwareCGMiner:
+		if result := opts.CGMiner().Query(host, opts.Port, "restart", nil); result.Error != "" {
+			return fmt.Errorf("failed to restart: %s", result.Error)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported firmware %q", opts.Firmware)
+	}
+}
+
+func rebootMiner(ctx context.Context, opts Options, host string) error {
+	switch opts.Firmware {
+	case FirmwareBraiins:
+		c, err := opts.Braiins(host)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		if err := c.Reboot(); err != nil {
+			return fmt.Errorf("failed to reboot: %w", err)
+		}
+		return nil
+	case FirmwareVnish:
+		if err := opts.Vnish(host).Reboot(ctx); err != nil {
+			return fmt.Errorf("failed to reboot: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("reboot is not supported on %s firmware", opts.Firmware)
+	}
+}
+
+// disableFailedBoards disables the enabled hashboards of a Braiins OS miner
+// that have no hashrate, as long as one working board remains
+func disableFailedBoards(opts Options, host string) error {
+	if opts.Firmware != FirmwareBraiins {
+		return fmt.Errorf("disabling hashboards is only supported on Braiins OS")
+	}
+	c, err := opts.Braiins(host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	boards, err := c.GetHashboards()
+	if err != nil {
+		return fmt.Errorf("failed to get hashboards: %w", err)
+	}
+	var failed []string
+	working := 0
+	for _, b := range boards.GetHashboards() {
+		if !b.GetEnabled() {
+			continue
+		}
+		if b.GetStats().GetRealHashrate().GetLast_5M().GetGigahashPerSecond() > 0 {
+			working++
+		} else {
+			failed = append(failed, b.GetId())
+		}
+	}
+	if len(failed) == 0 {
+		return fmt.Errorf("no enabled hashboard without hashrate")
+	}
+	if working == 0 {
+		return fmt.Errorf("no hashboard is hashing, not disabling all of them")
+	}
+	if _, err := c.DisableHashboards(failed); err != nil {
+		return fmt.Errorf("failed to disable hashboards %s: %w", strings.Join(failed, ", "), err)
+	}
+	return nil
+}
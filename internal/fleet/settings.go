@@ -0,0 +1,256 @@
+package fleet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+
+	vmodels "github.com/sinkers/miner-cli/internal/vnish/models"
+)
+
+// Sections of the vnish settings
+const (
+	SettingsPools       = "pools"
+	SettingsFan         = "fan"
+	SettingsTemperature = "temperature"
+	SettingsAdvanced    = "advanced"
+	SettingsNetwork     = "network"
+)
+
+// SettingsSections lists the sections applied by default. Network settings
+// are per miner and only applied when requested.
+var SettingsSections = []string{SettingsPools, SettingsFan, SettingsTemperature, SettingsAdvanced}
+
+// ValidateSettingsSections checks that every section exists
+func ValidateSettingsSections(sections []string) error {
+	if len(sections) == 0 {
+		return fmt.Errorf("no settings sections selected")
+	}
+	for _, s := range sections {
+		switch s {
+		case SettingsPools, SettingsFan, SettingsTemperature, SettingsAdvanced, SettingsNetwork:
+		default:
+			return fmt.Errorf("invalid settings section %q (expected pools, fan, temperature, advanced or network)", s)
+		}
+	}
+	return nil
+}
+
+// SettingsBackup reports where the settings of a miner were saved
+type SettingsBackup struct {
+	Host         string `json:"host"`
+	File         string `json:"file"`
+	DeviceBackup string `json:"device_backup,omitempty"` // backup file created on the miner
+}
+
+// BackupSettings saves the settings of a vnish miner as JSON in dir, as
+// sent by the miner so that fields models.Settings does not know are kept.
+// With onDevice the miner also keeps a backup of its own.
+func BackupSettings(ctx context.Context, opts Options, host, dir string, onDevice bool) (*SettingsBackup, error) {
+	vc := opts.Vnish(host)
+	raw, err := vc.GetSettingsJSON(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+
+	var data bytes.Buffer
+	if err := json.Indent(&data, raw, "", "  "); err != nil {
+		return nil, fmt.Errorf("failed to format settings: %w", err)
+	}
+	path := SnapshotPath(dir, host)
+	if err := os.WriteFile(path, data.Bytes(), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write settings backup: %w", err)
+	}
+	result := &SettingsBackup{Host: host, File: path}
+
+	if onDevice {
+		resp, err := vc.BackupSettings(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create backup on the miner: %w", err)
+		}
+		if !resp.Success {
+			return nil, fmt.Errorf("miner refused to create a backup: %s", resp.Message)
+		}
+		result.DeviceBackup = resp.Filename
+	}
+	return result, nil
+}
+
+// LoadSettings reads a settings backup or golden settings file
+func LoadSettings(path string) (*vmodels.Settings, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings: %w", err)
+	}
+	var settings vmodels.Settings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("failed to parse settings %s: %w", path, err)
+	}
+	return &settings, nil
+}
+
+// SettingsRestore reports the backup restored to a miner
+type SettingsRestore struct {
+	Host   string `json:"host"`
+	Source string `json:"source"`
+}
+
+// RestoreSettings writes the backup of host found in dir back to the miner.
+// The document is sent as saved, with the fields models.Settings does not
+// know.
+func RestoreSettings(ctx context.Context, opts Options, host, dir string) (*SettingsRestore, error) {
+	path := SnapshotPath(dir, host)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings: %w", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse settings %s: %w", path, err)
+	}
+	if err := ensureUnlocked(ctx, opts, host); err != nil {
+		return nil, err
+	}
+	if err := opts.Vnish(host).UpdateSettingsPartial(ctx, doc); err != nil {
+		return nil, fmt.Errorf("failed to update settings: %w", err)
+	}
+	return &SettingsRestore{Host: host, Source: path}, nil
+}
+
+// RestoreDeviceBackup restores a backup kept on the miner itself
+func RestoreDeviceBackup(ctx context.Context, opts Options, host, filename string) (*SettingsRestore, error) {
+	if err := ensureUnlocked(ctx, opts, host); err != nil {
+		return nil, err
+	}
+	if err := opts.Vnish(host).RestoreSettings(ctx, &vmodels.RestoreRequest{Filename: filename}); err != nil {
+		return nil, fmt.Errorf("failed to restore settings: %w", err)
+	}
+	return &SettingsRestore{Host: host, Source: filename}, nil
+}
+
+// DiffSettings compares two vnish settings value by value
+func DiffSettings(from, to *vmodels.Settings) ([]ConfigChange, error) {
+	a, err := flattenSettings(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := flattenSettings(to)
+	if err != nil {
+		return nil, err
+	}
+	return diffValues(a, b, topField), nil
+}
+
+func flattenSettings(s *vmodels.Settings) (map[string]string, error) {
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal settings: %w", err)
+	}
+	return flattenJSON(raw)
+}
+
+// DiffSettingsHost compares the settings of a miner with golden. Old values
+// are the miner's, new values the golden file's.
+func DiffSettingsHost(ctx context.Context, opts Options, host string, golden *vmodels.Settings) (*ConfigDiff, error) {
+	settings, err := opts.Vnish(host).GetSettings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+	changes, err := DiffSettings(settings, golden)
+	if err != nil {
+		return nil, err
+	}
+	return &ConfigDiff{Host: host, Changes: changes}, nil
+}
+
+// ApplySettings sends the values of the selected sections of golden that
+// differ from the miner's settings, as a partial settings document, and
+// leaves everything else untouched. Lists such as pools are sent whole when
+// they differ. Nothing is sent when the miner already matches or with dryRun.
+func ApplySettings(ctx context.Context, opts Options, host string, golden *vmodels.Settings, sections []string, dryRun bool) (*ConfigApplyResult, error) {
+	vc := opts.Vnish(host)
+	raw, err := vc.GetSettingsJSON(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+	var settings vmodels.Settings
+	var current map[string]interface{}
+	if err := json.Unmarshal(raw, &settings); err != nil {
+		return nil, fmt.Errorf("failed to parse settings: %w", err)
+	}
+	if err := json.Unmarshal(raw, &current); err != nil {
+		return nil, fmt.Errorf("failed to parse settings: %w", err)
+	}
+	changes, err := DiffSettings(&settings, golden)
+	if err != nil {
+		return nil, err
+	}
+	want, err := settingsDocument(golden)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ConfigApplyResult{Applied: []string{}, DryRun: dryRun}
+	partial := make(map[string]interface{})
+	for _, section := range sections {
+		differs := false
+		for _, ch := range changes {
+			if ch.Section == section {
+				result.Changes = append(result.Changes, ch)
+				differs = true
+			}
+		}
+		if !differs {
+			continue
+		}
+		if value, ok := differingValues(current[section], want[section]); ok {
+			partial[section] = value
+		}
+		result.Applied = append(result.Applied, section)
+	}
+
+	if dryRun || len(partial) == 0 {
+		result.Applied = []string{}
+		return result, nil
+	}
+	if err := ensureUnlocked(ctx, opts, host); err != nil {
+		return nil, err
+	}
+	if err := vc.UpdateSettingsPartial(ctx, partial); err != nil {
+		return nil, fmt.Errorf("failed to update settings: %w", err)
+	}
+	return result, nil
+}
+
+// settingsDocument turns settings into their generic JSON form
+func settingsDocument(s *vmodels.Settings) (map[string]interface{}, error) {
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal settings: %w", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode settings: %w", err)
+	}
+	return doc, nil
+}
+
+// differingValues returns the part of want that differs from current:
+// objects keep only their differing keys, other values are taken whole
+func differingValues(current, want interface{}) (interface{}, bool) {
+	wantObj, ok := want.(map[string]interface{})
+	currentObj, isObj := current.(map[string]interface{})
+	if !ok || !isObj {
+		return want, !reflect.DeepEqual(current, want)
+	}
+	out := make(map[string]interface{})
+	for key, value := range wantObj {
+		if diff, ok := differingValues(currentObj[key], value); ok {
+			out[key] = diff
+		}
+	}
+	return out, len(out) > 0
+}
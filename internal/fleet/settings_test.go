@@ -0,0 +1,198 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	vmodels "github.com/sinkers/miner-cli/internal/vnish/models"
+)
+
+// fakeVnishSettings serves GET/POST /settings and on-device backups
+type fakeVnishSettings struct {
+	settings vmodels.Settings
+	updates  int
+	restored string
+	posted   map[string]interface{} // body of the last POST /settings
+	raw      string                 // served instead of settings when set
+}
+
+func (f *fakeVnishSettings) start(t *testing.T) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/settings":
+			if r.Method == http.MethodPost {
+				// vnish merges the posted settings into its own
+				f.posted = nil
+				data, _ := io.ReadAll(r.Body)
+				json.Unmarshal(data, &f.posted)
+				json.Unmarshal(data, &f.settings)
+				f.updates++
+				return
+			}
+			if f.raw != "" {
+				io.WriteString(w, f.raw)
+				return
+			}
+			json.NewEncoder(w).Encode(f.settings)
+		case "/api/v1/settings/backup":
+			json.NewEncoder(w).Encode(vmodels.BackupResponse{Success: true, Filename: "backup-1.tar"})
+		case "/api/v1/settings/restore":
+			var req vmodels.RestoreRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			f.restored = req.Filename
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+func goldenSettings() vmodels.Settings {
+	return vmodels.Settings{
+		Pools:       []vmodels.PoolConfig{{ID: 0, URL: "stratum+tcp://pool:3333", User: "worker", Password: "x", Enabled: true}},
+		Fan:         vmodels.FanSettings{Mode: "auto", TargetTemp: 70},
+		Temperature: vmodels.TempSettings{TargetTemp: 70, HotTemp: 85, DangerousTemp: 95},
+		Advanced:    vmodels.AdvancedSettings{AutoTune: true},
+		Network:     vmodels.NetworkSettings{DHCP: false, IPAddress: "10.0.0.5"},
+	}
+}
+
+func TestBackupAndRestoreSettings(t *testing.T) {
+	f := &fakeVnishSettings{settings: goldenSettings()}
+	host := f.start(t)
+	dir := t.TempDir()
+
+	backup, err := BackupSettings(context.Background(), Options{}, host, dir, true)
+	if err != nil {
+		t.Fatalf("BackupSettings failed: %v", err)
+	}
+	if backup.DeviceBackup != "backup-1.tar" {
+		t.Errorf("unexpected device backup %q", backup.DeviceBackup)
+	}
+	if info, err := os.Stat(backup.File); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected a private backup file, got %v %v", info, err)
+	}
+
+	f.settings = vmodels.Settings{}
+	if _, err := RestoreSettings(context.Background(), Options{}, host, dir); err != nil {
+		t.Fatalf("RestoreSettings failed: %v", err)
+	}
+	want := goldenSettings()
+	if changes, _ := DiffSettings(&f.settings, &want); len(changes) != 0 {
+		t.Errorf("restored settings differ: %+v", changes)
+	}
+
+	if _, err := RestoreDeviceBackup(context.Background(), Options{}, host, "backup-1.tar"); err != nil || f.restored != "backup-1.tar" {
+		t.Errorf("RestoreDeviceBackup: restored %q, err %v", f.restored, err)
+	}
+}
+
+func TestBackupKeepsUnknownSettings(t *testing.T) {
+	f := &fakeVnishSettings{raw: `{"fan": {"mode": "auto"}, "miner": {"overclock": {"preset": "3000"}}}`}
+	host := f.start(t)
+	dir := t.TempDir()
+
+	if _, err := BackupSettings(context.Background(), Options{}, host, dir, false); err != nil {
+		t.Fatalf("BackupSettings failed: %v", err)
+	}
+	if _, err := RestoreSettings(context.Background(), Options{}, host, dir); err != nil {
+		t.Fatalf("RestoreSettings failed: %v", err)
+	}
+	want := map[string]interface{}{
+		"fan":   map[string]interface{}{"mode": "auto"},
+		"miner": map[string]interface{}{"overclock": map[string]interface{}{"preset": "3000"}},
+	}
+	if !reflect.DeepEqual(f.posted, want) {
+		t.Errorf("restored %v, want %v", f.posted, want)
+	}
+}
+
+func TestApplySettingsSendsOnlyDifferences(t *testing.T) {
+	current := goldenSettings()
+	current.Fan.Mode = "manual"
+	current.Temperature.HotTemp = 80
+	f := &fakeVnishSettings{settings: current}
+	host := f.start(t)
+
+	golden := goldenSettings()
+	if _, err := ApplySettings(context.Background(), Options{}, host, &golden, []string{SettingsFan}, false); err != nil {
+		t.Fatalf("ApplySettings failed: %v", err)
+	}
+	want := map[string]interface{}{"fan": map[string]interface{}{"mode": "auto"}}
+	if !reflect.DeepEqual(f.posted, want) {
+		t.Errorf("posted %v, want only %v", f.posted, want)
+	}
+	if f.settings.Fan.Mode != "auto" || f.settings.Fan.TargetTemp != 70 || f.settings.Temperature.HotTemp != 80 || len(f.settings.Pools) != 1 {
+		t.Errorf("untouched settings changed: %+v", f.settings)
+	}
+}
+
+func TestDiffSettings(t *testing.T) {
+	a, b := goldenSettings(), goldenSettings()
+	b.Pools[0].URL = "stratum+tcp://backup:3333"
+	b.Temperature.HotTemp = 80
+
+	changes, err := DiffSettings(&a, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %+v", changes)
+	}
+	if changes[0].Section != SettingsPools || changes[0].Path != "pools[0].url" {
+		t.Errorf("unexpected change %+v", changes[0])
+	}
+	if changes[1].Section != SettingsTemperature || changes[1].Old != "85" || changes[1].New != "80" {
+		t.Errorf("unexpected change %+v", changes[1])
+	}
+}
+
+func TestApplySettings(t *testing.T) {
+	golden := goldenSettings()
+
+	tests := []struct {
+		name        string
+		sections    []string
+		dryRun      bool
+		wantApplied []string
+		wantUpdates int
+	}{
+		{name: "default sections skip network", sections: SettingsSections, wantApplied: []string{SettingsPools, SettingsFan}, wantUpdates: 1},
+		{name: "selected section only", sections: []string{SettingsFan}, wantApplied: []string{SettingsFan}, wantUpdates: 1},
+		{name: "matching section sends nothing", sections: []string{SettingsTemperature}, wantApplied: []string{}},
+		{name: "dry run", sections: SettingsSections, dryRun: true, wantApplied: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := goldenSettings()
+			current.Pools[0].URL = "stratum+tcp://old:3333"
+			current.Fan.Mode = "manual"
+			current.Network = vmodels.NetworkSettings{DHCP: true}
+			f := &fakeVnishSettings{settings: current}
+			host := f.start(t)
+
+			result, err := ApplySettings(context.Background(), Options{}, host, &golden, tt.sections, tt.dryRun)
+			if err != nil {
+				t.Fatalf("ApplySettings failed: %v", err)
+			}
+			if strings.Join(result.Applied, ",") != strings.Join(tt.wantApplied, ",") {
+				t.Errorf("applied = %v, want %v", result.Applied, tt.wantApplied)
+			}
+			if f.updates != tt.wantUpdates {
+				t.Errorf("updates = %d, want %d", f.updates, tt.wantUpdates)
+			}
+			if tt.wantUpdates > 0 && !f.settings.Network.DHCP {
+				t.Error("network settings should be left unchanged")
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/sinkers/miner-cli/internal/braiins/bos/v1"
+)
+
+// StatusUnreachable is reported while the status stream of a miner is down
+const StatusUnreachable = "unreachable"
+
+// StatusEvent is a change of the mining status of one miner
+type StatusEvent struct {
+	Time  time.Time `json:"time"`
+	Host  string    `json:"host"`
+	Old   string    `json:"old_status"`
+	New   string    `json:"new_status"`
+	Error string    `json:"error,omitempty"`
+}
+
+// FollowOptions controls reconnection of status streams
+type FollowOptions struct {
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// statusStream is the receiving side of GetMinerStatus
+type statusStream interface {
+	Recv() (*pb.GetMinerStatusResponse, error)
+}
+
+// statusOpener opens a status stream to host and returns a function that
+// releases the connection
+type statusOpener func(ctx context.Context, host string) (statusStream, func(), error)
+
+// GetMinerStatus returns the current mining status of a Braiins OS miner
+func GetMinerStatus(ctx context.Context, opts Options, host string) (string, error) {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	stream, closeFn, err := braiinsStatusOpener(opts)(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	defer closeFn()
+
+	resp, err := stream.Recv()
+	if err != nil {
+		return "", fmt.Errorf("failed to receive status: %w", err)
+	}
+	return statusName(resp.GetStatus()), nil
+}
+
+// FollowStatus holds a status stream open to every host until ctx is done,
+// reconnecting with exponential backoff, and calls emit for every status
+// change. Calls to emit are serialized so events form one merged log.
+func FollowStatus(ctx context.Context, opts Options, hosts []string, f FollowOptions, emit func(StatusEvent)) {
+	followStatus(ctx, braiinsStatusOpener(opts), hosts, f, emit)
+}
+
+func followStatus(ctx context.Context, open statusOpener, hosts []string, f FollowOptions, emit func(StatusEvent)) {
+	if f.MinBackoff <= 0 {
+		f.MinBackoff = time.Second
+	}
+	if f.MaxBackoff < f.MinBackoff {
+		f.MaxBackoff = f.MinBackoff
+	}
+
+	var mu sync.Mutex
+	serialized := func(e StatusEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		emit(e)
+	}
+
+	var wg sync.WaitGroup
+	for _, host := range hosts {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			followHost(ctx, open, host, f, serialized)
+		}(host)
+	}
+	wg.Wait()
+}
+
+// followHost streams the status of one host until ctx is done
+func followHost(ctx context.Context, open statusOpener, host string, f FollowOptions, emit func(StatusEvent)) {
+	current := ""
+	backoff := f.MinBackoff
+
+	change := func(status string, err error) {
+		if status == current {
+			return
+		}
+		e := StatusEvent{Time: time.Now().UTC(), Host: host, Old: current, New: status}
+		if err != nil {
+			e.Error = err.Error()
+		}
+		current = status
+		emit(e)
+	}
+
+	for ctx.Err() == nil {
+		err := receiveStatus(ctx, open, host, func(status string) {
+			backoff = f.MinBackoff
+			change(status, nil)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		change(StatusUnreachable, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > f.MaxBackoff {
+			backoff = f.MaxBackoff
+		}
+	}
+}
+
+// receiveStatus reads one stream until it fails
+func receiveStatus(ctx context.Context, open statusOpener, host string, fn func(string)) error {
+	stream, closeFn, err := open(ctx, host)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return fmt.Errorf("status stream closed: %w", err)
+		}
+		fn(statusName(resp.GetStatus()))
+	}
+}
+
+func braiinsStatusOpener(opts Options) statusOpener {
+	return func(ctx context.Context, host string) (statusStream, func(), error) {
+		c, err := opts.Braiins(host)
+		if err != nil {
+			return nil, nil, err
+		}
+		stream, err := c.GetMinerStatus(ctx)
+		if err != nil {
+			c.Close()
+			return nil, nil, fmt.Errorf("failed to open status stream: %w", err)
+		}
+		return stream, func() { c.Close() }, nil
+	}
+}
+
+// statusName turns MINER_STATUS_NOT_STARTED into not_started
+func statusName(s pb.MinerStatus) string {
+	return strings.ToLower(strings.TrimPrefix(s.String(), "MINER_STATUS_"))
+}
@@ -0,0 +1,103 @@
+package fleet
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/sinkers/miner-cli/internal/braiins/bos/v1"
+)
+
+type fakeStatusStream struct {
+	ctx      context.Context
+	statuses []pb.MinerStatus
+	block    bool // wait for ctx instead of failing when drained
+}
+
+func (s *fakeStatusStream) Recv() (*pb.GetMinerStatusResponse, error) {
+	if len(s.statuses) > 0 {
+		status := s.statuses[0]
+		s.statuses = s.statuses[1:]
+		return &pb.GetMinerStatusResponse{Status: status}, nil
+	}
+	if s.block {
+		<-s.ctx.Done()
+		return nil, s.ctx.Err()
+	}
+	return nil, errors.New("connection reset")
+}
+
+func TestFollowStatus(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	attempts := 0
+	open := func(ctx context.Context, host string) (statusStream, func(), error) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		switch attempts {
+		case 1:
+			return nil, nil, errors.New("connection refused")
+		case 2:
+			return &fakeStatusStream{statuses: []pb.MinerStatus{
+				pb.MinerStatus_MINER_STATUS_NORMAL,
+				pb.MinerStatus_MINER_STATUS_NORMAL,
+				pb.MinerStatus_MINER_STATUS_PAUSED,
+			}}, func() {}, nil
+		default:
+			return &fakeStatusStream{ctx: ctx, block: true, statuses: []pb.MinerStatus{
+				pb.MinerStatus_MINER_STATUS_NORMAL,
+			}}, func() {}, nil
+		}
+	}
+
+	events := make(chan StatusEvent, 10)
+	done := make(chan struct{})
+	go func() {
+		followStatus(ctx, open, []string{"10.0.0.1"}, FollowOptions{MinBackoff: time.Millisecond}, func(e StatusEvent) {
+			events <- e
+		})
+		close(done)
+	}()
+
+	want := [][2]string{
+		{"", StatusUnreachable},
+		{StatusUnreachable, "normal"},
+		{"normal", "paused"},
+		{"paused", StatusUnreachable},
+		{StatusUnreachable, "normal"},
+	}
+	for i, w := range want {
+		select {
+		case e := <-events:
+			if e.Host != "10.0.0.1" || e.Old != w[0] || e.New != w[1] {
+				t.Errorf("event %d: expected %s -> %s, got %+v", i, w[0], w[1], e)
+			}
+			if w[1] == StatusUnreachable && e.Error == "" {
+				t.Errorf("event %d: expected an error", i)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for event %d", i)
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("FollowStatus did not stop after cancel")
+	}
+	if len(events) != 0 {
+		t.Errorf("unexpected extra events: %d", len(events))
+	}
+}
+
+func TestStatusName(t *testing.T) {
+	if got := statusName(pb.MinerStatus_MINER_STATUS_NOT_STARTED); got != "not_started" {
+		t.Errorf("expected not_started, got %s", got)
+	}
+}
@@ -0,0 +1,177 @@
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Temperatures holds the board and chip temperature readings of one miner
+// in degrees Celsius.
+type Temperatures struct {
+	Board []float64 `json:"board,omitempty"`
+	Chip  []float64 `json:"chip,omitempty"`
+	Max   float64   `json:"max"`
+}
+
+// GetTemperatures reads board and chip temperatures from host
+func GetTemperatures(ctx context.Context, opts Options, host string) (*Temperatures, error) {
+	opts, err := opts.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	switch opts.Firmware {
+	case FirmwareBraiins:
+		c, err := opts.Braiins(host)
+		if err != nil {
+			return nil, err
+		}
+		defer c.Close()
+
+		boards, err := c.GetHashboards()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get hashboards: %w", err)
+		}
+
+		t := &Temperatures{}
+		for _, board := range boards.Hashboards {
+			if temp := board.GetBoardTemp().GetDegreeC(); temp > 0 {
+				t.Board = append(t.Board, temp)
+			}
+			if temp := board.GetHighestChipTemp().GetTemperature().GetDegreeC(); temp > 0 {
+				t.Chip = append(t.Chip, temp)
+			}
+		}
+
+		// Fall back to the cooling state when the boards report nothing
+		if len(t.Board) == 0 && len(t.Chip) == 0 {
+			cooling, err := c.GetCoolingState()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get cooling state: %w", err)
+			}
+			if temp := cooling.GetHighestTemperature().GetTemperature().GetDegreeC(); temp > 0 {
+				t.Max = temp
+			}
+		}
+		t.updateMax()
+		return t, nil
+	case FirmwareVnish:
+		status, err := opts.Vnish(host).GetStatus(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get status: %w", err)
+		}
+		t := &Temperatures{}
+		for _, temp := range status.Temperature.Board {
+			if temp > 0 {
+				t.Board = append(t.Board, temp)
+			}
+		}
+		for _, temp := range status.Temperature.Chip {
+			if temp > 0 {
+				t.Chip = append(t.Chip, temp)
+			}
+		}
+		t.updateMax()
+		return t, nil
+	case FirmwareCGMiner:
+		result := opts.CGMiner().Query(host, opts.Port, "custom", map[string]interface{}{"cmd": "stats"})
+		if result.Error != "" {
+			return nil, fmt.Errorf("failed to get stats: %s", result.Error)
+		}
+		t := temperaturesFromStats(result.Response)
+		if t.Max == 0 {
+			return nil, fmt.Errorf("no temperature readings in stats response")
+		}
+		return t, nil
+	default:
+		return nil, fmt.Errorf("unsupported firmware %q", opts.Firmware)
+	}
+}
+
+// temperaturesFromStats extracts temperature fields from a raw CGMiner stats
+// response. Antminer style firmwares report board sensors as temp1..tempN or
+// temp_pcbN and chip sensors as temp2_N or temp_chipN, the latter as dash
+// separated strings such as "57-57-74-74".
+func temperaturesFromStats(response interface{}) *Temperatures {
+	t := &Temperatures{}
+
+	respMap, ok := response.(map[string]interface{})
+	if !ok {
+		return t
+	}
+	statsList, ok := respMap["STATS"].([]interface{})
+	if !ok {
+		return t
+	}
+
+	for _, item := range statsList {
+		stats, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		keys := make([]string, 0, len(stats))
+		for key := range stats {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			name := strings.ToLower(key)
+			if !strings.HasPrefix(name, "temp") {
+				continue
+			}
+
+			switch {
+			case name == "temp_max":
+				if v := maxReading(stats[key]); v > t.Max {
+					t.Max = v
+				}
+			case name == "temp_num" || name == "temp_avg":
+				continue
+			case strings.Contains(name, "chip") || strings.HasPrefix(name, "temp2_"):
+				if v := maxReading(stats[key]); v > 0 {
+					t.Chip = append(t.Chip, v)
+				}
+			default:
+				if v := maxReading(stats[key]); v > 0 {
+					t.Board = append(t.Board, v)
+				}
+			}
+		}
+	}
+
+	t.updateMax()
+	return t
+}
+
+// maxReading returns the highest value of a numeric or dash separated reading
+func maxReading(v interface{}) float64 {
+	switch val := v.(type) {
+	case float64:
+		return val
+	case string:
+		max := 0.0
+		for _, part := range strings.Split(val, "-") {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(part), 64); err == nil && f > max {
+				max = f
+			}
+		}
+		return max
+	default:
+		return 0
+	}
+}
+
+func (t *Temperatures) updateMax() {
+	for _, temps := range [][]float64{t.Board, t.Chip} {
+		for _, temp := range temps {
+			if temp > t.Max {
+				t.Max = temp
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	vmodels "github.com/sinkers/miner-cli/internal/vnish/models"
+)
+
+func TestTemperaturesFromStats(t *testing.T) {
+	var response interface{}
+	raw := `{"STATS":[{"BMMiner":"2.0.0"},{"temp_num":3,"temp1":62,"temp2":0,"temp3":64,"temp2_1":77,"temp2_3":79,"temp_chip1":"57-57-81-80","temp_max":64,"fan1":5400}]}`
+	if err := json.Unmarshal([]byte(raw), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	temps := temperaturesFromStats(response)
+
+	if !reflect.DeepEqual(temps.Board, []float64{62, 64}) {
+		t.Errorf("unexpected board temps: %v", temps.Board)
+	}
+	if !reflect.DeepEqual(temps.Chip, []float64{77, 79, 81}) {
+		t.Errorf("unexpected chip temps: %v", temps.Chip)
+	}
+	if temps.Max != 81 {
+		t.Errorf("expected max 81, got %v", temps.Max)
+	}
+}
+
+func TestTemperaturesFromStatsInvalid(t *testing.T) {
+	for _, response := range []interface{}{nil, "text", map[string]interface{}{"STATS": "x"}} {
+		if temps := temperaturesFromStats(response); temps.Max != 0 {
+			t.Errorf("expected no readings for %v", response)
+		}
+	}
+}
+
+func TestVnishTemperatures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(vmodels.Status{
+			Temperature: vmodels.TempInfo{Board: []float64{55, 0, 58}, Chip: []float64{70, 73}},
+		})
+	}))
+	defer server.Close()
+
+	opts := Options{Firmware: FirmwareVnish}
+	temps, err := GetTemperatures(context.Background(), opts, strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("GetTemperatures failed: %v", err)
+	}
+	if len(temps.Board) != 2 || temps.Max != 73 {
+		t.Errorf("unexpected temperatures: %+v", temps)
+	}
+}
@@ -0,0 +1,184 @@
+package inventory
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Miner describes where a miner is installed in the facility
+type Miner struct {
+	IP       string `json:"ip"`
+	Rack     string `json:"rack"`
+	Shelf    string `json:"shelf"`
+	Firmware string `json:"firmware,omitempty"`
+	Model    string `json:"model,omitempty"`
+}
+
+// Inventory is the list of miners known to the facility
+type Inventory struct {
+	Miners []Miner
+}
+
+// Load reads an inventory CSV file. The first row is a header naming the
+// columns; ip, rack and shelf are required, firmware and model are optional.
+func Load(path string) (*Inventory, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open inventory: %w", err)
+	}
+	defer f.Close()
+
+	return Parse(f)
+}
+
+// Parse reads an inventory in CSV format
+func Parse(r io.Reader) (*Inventory, error) {
+	t, err := newTable(r, "inventory", "ip", "rack", "shelf")
+	if err != nil {
+		return nil, err
+	}
+
+	inv := &Inventory{}
+	seen := make(map[string]bool)
+
+	for {
+		record, err := t.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		m := Miner{
+			IP:       t.field(record, "ip"),
+			Rack:     t.field(record, "rack"),
+			Shelf:    t.field(record, "shelf"),
+			Firmware: strings.ToLower(t.field(record, "firmware")),
+			Model:    t.field(record, "model"),
+		}
+
+		if net.ParseIP(m.IP) == nil {
+			return nil, fmt.Errorf("invalid IP address in inventory: %q", m.IP)
+		}
+		if seen[m.IP] {
+			return nil, fmt.Errorf("duplicate IP address in inventory: %s", m.IP)
+		}
+		seen[m.IP] = true
+
+		inv.Miners = append(inv.Miners, m)
+	}
+
+	return inv, nil
+}
+
+// table reads CSV records whose columns are named by a header row
+type table struct {
+	name    string
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+func newTable(r io.Reader, name string, required ...string) (*table, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s header: %w", name, err)
+	}
+
+	columns := make(map[string]int)
+	for i, col := range header {
+		columns[strings.ToLower(strings.TrimSpace(col))] = i
+	}
+	for _, col := range required {
+		if _, ok := columns[col]; !ok {
+			return nil, fmt.Errorf("%s is missing required column %q", name, col)
+		}
+	}
+
+	return &table{name: name, reader: reader, columns: columns}, nil
+}
+
+// next returns the next record or io.EOF
+func (t *table) next() ([]string, error) {
+	record, err := t.reader.Read()
+	if err == io.EOF {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", t.name, err)
+	}
+	return record, nil
+}
+
+func (t *table) field(record []string, name string) string {
+	if i, ok := t.columns[name]; ok && i < len(record) {
+		return strings.TrimSpace(record[i])
+	}
+	return ""
+}
+
+// Lookup returns the inventory entry for ip
+func (inv *Inventory) Lookup(ip string) (Miner, bool) {
+	for _, m := range inv.Miners {
+		if m.IP == ip {
+			return m, true
+		}
+	}
+	return Miner{}, false
+}
+
+// IPs returns the addresses of all miners in the inventory
+func (inv *Inventory) IPs() []string {
+	ips := make([]string, 0, len(inv.Miners))
+	for _, m := range inv.Miners {
+		ips = append(ips, m.IP)
+	}
+	return ips
+}
+
+// Racks returns the distinct rack names in natural order
+func (inv *Inventory) Racks() []string {
+	return distinct(inv.Miners, func(m Miner) string { return m.Rack })
+}
+
+// Shelves returns the distinct shelf names in natural order
+func (inv *Inventory) Shelves() []string {
+	return distinct(inv.Miners, func(m Miner) string { return m.Shelf })
+}
+
+func distinct(miners []Miner, key func(Miner) string) []string {
+	seen := make(map[string]bool)
+	var values []string
+	for _, m := range miners {
+		k := key(m)
+		if !seen[k] {
+			seen[k] = true
+			values = append(values, k)
+		}
+	}
+	SortNatural(values)
+	return values
+}
+
+// SortNatural sorts names so that numeric names compare by value, e.g.
+// "2" before "10".
+func SortNatural(values []string) {
+	sort.SliceStable(values, func(i, j int) bool {
+		a, errA := strconv.Atoi(values[i])
+		b, errB := strconv.Atoi(values[j])
+		if errA == nil && errB == nil {
+			return a < b
+		}
+		return values[i] < values[j]
+	})
+}
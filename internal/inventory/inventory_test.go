@@ -0,0 +1,119 @@
+package inventory
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	input := `ip,rack,shelf,firmware,model
+# container A
+10.0.0.1,R1,1,braiins,S19
+10.0.0.2, R1, 2, VNISH, S19j
+10.0.0.3,R10,1,,
+10.0.0.4,R2,10,,
+`
+
+	inv, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(inv.Miners) != 4 {
+		t.Fatalf("expected 4 miners, got %d", len(inv.Miners))
+	}
+
+	m, ok := inv.Lookup("10.0.0.2")
+	if !ok {
+		t.Fatal("expected to find 10.0.0.2")
+	}
+	if m.Rack != "R1" || m.Shelf != "2" || m.Firmware != "vnish" || m.Model != "S19j" {
+		t.Errorf("unexpected miner: %+v", m)
+	}
+
+	if _, ok := inv.Lookup("10.0.0.9"); ok {
+		t.Error("unexpected lookup hit")
+	}
+
+	if got := inv.Shelves(); !reflect.DeepEqual(got, []string{"1", "2", "10"}) {
+		t.Errorf("unexpected shelves: %v", got)
+	}
+	if got := inv.Racks(); !reflect.DeepEqual(got, []string{"R1", "R10", "R2"}) {
+		t.Errorf("unexpected racks: %v", got)
+	}
+	if got := inv.IPs(); len(got) != 4 || got[0] != "10.0.0.1" {
+		t.Errorf("unexpected IPs: %v", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"empty", ""},
+		{"missing column", "ip,rack\n10.0.0.1,R1\n"},
+		{"invalid ip", "ip,rack,shelf\nnot-an-ip,R1,1\n"},
+		{"duplicate ip", "ip,rack,shelf\n10.0.0.1,R1,1\n10.0.0.1,R1,2\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.input)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestSortNatural(t *testing.T) {
+	values := []string{"10", "2", "1", "b", "a"}
+	SortNatural(values)
+	if !reflect.DeepEqual(values, []string{"1", "2", "10", "a", "b"}) {
+		t.Errorf("unexpected order: %v", values)
+	}
+}
+
+func TestParseNetworkMappings(t *testing.T) {
+	input := `mac,ip,netmask,gateway,dns,hostname
+AA:BB:CC:00:00:01,10.1.0.10,255.255.255.0,10.1.0.1,1.1.1.1;8.8.8.8,r1-s1
+aa-bb-cc-00-00-02,10.1.0.11,255.255.255.0,10.1.0.1,,
+`
+
+	mappings, err := ParseNetworkMappings(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseNetworkMappings failed: %v", err)
+	}
+	if len(mappings) != 2 {
+		t.Fatalf("expected 2 mappings, got %d", len(mappings))
+	}
+	if mappings[0].MAC != "aa:bb:cc:00:00:01" || !reflect.DeepEqual(mappings[0].DNS, []string{"1.1.1.1", "8.8.8.8"}) || mappings[0].Hostname != "r1-s1" {
+		t.Errorf("unexpected mapping: %+v", mappings[0])
+	}
+	if mappings[1].MAC != "aa:bb:cc:00:00:02" || len(mappings[1].DNS) != 0 {
+		t.Errorf("unexpected mapping: %+v", mappings[1])
+	}
+}
+
+func TestParseNetworkMappingsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"missing column", "mac,ip,gateway\naa:bb:cc:00:00:01,10.1.0.10,10.1.0.1\n"},
+		{"bad mac", "mac,ip,netmask,gateway\nnot-a-mac,10.1.0.10,255.255.255.0,10.1.0.1\n"},
+		{"bad netmask", "mac,ip,netmask,gateway\naa:bb:cc:00:00:01,10.1.0.10,255.0.255.0,10.1.0.1\n"},
+		{"gateway outside network", "mac,ip,netmask,gateway\naa:bb:cc:00:00:01,10.1.0.10,255.255.255.0,10.2.0.1\n"},
+		{"duplicate mac", "mac,ip,netmask,gateway\naa:bb:cc:00:00:01,10.1.0.10,255.255.255.0,10.1.0.1\nAA:BB:CC:00:00:01,10.1.0.11,255.255.255.0,10.1.0.1\n"},
+		{"duplicate ip", "mac,ip,netmask,gateway\naa:bb:cc:00:00:01,10.1.0.10,255.255.255.0,10.1.0.1\naa:bb:cc:00:00:02,10.1.0.10,255.255.255.0,10.1.0.1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseNetworkMappings(strings.NewReader(tt.input)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
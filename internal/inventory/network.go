@@ -0,0 +1,118 @@
+package inventory
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// NetworkMapping is the static address plan for one miner, keyed by MAC
+type NetworkMapping struct {
+	MAC      string   `json:"mac"`
+	IP       string   `json:"ip"`
+	Netmask  string   `json:"netmask"`
+	Gateway  string   `json:"gateway"`
+	DNS      []string `json:"dns,omitempty"`
+	Hostname string   `json:"hostname,omitempty"`
+}
+
+// LoadNetworkMappings reads a network mapping CSV file. The columns mac, ip,
+// netmask and gateway are required; dns (separated by spaces or semicolons)
+// and hostname are optional.
+func LoadNetworkMappings(path string) ([]NetworkMapping, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open network mapping: %w", err)
+	}
+	defer f.Close()
+
+	return ParseNetworkMappings(f)
+}
+
+// ParseNetworkMappings reads network mappings in CSV format and rejects
+// invalid addresses and duplicate MAC or IP addresses
+func ParseNetworkMappings(r io.Reader) ([]NetworkMapping, error) {
+	t, err := newTable(r, "network mapping", "mac", "ip", "netmask", "gateway")
+	if err != nil {
+		return nil, err
+	}
+
+	var mappings []NetworkMapping
+	seenMAC := make(map[string]bool)
+	seenIP := make(map[string]string)
+
+	for {
+		record, err := t.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		hw, err := net.ParseMAC(t.field(record, "mac"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAC address in network mapping: %q", t.field(record, "mac"))
+		}
+
+		m := NetworkMapping{
+			MAC:      hw.String(),
+			IP:       t.field(record, "ip"),
+			Netmask:  t.field(record, "netmask"),
+			Gateway:  t.field(record, "gateway"),
+			DNS:      strings.FieldsFunc(t.field(record, "dns"), func(r rune) bool { return r == ';' || r == ' ' }),
+			Hostname: t.field(record, "hostname"),
+		}
+
+		if err := m.Validate(); err != nil {
+			return nil, fmt.Errorf("network mapping for %s: %w", m.MAC, err)
+		}
+		if seenMAC[m.MAC] {
+			return nil, fmt.Errorf("duplicate MAC address in network mapping: %s", m.MAC)
+		}
+		seenMAC[m.MAC] = true
+		if other, ok := seenIP[m.IP]; ok {
+			return nil, fmt.Errorf("duplicate IP address in network mapping: %s assigned to %s and %s", m.IP, other, m.MAC)
+		}
+		seenIP[m.IP] = m.MAC
+
+		mappings = append(mappings, m)
+	}
+
+	return mappings, nil
+}
+
+// Validate checks the addresses of the mapping
+func (m NetworkMapping) Validate() error {
+	ip := net.ParseIP(m.IP).To4()
+	if ip == nil {
+		return fmt.Errorf("invalid IP address %q", m.IP)
+	}
+	mask := net.ParseIP(m.Netmask).To4()
+	if mask == nil || !isContiguous(net.IPMask(mask)) {
+		return fmt.Errorf("invalid netmask %q", m.Netmask)
+	}
+	gw := net.ParseIP(m.Gateway).To4()
+	if gw == nil {
+		return fmt.Errorf("invalid gateway %q", m.Gateway)
+	}
+	if !ip.Mask(net.IPMask(mask)).Equal(gw.Mask(net.IPMask(mask))) {
+		return fmt.Errorf("gateway %s is not in the network of %s/%s", m.Gateway, m.IP, m.Netmask)
+	}
+	if ip.Equal(gw) {
+		return fmt.Errorf("IP address %s is the gateway", m.IP)
+	}
+	for _, dns := range m.DNS {
+		if net.ParseIP(dns) == nil {
+			return fmt.Errorf("invalid DNS server %q", dns)
+		}
+	}
+	return nil
+}
+
+func isContiguous(mask net.IPMask) bool {
+	ones, bits := mask.Size()
+	return bits != 0 && ones > 0
+}
@@ -2,6 +2,7 @@ package iprange
 
 import (
 	"fmt"
+	"math"
 	"net"
 	"strconv"
 	"strings"
@@ -110,6 +111,117 @@ func ParseMultipleRanges(inputs []string) (*IPRange, error) {
 	return &IPRange{IPs: allIPs}, nil
 }
 
+// Size returns how many addresses inputs cover at most, without expanding
+// them, so that oversized ranges can be refused before parsing
+func Size(inputs []string) (uint64, error) {
+	var total uint64
+	for _, input := range inputs {
+		input = strings.TrimSpace(input)
+		var n uint64
+		switch {
+		case strings.Contains(input, "/"):
+			_, ipNet, err := net.ParseCIDR(input)
+			if err != nil {
+				return 0, fmt.Errorf("invalid CIDR notation: %w", err)
+			}
+			ones, bits := ipNet.Mask.Size()
+			if bits-ones >= 64 {
+				return math.MaxUint64, nil
+			}
+			n = 1 << uint(bits-ones)
+		case strings.Contains(input, "-"):
+			parts := strings.Split(input, "-")
+			if len(parts) != 2 {
+				return 0, fmt.Errorf("invalid range format, expected start-end: %s", input)
+			}
+			start := net.ParseIP(strings.TrimSpace(parts[0])).To4()
+			end := net.ParseIP(strings.TrimSpace(parts[1])).To4()
+			if start == nil || end == nil || ipToUint32(start) > ipToUint32(end) {
+				return 0, fmt.Errorf("invalid IP range: %s", input)
+			}
+			n = uint64(ipToUint32(end)-ipToUint32(start)) + 1
+		default:
+			n = 1
+		}
+		if total += n; total < n {
+			return math.MaxUint64, nil
+		}
+	}
+	return total, nil
+}
+
+// Set holds addresses, CIDRs and ranges unexpanded, so that membership in
+// large networks is cheap to test
+type Set struct {
+	nets  []*net.IPNet
+	spans [][2]uint32 // inclusive IPv4 ranges
+	size  uint64
+}
+
+// ParseSet parses inputs in the forms ParseIPRange accepts
+func ParseSet(inputs []string) (*Set, error) {
+	size, err := Size(inputs)
+	if err != nil {
+		return nil, err
+	}
+	s := &Set{size: size}
+	for _, input := range inputs {
+		input = strings.TrimSpace(input)
+		switch {
+		case strings.Contains(input, "/"):
+			_, ipNet, err := net.ParseCIDR(input)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR notation: %w", err)
+			}
+			s.nets = append(s.nets, ipNet)
+		case strings.Contains(input, "-"):
+			// validated by Size
+			parts := strings.Split(input, "-")
+			start := net.ParseIP(strings.TrimSpace(parts[0])).To4()
+			end := net.ParseIP(strings.TrimSpace(parts[1])).To4()
+			s.spans = append(s.spans, [2]uint32{ipToUint32(start), ipToUint32(end)})
+		default:
+			ip := net.ParseIP(input)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address: %s", input)
+			}
+			bits := 8 * len(ip)
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 32
+			}
+			s.nets = append(s.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		}
+	}
+	return s, nil
+}
+
+// Contains reports whether the address host is in the set
+func (s *Set) Contains(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range s.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		n := ipToUint32(ip4)
+		for _, span := range s.spans {
+			if n >= span[0] && n <= span[1] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Size returns how many addresses the set covers at most, as Size
+func (s *Set) Size() uint64 {
+	return s.size
+}
+
 func (r *IPRange) GetIPs() []string {
 	result := make([]string, len(r.IPs))
 	for i, ip := range r.IPs {
@@ -296,6 +296,62 @@ func TestIPRangeGetIPs(t *testing.T) {
 	}
 }
 
+func TestSize(t *testing.T) {
+	tests := []struct {
+		inputs   []string
+		expected uint64
+		wantErr  bool
+	}{
+		{[]string{"192.168.1.1"}, 1, false},
+		{[]string{"192.168.1.0/24", "10.0.0.1-10.0.0.10"}, 266, false},
+		{[]string{"0.0.0.0/0"}, 1 << 32, false},
+		{[]string{"10.0.0.10-10.0.0.1"}, 0, true},
+		{[]string{"10.0.0.0/33"}, 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := Size(tt.inputs)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Size(%v) error = %v, wantErr %v", tt.inputs, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.expected {
+			t.Errorf("Size(%v) = %d, expected %d", tt.inputs, got, tt.expected)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	s, err := ParseSet([]string{"0.0.0.0/0", "10.0.0.1-10.0.0.10", "192.168.1.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ip := range []string{"8.8.8.8", "10.0.0.10", "192.168.1.5"} {
+		if !s.Contains(ip) {
+			t.Errorf("expected %s in the set", ip)
+		}
+	}
+
+	s, err = ParseSet([]string{"10.0.0.1-10.0.0.10", "192.168.1.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ip := range []string{"10.0.0.11", "192.168.1.6", "not an ip"} {
+		if s.Contains(ip) {
+			t.Errorf("unexpected %s in the set", ip)
+		}
+	}
+	if s.Size() != 11 {
+		t.Errorf("Size() = %d, expected 11", s.Size())
+	}
+
+	for _, inputs := range [][]string{{"10.0.0.0/33"}, {"10.0.0.10-10.0.0.1"}, {"bogus"}} {
+		if _, err := ParseSet(inputs); err == nil {
+			t.Errorf("ParseSet(%v): expected an error", inputs)
+		}
+	}
+}
+
 func TestParsePort(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,46 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/sinkers/miner-cli/internal/alert"
+)
+
+// AlertEventWriter prints alert notifications as a colored log or as
+// newline delimited JSON
+type AlertEventWriter struct {
+	W    io.Writer
+	JSON bool
+}
+
+// Write prints one event
+func (w *AlertEventWriter) Write(e alert.Event) error {
+	if w.JSON {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(w.W, string(data))
+		return err
+	}
+
+	status := color.GreenString("RESOLVED")
+	detail := fmt.Sprintf("after %s", e.Time.Sub(e.Since).Round(time.Second))
+	if e.Status == alert.StatusFiring {
+		status = color.YellowString("FIRING")
+		if e.Severity == alert.SeverityCritical {
+			status = color.RedString("FIRING")
+		}
+		detail = fmt.Sprintf("%s (value %g)", e.Condition, e.Value)
+		if e.Error != "" {
+			detail += color.New(color.FgHiBlack).Sprintf(" (%s)", e.Error)
+		}
+	}
+	_, err := fmt.Fprintf(w.W, "%s %-15s %s %s [%s] %s\n",
+		e.Time.Local().Format(time.RFC3339), e.Host, status, e.Rule, e.Severity, detail)
+	return err
+}
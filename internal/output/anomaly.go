@@ -0,0 +1,49 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/sinkers/miner-cli/internal/fleet"
+)
+
+// WriteAnomalyReport prints the flagged miners, worst first, with their
+// findings
+func WriteAnomalyReport(w io.Writer, report *fleet.AnomalyReport, verbose bool) {
+	bold := color.New(color.Bold).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+
+	fmt.Fprintf(w, "\n%s\n", bold("=== Anomalies (last "+report.Window+") ==="))
+	fmt.Fprintf(w, "Analyzed: %d | Flagged: %d | Healthy: %d | No baseline: %d\n",
+		report.Analyzed, len(report.Flagged), len(report.Healthy), len(report.NoBaseline))
+
+	if report.Analyzed == 0 {
+		fmt.Fprintln(w, "\nNo recent samples, record some with: miner-cli history record -i <range>")
+		return
+	}
+	if len(report.Flagged) == 0 {
+		fmt.Fprintf(w, "\n%s\n", green("No anomalies found"))
+	}
+
+	for _, m := range report.Flagged {
+		score := yellow(fmt.Sprintf("%3d", m.Score))
+		if m.Severity == fleet.SeverityError {
+			score = red(fmt.Sprintf("%3d", m.Score))
+		}
+		fmt.Fprintf(w, "\n%s  %s %s\n", score, bold(m.Host), m.Model)
+		for _, a := range m.Anomalies {
+			fmt.Fprintf(w, "     %-14s %3d  %s\n", a.Kind, a.Score, a.Message)
+		}
+	}
+
+	if verbose && len(report.NoBaseline) > 0 {
+		fmt.Fprintf(w, "\nNo baseline yet (peers only): %s\n", strings.Join(report.NoBaseline, ", "))
+	}
+	if verbose && len(report.Healthy) > 0 {
+		fmt.Fprintf(w, "\nHealthy: %s\n", strings.Join(report.Healthy, ", "))
+	}
+}
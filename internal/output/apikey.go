@@ -0,0 +1,55 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/sinkers/miner-cli/internal/fleet"
+)
+
+// WriteAPIKeyReport prints the outcome of an API key run. Keys themselves
+// are never printed.
+func WriteAPIKeyReport(w io.Writer, report *fleet.APIKeyReport, verbose bool) {
+	bold := color.New(color.Bold).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	fmt.Fprintf(w, "\n%s\n", bold("=== API Keys: "+report.Action+" ==="))
+	fmt.Fprintf(w, "Scanned: %d | Changed: %d | Skipped: %d | Failed: %d\n",
+		report.Scanned, len(report.Changed), len(report.Skipped), len(report.Failed))
+
+	if len(report.Changed) > 0 {
+		fmt.Fprintln(w)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "Host\tMAC\tStored As\tCreated\tRemoved\tNote")
+		fmt.Fprintln(tw, "----\t---\t---------\t-------\t-------\t----")
+		for _, r := range report.Changed {
+			stored := "-"
+			if r.Created {
+				stored = r.EntryName()
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%t\t%d\t%s\n", r.Host, dash(r.MAC), stored, r.Created, r.Removed, yellow(dash(r.Warning)))
+		}
+		tw.Flush()
+	}
+
+	if len(report.Failed) > 0 {
+		fmt.Fprintf(w, "\n%s\n", bold("=== Failed ==="))
+		hosts := make([]string, 0, len(report.Failed))
+		for host := range report.Failed {
+			hosts = append(hosts, host)
+		}
+		sort.Strings(hosts)
+		for _, host := range hosts {
+			fmt.Fprintf(w, "%s: %s\n", host, red(report.Failed[host]))
+		}
+	}
+
+	if verbose && len(report.Skipped) > 0 {
+		fmt.Fprintf(w, "\nAlready provisioned: %s\n", strings.Join(report.Skipped, ", "))
+	}
+}
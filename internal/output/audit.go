@@ -0,0 +1,67 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/sinkers/miner-cli/internal/audit"
+)
+
+const auditTime = "2006-01-02 15:04:05"
+
+// WriteAuditRecords prints audit records oldest first; verbose adds the
+// parameters and per-host errors of each record
+func WriteAuditRecords(w io.Writer, records []audit.Record, verbose bool) {
+	bold := color.New(color.Bold).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+
+	fmt.Fprintf(w, "\n%s\n", bold("=== Audit Log ==="))
+	if len(records) == 0 {
+		fmt.Fprintln(w, "No records")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Time\tUser\tCommand\tTargets\tOK\tFailed")
+	fmt.Fprintln(tw, "----\t----\t-------\t-------\t--\t------")
+	for _, r := range records {
+		failed := fmt.Sprint(r.Failed)
+		if r.Failed > 0 {
+			failed = red(failed)
+		}
+		command := strings.TrimSpace(r.Command + " " + strings.Join(r.Args, " "))
+		if r.Error != "" {
+			command += red(" (failed)")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n", r.Time.Local().Format(auditTime), r.User, command,
+			strings.Join(r.Targets, ","), r.Succeeded, failed)
+	}
+	tw.Flush()
+
+	if !verbose {
+		return
+	}
+	for _, r := range records {
+		fmt.Fprintf(w, "\n%s %s by %s@%s\n", bold(r.Time.Local().Format(auditTime)), r.Command, r.User, r.Machine)
+		names := make([]string, 0, len(r.Flags))
+		for name := range r.Flags {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "  --%s=%s\n", name, r.Flags[name])
+		}
+		if r.Error != "" {
+			fmt.Fprintf(w, "  %s %s\n", red("Error:"), r.Error)
+		}
+		for _, o := range r.Hosts {
+			if o.Error != "" {
+				fmt.Fprintf(w, "  %s %s\n", red(o.Host), o.Error)
+			}
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/sinkers/miner-cli/internal/fleet"
+)
+
+// WritePresetReport prints the autotune presets offered per model and how
+// many miners run each of them
+func WritePresetReport(w io.Writer, report *fleet.PresetReport, verbose bool) {
+	bold := color.New(color.Bold).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+
+	fmt.Fprintf(w, "\n%s\n", bold("=== Autotune Presets ==="))
+	fmt.Fprintf(w, "Scanned: %d | Models: %d | Unreachable: %d\n", report.Scanned, len(report.Models), len(report.Unreachable))
+
+	for _, m := range report.Models {
+		fmt.Fprintf(w, "\n%s (%d hosts)\n", bold(m.Model), m.Hosts)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "Preset\tName\tHashrate\tPower\tEfficiency\tActive On")
+		fmt.Fprintln(tw, "------\t----\t--------\t-----\t----------\t---------")
+		for _, p := range m.Presets {
+			fmt.Fprintf(tw, "%s\t%s\t%.2f\t%.0f W\t%.2f\t%d\n", p.ID, p.Name, p.HashRate, p.Power, p.Efficiency, m.Current[p.ID])
+		}
+		tw.Flush()
+		if n := m.Current["none"]; n > 0 {
+			fmt.Fprintf(w, "No preset: %d hosts\n", n)
+		}
+	}
+
+	if verbose && len(report.Unreachable) > 0 {
+		fmt.Fprintf(w, "\n%s: %s\n", red("Unreachable"), strings.Join(report.Unreachable, ", "))
+	}
+}
+
+// WriteRolloutReport prints the per-host outcome of a preset rollout and the
+// before/after comparison
+func WriteRolloutReport(w io.Writer, report *fleet.RolloutReport, verbose bool) {
+	bold := color.New(color.Bold).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	changed, skipped := 0, 0
+	for _, r := range report.Results {
+		if r.Skipped != "" {
+			skipped++
+		} else {
+			changed++
+		}
+	}
+
+	fmt.Fprintf(w, "\n%s\n", bold("=== Preset Rollout: "+report.Preset+" ==="))
+	fmt.Fprintf(w, "Changed: %d | Skipped: %d | Failed: %d\n", changed, skipped, len(report.Failed))
+
+	if verbose && len(report.Results) > 0 {
+		fmt.Fprintln(w)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "Host\tBatch\tModel\tPrevious\tHashrate\tPower\tNote")
+		fmt.Fprintln(tw, "----\t-----\t-----\t--------\t--------\t-----\t----")
+		for _, r := range report.Results {
+			hashrate, power := "-", "-"
+			if r.Before != nil && r.After != nil {
+				hashrate = fmt.Sprintf("%.2f -> %.2f", r.Before.HashRate, r.After.HashRate)
+				power = fmt.Sprintf("%.0f -> %.0f W", r.Before.Power, r.After.Power)
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\n", r.Host, r.Batch, r.Model, dash(r.Previous), hashrate, power, dash(r.Skipped))
+		}
+		tw.Flush()
+	}
+
+	if len(report.Failed) > 0 {
+		fmt.Fprintf(w, "\n%s\n", bold("=== Failed ==="))
+		hosts := make([]string, 0, len(report.Failed))
+		for host := range report.Failed {
+			hosts = append(hosts, host)
+		}
+		sort.Strings(hosts)
+		for _, host := range hosts {
+			fmt.Fprintf(w, "%s: %s\n", host, red(report.Failed[host]))
+		}
+	}
+
+	if len(report.Comparisons) == 0 {
+		fmt.Fprintf(w, "\n%s\n", yellow("No miners measured before and after the change"))
+		return
+	}
+	WritePresetComparisons(w, report.Comparisons)
+}
+
+// WritePresetComparisons prints average performance per model and preset,
+// best efficiency first within each model
+func WritePresetComparisons(w io.Writer, comparisons []fleet.PresetComparison) {
+	bold := color.New(color.Bold).SprintFunc()
+
+	fmt.Fprintf(w, "\n%s\n", bold("=== Before / After ==="))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Model\tPreset\tHosts\tHashrate\tPower\tEfficiency")
+	fmt.Fprintln(tw, "-----\t------\t-----\t--------\t-----\t----------")
+	for _, c := range comparisons {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%.2f %s (%+.1f%%)\t%.0f W (%+.1f%%)\t%.2f (%+.1f%%)\n",
+			c.Model, c.Preset, c.Hosts,
+			c.After.HashRate, c.After.HashRateUnit, c.HashRateChange,
+			c.After.Power, c.PowerChange,
+			c.After.Efficiency, c.EfficiencyChange)
+	}
+	tw.Flush()
+}
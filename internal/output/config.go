@@ -0,0 +1,62 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/sinkers/miner-cli/internal/fleet"
+)
+
+// WriteConfigChanges prints configuration differences as a table. The
+// columns are labelled with the names of the compared configurations.
+func WriteConfigChanges(w io.Writer, changes []fleet.ConfigChange, oldName, newName string) {
+	if len(changes) == 0 {
+		fmt.Fprintln(w, color.GreenString("No differences"))
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Section\tPath\t%s\t%s\n", oldName, newName)
+	fmt.Fprintf(tw, "-------\t----\t%s\t%s\n", strings.Repeat("-", len(oldName)), strings.Repeat("-", len(newName)))
+	for _, ch := range changes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", ch.Section, ch.Path, unset(ch.Old), unset(ch.New))
+	}
+	tw.Flush()
+}
+
+// WriteConfigDiffReport prints the miners that drift from the template and
+// how they differ
+func WriteConfigDiffReport(w io.Writer, report *fleet.ConfigDiffReport, verbose bool) {
+	bold := color.New(color.Bold).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+
+	fmt.Fprintf(w, "\n%s\n", bold("=== Configuration Drift ==="))
+	fmt.Fprintf(w, "Scanned: %d | Matching: %d | Differing: %d | Unreachable: %d\n",
+		report.Scanned, len(report.Matching), len(report.Differing), len(report.Unreachable))
+
+	if len(report.Differing) == 0 {
+		fmt.Fprintf(w, "\n%s\n", green("All reachable miners match the template"))
+	}
+	for _, d := range report.Differing {
+		fmt.Fprintf(w, "\n%s (%d differences)\n", bold(d.Host), len(d.Changes))
+		WriteConfigChanges(w, d.Changes, "Miner", "Template")
+	}
+
+	if verbose && len(report.Matching) > 0 {
+		fmt.Fprintf(w, "\n%s: %s\n", green("Matching"), strings.Join(report.Matching, ", "))
+	}
+	if verbose && len(report.Unreachable) > 0 {
+		fmt.Fprintf(w, "\n%s: %s\n", red("Unreachable"), strings.Join(report.Unreachable, ", "))
+	}
+}
+
+func unset(s string) string {
+	if s == "" {
+		return "(unset)"
+	}
+	return s
+}
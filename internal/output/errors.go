@@ -0,0 +1,113 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/sinkers/miner-cli/internal/fleet"
+)
+
+// WriteErrorReport prints the fleet error report as tables
+func WriteErrorReport(w io.Writer, report *fleet.ErrorReport, verbose bool) {
+	bold := color.New(color.Bold).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+
+	fmt.Fprintf(w, "\n%s\n", bold("=== Miner Errors ==="))
+	fmt.Fprintf(w, "Scanned: %d | Affected: %d | Unsupported: %d | Unreachable: %d | Failed: %d\n",
+		report.Scanned, report.Affected, len(report.Unsupported), len(report.Unreachable), len(report.Failed))
+
+	if len(report.Errors) == 0 {
+		fmt.Fprintf(w, "\n%s\n", green("No active errors reported"))
+	} else {
+		fmt.Fprintln(w)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "Severity\tCode\tComponent\tHosts\tMessage")
+		fmt.Fprintln(tw, "--------\t----\t---------\t-----\t-------")
+		for _, e := range report.Errors {
+			severity := red(e.Severity)
+			if e.Severity == fleet.SeverityWarning {
+				severity = yellow(e.Severity)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", severity, dash(e.Code), dash(e.Component), e.Count, e.Message)
+		}
+		tw.Flush()
+
+		fmt.Fprintf(w, "\n%s\n", bold("=== By Error Code ==="))
+		writeCounts(w, report.ByCode)
+		if len(report.ByComponent) > 0 {
+			fmt.Fprintf(w, "\n%s\n", bold("=== By Component ==="))
+			writeCounts(w, report.ByComponent)
+		}
+
+		fmt.Fprintf(w, "\n%s\n", bold("=== Affected Hosts ==="))
+		for _, e := range report.Errors {
+			label := e.Code
+			if label == "" {
+				label = e.Message
+			}
+			fmt.Fprintf(w, "%s: %s\n", label, strings.Join(e.Hosts, ", "))
+			if verbose && e.Hint != "" {
+				fmt.Fprintf(w, "  hint: %s\n", e.Hint)
+			}
+		}
+	}
+
+	if verbose && len(report.Unsupported) > 0 {
+		hosts := make([]string, 0, len(report.Unsupported))
+		for host := range report.Unsupported {
+			hosts = append(hosts, host)
+		}
+		sort.Strings(hosts)
+		fmt.Fprintf(w, "\n%s\n", bold("=== Unsupported ==="))
+		for _, host := range hosts {
+			fmt.Fprintf(w, "%s: %s\n", host, report.Unsupported[host])
+		}
+	}
+	if verbose && len(report.Unreachable) > 0 {
+		fmt.Fprintf(w, "\n%s: %s\n", red("Unreachable"), strings.Join(report.Unreachable, ", "))
+	}
+	if verbose && len(report.Failed) > 0 {
+		hosts := make([]string, 0, len(report.Failed))
+		for host := range report.Failed {
+			hosts = append(hosts, host)
+		}
+		sort.Strings(hosts)
+		fmt.Fprintf(w, "\n%s\n", red("=== Failed ==="))
+		for _, host := range hosts {
+			fmt.Fprintf(w, "%s: %s\n", host, report.Failed[host])
+		}
+	}
+}
+
+// writeCounts prints name/count pairs, highest count first
+func writeCounts(w io.Writer, counts map[string]int) {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(tw, "%s\t%d hosts\n", name, counts[name])
+	}
+	tw.Flush()
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
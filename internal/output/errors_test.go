@@ -0,0 +1,42 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sinkers/miner-cli/internal/fleet"
+)
+
+func TestWriteErrorReport(t *testing.T) {
+	report := &fleet.ErrorReport{
+		Scanned:  3,
+		Affected: 2,
+		Errors: []fleet.ErrorSummary{
+			{Severity: fleet.SeverityError, Code: "HB-001", Component: "hashboard", Message: "Hashboard not detected", Hint: "Check the cable", Count: 2, Hosts: []string{"10.0.0.1", "10.0.0.2"}},
+			{Severity: fleet.SeverityWarning, Message: "high temperature", Count: 1, Hosts: []string{"10.0.0.2"}},
+		},
+		ByCode:      map[string]int{"HB-001": 2, "high temperature": 1},
+		ByComponent: map[string]int{"hashboard": 2},
+		Unsupported: map[string]string{"10.0.0.4": "error reporting is not supported for firmware \"cgminer\""},
+		Unreachable: []string{"10.0.0.3"},
+	}
+
+	var buf bytes.Buffer
+	WriteErrorReport(&buf, report, true)
+	out := buf.String()
+
+	for _, want := range []string{"Affected: 2", "HB-001", "By Component", "HB-001: 10.0.0.1, 10.0.0.2", "hint: Check the cable", "Unsupported: 1", "10.0.0.4: error reporting is not supported", "Unreachable: 10.0.0.3"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteErrorReportEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	WriteErrorReport(&buf, &fleet.ErrorReport{Scanned: 2}, false)
+	if !strings.Contains(buf.String(), "No active errors") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+}
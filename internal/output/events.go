@@ -0,0 +1,55 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/sinkers/miner-cli/internal/fleet"
+)
+
+// StatusEventWriter prints miner status changes as a colored log or as
+// newline delimited JSON
+type StatusEventWriter struct {
+	W    io.Writer
+	JSON bool
+}
+
+// Write prints one event
+func (w *StatusEventWriter) Write(e fleet.StatusEvent) error {
+	if w.JSON {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(w.W, string(data))
+		return err
+	}
+
+	old := e.Old
+	if old == "" {
+		old = "-"
+	}
+	line := fmt.Sprintf("%s %-15s %s -> %s",
+		e.Time.Local().Format(time.RFC3339), e.Host, statusColor(old), statusColor(e.New))
+	if e.Error != "" {
+		line += color.New(color.FgHiBlack).Sprintf(" (%s)", e.Error)
+	}
+	_, err := fmt.Fprintln(w.W, line)
+	return err
+}
+
+func statusColor(status string) string {
+	switch status {
+	case "normal":
+		return color.GreenString(status)
+	case "paused", "suspended", "restricted":
+		return color.YellowString(status)
+	case "-":
+		return status
+	default:
+		return color.RedString(status)
+	}
+}
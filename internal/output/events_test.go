@@ -0,0 +1,49 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/fleet"
+)
+
+func TestStatusEventWriter(t *testing.T) {
+	e := fleet.StatusEvent{
+		Time: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		Host: "10.0.0.1",
+		Old:  "normal",
+		New:  "paused",
+	}
+
+	var buf bytes.Buffer
+	w := &StatusEventWriter{W: &buf, JSON: true}
+	if err := w.Write(e); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := w.Write(e); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &decoded); err != nil {
+		t.Fatalf("invalid JSON line: %v", err)
+	}
+	if decoded["old_status"] != "normal" || decoded["new_status"] != "paused" {
+		t.Errorf("unexpected event: %v", decoded)
+	}
+
+	buf.Reset()
+	w.JSON = false
+	e.Old = ""
+	w.Write(e)
+	if out := buf.String(); !strings.Contains(out, "10.0.0.1") || !strings.Contains(out, "- -> ") {
+		t.Errorf("unexpected text output: %q", out)
+	}
+}
@@ -0,0 +1,49 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/sinkers/miner-cli/internal/fleet"
+)
+
+// WriteUpgradeReport prints the outcome of a firmware upgrade per host
+func WriteUpgradeReport(w io.Writer, report *fleet.UpgradeReport, verbose bool) {
+	bold := color.New(color.Bold).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+
+	fmt.Fprintf(w, "\n%s\n", bold("=== Firmware Upgrade ==="))
+	fmt.Fprintf(w, "Scanned: %d | Upgraded: %d | Up to date: %d | Failed: %d\n",
+		report.Scanned, len(report.Upgraded), len(report.Skipped), len(report.Failed))
+
+	if len(report.Upgraded) > 0 {
+		fmt.Fprintf(w, "\n%s\n", bold("=== Upgraded ==="))
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "Host\tFrom\tTo\tAttempts\tDuration")
+		fmt.Fprintln(tw, "----\t----\t--\t--------\t--------")
+		for _, u := range report.Upgraded {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", u.Host, dash(u.From), green(u.To), u.Attempts, u.Duration)
+		}
+		tw.Flush()
+	}
+
+	if len(report.Failed) > 0 {
+		fmt.Fprintf(w, "\n%s\n", bold("=== Failed ==="))
+		hosts := report.FailedHosts()
+		for _, host := range hosts {
+			fmt.Fprintf(w, "%s: %s\n", host, red(report.Failed[host]))
+		}
+		fmt.Fprintf(w, "\nRetry with: -i %s\n", strings.Join(hosts, ","))
+	}
+
+	fmt.Fprintf(w, "\n%s\n", bold("=== Versions ==="))
+	writeCounts(w, report.ByVersion)
+
+	if verbose && len(report.Skipped) > 0 {
+		fmt.Fprintf(w, "\n%s: %s\n", green("Up to date"), strings.Join(report.Skipped, ", "))
+	}
+}
@@ -35,13 +35,18 @@ type JSONFormatter struct {
 }
 
 func (f *JSONFormatter) Format(results []client.Result) error {
+	return PrintJSON(results, f.Pretty)
+}
+
+// PrintJSON writes v to stdout as JSON, indented when pretty is set
+func PrintJSON(v interface{}, pretty bool) error {
 	var data []byte
 	var err error
 
-	if f.Pretty {
-		data, err = json.MarshalIndent(results, "", "  ")
+	if pretty {
+		data, err = json.MarshalIndent(v, "", "  ")
 	} else {
-		data, err = json.Marshal(results)
+		data, err = json.Marshal(v)
 	}
 
 	if err != nil {
@@ -0,0 +1,252 @@
+package output
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+
+	"github.com/fatih/color"
+	"github.com/sinkers/miner-cli/internal/inventory"
+)
+
+// HeatmapCell is the temperature reading of one miner at its rack position
+type HeatmapCell struct {
+	IP    string  `json:"ip"`
+	Rack  string  `json:"rack"`
+	Shelf string  `json:"shelf"`
+	Temp  float64 `json:"temp"`
+	Error string  `json:"error,omitempty"`
+}
+
+// Heatmap renders miner temperatures as a rack by shelf grid. Racks are
+// columns and shelves are rows; when several miners share a position the
+// hottest one is shown.
+type Heatmap struct {
+	Cells []HeatmapCell `json:"cells"`
+	Warn  float64       `json:"warn"` // temperatures at or above Warn are highlighted
+	Hot   float64       `json:"hot"`  // temperatures at or above Hot are hot spots
+}
+
+type heatmapGrid struct {
+	racks   []string
+	shelves []string
+	cells   map[string]map[string]HeatmapCell
+}
+
+func (h *Heatmap) grid() heatmapGrid {
+	g := heatmapGrid{cells: make(map[string]map[string]HeatmapCell)}
+	seenRack := make(map[string]bool)
+	seenShelf := make(map[string]bool)
+
+	for _, c := range h.Cells {
+		if !seenRack[c.Rack] {
+			seenRack[c.Rack] = true
+			g.racks = append(g.racks, c.Rack)
+		}
+		if !seenShelf[c.Shelf] {
+			seenShelf[c.Shelf] = true
+			g.shelves = append(g.shelves, c.Shelf)
+		}
+		if g.cells[c.Shelf] == nil {
+			g.cells[c.Shelf] = make(map[string]HeatmapCell)
+		}
+		if existing, ok := g.cells[c.Shelf][c.Rack]; !ok || c.Temp > existing.Temp {
+			g.cells[c.Shelf][c.Rack] = c
+		}
+	}
+
+	inventory.SortNatural(g.racks)
+	inventory.SortNatural(g.shelves)
+	return g
+}
+
+// HotSpots returns the cells at or above the hot threshold, hottest first
+func (h *Heatmap) HotSpots() []HeatmapCell {
+	var spots []HeatmapCell
+	for _, c := range h.Cells {
+		if c.Error == "" && c.Temp >= h.Hot {
+			spots = append(spots, c)
+		}
+	}
+	sort.Slice(spots, func(i, j int) bool {
+		return spots[i].Temp > spots[j].Temp
+	})
+	return spots
+}
+
+// WriteTerminal prints the grid with colored cells
+func (h *Heatmap) WriteTerminal(w io.Writer) {
+	g := h.grid()
+	bold := color.New(color.Bold).SprintFunc()
+	cold := color.New(color.BgGreen, color.FgBlack).SprintFunc()
+	warm := color.New(color.BgYellow, color.FgBlack).SprintFunc()
+	hot := color.New(color.BgRed, color.FgWhite, color.Bold).SprintFunc()
+	missing := color.New(color.FgHiBlack).SprintFunc()
+
+	const width = 7
+
+	fmt.Fprintf(w, "\n%s\n", bold("=== Facility Heatmap ==="))
+	fmt.Fprintf(w, "%-8s", "Shelf")
+	for _, rack := range g.racks {
+		fmt.Fprintf(w, " %*s", width, truncate(rack, width))
+	}
+	fmt.Fprintln(w)
+
+	for _, shelf := range g.shelves {
+		fmt.Fprintf(w, "%-8s", truncate(shelf, 8))
+		for _, rack := range g.racks {
+			c, ok := g.cells[shelf][rack]
+			switch {
+			case !ok:
+				fmt.Fprintf(w, " %*s", width, "")
+			case c.Error != "" || c.Temp == 0:
+				fmt.Fprintf(w, " %s", missing(fmt.Sprintf("%*s", width, "--")))
+			case c.Temp >= h.Hot:
+				fmt.Fprintf(w, " %s", hot(fmt.Sprintf("%*.1f", width, c.Temp)))
+			case c.Temp >= h.Warn:
+				fmt.Fprintf(w, " %s", warm(fmt.Sprintf("%*.1f", width, c.Temp)))
+			default:
+				fmt.Fprintf(w, " %s", cold(fmt.Sprintf("%*.1f", width, c.Temp)))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "\nLegend: %s < %.0f°C <= %s < %.0f°C <= %s, %s no data\n",
+		cold(" ok "), h.Warn, warm(" warm "), h.Hot, hot(" hot "), missing("--"))
+
+	if spots := h.HotSpots(); len(spots) > 0 {
+		fmt.Fprintf(w, "\n%s\n", bold("=== Hot Spots ==="))
+		for _, c := range spots {
+			fmt.Fprintf(w, "%s rack %s shelf %s: %.1f°C\n", c.IP, c.Rack, c.Shelf, c.Temp)
+		}
+	}
+}
+
+// WriteHTML writes a standalone HTML page containing the grid as SVG
+func (h *Heatmap) WriteHTML(w io.Writer) error {
+	g := h.grid()
+
+	const cellW, cellH, labelW, labelH = 90, 40, 80, 30
+
+	type svgCell struct {
+		X, Y  int
+		Fill  string
+		Label string
+		Title string
+	}
+	type svgLabel struct {
+		X, Y int
+		Text string
+	}
+
+	data := struct {
+		Width, Height int
+		CellW, CellH  int
+		Cells         []svgCell
+		Racks         []svgLabel
+		Shelves       []svgLabel
+		HotSpots      []HeatmapCell
+		Warn, Hot     float64
+	}{
+		Width:    labelW + len(g.racks)*cellW,
+		Height:   labelH + len(g.shelves)*cellH,
+		CellW:    cellW - 4,
+		CellH:    cellH - 4,
+		HotSpots: h.HotSpots(),
+		Warn:     h.Warn,
+		Hot:      h.Hot,
+	}
+
+	for i, rack := range g.racks {
+		data.Racks = append(data.Racks, svgLabel{X: labelW + i*cellW + cellW/2, Y: labelH - 10, Text: rack})
+	}
+	for j, shelf := range g.shelves {
+		data.Shelves = append(data.Shelves, svgLabel{X: labelW - 10, Y: labelH + j*cellH + cellH/2 + 5, Text: shelf})
+		for i, rack := range g.racks {
+			c, ok := g.cells[shelf][rack]
+			if !ok {
+				continue
+			}
+			cell := svgCell{
+				X:     labelW + i*cellW,
+				Y:     labelH + j*cellH,
+				Fill:  h.fill(c),
+				Label: "--",
+				Title: fmt.Sprintf("%s rack %s shelf %s", c.IP, c.Rack, c.Shelf),
+			}
+			if c.Error != "" {
+				cell.Title += ": " + c.Error
+			} else if c.Temp > 0 {
+				cell.Label = fmt.Sprintf("%.1f", c.Temp)
+				cell.Title += fmt.Sprintf(": %.1f°C", c.Temp)
+			}
+			data.Cells = append(data.Cells, cell)
+		}
+	}
+
+	return heatmapTemplate.Execute(w, data)
+}
+
+// fill returns a color on a green to red scale
+func (h *Heatmap) fill(c HeatmapCell) string {
+	if c.Error != "" || c.Temp == 0 {
+		return "#cccccc"
+	}
+	if c.Temp >= h.Hot {
+		return "#d73027"
+	}
+	if c.Temp < h.Warn || h.Hot <= h.Warn {
+		return "#1a9850"
+	}
+	// interpolate between yellow and orange inside the warning band
+	ratio := (c.Temp - h.Warn) / (h.Hot - h.Warn)
+	return fmt.Sprintf("#%02x%02x%02x", 0xfe, int(0xe0-ratio*0x80), int(0x8b-ratio*0x60))
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+var heatmapTemplate = template.Must(template.New("heatmap").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Miner Heatmap</title>
+<style>
+body { font-family: sans-serif; }
+text { font-size: 13px; }
+table { border-collapse: collapse; }
+td, th { padding: 2px 8px; text-align: left; }
+</style>
+</head>
+<body>
+<h1>Miner Heatmap</h1>
+<p>Warning at {{printf "%.0f" .Warn}}&deg;C, hot at {{printf "%.0f" .Hot}}&deg;C.</p>
+<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="{{.Height}}">
+{{- range .Racks}}
+<text x="{{.X}}" y="{{.Y}}" text-anchor="middle">{{.Text}}</text>
+{{- end}}
+{{- range .Shelves}}
+<text x="{{.X}}" y="{{.Y}}" text-anchor="end">{{.Text}}</text>
+{{- end}}
+{{- range .Cells}}
+<g><title>{{.Title}}</title><rect x="{{.X}}" y="{{.Y}}" width="{{$.CellW}}" height="{{$.CellH}}" fill="{{.Fill}}"/><text x="{{.X}}" y="{{.Y}}" dx="43" dy="23" text-anchor="middle">{{.Label}}</text></g>
+{{- end}}
+</svg>
+{{- if .HotSpots}}
+<h2>Hot Spots</h2>
+<table>
+<tr><th>IP</th><th>Rack</th><th>Shelf</th><th>Temperature</th></tr>
+{{- range .HotSpots}}
+<tr><td>{{.IP}}</td><td>{{.Rack}}</td><td>{{.Shelf}}</td><td>{{printf "%.1f" .Temp}}&deg;C</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
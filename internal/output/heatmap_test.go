@@ -0,0 +1,74 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func testHeatmap() *Heatmap {
+	return &Heatmap{
+		Warn: 75,
+		Hot:  85,
+		Cells: []HeatmapCell{
+			{IP: "10.0.0.1", Rack: "1", Shelf: "1", Temp: 60},
+			{IP: "10.0.0.2", Rack: "2", Shelf: "1", Temp: 80},
+			{IP: "10.0.0.3", Rack: "10", Shelf: "2", Temp: 91.5},
+			{IP: "10.0.0.4", Rack: "1", Shelf: "2", Error: "connection refused"},
+			{IP: "10.0.0.5", Rack: "1", Shelf: "1", Temp: 70},
+		},
+	}
+}
+
+func TestHeatmapGrid(t *testing.T) {
+	g := testHeatmap().grid()
+
+	if strings.Join(g.racks, ",") != "1,2,10" {
+		t.Errorf("unexpected racks: %v", g.racks)
+	}
+	if strings.Join(g.shelves, ",") != "1,2" {
+		t.Errorf("unexpected shelves: %v", g.shelves)
+	}
+	if c := g.cells["1"]["1"]; c.IP != "10.0.0.5" {
+		t.Errorf("expected hottest miner in shared cell, got %+v", c)
+	}
+}
+
+func TestHeatmapHotSpots(t *testing.T) {
+	spots := testHeatmap().HotSpots()
+	if len(spots) != 1 || spots[0].IP != "10.0.0.3" {
+		t.Errorf("unexpected hot spots: %+v", spots)
+	}
+}
+
+func TestHeatmapWriteTerminal(t *testing.T) {
+	var buf bytes.Buffer
+	testHeatmap().WriteTerminal(&buf)
+	out := buf.String()
+
+	for _, want := range []string{"Facility Heatmap", "91.5", "80.0", "--", "Hot Spots", "10.0.0.3 rack 10 shelf 2"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q\n%s", want, out)
+		}
+	}
+}
+
+func TestHeatmapWriteHTML(t *testing.T) {
+	h := testHeatmap()
+	h.Cells = append(h.Cells, HeatmapCell{IP: "10.0.0.6", Rack: "<b>", Shelf: "3", Temp: 50})
+
+	var buf bytes.Buffer
+	if err := h.WriteHTML(&buf); err != nil {
+		t.Fatalf("WriteHTML failed: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{"<svg", "#d73027", "#1a9850", "#cccccc", "connection refused", "Hot Spots"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected HTML to contain %q", want)
+		}
+	}
+	if strings.Contains(out, "<b>") {
+		t.Error("rack names should be escaped")
+	}
+}
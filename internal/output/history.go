@@ -0,0 +1,79 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/sinkers/miner-cli/internal/fleet"
+)
+
+// WriteRecordReport prints the outcome of a history record run
+func WriteRecordReport(w io.Writer, report *fleet.RecordReport, verbose bool) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	fmt.Fprintf(w, "%s recorded %d samples, %d failed\n", report.Time.Local().Format("15:04:05"), report.Recorded, len(report.Failed))
+	if verbose && len(report.Failed) > 0 {
+		hosts := make([]string, 0, len(report.Failed))
+		for host := range report.Failed {
+			hosts = append(hosts, host)
+		}
+		sort.Strings(hosts)
+		for _, host := range hosts {
+			fmt.Fprintf(w, "  %s %s: %s\n", red("failed"), host, report.Failed[host])
+		}
+	}
+}
+
+// WriteHistoryReport prints the aggregated history of each miner, with its
+// buckets when the report was bucketed
+func WriteHistoryReport(w io.Writer, report *fleet.HistoryReport, verbose bool) {
+	bold := color.New(color.Bold).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	title := "=== History ==="
+	if report.Bucket != "" {
+		title = "=== History (" + report.Bucket + " buckets) ==="
+	}
+	fmt.Fprintf(w, "\n%s\n", bold(title))
+	if len(report.Hosts) == 0 {
+		fmt.Fprintln(w, "No samples recorded for these miners in this period")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Host\tModel\tSamples\tFrom\tTo\tMin TH/s\tAvg TH/s\tMax TH/s\tDropping Since")
+	fmt.Fprintln(tw, "----\t-----\t-------\t----\t--\t--------\t--------\t--------\t--------------")
+	for _, h := range report.Hosts {
+		drop := "-"
+		if h.DropSince != nil {
+			drop = yellow(formatHistoryTime(*h.DropSince))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%.2f\t%.2f\t%.2f\t%s\n", h.Host, h.Model, h.Samples,
+			formatHistoryTime(h.First), formatHistoryTime(h.Last), h.HashRate.Min, h.HashRate.Avg, h.HashRate.Max, drop)
+	}
+	tw.Flush()
+
+	for _, h := range report.Hosts {
+		if len(h.Buckets) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s\n", bold(strings.TrimSpace(h.Host+" "+h.Model)))
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "Start\tSamples\tAvg TH/s\tMin TH/s\tPower\tMax Temp\tHW Errors")
+		fmt.Fprintln(tw, "-----\t-------\t--------\t--------\t-----\t--------\t---------")
+		for _, b := range h.Buckets {
+			fmt.Fprintf(tw, "%s\t%d\t%.2f\t%.2f\t%.0f W\t%.0f°C\t%d\n", formatHistoryTime(b.Start), b.Samples,
+				b.HashRate.Avg, b.HashRate.Min, b.Power.Avg, b.Temp.Max, b.HardwareErrors)
+		}
+		tw.Flush()
+	}
+}
+
+func formatHistoryTime(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04")
+}
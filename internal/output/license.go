@@ -0,0 +1,50 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/sinkers/miner-cli/internal/fleet"
+)
+
+// WriteLicenseReport prints license counts and the miners needing attention
+func WriteLicenseReport(w io.Writer, report *fleet.LicenseReport, verbose bool) {
+	bold := color.New(color.Bold).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+
+	fmt.Fprintf(w, "\n%s\n", bold("=== Licenses ==="))
+	fmt.Fprintf(w, "Scanned: %d | Flagged: %d | Unreachable: %d\n", report.Scanned, len(report.Flagged), len(report.Unreachable))
+
+	fmt.Fprintf(w, "\n%s\n", bold("=== By State ==="))
+	writeCounts(w, report.ByState)
+	if len(report.ByDevFee) > 0 {
+		fmt.Fprintf(w, "\n%s\n", bold("=== By Dev Fee ==="))
+		writeCounts(w, report.ByDevFee)
+	}
+
+	if len(report.Flagged) == 0 {
+		fmt.Fprintf(w, "\n%s\n", green("All reachable miners have a valid license"))
+	} else {
+		fmt.Fprintf(w, "\n%s\n", bold("=== Needs Attention ==="))
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "Host\tIssue\tState\tDetail")
+		fmt.Fprintln(tw, "----\t-----\t-----\t------")
+		for _, f := range report.Flagged {
+			issue := red(f.Issue)
+			if f.Issue == fleet.LicenseExpiring {
+				issue = yellow(f.Issue)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", f.Host, issue, f.State, dash(f.Detail))
+		}
+		tw.Flush()
+	}
+
+	if verbose && len(report.Unreachable) > 0 {
+		fmt.Fprintf(w, "\n%s: %s\n", red("Unreachable"), strings.Join(report.Unreachable, ", "))
+	}
+}
@@ -0,0 +1,47 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/sinkers/miner-cli/internal/fleet"
+)
+
+// WriteLockReport prints which vnish miners are locked
+func WriteLockReport(w io.Writer, report *fleet.LockReport, verbose bool) {
+	bold := color.New(color.Bold).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	fmt.Fprintf(w, "\n%s\n", bold("=== Lock Status ==="))
+	fmt.Fprintf(w, "Scanned: %d | Locked: %d | Unlocked: %d | Unreachable: %d\n",
+		report.Scanned, len(report.Locked), len(report.Unlocked), len(report.Unreachable))
+
+	if len(report.Locked) > 0 {
+		fmt.Fprintf(w, "\n%s\n", bold("=== Locked ==="))
+		for _, s := range report.Locked {
+			line := yellow(s.Host)
+			if s.UnlockTimeout > 0 {
+				line += fmt.Sprintf(" (unlocks in %s)", time.Duration(s.UnlockTimeout)*time.Second)
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+
+	if verbose && len(report.Unlocked) > 0 {
+		fmt.Fprintf(w, "\nUnlocked: %s\n", strings.Join(report.Unlocked, ", "))
+	}
+
+	if verbose && len(report.Unreachable) > 0 {
+		hosts := make([]string, 0, len(report.Unreachable))
+		for host := range report.Unreachable {
+			hosts = append(hosts, host)
+		}
+		sort.Strings(hosts)
+		fmt.Fprintf(w, "\n%s: %s\n", red("Unreachable"), strings.Join(hosts, ", "))
+	}
+}
@@ -0,0 +1,55 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/sinkers/miner-cli/internal/fleet"
+)
+
+// LogWriter prints collected miner logs tagged by host as colored text or
+// as newline delimited JSON
+type LogWriter struct {
+	W    io.Writer
+	JSON bool
+}
+
+// Write prints one log line
+func (w *LogWriter) Write(line fleet.LogLine) error {
+	if w.JSON {
+		data, err := json.Marshal(line)
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(w.W, string(data))
+		return err
+	}
+
+	stamp := "-"
+	if !line.Time.IsZero() {
+		stamp = line.Time.Local().Format(time.RFC3339)
+	}
+	source := line.Type
+	if line.Source != "" {
+		source = line.Source
+	}
+	_, err := fmt.Fprintf(w.W, "%s %-15s %s %s%s\n",
+		stamp, line.Host, color.New(color.FgHiBlack).Sprintf("[%s]", source), levelColor(line.Level), line.Message)
+	return err
+}
+
+func levelColor(level string) string {
+	switch level {
+	case "":
+		return ""
+	case "error":
+		return color.RedString(level) + " "
+	case "warn", "warning":
+		return color.YellowString(level) + " "
+	default:
+		return level + " "
+	}
+}
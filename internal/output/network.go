@@ -0,0 +1,45 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/sinkers/miner-cli/internal/fleet"
+)
+
+// WriteMigrationPlan prints the planned address changes and any problems
+func WriteMigrationPlan(w io.Writer, plan *fleet.MigrationPlan) {
+	bold := color.New(color.Bold).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	fmt.Fprintf(w, "\n%s\n", bold("=== Network Migration Plan ==="))
+	if len(plan.Steps) == 0 {
+		fmt.Fprintln(w, "No scanned miner matches the mapping")
+	} else {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "Host\tMAC\tNew Address\tGateway\tDNS\tHostname")
+		fmt.Fprintln(tw, "----\t---\t-----------\t-------\t---\t--------")
+		for _, s := range plan.Steps {
+			fmt.Fprintf(tw, "%s\t%s\t%s/%s\t%s\t%s\t%s\n", s.Host, s.MAC, s.To.IP, s.To.Netmask,
+				s.To.Gateway, dash(strings.Join(s.To.DNS, " ")), dash(s.To.Hostname))
+		}
+		tw.Flush()
+	}
+
+	if len(plan.Unmatched) > 0 {
+		fmt.Fprintf(w, "\n%s: %s\n", yellow("Mapped MACs not found"), strings.Join(plan.Unmatched, ", "))
+	}
+	if len(plan.Unmapped) > 0 {
+		fmt.Fprintf(w, "%s: %s\n", yellow("Miners without a mapping"), strings.Join(plan.Unmapped, ", "))
+	}
+	if len(plan.Conflicts) > 0 {
+		fmt.Fprintf(w, "\n%s\n", red("Address conflicts:"))
+		for _, c := range plan.Conflicts {
+			fmt.Fprintf(w, "  %s\n", c)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/sinkers/miner-cli/internal/fleet"
+)
+
+// WriteNotes prints maintenance notes of the fleet, oldest first
+func WriteNotes(w io.Writer, history *fleet.MaintenanceHistory, verbose bool) {
+	bold := color.New(color.Bold).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+
+	fmt.Fprintf(w, "\n%s\n", bold("=== Maintenance Notes ==="))
+	fmt.Fprintf(w, "Scanned: %d | Notes: %d | Unreachable: %d\n", history.Scanned, len(history.Notes), len(history.Unreachable))
+
+	if len(history.Notes) > 0 {
+		fmt.Fprintln(w)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "Time\tHost\tCategory\tAuthor\tNote")
+		fmt.Fprintln(tw, "----\t----\t--------\t------\t----")
+		for _, n := range history.Notes {
+			text := strings.Join(strings.Fields(n.Text), " ")
+			if verbose {
+				text += fmt.Sprintf(" (%s #%s)", n.Stored, n.ID)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+				n.Time.Local().Format(time.RFC3339), n.Host, dash(n.Category), dash(n.Author), text)
+		}
+		tw.Flush()
+	}
+
+	if verbose && len(history.Unreachable) > 0 {
+		hosts := make([]string, 0, len(history.Unreachable))
+		for host := range history.Unreachable {
+			hosts = append(hosts, host)
+		}
+		sort.Strings(hosts)
+		fmt.Fprintf(w, "\n%s: %s\n", red("Unreachable"), strings.Join(hosts, ", "))
+	}
+}
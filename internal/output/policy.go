@@ -0,0 +1,41 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/sinkers/miner-cli/internal/policy"
+)
+
+// WritePolicyReport prints the write commands an operator may run
+func WritePolicyReport(w io.Writer, report *policy.Report) {
+	bold := color.New(color.Bold).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+
+	fmt.Fprintf(w, "\n%s\n", bold("=== Policy ==="))
+	if report.Path == "" {
+		fmt.Fprintln(w, "Policy: none")
+	} else {
+		fmt.Fprintf(w, "Policy: %s\n", report.Path)
+	}
+	role := report.Role
+	if role == "" {
+		role = "-"
+	}
+	fmt.Fprintf(w, "User: %s | Role: %s | Read-only mode: %t\n\n", report.User, role, report.ReadOnly)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Write Command\tAllowed\tReason")
+	fmt.Fprintln(tw, "-------------\t-------\t------")
+	for _, a := range report.Writes {
+		allowed := green("yes")
+		if !a.Allowed {
+			allowed = red("no")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", a.Command, allowed, a.Reason)
+	}
+	tw.Flush()
+}
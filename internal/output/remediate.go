@@ -0,0 +1,52 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/sinkers/miner-cli/internal/remediate"
+)
+
+// RemediationWriter prints remediation audit entries as a colored log or
+// as newline delimited JSON
+type RemediationWriter struct {
+	W    io.Writer
+	JSON bool
+}
+
+// Write prints one entry
+func (w *RemediationWriter) Write(e remediate.Entry) error {
+	if w.JSON {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(w.W, string(data))
+		return err
+	}
+
+	var event, detail string
+	switch e.Event {
+	case remediate.EventAction:
+		event = color.YellowString("ACTION")
+		detail = fmt.Sprintf("%s (attempt %d)", e.Action, e.Attempt)
+		if e.DryRun {
+			detail += " [dry-run]"
+		}
+	case remediate.EventExhausted:
+		event = color.RedString("GAVE UP")
+		detail = fmt.Sprintf("after %d attempts", e.Attempt)
+	default:
+		event = color.GreenString("RECOVERED")
+		detail = fmt.Sprintf("after %s", e.Time.Sub(e.Since).Round(time.Second))
+	}
+	if e.Error != "" {
+		detail += color.RedString(" failed: %s", e.Error)
+	}
+	_, err := fmt.Fprintf(w.W, "%s %-15s %s %s [%s] %s\n",
+		e.Time.Local().Format(time.RFC3339), e.Host, event, e.Playbook, e.Condition, detail)
+	return err
+}
@@ -0,0 +1,292 @@
+// Package policy classifies commands as reads or writes and decides which
+// ones an operator may run.
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// EnvPath names the environment variable pointing at a policy file
+const EnvPath = "MINER_CLI_POLICY"
+
+// SystemPath is the machine wide policy. When it exists it is the policy,
+// whatever the environment names.
+const SystemPath = "/etc/miner-cli/policy.json"
+
+// systemPath is SystemPath, replaced by tests
+var systemPath = SystemPath
+
+// writeCommands change miners or local state
+var writeCommands = map[string]bool{
+	"addpool":     true,
+	"removepool":  true,
+	"switchpool":  true,
+	"enablepool":  true,
+	"disablepool": true,
+	"restart":     true,
+	"quit":        true,
+
+	"bos config apply":  true,
+	"bos license apply": true,
+	"bos network set":   true,
+	"bos passwd":        true,
+	"cooling set":       true,
+	"locate on":         true,
+	"locate off":        true,
+	"notes add":         true,
+	"logs clear":        true,
+	"remediate":         true,
+	"history prune":     true,
+	"serve":             true, // runs write jobs for API clients
+
+	"credentials set":    true,
+	"credentials remove": true,
+
+	"vnish apikey provision": true,
+	"vnish apikey rotate":    true,
+	"vnish apikey revoke":    true,
+	"vnish autotune rollout": true,
+	"vnish firmware upgrade": true,
+	"vnish lock":             true,
+	"vnish unlock":           true,
+	"vnish settings apply":   true,
+	"vnish settings restore": true,
+
+	// serve API jobs without a command of their own
+	"restart-mining": true,
+	"reboot":         true,
+}
+
+// readAPICommands are the CGMiner API commands that only report; any other
+// command sent through custom counts as a write
+var readAPICommands = map[string]bool{
+	"version": true, "config": true, "summary": true, "pools": true, "devs": true, "edevs": true,
+	"devdetails": true, "stats": true, "estats": true, "coin": true, "notify": true, "lcd": true,
+	"check": true, "privileged": true, "asc": true, "asccount": true, "pga": true, "pgacount": true,
+	"gpu": true, "gpucount": true, "usbstats": true,
+}
+
+// WriteCommands lists the write commands by path, e.g. "vnish settings apply"
+func WriteCommands() []string {
+	commands := make([]string, 0, len(writeCommands)+1)
+	for c := range writeCommands {
+		commands = append(commands, c)
+	}
+	commands = append(commands, "custom")
+	sort.Strings(commands)
+	return commands
+}
+
+// APICommands splits a custom CGMiner command such as "summary+pools" or
+// "addpool|url,user,pass" into its lower case command names
+func APICommands(custom string) []string {
+	var names []string
+	for _, part := range strings.Split(custom, "+") {
+		name := strings.ToLower(strings.TrimSpace(strings.SplitN(part, "|", 2)[0]))
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// IsWrite reports whether command changes anything. A custom command is a
+// write unless every API command in it is a known read.
+func IsWrite(command, custom string) bool {
+	if command != "custom" {
+		return writeCommands[command]
+	}
+	names := APICommands(custom)
+	if len(names) == 0 {
+		return true
+	}
+	for _, name := range names {
+		if !readAPICommands[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// Role is what a group of operators may run. Commands match by path or
+// path prefix, "vnish settings" covering "vnish settings apply".
+type Role struct {
+	ReadOnly    bool     `json:"read_only,omitempty"`
+	Allow       []string `json:"allow,omitempty"`        // writes allowed despite read_only
+	Deny        []string `json:"deny,omitempty"`         // commands never allowed
+	CustomAllow []string `json:"custom_allow,omitempty"` // only these custom API commands, all when empty
+	CustomDeny  []string `json:"custom_deny,omitempty"`  // custom API commands never allowed
+}
+
+// Config is a policy file mapping operators to roles
+type Config struct {
+	DefaultRole string            `json:"default_role,omitempty"`
+	Roles       map[string]Role   `json:"roles"`
+	Users       map[string]string `json:"users,omitempty"`        // user name to role
+	AuditLog    string            `json:"audit_log,omitempty"`    // replaces --audit-log
+	AuditSyslog bool              `json:"audit_syslog,omitempty"` // forces --audit-syslog
+}
+
+// Paths returns where a policy is looked up, first found wins. The system
+// policy comes first so an operator cannot replace it with a file of their
+// own; $MINER_CLI_POLICY, else the user's policy, only apply without one.
+func Paths() []string {
+	paths := []string{systemPath}
+	if path := os.Getenv(EnvPath); path != "" {
+		return append(paths, path)
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "miner-cli", "policy.json"))
+	}
+	return paths
+}
+
+// Find loads the first policy of Paths, nil if there is none. A policy
+// named by the environment must exist.
+func Find() (*Config, string, error) {
+	named := os.Getenv(EnvPath)
+	for _, path := range Paths() {
+		c, err := Load(path)
+		if errors.Is(err, os.ErrNotExist) && (named == "" || path != named) {
+			continue
+		}
+		if err != nil {
+			return nil, path, err
+		}
+		return c, path, nil
+	}
+	return nil, "", nil
+}
+
+// Load reads and validates a policy file
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+	c := &Config{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse policy %s: %w", path, err)
+	}
+	if c.DefaultRole != "" {
+		if _, ok := c.Roles[c.DefaultRole]; !ok {
+			return nil, fmt.Errorf("policy %s: unknown default role %q", path, c.DefaultRole)
+		}
+	}
+	for user, role := range c.Users {
+		if _, ok := c.Roles[role]; !ok {
+			return nil, fmt.Errorf("policy %s: unknown role %q for user %s", path, role, user)
+		}
+	}
+	return c, nil
+}
+
+// RoleFor returns the role of user. A user without a role, and no default
+// role, may only read.
+func (c *Config) RoleFor(user string) (string, Role) {
+	name, ok := c.Users[user]
+	if !ok {
+		name = c.DefaultRole
+	}
+	if role, ok := c.Roles[name]; ok {
+		return name, role
+	}
+	return "", Role{ReadOnly: true}
+}
+
+// DeniedError explains why a command was refused
+type DeniedError struct {
+	Command string
+	Reason  string
+}
+
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("%s is not allowed: %s", e.Command, e.Reason)
+}
+
+// Check decides whether a role may run command; custom is the API command
+// of custom and readOnly the global --read-only mode, which no role lifts
+func (r Role) Check(command, custom string, readOnly bool) error {
+	if matches(r.Deny, command) {
+		return &DeniedError{command, "denied by policy"}
+	}
+	if command == "custom" {
+		for _, name := range APICommands(custom) {
+			if contains(r.CustomDeny, name) {
+				return &DeniedError{command, fmt.Sprintf("API command %q is denied by policy", name)}
+			}
+			if len(r.CustomAllow) > 0 && !contains(r.CustomAllow, name) {
+				return &DeniedError{command, fmt.Sprintf("API command %q is not in the allowed list", name)}
+			}
+		}
+	}
+	if !IsWrite(command, custom) {
+		return nil
+	}
+	if readOnly {
+		return &DeniedError{command, "read-only mode"}
+	}
+	if r.ReadOnly && !matches(r.Allow, command) {
+		return &DeniedError{command, "the role may only read"}
+	}
+	return nil
+}
+
+// matches reports whether command is one of the paths or below one of them
+func matches(paths []string, command string) bool {
+	for _, p := range paths {
+		if command == p || strings.HasPrefix(command, p+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Access is whether the write commands are allowed for an operator
+type Access struct {
+	Command string `json:"command"`
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Report describes the policy in effect for an operator
+type Report struct {
+	Path     string   `json:"path,omitempty"` // empty when there is no policy
+	User     string   `json:"user"`
+	Role     string   `json:"role,omitempty"`
+	ReadOnly bool     `json:"read_only"` // --read-only mode
+	Writes   []Access `json:"writes"`
+}
+
+// Summarize reports the write commands user may run under c, which may
+// be nil for no policy
+func Summarize(c *Config, path, user string, readOnly bool) *Report {
+	report := &Report{Path: path, User: user, ReadOnly: readOnly}
+	var role Role
+	if c != nil {
+		report.Role, role = c.RoleFor(user)
+	}
+	for _, command := range WriteCommands() {
+		a := Access{Command: command, Allowed: true}
+		if err := role.Check(command, "", readOnly); err != nil {
+			a.Allowed, a.Reason = false, err.(*DeniedError).Reason
+		}
+		report.Writes = append(report.Writes, a)
+	}
+	return report
+}
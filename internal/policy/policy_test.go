@@ -0,0 +1,152 @@
+package policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testPolicy = `{
+  "default_role": "operator",
+  "roles": {
+    "viewer": {"read_only": true, "allow": ["locate"], "custom_allow": ["summary", "pools", "stats"]},
+    "operator": {"deny": ["vnish firmware", "quit"], "custom_deny": ["quit", "restart"]},
+    "admin": {}
+  },
+  "users": {"alice": "viewer", "root": "admin"}
+}`
+
+func loadTest(t *testing.T, policy string) *Config {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(policy), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	return c
+}
+
+func TestIsWrite(t *testing.T) {
+	for _, tc := range []struct {
+		command, custom string
+		want            bool
+	}{
+		{"summary", "", false},
+		{"addpool", "", true},
+		{"serve", "", true},
+		{"vnish settings apply", "", true},
+		{"vnish settings diff", "", false},
+		{"custom", "summary", false},
+		{"custom", "Summary+pools", false},
+		{"custom", "summary+restart", true},
+		{"custom", "addpool|url,user,pass", true},
+		{"custom", "ascset|0,freq,600", true},
+		{"custom", "", true},
+	} {
+		if got := IsWrite(tc.command, tc.custom); got != tc.want {
+			t.Errorf("IsWrite(%q, %q) = %v, want %v", tc.command, tc.custom, got, tc.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	c := loadTest(t, testPolicy)
+
+	if name, _ := c.RoleFor("bob"); name != "operator" {
+		t.Errorf("expected the default role, got %q", name)
+	}
+	_, viewer := c.RoleFor("alice")
+	_, operator := c.RoleFor("bob")
+	_, admin := c.RoleFor("root")
+
+	for _, tc := range []struct {
+		name            string
+		role            Role
+		command, custom string
+		readOnly        bool
+		allowed         bool
+	}{
+		{"viewer reads", viewer, "summary", "", false, true},
+		{"viewer writes", viewer, "addpool", "", false, false},
+		{"viewer allowed write", viewer, "locate on", "", false, true},
+		{"viewer custom read", viewer, "custom", "stats", false, true},
+		{"viewer custom not listed", viewer, "custom", "devs", false, false},
+		{"operator writes", operator, "vnish settings apply", "", false, true},
+		{"operator denied prefix", operator, "vnish firmware upgrade", "", false, false},
+		{"operator denied command", operator, "quit", "", false, false},
+		{"operator custom denied", operator, "custom", "summary+restart", false, false},
+		{"operator custom write", operator, "custom", "ascset|0,freq,600", false, true},
+		{"read-only flag", admin, "restart", "", true, false},
+		{"read-only flag reads", admin, "custom", "summary", true, true},
+		{"read-only flag over allow", viewer, "locate on", "", true, false},
+	} {
+		err := tc.role.Check(tc.command, tc.custom, tc.readOnly)
+		if (err == nil) != tc.allowed {
+			t.Errorf("%s: expected allowed=%v, got %v", tc.name, tc.allowed, err)
+		}
+		var denied *DeniedError
+		if err != nil && !errors.As(err, &denied) {
+			t.Errorf("%s: expected a DeniedError, got %T", tc.name, err)
+		}
+	}
+}
+
+func TestRoleWithoutDefault(t *testing.T) {
+	c := loadTest(t, `{"roles": {"admin": {}}, "users": {"root": "admin"}}`)
+	name, role := c.RoleFor("mallory")
+	if name != "" || role.Check("restart", "", false) == nil || role.Check("summary", "", false) != nil {
+		t.Errorf("expected a user without a role to only read, got %q %+v", name, role)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	dir := t.TempDir()
+	for name, policy := range map[string]string{
+		"json":         `{"roles": `,
+		"default role": `{"default_role": "ops", "roles": {}}`,
+		"user role":    `{"roles": {}, "users": {"alice": "ops"}}`,
+	} {
+		path := filepath.Join(dir, "policy.json")
+		os.WriteFile(path, []byte(policy), 0600)
+		if _, err := Load(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	systemPath = filepath.Join(dir, "system.json")
+	defer func() { systemPath = SystemPath }()
+	path := filepath.Join(dir, "policy.json")
+	t.Setenv(EnvPath, path)
+	if _, _, err := Find(); err == nil {
+		t.Error("expected an error for a missing policy named by the environment")
+	}
+	os.WriteFile(path, []byte(testPolicy), 0600)
+	c, found, err := Find()
+	if err != nil || c == nil || found != path {
+		t.Errorf("unexpected result: %v %q %v", c, found, err)
+	}
+}
+
+func TestFindSystemWins(t *testing.T) {
+	dir := t.TempDir()
+	systemPath = filepath.Join(dir, "system.json")
+	defer func() { systemPath = SystemPath }()
+	os.WriteFile(systemPath, []byte(testPolicy), 0600)
+	path := filepath.Join(dir, "policy.json")
+	os.WriteFile(path, []byte(`{"default_role": "admin", "roles": {"admin": {}}}`), 0600)
+	t.Setenv(EnvPath, path)
+
+	c, found, err := Find()
+	if err != nil || found != systemPath {
+		t.Fatalf("expected the system policy, got %q %v", found, err)
+	}
+	if c.DefaultRole != "operator" {
+		t.Errorf("expected the environment's policy to be ignored, got default role %q", c.DefaultRole)
+	}
+}
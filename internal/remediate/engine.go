@@ -0,0 +1,171 @@
+package remediate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/alert"
+	"github.com/sinkers/miner-cli/internal/fleet"
+)
+
+// Audit trail events
+const (
+	EventAction    = "action"    // a step was taken
+	EventExhausted = "exhausted" // max attempts reached, a human takes over
+	EventRecovered = "recovered" // the condition cleared
+)
+
+// Entry is one line of the audit trail
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Event     string    `json:"event"`
+	Playbook  string    `json:"playbook"`
+	Condition string    `json:"condition"`
+	Host      string    `json:"host"`
+	Model     string    `json:"model,omitempty"`
+	Value     float64   `json:"value,omitempty"` // hashrate or temperature behind the condition
+	Since     time.Time `json:"since"`           // when the condition was first seen
+	Action    string    `json:"action,omitempty"`
+	Attempt   int       `json:"attempt,omitempty"`
+	DryRun    bool      `json:"dry_run,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Alert turns a notify step or an exhausted playbook into an alert event
+// for the notifiers
+func (e Entry) Alert() alert.Event {
+	a := alert.Event{
+		Time: e.Time, Status: alert.StatusFiring, Rule: e.Playbook, Severity: alert.SeverityWarning,
+		Host: e.Host, Model: e.Model, Condition: e.Condition, Value: e.Value, Since: e.Since,
+	}
+	if e.Event == EventExhausted {
+		a.Severity = alert.SeverityCritical
+		a.Error = fmt.Sprintf("still failing after %d remediation attempts", e.Attempt)
+	}
+	return a
+}
+
+// State is the progress of one playbook on one miner. It is persisted
+// between runs so restarts do not reset cooldowns and attempt counts.
+type State struct {
+	Playbook   string    `json:"playbook"`
+	Host       string    `json:"host"`
+	Since      time.Time `json:"since"`
+	Attempts   int       `json:"attempts"`
+	LastAction time.Time `json:"last_action,omitempty"`
+	Exhausted  bool      `json:"exhausted,omitempty"`
+}
+
+// Engine decides the remediation steps of a fleet round by round
+type Engine struct {
+	config *Config
+	states map[string]*State
+	models map[string]string // last known model per host, for unreachable miners
+}
+
+// NewEngine creates an engine resuming from saved states
+func NewEngine(c *Config, states []State) *Engine {
+	e := &Engine{config: c, states: make(map[string]*State), models: make(map[string]string)}
+	for i := range states {
+		s := states[i]
+		e.states[s.Playbook+"/"+s.Host] = &s
+	}
+	return e
+}
+
+// States returns the playbooks in progress ordered by host
+func (e *Engine) States() []State {
+	states := make([]State, 0, len(e.states))
+	for _, s := range e.states {
+		states = append(states, *s)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].Host != states[j].Host {
+			return states[i].Host < states[j].Host
+		}
+		return states[i].Playbook < states[j].Playbook
+	})
+	return states
+}
+
+// Evaluate updates the playbooks with one round of health checks and
+// returns what happened. A miner gets at most one action per round, from
+// the first playbook in file order that is due, and no action within the
+// cooldown of its last one, whichever playbook took it. A condition of an
+// unreachable miner other than unreachable itself is unknown and neither
+// escalates nor recovers.
+func (e *Engine) Evaluate(now time.Time, health []*fleet.Health) []Entry {
+	var entries []Entry
+	for _, h := range health {
+		if h.Model != "" {
+			e.models[h.Host] = h.Model
+		}
+		model := e.models[h.Host]
+		conditions := h.Conditions(e.config.MaxTemp)
+		last := e.lastAction(h.Host)
+		acted := false
+
+		for i := range e.config.Playbooks {
+			p := &e.config.Playbooks[i]
+			if !p.applies(h.Host, model) {
+				continue
+			}
+			key := p.Name + "/" + h.Host
+			s := e.states[key]
+			entry := Entry{Time: now, Playbook: p.Name, Condition: p.Condition, Host: h.Host, Model: model, Value: valueOf(p.Condition, h)}
+
+			if !contains(conditions, p.Condition) {
+				if s != nil && (h.Reachable || p.Condition == fleet.ConditionUnreachable) {
+					entry.Event, entry.Since, entry.Attempt = EventRecovered, s.Since, s.Attempts
+					entries = append(entries, entry)
+					delete(e.states, key)
+				}
+				continue
+			}
+
+			if s == nil {
+				s = &State{Playbook: p.Name, Host: h.Host, Since: now}
+				e.states[key] = s
+			}
+			entry.Since = s.Since
+			if acted || s.Exhausted || (!last.IsZero() && now.Sub(last) < time.Duration(p.Cooldown)) {
+				continue
+			}
+			if s.Attempts >= p.MaxAttempts {
+				s.Exhausted = true
+				entry.Event, entry.Attempt = EventExhausted, s.Attempts
+				entries = append(entries, entry)
+				continue
+			}
+
+			s.Attempts++
+			s.LastAction, last, acted = now, now, true
+			entry.Event, entry.Action, entry.Attempt = EventAction, p.step(s.Attempts), s.Attempts
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// lastAction returns when any playbook last acted on host
+func (e *Engine) lastAction(host string) time.Time {
+	var last time.Time
+	for _, s := range e.states {
+		if s.Host == host && s.LastAction.After(last) {
+			last = s.LastAction
+		}
+	}
+	return last
+}
+
+func valueOf(condition string, h *fleet.Health) float64 {
+	switch condition {
+	case fleet.ConditionZeroHashrate:
+		return h.HashRate
+	case fleet.ConditionOverTemp:
+		return h.Temp
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,191 @@
+package remediate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/alert"
+	"github.com/sinkers/miner-cli/internal/fleet"
+)
+
+const testPlaybooks = `{
+  "max_temp": 85,
+  "playbooks": [
+    {"name": "no-hashrate", "condition": "zero_hashrate", "steps": ["restart", "reboot", "disable-board"], "cooldown": "10m", "max_attempts": 3},
+    {"name": "dead-pool", "condition": "pool_dead", "steps": ["restart", "notify"], "cooldown": "5m", "max_attempts": 2},
+    {"name": "offline", "condition": "unreachable", "steps": ["reboot"], "hosts": ["10.0.0.1-10.0.0.5"], "max_attempts": 1}
+  ],
+  "notifiers": [{"name": "hook", "type": "webhook", "url": "http://127.0.0.1/hook"}]
+}`
+
+func healthy(host string) *fleet.Health {
+	return &fleet.Health{Host: host, Model: "S19", Reachable: true, HashRate: 100, Temp: 70, PoolsAlive: true, PoolsKnown: true}
+}
+
+func mustParse(t *testing.T) *Config {
+	c, err := Parse([]byte(testPlaybooks))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return c
+}
+
+func TestParsePlaybooks(t *testing.T) {
+	c := mustParse(t)
+	if c.MaxTemp != 85 || len(c.Playbooks) != 3 || time.Duration(c.Playbooks[2].Cooldown) != DefaultCooldown {
+		t.Errorf("unexpected config: %+v", c)
+	}
+	if notifiers, err := c.NewNotifiers(); err != nil || len(notifiers) != 1 {
+		t.Errorf("unexpected notifiers: %v %v", notifiers, err)
+	}
+
+	invalid := map[string]string{
+		"condition": `{"playbooks": [{"name": "x", "condition": "slow", "steps": ["restart"]}]}`,
+		"step":      `{"playbooks": [{"name": "x", "condition": "over_temp", "steps": ["shutdown"]}]}`,
+		"no steps":  `{"playbooks": [{"name": "x", "condition": "over_temp"}]}`,
+		"duplicate": `{"playbooks": [{"name": "x", "condition": "over_temp", "steps": ["reboot"]}, {"name": "x", "condition": "pool_dead", "steps": ["restart"]}]}`,
+		"hosts":     `{"playbooks": [{"name": "x", "condition": "over_temp", "steps": ["reboot"], "hosts": ["10.0.0"]}]}`,
+		"notifier":  `{"playbooks": [], "notifiers": [{"name": "a", "type": "pager"}, {"name": "a", "type": "pager"}]}`,
+	}
+	for name, playbooks := range invalid {
+		if _, err := Parse([]byte(playbooks)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestEngineEscalation(t *testing.T) {
+	e := NewEngine(mustParse(t), nil)
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	at := func(m int) time.Time { return start.Add(time.Duration(m) * time.Minute) }
+	broken := healthy("10.0.0.9")
+	broken.HashRate = 0
+
+	var actions []string
+	for m := 0; m <= 40; m += 5 {
+		for _, entry := range e.Evaluate(at(m), []*fleet.Health{broken}) {
+			actions = append(actions, entry.Event+":"+entry.Action)
+		}
+	}
+	// restart, reboot and disable-board 10 minutes apart, then give up once
+	want := []string{"action:restart", "action:reboot", "action:disable-board", "exhausted:"}
+	if len(actions) != len(want) {
+		t.Fatalf("expected %v, got %v", want, actions)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, actions)
+		}
+	}
+
+	entries := e.Evaluate(at(45), []*fleet.Health{healthy("10.0.0.9")})
+	if len(entries) != 1 || entries[0].Event != EventRecovered || entries[0].Attempt != 3 || !entries[0].Since.Equal(at(0)) {
+		t.Fatalf("expected a recovery, got %+v", entries)
+	}
+	if len(e.States()) != 0 {
+		t.Errorf("expected no state left, got %+v", e.States())
+	}
+}
+
+func TestEngineOneActionPerHost(t *testing.T) {
+	e := NewEngine(mustParse(t), nil)
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	broken := healthy("10.0.0.9")
+	broken.HashRate, broken.PoolsAlive = 0, false
+
+	// both playbooks apply, only the first acts and every later action
+	// waits for the cooldown of the last one on the host
+	entries := e.Evaluate(start, []*fleet.Health{broken})
+	if len(entries) != 1 || entries[0].Playbook != "no-hashrate" {
+		t.Fatalf("expected one action, got %+v", entries)
+	}
+	if entries := e.Evaluate(start.Add(3*time.Minute), []*fleet.Health{broken}); len(entries) != 0 {
+		t.Fatalf("expected the cooldown to hold, got %+v", entries)
+	}
+	entries = e.Evaluate(start.Add(5*time.Minute), []*fleet.Health{broken})
+	if len(entries) != 1 || entries[0].Playbook != "dead-pool" || entries[0].Action != fleet.ActionRestart {
+		t.Fatalf("expected the pool restart, got %+v", entries)
+	}
+	if states := e.States(); len(states) != 2 || states[0].Attempts != 1 || states[1].Attempts != 1 {
+		t.Errorf("unexpected states: %+v", states)
+	}
+}
+
+func TestEngineUnreachable(t *testing.T) {
+	e := NewEngine(mustParse(t), nil)
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	broken := healthy("10.0.0.2")
+	broken.HashRate = 0
+	e.Evaluate(start, []*fleet.Health{broken})
+
+	// offline after the restart: zero_hashrate is unknown and stays, and
+	// the offline playbook waits for the cooldown of the restart
+	down := &fleet.Health{Host: "10.0.0.2", Error: "connection refused"}
+	if entries := e.Evaluate(start.Add(time.Minute), []*fleet.Health{down}); len(entries) != 0 {
+		t.Fatalf("expected nothing, got %+v", entries)
+	}
+	entries := e.Evaluate(start.Add(10*time.Minute), []*fleet.Health{down})
+	if len(entries) != 1 || entries[0].Playbook != "offline" || entries[0].Action != fleet.ActionReboot || entries[0].Model != "S19" {
+		t.Fatalf("expected the offline reboot, got %+v", entries)
+	}
+
+	// hosts outside the playbook range are left alone
+	if entries := e.Evaluate(start.Add(time.Hour), []*fleet.Health{{Host: "10.0.0.50"}}); len(entries) != 0 {
+		t.Fatalf("expected nothing outside the range, got %+v", entries)
+	}
+
+	// back up and healthy: both clear
+	entries = e.Evaluate(start.Add(30*time.Minute), []*fleet.Health{healthy("10.0.0.2")})
+	if len(entries) != 2 || entries[0].Event != EventRecovered || entries[1].Event != EventRecovered {
+		t.Fatalf("expected two recoveries, got %+v", entries)
+	}
+}
+
+func TestEngineResume(t *testing.T) {
+	c := mustParse(t)
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	saved := []State{{Playbook: "dead-pool", Host: "10.0.0.9", Since: start, Attempts: 2, LastAction: start}}
+	e := NewEngine(c, saved)
+
+	broken := healthy("10.0.0.9")
+	broken.PoolsAlive = false
+	if entries := e.Evaluate(start.Add(time.Minute), []*fleet.Health{broken}); len(entries) != 0 {
+		t.Fatalf("expected the saved cooldown to hold, got %+v", entries)
+	}
+	entries := e.Evaluate(start.Add(5*time.Minute), []*fleet.Health{broken})
+	if len(entries) != 1 || entries[0].Event != EventExhausted {
+		t.Fatalf("expected the attempts to be exhausted, got %+v", entries)
+	}
+	if a := entries[0].Alert(); a.Severity != alert.SeverityCritical || a.Rule != "dead-pool" || a.Error == "" {
+		t.Errorf("unexpected alert: %+v", a)
+	}
+	if entries := e.Evaluate(start.Add(time.Hour), []*fleet.Health{broken}); len(entries) != 0 {
+		t.Fatalf("expected no more entries, got %+v", entries)
+	}
+}
+
+func TestStore(t *testing.T) {
+	s := NewStore(t.TempDir())
+	if states, err := s.LoadStates(); err != nil || states != nil {
+		t.Fatalf("expected no states, got %v %v", states, err)
+	}
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	if err := s.SaveStates([]State{{Playbook: "p", Host: "10.0.0.1", Since: start, Attempts: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if states, err := s.LoadStates(); err != nil || len(states) != 1 || states[0].Attempts != 1 {
+		t.Fatalf("unexpected states: %v %v", states, err)
+	}
+
+	if err := s.Append(
+		Entry{Time: start, Event: EventAction, Host: "10.0.0.1", Action: "restart"},
+		Entry{Time: start.Add(time.Hour), Event: EventRecovered, Host: "10.0.0.1"},
+		Entry{Time: start.Add(time.Hour), Event: EventAction, Host: "10.0.0.2", Action: "reboot", Error: "timeout"},
+	); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := s.Entries(map[string]bool{"10.0.0.1": true}, start.Add(time.Minute))
+	if err != nil || len(entries) != 1 || entries[0].Event != EventRecovered {
+		t.Fatalf("unexpected entries: %+v %v", entries, err)
+	}
+}
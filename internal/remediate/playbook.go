@@ -0,0 +1,171 @@
+package remediate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/alert"
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/iprange"
+)
+
+// Defaults of a playbook
+const (
+	DefaultCooldown    = 10 * time.Minute
+	DefaultMaxAttempts = 3
+	DefaultMaxTemp     = 90
+)
+
+// Conditions lists the conditions a playbook can react to
+var Conditions = []string{fleet.ConditionUnreachable, fleet.ConditionZeroHashrate, fleet.ConditionPoolDead, fleet.ConditionOverTemp}
+
+// Actions lists the steps a playbook can take
+var Actions = []string{fleet.ActionRestart, fleet.ActionReboot, fleet.ActionDisableBoard, fleet.ActionNotify}
+
+// Playbook escalates through Steps while a miner stays in Condition: the
+// first attempt takes the first step, the next one the second and so on,
+// repeating the last step. Attempts are Cooldown apart and stop after
+// MaxAttempts, when a human is notified.
+type Playbook struct {
+	Name        string         `json:"name"`
+	Condition   string         `json:"condition"`
+	Steps       []string       `json:"steps"`
+	Cooldown    alert.Duration `json:"cooldown,omitempty"`
+	MaxAttempts int            `json:"max_attempts,omitempty"`
+	Hosts       []string       `json:"hosts,omitempty"` // IP ranges, all miners when empty
+	Model       string         `json:"model,omitempty"` // only miners of this model
+
+	hosts map[string]bool
+}
+
+// Config is a playbooks file. It is JSON, which YAML parsers read as well.
+type Config struct {
+	MaxTemp   float64                `json:"max_temp,omitempty"` // over_temp threshold in °C
+	Playbooks []Playbook             `json:"playbooks"`
+	Notifiers []alert.NotifierConfig `json:"notifiers,omitempty"`
+
+	alerting *alert.Config
+}
+
+// Load reads and validates a playbooks file
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read playbooks: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse reads and validates playbooks in their JSON form
+func Parse(data []byte) (*Config, error) {
+	c := &Config{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse playbooks: %w", err)
+	}
+	// the notifiers section has the same shape as in alert rules
+	alerting, err := alert.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	c.alerting = alerting
+	if err := c.compile(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewNotifiers builds the notifiers of the notify steps
+func (c *Config) NewNotifiers() ([]alert.Notifier, error) {
+	return alert.NewNotifiers(c.alerting)
+}
+
+func (c *Config) compile() error {
+	if c.MaxTemp == 0 {
+		c.MaxTemp = DefaultMaxTemp
+	}
+	if c.MaxTemp < 0 {
+		return fmt.Errorf("max_temp must be positive")
+	}
+
+	names := make(map[string]bool)
+	for i := range c.Playbooks {
+		p := &c.Playbooks[i]
+		if p.Name == "" {
+			return fmt.Errorf("playbook %d has no name", i+1)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("duplicate playbook %q", p.Name)
+		}
+		names[p.Name] = true
+		if err := p.compile(); err != nil {
+			return fmt.Errorf("playbook %q: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+func (p *Playbook) compile() error {
+	if !contains(Conditions, p.Condition) {
+		return fmt.Errorf("unknown condition %q, expected one of %s", p.Condition, strings.Join(Conditions, ", "))
+	}
+	if len(p.Steps) == 0 {
+		return fmt.Errorf("no steps")
+	}
+	for _, step := range p.Steps {
+		if !contains(Actions, step) {
+			return fmt.Errorf("unknown step %q, expected one of %s", step, strings.Join(Actions, ", "))
+		}
+	}
+	if p.Cooldown == 0 {
+		p.Cooldown = alert.Duration(DefaultCooldown)
+	}
+	if p.Cooldown < 0 {
+		return fmt.Errorf("cooldown must be positive")
+	}
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = DefaultMaxAttempts
+	}
+	if p.MaxAttempts < 0 {
+		return fmt.Errorf("max_attempts must be positive")
+	}
+
+	if len(p.Hosts) > 0 {
+		parsed, err := iprange.ParseMultipleRanges(p.Hosts)
+		if err != nil {
+			return fmt.Errorf("failed to parse hosts: %w", err)
+		}
+		p.hosts = make(map[string]bool)
+		for _, ip := range parsed.GetIPs() {
+			p.hosts[ip] = true
+		}
+	}
+	return nil
+}
+
+// applies reports whether the playbook covers a miner
+func (p *Playbook) applies(host, model string) bool {
+	if p.hosts != nil && !p.hosts[host] {
+		return false
+	}
+	return p.Model == "" || strings.EqualFold(p.Model, model)
+}
+
+// step returns the action of the given attempt, counting from 1
+func (p *Playbook) step(attempt int) string {
+	if attempt > len(p.Steps) {
+		return p.Steps[len(p.Steps)-1]
+	}
+	return p.Steps[attempt-1]
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package remediate
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	stateFile = "state.json"
+	auditFile = "audit.jsonl"
+)
+
+// DefaultDir returns where remediation state and the audit trail are kept
+func DefaultDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "remediation"
+	}
+	return filepath.Join(dir, "miner-cli", "remediation")
+}
+
+// Store keeps the playbook states and the append-only audit trail of
+// every remediation step
+type Store struct {
+	Dir string
+}
+
+// NewStore returns the store kept in dir
+func NewStore(dir string) *Store {
+	return &Store{Dir: dir}
+}
+
+// LoadStates reads the saved playbook states, none if there are none yet
+func (s *Store) LoadStates() ([]State, error) {
+	data, err := os.ReadFile(filepath.Join(s.Dir, stateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read remediation state: %w", err)
+	}
+	var states []State
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("failed to parse remediation state: %w", err)
+	}
+	return states, nil
+}
+
+// SaveStates replaces the saved playbook states
+func (s *Store) SaveStates(states []State) error {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create remediation directory: %w", err)
+	}
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal remediation state: %w", err)
+	}
+	// write then rename so an interrupted save keeps the previous state
+	path := filepath.Join(s.Dir, stateFile)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("failed to write remediation state: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to write remediation state: %w", err)
+	}
+	return nil
+}
+
+// Append adds entries to the audit trail
+func (s *Store) Append(entries ...Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create remediation directory: %w", err)
+	}
+
+	var buf []byte
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to marshal audit entry: %w", err)
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+
+	f, err := os.OpenFile(filepath.Join(s.Dir, auditFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open audit trail: %w", err)
+	}
+	_, err = f.Write(buf)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write audit trail: %w", err)
+	}
+	return nil
+}
+
+// Entries returns the audit trail since a time, zero for all, for some
+// hosts or all of them, oldest first. Unreadable lines are skipped.
+func (s *Store) Entries(hosts map[string]bool, since time.Time) ([]Entry, error) {
+	f, err := os.Open(filepath.Join(s.Dir, auditFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit trail: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if len(hosts) > 0 && !hosts[e.Host] {
+			continue
+		}
+		if !since.IsZero() && e.Time.Before(since) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit trail: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/sinkers/miner-cli/internal/client"
+	"github.com/sinkers/miner-cli/internal/fleet"
+)
+
+// Command builds the per-host task of a job from its parameters
+type Command func(opts fleet.Options, params map[string]string) (fleet.Task, error)
+
+// DefaultCommands returns the CGMiner API commands, run through
+// client.Client.ExecuteCommand, and the firmware independent fleet
+// commands that work on Braiins OS and vnish as well
+func DefaultCommands() map[string]Command {
+	commands := map[string]Command{
+		"health": fleetCommand(func(ctx context.Context, opts fleet.Options, host string) (interface{}, error) {
+			return fleet.CheckHealth(ctx, opts, host)
+		}),
+		"errors": fleetCommand(func(ctx context.Context, opts fleet.Options, host string) (interface{}, error) {
+			return fleet.GetErrors(ctx, opts, host)
+		}),
+		"cooling get": fleetCommand(func(ctx context.Context, opts fleet.Options, host string) (interface{}, error) {
+			return fleet.GetCooling(ctx, opts, host)
+		}),
+		"bos status": fleetCommand(func(ctx context.Context, opts fleet.Options, host string) (interface{}, error) {
+			return fleet.GetMinerStatus(ctx, opts, host)
+		}),
+		"locate status": fleetCommand(func(ctx context.Context, opts fleet.Options, host string) (interface{}, error) {
+			return fleet.GetLocate(ctx, opts, host)
+		}),
+		"locate on": fleetCommand(func(ctx context.Context, opts fleet.Options, host string) (interface{}, error) {
+			return fleet.SetLocate(ctx, opts, host, true)
+		}),
+		"locate off": fleetCommand(func(ctx context.Context, opts fleet.Options, host string) (interface{}, error) {
+			return fleet.SetLocate(ctx, opts, host, false)
+		}),
+		"restart-mining": fleetCommand(func(ctx context.Context, opts fleet.Options, host string) (interface{}, error) {
+			return nil, fleet.Remediate(ctx, opts, host, fleet.ActionRestart)
+		}),
+		"reboot": fleetCommand(func(ctx context.Context, opts fleet.Options, host string) (interface{}, error) {
+			return nil, fleet.Remediate(ctx, opts, host, fleet.ActionReboot)
+		}),
+	}
+	for _, name := range client.GetAvailableCommands() {
+		commands[name] = cgminerCommand(name)
+	}
+	return commands
+}
+
+func fleetCommand(run func(ctx context.Context, opts fleet.Options, host string) (interface{}, error)) Command {
+	return func(opts fleet.Options, params map[string]string) (fleet.Task, error) {
+		return func(ctx context.Context, host string) (interface{}, error) {
+			return run(ctx, opts, host)
+		}, nil
+	}
+}
+
+// cgminerCommand sends command with the same parameters as the CLI flags
+func cgminerCommand(command string) Command {
+	return func(opts fleet.Options, params map[string]string) (fleet.Task, error) {
+		args := make(map[string]interface{})
+		switch command {
+		case "switchpool", "enablepool", "disablepool", "removepool":
+			pool, err := strconv.Atoi(params["pool"])
+			if err != nil {
+				return nil, fmt.Errorf("%s requires a numeric pool parameter", command)
+			}
+			args["pool"] = pool
+		case "addpool":
+			for _, name := range []string{"url", "user", "pass"} {
+				if params[name] == "" {
+					return nil, fmt.Errorf("addpool requires url, user and pass parameters")
+				}
+				args[name] = params[name]
+			}
+		case "custom":
+			if params["cmd"] == "" {
+				return nil, fmt.Errorf("custom requires a cmd parameter")
+			}
+			args["cmd"] = params["cmd"]
+		}
+
+		c := client.NewClient(opts.Timeout, 1)
+		return func(ctx context.Context, host string) (interface{}, error) {
+			r := c.ExecuteCommand(ctx, []string{host}, opts.Port, command, args)[0]
+			if r.Error != "" {
+				return nil, errors.New(r.Error)
+			}
+			return r.Response, nil
+		}, nil
+	}
+}
+
+// isCGMinerCommand reports whether name is sent to the CGMiner API, which
+// answers on the --port of every firmware
+func isCGMinerCommand(name string) bool {
+	for _, command := range client.GetAvailableCommands() {
+		if name == command {
+			return true
+		}
+	}
+	return false
+}
+
+// commandNames returns the command names in order
+func commandNames(commands map[string]Command) []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/client"
+)
+
+// Job statuses
+const (
+	StatusRunning = "running"
+	StatusDone    = "done"
+)
+
+// Job is one command run against a set of miners
+type Job struct {
+	ID        string            `json:"id"`
+	Command   string            `json:"command"`
+	User      string            `json:"user"`
+	Targets   []string          `json:"targets,omitempty"`
+	Params    map[string]string `json:"params,omitempty"` // secrets redacted
+	Status    string            `json:"status"`
+	Hosts     int               `json:"hosts"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Created   time.Time         `json:"created"`
+	Finished  *time.Time        `json:"finished,omitempty"`
+	Results   []client.Result   `json:"results,omitempty"`
+}
+
+// job is a Job updated by its runner while clients read it
+type job struct {
+	mu      sync.Mutex
+	j       Job
+	changed chan struct{} // closed and replaced on every update
+}
+
+func newJob(command, user string, targets []string, params map[string]string, hosts int) *job {
+	return &job{
+		j: Job{
+			ID:      newID(),
+			Command: command,
+			User:    user,
+			Targets: targets,
+			Params:  params,
+			Status:  StatusRunning,
+			Hosts:   hosts,
+			Created: time.Now(),
+		},
+		changed: make(chan struct{}),
+	}
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// add records the result of one host
+func (j *job) add(r client.Result) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.j.Results = append(j.j.Results, r)
+	if r.Error == "" {
+		j.j.Succeeded++
+	} else {
+		j.j.Failed++
+	}
+	j.notify()
+}
+
+func (j *job) finish() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	j.j.Status, j.j.Finished = StatusDone, &now
+	j.notify()
+}
+
+func (j *job) notify() {
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// since returns the results after the first n, whether the job is done and
+// a channel closed on the next update
+func (j *job) since(n int) ([]client.Result, bool, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	results := append([]client.Result(nil), j.j.Results[n:]...)
+	return results, j.j.Status == StatusDone, j.changed
+}
+
+// user is the client who started the job, which never changes
+func (j *job) user() string {
+	return j.j.User
+}
+
+func (j *job) done() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.j.Status == StatusDone
+}
+
+// snapshot copies the job, without the results unless withResults
+func (j *job) snapshot(withResults bool) *Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	s := j.j
+	s.Results = nil
+	if withResults {
+		s.Results = append([]client.Result{}, j.j.Results...)
+	}
+	return &s
+}
+
+// jobStore keeps the running jobs and the last finished ones
+type jobStore struct {
+	mu   sync.Mutex
+	max  int
+	jobs []*job // oldest first
+}
+
+// add keeps j unless its user already runs maxRunning jobs
+func (s *jobStore) add(j *job, maxRunning int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	running := 0
+	for _, job := range s.jobs {
+		if job.user() == j.user() && !job.done() {
+			running++
+		}
+	}
+	if running >= maxRunning {
+		return false
+	}
+	s.jobs = append(s.jobs, j)
+
+	finished := 0
+	for _, job := range s.jobs {
+		if job.done() {
+			finished++
+		}
+	}
+	kept := s.jobs[:0]
+	for _, job := range s.jobs {
+		if finished > s.max && job.done() {
+			finished--
+			continue
+		}
+		kept = append(kept, job)
+	}
+	s.jobs = kept
+	return true
+}
+
+func (s *jobStore) get(id string) *job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		if j.j.ID == id {
+			return j
+		}
+	}
+	return nil
+}
+
+// list returns the jobs of the users visible passes, newest first
+func (s *jobStore) list(visible func(user string) bool) []*Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]*Job, 0, len(s.jobs))
+	for i := len(s.jobs) - 1; i >= 0; i-- {
+		if visible(s.jobs[i].user()) {
+			jobs = append(jobs, s.jobs[i].snapshot(false))
+		}
+	}
+	return jobs
+}
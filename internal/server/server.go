@@ -0,0 +1,475 @@
+// Package server exposes the fleet commands as a REST API: jobs started
+// with a POST, their results polled or streamed with server-sent events,
+// and the inventory.
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/audit"
+	"github.com/sinkers/miner-cli/internal/client"
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/inventory"
+	"github.com/sinkers/miner-cli/internal/iprange"
+	"github.com/sinkers/miner-cli/internal/policy"
+)
+
+// Config holds the settings of a Server
+type Config struct {
+	Options fleet.Options
+	Workers int
+	Timeout time.Duration // per host, as --timeout
+
+	// Tokens maps API tokens to the client names used for the policy and
+	// the audit log, as "api:<name>"
+	Tokens map[string]string
+
+	Inventory *inventory.Inventory // optional, for racks and GET inventory
+	Policy    *policy.Config       // optional
+	ReadOnly  bool
+
+	// Writers names the clients that may run write jobs without a policy;
+	// the others may only read. With a policy the roles decide.
+	Writers map[string]bool
+	// Admins names the clients that see the jobs of every client; the
+	// others only see their own
+	Admins map[string]bool
+
+	// Operator is the role of who started the server; no client may run
+	// what it does not allow
+	Operator policy.Role
+
+	Audit      *audit.Logger // optional, records write jobs
+	MaxJobs    int           // finished jobs kept, 100 by default
+	MaxHosts   int           // miners per job, DefaultMaxHosts by default
+	MaxRunning int           // jobs running at once per client, DefaultMaxRunning by default
+
+	// Commands defaults to DefaultCommands
+	Commands map[string]Command
+}
+
+// DefaultMaxHosts is the default limit of miners per job, a /16
+const DefaultMaxHosts = 65536
+
+// DefaultMaxRunning is the default limit of jobs a client runs at once
+const DefaultMaxRunning = 4
+
+// ErrTooManyJobs is returned when a client already runs MaxRunning jobs
+var ErrTooManyJobs = errors.New("too many running jobs, wait for one to finish")
+
+// maxRequestBody limits the size of a job request
+const maxRequestBody = 1 << 20
+
+// Request starts a job
+type Request struct {
+	Command  string            `json:"command"`
+	IPs      []string          `json:"ips,omitempty"`   // IP ranges as -i
+	Racks    []string          `json:"racks,omitempty"` // inventory racks
+	Firmware string            `json:"firmware,omitempty"`
+	Params   map[string]string `json:"params,omitempty"`
+}
+
+// CommandInfo describes a command of the API
+type CommandInfo struct {
+	Name  string `json:"name"`
+	Write bool   `json:"write"`
+}
+
+// Server runs API jobs
+type Server struct {
+	c    Config
+	jobs *jobStore
+	ctx  context.Context // cancels the running jobs
+}
+
+// New creates a server; jobs run until ctx is cancelled
+func New(ctx context.Context, c Config) *Server {
+	if c.Workers <= 0 {
+		c.Workers = 10
+	}
+	if c.MaxJobs <= 0 {
+		c.MaxJobs = 100
+	}
+	if c.MaxHosts <= 0 {
+		c.MaxHosts = DefaultMaxHosts
+	}
+	if c.MaxRunning <= 0 {
+		c.MaxRunning = DefaultMaxRunning
+	}
+	if c.Commands == nil {
+		c.Commands = DefaultCommands()
+	}
+	return &Server{c: c, jobs: &jobStore{max: c.MaxJobs}, ctx: ctx}
+}
+
+// Handler returns the API routes behind token authentication
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/commands", s.handleCommands)
+	mux.HandleFunc("/api/v1/jobs", s.handleJobs)
+	mux.HandleFunc("/api/v1/jobs/", s.handleJob)
+	mux.HandleFunc("/api/v1/inventory", s.handleInventory)
+	return s.authenticate(mux)
+}
+
+type userKey struct{}
+
+// authenticate accepts "Authorization: Bearer <token>"
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		token := strings.TrimPrefix(header, "Bearer ")
+		for t, name := range s.c.Tokens {
+			if token != header && token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+				ctx := context.WithValue(r.Context(), userKey{}, "api:"+name)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+		}
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, "missing or invalid API token")
+	})
+}
+
+func apiUser(r *http.Request) string {
+	user, _ := r.Context().Value(userKey{}).(string)
+	return user
+}
+
+// clientName is the name of the token of an API user
+func clientName(user string) string {
+	return strings.TrimPrefix(user, "api:")
+}
+
+// sees reports whether user may see the jobs of owner
+func (s *Server) sees(user, owner string) bool {
+	return user == owner || s.c.Admins[clientName(user)]
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	return false
+}
+
+func (s *Server) handleCommands(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	var commands []CommandInfo
+	for _, name := range commandNames(s.c.Commands) {
+		commands = append(commands, CommandInfo{Name: name, Write: policy.IsWrite(name, "")})
+	}
+	writeJSON(w, http.StatusOK, commands)
+}
+
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+	if r.Method == http.MethodGet {
+		user := apiUser(r)
+		writeJSON(w, http.StatusOK, s.jobs.list(func(owner string) bool { return s.sees(user, owner) }))
+		return
+	}
+
+	var req Request
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse request: %v", err))
+		return
+	}
+	job, err := s.start(apiUser(r), req)
+	if err != nil {
+		status := http.StatusBadRequest
+		var denied *policy.DeniedError
+		if errors.As(err, &denied) {
+			status = http.StatusForbidden
+		} else if errors.Is(err, ErrTooManyJobs) {
+			status = http.StatusTooManyRequests
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+	w.Header().Set("Location", "/api/v1/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	id, events := strings.TrimPrefix(r.URL.Path, "/api/v1/jobs/"), false
+	if strings.HasSuffix(id, "/events") {
+		id, events = strings.TrimSuffix(id, "/events"), true
+	}
+	// the jobs of other clients are not found rather than forbidden
+	j := s.jobs.get(id)
+	if j != nil && !s.sees(apiUser(r), j.user()) {
+		j = nil
+	}
+	if j == nil {
+		writeError(w, http.StatusNotFound, "unknown job "+id)
+		return
+	}
+	if events {
+		s.streamJob(w, r, j)
+		return
+	}
+	writeJSON(w, http.StatusOK, j.snapshot(true))
+}
+
+// streamJob sends every result of job, earlier ones first, as a "result"
+// event and the finished job without results as a "done" event
+func (s *Server) streamJob(w http.ResponseWriter, r *http.Request, j *job) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	sent := 0
+	for {
+		results, done, changed := j.since(sent)
+		for _, result := range results {
+			writeEvent(w, "result", result)
+		}
+		sent += len(results)
+		if done {
+			writeEvent(w, "done", j.snapshot(false))
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event string, v interface{}) {
+	data, _ := json.Marshal(v)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
+
+// InventoryResponse lists the racks and miners of the inventory
+type InventoryResponse struct {
+	Racks  []string          `json:"racks"`
+	Miners []inventory.Miner `json:"miners"`
+}
+
+func (s *Server) handleInventory(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	if s.c.Inventory == nil {
+		writeError(w, http.StatusNotFound, "no inventory loaded")
+		return
+	}
+	resp := InventoryResponse{Racks: s.c.Inventory.Racks(), Miners: []inventory.Miner{}}
+	rack := r.URL.Query().Get("rack")
+	for _, m := range s.c.Inventory.Miners {
+		if rack == "" || m.Rack == rack {
+			resp.Miners = append(resp.Miners, m)
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// start checks the request against the policy and runs it in the
+// background
+func (s *Server) start(user string, req Request) (*Job, error) {
+	command, ok := s.c.Commands[req.Command]
+	if !ok {
+		return nil, fmt.Errorf("unknown command %q", req.Command)
+	}
+
+	if err := s.authorize(user, req); err != nil {
+		return nil, err
+	}
+
+	hosts, err := s.hosts(req)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := s.c.Options
+	if req.Firmware != "" {
+		if err := fleet.ValidateFirmware(req.Firmware, fleet.FirmwareAuto, fleet.FirmwareCGMiner, fleet.FirmwareBraiins, fleet.FirmwareVnish); err != nil {
+			return nil, err
+		}
+		opts.Firmware = req.Firmware
+	}
+	task, err := command(opts, req.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	port := opts.ResultPort()
+	if isCGMinerCommand(req.Command) {
+		port = opts.Port
+	}
+
+	j := newJob(req.Command, user, targets(req), redactParams(req.Params), len(hosts))
+	if !s.jobs.add(j, s.c.MaxRunning) {
+		return nil, ErrTooManyJobs
+	}
+
+	go func() {
+		perHost := s.c.Timeout + fleet.DetectBudget(opts.Firmware, s.c.Timeout)
+		ctx, cancel := context.WithTimeout(s.ctx, perHost*time.Duration(len(hosts)/s.c.Workers+1))
+		defer cancel()
+
+		results := fleet.NewRunner(s.c.Workers).Stream(ctx, hosts, port, req.Command, task, j.add)
+		if policy.IsWrite(req.Command, req.Params["cmd"]) {
+			s.audit(j.snapshot(false), results, "")
+		}
+		j.finish()
+	}()
+	return j.snapshot(false), nil
+}
+
+// authorize applies --read-only, the client's role and the operator's
+// role; refusals are audited. Without a policy only Writers may write.
+func (s *Server) authorize(user string, req Request) error {
+	role := policy.Role{ReadOnly: !s.c.Writers[clientName(user)]}
+	if s.c.Policy != nil {
+		_, role = s.c.Policy.RoleFor(user)
+	}
+	err := role.Check(req.Command, req.Params["cmd"], s.c.ReadOnly)
+	if err == nil {
+		var denied *policy.DeniedError
+		if errors.As(s.c.Operator.Check(req.Command, req.Params["cmd"], false), &denied) {
+			err = &policy.DeniedError{Command: denied.Command, Reason: "beyond the server operator's role, " + denied.Reason}
+		}
+	}
+	if err != nil {
+		s.audit(newJob(req.Command, user, targets(req), redactParams(req.Params), 0).snapshot(false), nil, err.Error())
+	}
+	return err
+}
+
+func (s *Server) audit(job *Job, results []client.Result, runErr string) {
+	if s.c.Audit == nil {
+		return
+	}
+	r := audit.NewRecord(job.Command)
+	r.User = job.User
+	r.Targets = job.Targets
+	for name, value := range job.Params {
+		r.SetFlag(name, value)
+	}
+	for _, result := range results {
+		r.AddOutcome(result.IP, result.Error)
+	}
+	r.Error = runErr
+	if err := s.c.Audit.Log(r); err != nil {
+		log.Printf("Failed to write the audit record of job %s: %v", job.ID, err)
+	}
+}
+
+// hosts expands the IP ranges and inventory racks of req
+func (s *Server) hosts(req Request) ([]string, error) {
+	if len(req.IPs) == 0 && len(req.Racks) == 0 {
+		return nil, fmt.Errorf("no IP ranges or racks specified")
+	}
+
+	seen := make(map[string]bool)
+	var hosts []string
+	add := func(ip string) {
+		if !seen[ip] {
+			seen[ip] = true
+			hosts = append(hosts, ip)
+		}
+	}
+
+	if len(req.IPs) > 0 {
+		// refuse huge ranges before expanding them
+		size, err := iprange.Size(req.IPs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse IP ranges: %w", err)
+		}
+		if size > uint64(s.c.MaxHosts) {
+			return nil, fmt.Errorf("the IP ranges cover %d addresses, more than the limit of %d", size, s.c.MaxHosts)
+		}
+		ipRange, err := iprange.ParseMultipleRanges(req.IPs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse IP ranges: %w", err)
+		}
+		for _, ip := range ipRange.GetIPs() {
+			add(ip)
+		}
+	}
+	if len(req.Racks) > 0 {
+		if s.c.Inventory == nil {
+			return nil, fmt.Errorf("racks need an inventory")
+		}
+		for _, rack := range req.Racks {
+			found := false
+			for _, m := range s.c.Inventory.Miners {
+				if m.Rack == rack {
+					add(m.IP)
+					found = true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("unknown rack %q", rack)
+			}
+		}
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no valid IPs in specified ranges")
+	}
+	if len(hosts) > s.c.MaxHosts {
+		return nil, fmt.Errorf("the job targets %d miners, more than the limit of %d", len(hosts), s.c.MaxHosts)
+	}
+	return hosts, nil
+}
+
+// targets lists the IP ranges and racks of req, racks as "rack:<name>"
+func targets(req Request) []string {
+	targets := append([]string{}, req.IPs...)
+	for _, rack := range req.Racks {
+		targets = append(targets, "rack:"+rack)
+	}
+	return targets
+}
+
+func redactParams(params map[string]string) map[string]string {
+	if len(params) == 0 {
+		return nil
+	}
+	redacted := make(map[string]string)
+	for name, value := range params {
+		redacted[name] = audit.Redact(name, value)
+	}
+	return redacted
+}
@@ -0,0 +1,375 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sinkers/miner-cli/internal/audit"
+	"github.com/sinkers/miner-cli/internal/fleet"
+	"github.com/sinkers/miner-cli/internal/inventory"
+	"github.com/sinkers/miner-cli/internal/policy"
+)
+
+func echoCommand(opts fleet.Options, params map[string]string) (fleet.Task, error) {
+	return func(ctx context.Context, host string) (interface{}, error) {
+		if host == "10.0.0.3" {
+			return nil, fmt.Errorf("connection refused")
+		}
+		return map[string]string{"host": host, "pool": params["pool"]}, nil
+	}, nil
+}
+
+func newTestServer(t *testing.T, c Config) *httptest.Server {
+	t.Helper()
+	c.Tokens = map[string]string{"secret": "dashboard", "other": "grafana"}
+	c.Timeout = time.Second
+	if c.Commands == nil {
+		c.Commands = map[string]Command{"summary": echoCommand, "switchpool": echoCommand}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := httptest.NewServer(New(ctx, c).Handler())
+	t.Cleanup(func() {
+		srv.Close()
+		cancel()
+	})
+	return srv
+}
+
+func do(t *testing.T, srv *httptest.Server, method, path, body string, v interface{}) int {
+	t.Helper()
+	return doAs(t, srv, "secret", method, path, body, v)
+}
+
+func doAs(t *testing.T, srv *httptest.Server, token, method, path, body string, v interface{}) int {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("failed to decode %s %s: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func waitJob(t *testing.T, srv *httptest.Server, id string) *Job {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		var job Job
+		do(t, srv, http.MethodGet, "/api/v1/jobs/"+id, "", &job)
+		if job.Status == StatusDone {
+			return &job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return nil
+}
+
+func TestAuthentication(t *testing.T) {
+	srv := newTestServer(t, Config{})
+
+	for _, header := range []string{"", "Bearer wrong", "secret"} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/jobs", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: status %d, want 401", header, resp.StatusCode)
+		}
+	}
+
+	var commands []CommandInfo
+	if status := do(t, srv, http.MethodGet, "/api/v1/commands", "", &commands); status != http.StatusOK {
+		t.Fatalf("commands: status %d", status)
+	}
+	if len(commands) != 2 || commands[0].Name != "summary" || commands[0].Write || !commands[1].Write {
+		t.Errorf("commands = %+v", commands)
+	}
+}
+
+func TestJobLifecycle(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	srv := newTestServer(t, Config{Audit: &audit.Logger{Path: logPath}, Writers: map[string]bool{"dashboard": true}})
+
+	var started Job
+	status := do(t, srv, http.MethodPost, "/api/v1/jobs", `{"command":"switchpool","ips":["10.0.0.1-10.0.0.3"],"params":{"pool":"1","password":"hunter2"}}`, &started)
+	if status != http.StatusAccepted {
+		t.Fatalf("status %d, want 202", status)
+	}
+	if started.ID == "" || started.Hosts != 3 || started.User != "api:dashboard" || started.Params["password"] != audit.Redacted {
+		t.Errorf("started = %+v", started)
+	}
+
+	job := waitJob(t, srv, started.ID)
+	if job.Succeeded != 2 || job.Failed != 1 || len(job.Results) != 3 {
+		t.Fatalf("job = %+v", job)
+	}
+	for _, r := range job.Results {
+		if r.Command != "switchpool" || (r.IP == "10.0.0.3") != (r.Error != "") {
+			t.Errorf("result = %+v", r)
+		}
+	}
+
+	var jobs []Job
+	do(t, srv, http.MethodGet, "/api/v1/jobs", "", &jobs)
+	if len(jobs) != 1 || jobs[0].ID != started.ID || jobs[0].Results != nil {
+		t.Errorf("jobs = %+v", jobs)
+	}
+
+	records, err := audit.Read(logPath, audit.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].User != "api:dashboard" || records[0].Command != "switchpool" ||
+		records[0].Flags["password"] != audit.Redacted || records[0].Failed != 1 {
+		t.Errorf("records = %+v", records)
+	}
+
+	var custom Job
+	do(t, srv, http.MethodPost, "/api/v1/jobs", `{"command":"switchpool","ips":["10.0.0.1"],"params":{"cmd":"addpool|stratum+tcp://pool:3333,w1,hunter2"}}`, &custom)
+	waitJob(t, srv, custom.ID)
+	records, err = audit.Read(logPath, audit.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(custom.Params["cmd"], "hunter2") || len(records) != 2 || strings.Contains(records[1].Flags["cmd"], "hunter2") {
+		t.Errorf("custom params = %v, records = %+v", custom.Params, records)
+	}
+
+	var e map[string]string
+	if status := do(t, srv, http.MethodGet, "/api/v1/jobs/nope", "", &e); status != http.StatusNotFound {
+		t.Errorf("unknown job: status %d", status)
+	}
+}
+
+func TestStartErrors(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	p := &policy.Config{DefaultRole: "viewer", Roles: map[string]policy.Role{"viewer": {ReadOnly: true}}}
+	srv := newTestServer(t, Config{Policy: p, Audit: &audit.Logger{Path: logPath}, MaxHosts: 300})
+
+	tests := []struct {
+		body   string
+		status int
+	}{
+		{`{"command":"bogus","ips":["10.0.0.1"]}`, http.StatusBadRequest},
+		{`{"command":"summary"}`, http.StatusBadRequest},
+		{`{"command":"summary","racks":["A"]}`, http.StatusBadRequest},
+		{`{"command":"summary","ips":["10.0.0.1"],"firmware":"bogus"}`, http.StatusBadRequest},
+		{`{"command":"summary","ips":["0.0.0.0/0"]}`, http.StatusBadRequest},
+		{`{"command":"summary","ips":["10.0.0.0/24","10.0.1.0/24"]}`, http.StatusBadRequest},
+		{`{"command":"summary","ips":["` + strings.Repeat("1", 2<<20) + `"]}`, http.StatusBadRequest},
+		{`{"command":"switchpool","ips":["10.0.0.1"]}`, http.StatusForbidden},
+		{`not json`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		var e map[string]string
+		if status := do(t, srv, http.MethodPost, "/api/v1/jobs", tt.body, &e); status != tt.status || e["error"] == "" {
+			t.Errorf("%s: status %d %v, want %d", tt.body, status, e, tt.status)
+		}
+	}
+
+	records, err := audit.Read(logPath, audit.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Command != "switchpool" || !strings.Contains(records[0].Error, "may only read") {
+		t.Errorf("records = %+v", records)
+	}
+}
+
+func TestWritesNeedPolicyOrWriter(t *testing.T) {
+	srv := newTestServer(t, Config{Writers: map[string]bool{"dashboard": true}})
+
+	body := `{"command":"switchpool","ips":["10.0.0.1"],"params":{"pool":"1"}}`
+	var e map[string]string
+	if status := doAs(t, srv, "other", http.MethodPost, "/api/v1/jobs", body, &e); status != http.StatusForbidden {
+		t.Errorf("write job without a policy: status %d %v, want 403", status, e)
+	}
+	var started Job
+	if status := doAs(t, srv, "other", http.MethodPost, "/api/v1/jobs", `{"command":"summary","ips":["10.0.0.1"]}`, &started); status != http.StatusAccepted {
+		t.Errorf("read job without a policy: status %d, want 202", status)
+	}
+	if status := do(t, srv, http.MethodPost, "/api/v1/jobs", body, &started); status != http.StatusAccepted {
+		t.Errorf("write job of a writer: status %d, want 202", status)
+	}
+}
+
+func TestJobsScopedToClient(t *testing.T) {
+	srv := newTestServer(t, Config{Admins: map[string]bool{"grafana": true}})
+
+	var started Job
+	do(t, srv, http.MethodPost, "/api/v1/jobs", `{"command":"summary","ips":["10.0.0.1"]}`, &started)
+	var other Job
+	doAs(t, srv, "other", http.MethodPost, "/api/v1/jobs", `{"command":"summary","ips":["10.0.0.2"]}`, &other)
+	waitJob(t, srv, started.ID)
+
+	var jobs []Job
+	do(t, srv, http.MethodGet, "/api/v1/jobs", "", &jobs)
+	if len(jobs) != 1 || jobs[0].ID != started.ID {
+		t.Errorf("jobs of dashboard = %+v", jobs)
+	}
+	var e map[string]string
+	if status := do(t, srv, http.MethodGet, "/api/v1/jobs/"+other.ID, "", &e); status != http.StatusNotFound {
+		t.Errorf("job of another client: status %d, want 404", status)
+	}
+
+	// grafana is an admin
+	doAs(t, srv, "other", http.MethodGet, "/api/v1/jobs", "", &jobs)
+	if len(jobs) != 2 {
+		t.Errorf("jobs of the admin = %+v", jobs)
+	}
+	var job Job
+	if status := doAs(t, srv, "other", http.MethodGet, "/api/v1/jobs/"+started.ID, "", &job); status != http.StatusOK {
+		t.Errorf("job of another client for the admin: status %d, want 200", status)
+	}
+}
+
+func TestMaxRunning(t *testing.T) {
+	release := make(chan struct{})
+	slow := func(opts fleet.Options, params map[string]string) (fleet.Task, error) {
+		return func(ctx context.Context, host string) (interface{}, error) {
+			<-release
+			return host, nil
+		}, nil
+	}
+	srv := newTestServer(t, Config{Commands: map[string]Command{"summary": slow}, MaxRunning: 1})
+
+	body := `{"command":"summary","ips":["10.0.0.1"]}`
+	var started Job
+	if status := do(t, srv, http.MethodPost, "/api/v1/jobs", body, &started); status != http.StatusAccepted {
+		t.Fatalf("first job: status %d, want 202", status)
+	}
+	var e map[string]string
+	if status := do(t, srv, http.MethodPost, "/api/v1/jobs", body, &e); status != http.StatusTooManyRequests {
+		t.Errorf("second job: status %d %v, want 429", status, e)
+	}
+	var other Job
+	if status := doAs(t, srv, "other", http.MethodPost, "/api/v1/jobs", body, &other); status != http.StatusAccepted {
+		t.Errorf("job of another client: status %d, want 202", status)
+	}
+
+	close(release)
+	waitJob(t, srv, started.ID)
+	if status := do(t, srv, http.MethodPost, "/api/v1/jobs", body, &started); status != http.StatusAccepted {
+		t.Errorf("job after the first finished: status %d, want 202", status)
+	}
+}
+
+func TestResultPort(t *testing.T) {
+	opts := fleet.Options{Firmware: fleet.FirmwareVnish, Port: 4028, GRPCPort: 50051}
+	srv := newTestServer(t, Config{Options: opts, Commands: map[string]Command{"summary": echoCommand, "health": echoCommand}})
+
+	for command, want := range map[string]int{"summary": 4028, "health": 80} {
+		var started Job
+		do(t, srv, http.MethodPost, "/api/v1/jobs", `{"command":"`+command+`","ips":["10.0.0.1"]}`, &started)
+		job := waitJob(t, srv, started.ID)
+		if len(job.Results) != 1 || job.Results[0].Port != want {
+			t.Errorf("%s: results = %+v, want port %d", command, job.Results, want)
+		}
+	}
+}
+
+func TestOperatorRoleCapsClients(t *testing.T) {
+	// a read-only operator allowed to serve, clients falling back to admin
+	p := &policy.Config{DefaultRole: "admin", Roles: map[string]policy.Role{"admin": {}}}
+	operator := policy.Role{ReadOnly: true, Allow: []string{"serve"}}
+	srv := newTestServer(t, Config{Policy: p, Operator: operator})
+
+	var e map[string]string
+	if status := do(t, srv, http.MethodPost, "/api/v1/jobs", `{"command":"switchpool","ips":["10.0.0.1"],"params":{"pool":"1"}}`, &e); status != http.StatusForbidden {
+		t.Errorf("write job: status %d %v, want 403", status, e)
+	}
+	if !strings.Contains(e["error"], "operator") {
+		t.Errorf("error = %q", e["error"])
+	}
+
+	var started Job
+	if status := do(t, srv, http.MethodPost, "/api/v1/jobs", `{"command":"summary","ips":["10.0.0.1"]}`, &started); status != http.StatusAccepted {
+		t.Errorf("read job: status %d, want 202", status)
+	}
+}
+
+func TestEventsStream(t *testing.T) {
+	release := make(chan struct{})
+	slow := func(opts fleet.Options, params map[string]string) (fleet.Task, error) {
+		return func(ctx context.Context, host string) (interface{}, error) {
+			if host == "10.0.0.2" {
+				<-release
+			}
+			return host, nil
+		}, nil
+	}
+	srv := newTestServer(t, Config{Commands: map[string]Command{"summary": slow}})
+
+	var started Job
+	do(t, srv, http.MethodPost, "/api/v1/jobs", `{"command":"summary","ips":["10.0.0.1-10.0.0.2"]}`, &started)
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/jobs/"+started.ID+"/events", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	var events []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "event: ") {
+			events = append(events, strings.TrimPrefix(line, "event: "))
+			if len(events) == 1 {
+				close(release)
+			}
+		}
+	}
+	if strings.Join(events, ",") != "result,result,done" {
+		t.Errorf("events = %v", events)
+	}
+}
+
+func TestInventory(t *testing.T) {
+	inv, err := inventory.Parse(strings.NewReader("ip,rack,shelf\n10.0.1.1,A,1\n10.0.1.2,B,1\n10.0.1.3,A,2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newTestServer(t, Config{Inventory: inv})
+
+	var resp InventoryResponse
+	do(t, srv, http.MethodGet, "/api/v1/inventory?rack=A", "", &resp)
+	if len(resp.Racks) != 2 || len(resp.Miners) != 2 {
+		t.Errorf("inventory = %+v", resp)
+	}
+
+	var started Job
+	do(t, srv, http.MethodPost, "/api/v1/jobs", `{"command":"summary","racks":["A"],"ips":["10.0.1.1"]}`, &started)
+	job := waitJob(t, srv, started.ID)
+	if job.Hosts != 2 || strings.Join(job.Targets, ",") != "10.0.1.1,rack:A" {
+		t.Errorf("job = %+v", job)
+	}
+}
@@ -16,6 +16,7 @@ import (
 type Client struct {
 	baseURL    string
 	apiKey     string
+	token      string
 	httpClient *http.Client
 	debug      bool
 }
@@ -30,6 +31,13 @@ func WithAPIKey(apiKey string) Option {
 	}
 }
 
+// WithToken sets a bearer token obtained from Login
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
 // WithHTTPClient sets a custom HTTP client
 func WithHTTPClient(httpClient *http.Client) Option {
 	return func(c *Client) {
@@ -67,6 +75,17 @@ func NewClient(host string, opts ...Option) *Client {
 	return c
 }
 
+// APIError is returned when the vnish API responds with an HTTP error status
+type APIError struct {
+	StatusCode int
+	Message    string
+	JSON       bool // the body was a vnish JSON error description
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Message)
+}
+
 // doRequest performs an HTTP request with proper headers and error handling
 func (c *Client) doRequest(ctx context.Context, method, path string, body interface{}) ([]byte, error) {
 	var reqBody io.Reader
@@ -89,6 +108,9 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body interf
 	if c.apiKey != "" {
 		req.Header.Set("X-API-Key", c.apiKey)
 	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
 
 	if c.debug {
 		fmt.Printf("Request: %s %s\n", method, req.URL.String())
@@ -116,9 +138,9 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body interf
 	if resp.StatusCode >= 400 {
 		var errResp models.ErrDescr
 		if err := json.Unmarshal(respBody, &errResp); err != nil {
-			return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(respBody))
+			return nil, &APIError{StatusCode: resp.StatusCode, Message: string(respBody)}
 		}
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, errResp.Error)
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: errResp.Error, JSON: true}
 	}
 
 	return respBody, nil
@@ -435,6 +457,74 @@ func (c *Client) UpdateSettings(ctx context.Context, settings *models.Settings)
 	return err
 }
 
+// GetSettingsJSON retrieves the settings as sent by the miner, with the
+// fields models.Settings does not know
+func (c *Client) GetSettingsJSON(ctx context.Context) (json.RawMessage, error) {
+	respBody, err := c.doRequest(ctx, http.MethodGet, "/settings", nil)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(respBody) {
+		return nil, fmt.Errorf("failed to parse response: invalid JSON")
+	}
+	return respBody, nil
+}
+
+// UpdateSettingsPartial sends only the given settings, a partial settings
+// document; the miner keeps the others
+func (c *Client) UpdateSettingsPartial(ctx context.Context, partial map[string]interface{}) error {
+	_, err := c.doRequest(ctx, http.MethodPost, "/settings", partial)
+	return err
+}
+
+// GetPreset returns the autotune preset of the miner overclock settings
+func (c *Client) GetPreset(ctx context.Context) (string, error) {
+	respBody, err := c.doRequest(ctx, http.MethodGet, "/settings", nil)
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		Miner struct {
+			Overclock *models.OverclockSettings `json:"overclock"`
+		} `json:"miner"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if result.Miner.Overclock == nil {
+		return "", nil
+	}
+	return result.Miner.Overclock.Preset, nil
+}
+
+// SetPreset saves the autotune preset in the miner overclock settings and
+// sends the other overclock settings back unchanged
+func (c *Client) SetPreset(ctx context.Context, preset string) error {
+	respBody, err := c.doRequest(ctx, http.MethodGet, "/settings", nil)
+	if err != nil {
+		return err
+	}
+
+	var current struct {
+		Miner struct {
+			Overclock map[string]json.RawMessage `json:"overclock"`
+		} `json:"miner"`
+	}
+	if err := json.Unmarshal(respBody, &current); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	overclock := current.Miner.Overclock
+	if overclock == nil {
+		overclock = make(map[string]json.RawMessage)
+	}
+	overclock["preset"], _ = json.Marshal(preset)
+
+	body := map[string]interface{}{"miner": map[string]interface{}{"overclock": overclock}}
+	_, err = c.doRequest(ctx, http.MethodPost, "/settings", body)
+	return err
+}
+
 // BackupSettings backs up current settings
 func (c *Client) BackupSettings(ctx context.Context) (*models.BackupResponse, error) {
 	respBody, err := c.doRequest(ctx, http.MethodPost, "/settings/backup", nil)
@@ -630,6 +720,26 @@ func (c *Client) Lock(ctx context.Context, password string) error {
 	return err
 }
 
+// Login unlocks the miner with its password and returns a bearer token for
+// WithToken
+func (c *Client) Login(ctx context.Context, password string) (string, error) {
+	req := models.UnlockRequest{Password: password}
+	respBody, err := c.doRequest(ctx, http.MethodPost, "/unlock", req)
+	if err != nil {
+		return "", err
+	}
+
+	var result models.UnlockSuccess
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if result.Token == "" {
+		return "", fmt.Errorf("no token in login response")
+	}
+
+	return result.Token, nil
+}
+
 // LockOthers locks other miners
 func (c *Client) LockOthers(ctx context.Context, password string) error {
 	req := models.LockRequest{Password: password}
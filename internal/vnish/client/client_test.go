@@ -3,6 +3,7 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -398,6 +399,11 @@ func TestErrorHandling(t *testing.T) {
 			if !contains(err.Error(), tt.expectedError) {
 				t.Errorf("expected error containing '%s', got '%s'", tt.expectedError, err.Error())
 			}
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.statusCode {
+				t.Errorf("expected APIError with status %d, got %v", tt.statusCode, err)
+			}
 		})
 	}
 }
@@ -641,7 +647,40 @@ func TestContextCancellation(t *testing.T) {
 	}
 }
 
+func TestLogin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/unlock":
+			var req models.UnlockRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.Password != "secret" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			json.NewEncoder(w).Encode(models.UnlockSuccess{Token: "tok"})
+		case "/api/v1/apikeys":
+			if r.Header.Get("Authorization") != "Bearer tok" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode([]models.ApiKeysJsonItem{})
+		}
+	}))
+	defer server.Close()
+
+	token, err := NewClient(server.URL[7:]).Login(context.Background(), "secret")
+	if err != nil || token != "tok" {
+		t.Fatalf("Login = %q, %v", token, err)
+	}
+	if _, err := NewClient(server.URL[7:], WithToken(token)).GetAPIKeys(context.Background()); err != nil {
+		t.Errorf("token not sent: %v", err)
+	}
+	if _, err := NewClient(server.URL[7:]).Login(context.Background(), "wrong"); err == nil {
+		t.Error("expected an error for a wrong password")
+	}
+}
+
 // Helper function
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && s[:len(substr)] == substr
-}
\ No newline at end of file
+}
@@ -64,6 +64,17 @@ type SystemInfo struct {
 	Version     string `json:"version"`
 	Uptime      int64  `json:"uptime"`
 	LoadAverage []float64 `json:"load_average"`
+	System      *InfoSystem `json:"system,omitempty"`
+}
+
+type InfoSystem struct {
+	NetworkStatus NetworkStatus `json:"network_status"`
+}
+
+type NetworkStatus struct {
+	MAC      string `json:"mac"`
+	IP       string `json:"ip"`
+	Hostname string `json:"hostname"`
 }
 
 type ModelInfo struct {
@@ -170,6 +181,12 @@ type AdvancedSettings struct {
 	CustomFirmware string `json:"custom_firmware,omitempty"`
 }
 
+// OverclockSettings is the overclock section of the miner settings, where
+// the active autotune preset is kept
+type OverclockSettings struct {
+	Preset string `json:"preset,omitempty"` // autotune preset ID
+}
+
 type NetworkSettings struct {
 	DHCP       bool   `json:"dhcp"`
 	IPAddress  string `json:"ip_address,omitempty"`
@@ -272,6 +289,10 @@ type UnlockRequest struct {
 	Password string `json:"password"`
 }
 
+type UnlockSuccess struct {
+	Token string `json:"token"`
+}
+
 type LockStatus struct {
 	Locked   bool      `json:"locked"`
 	LockedAt time.Time `json:"locked_at,omitempty"`
@@ -297,6 +318,9 @@ type Status struct {
 	Fans        []int        `json:"fans"`
 	Errors      []string     `json:"errors,omitempty"`
 	Warnings    []string     `json:"warnings,omitempty"`
+	// Screen lock state; nil on firmware that does not report it
+	Unlocked      *bool  `json:"unlocked,omitempty"`
+	UnlockTimeout *int64 `json:"unlock_timeout,omitempty"`
 }
 
 // Backup/Restore